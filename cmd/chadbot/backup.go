@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/fipso/chadbot/internal/backup"
+)
+
+// runBackup handles "chadbot backup": creates an archive, or lists them with -list
+func runBackup(args []string) {
+	fs := flag.NewFlagSet("backup", flag.ExitOnError)
+	dbPath := fs.String("db", "chadbot.db", "SQLite database path")
+	configPath := fs.String("config", "", "Backup settings file (default ~/.config/chadbot/backup.toml)")
+	list := fs.Bool("list", false, "List archives instead of creating one")
+	fs.Parse(args)
+
+	manager, err := backup.NewManager(*configPath, *dbPath)
+	if err != nil {
+		log.Fatalf("[Backup] %v", err)
+	}
+
+	if *list {
+		archives, err := manager.List()
+		if err != nil {
+			log.Fatalf("[Backup] %v", err)
+		}
+		for _, info := range archives {
+			fmt.Printf("%s\t%d\t%s\n", info.Path, info.Size, info.CreatedAt.Local().Format(time.RFC3339))
+		}
+		return
+	}
+
+	info, err := manager.Create()
+	if err != nil {
+		log.Fatalf("[Backup] Backup failed: %v", err)
+	}
+	fmt.Println(info.Path)
+}
+
+// runRestore handles "chadbot restore <archive>": verifies an archive and swaps in its state
+func runRestore(args []string) {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	dbPath := fs.String("db", "chadbot.db", "SQLite database path to replace")
+	force := fs.Bool("force", false, "Restore a database with a newer schema than this build knows")
+	verify := fs.Bool("verify", false, "Only verify the archive")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: chadbot restore [flags] <archive>\n\nStop chadbot and its plugins before restoring.\n\nFlags:")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	archive := fs.Arg(0)
+
+	if *verify {
+		manifest, err := backup.Verify(archive)
+		if err != nil {
+			log.Fatalf("[Backup] Verification failed: %v", err)
+		}
+		fmt.Printf("OK: format version %d, schema version %d, %d files, created %s\n",
+			manifest.FormatVersion, manifest.SchemaVersion, len(manifest.Entries), manifest.CreatedAt.Local().Format(time.RFC3339))
+		return
+	}
+
+	manifest, err := backup.Restore(archive, backup.RestoreOptions{DBPath: *dbPath, Force: *force})
+	if err != nil {
+		log.Fatalf("[Backup] Restore failed: %v", err)
+	}
+	fmt.Printf("Restored %d files from the backup created %s\n", len(manifest.Entries), manifest.CreatedAt.Local().Format(time.RFC3339))
+}
@@ -5,17 +5,58 @@ import (
 	"flag"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 
+	"github.com/fipso/chadbot/internal/plugin"
 	"github.com/fipso/chadbot/internal/server"
 )
 
 func main() {
+	// Subcommands that work on the data of a server, which doesn't need to run
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "backup":
+			runBackup(os.Args[2:])
+			return
+		case "restore":
+			runRestore(os.Args[2:])
+			return
+		case "secret":
+			runSecret(os.Args[2:])
+			return
+		case "passwd":
+			runPasswd(os.Args[2:])
+			return
+		case "plugin-token":
+			runPluginToken(os.Args[2:])
+			return
+		}
+	}
+
 	socket := flag.String("socket", server.DefaultSocket, "Unix socket path for plugin IPC")
+	socketMode := flag.String("socket-mode", "0660", "File mode of the plugin socket (octal)")
+	socketGroup := flag.String("socket-group", "", "Group of the plugin socket (default the backend's group)")
 	httpAddr := flag.String("http", ":8080", "HTTP/WebSocket listen address")
 	dbPath := flag.String("db", "chadbot.db", "SQLite database path")
-	openaiKey := flag.String("openai-key", "", "OpenAI API key (or set OPENAI_API_KEY)")
-	anthropicKey := flag.String("anthropic-key", "", "Anthropic API key (or set ANTHROPIC_API_KEY)")
+	openaiKey := flag.String("openai-key", "", "OpenAI API key, env:NAME, file:/path or encrypted value (or set OPENAI_API_KEY)")
+	anthropicKey := flag.String("anthropic-key", "", "Anthropic API key, env:NAME, file:/path or encrypted value (or set ANTHROPIC_API_KEY)")
 	defaultLLM := flag.String("llm", "", "Default LLM provider (openai or anthropic)")
+	toolSearch := flag.Bool("tool-search", false, "Only send core tools to the LLM and let it discover others via search_tools")
+	coreTools := flag.String("core-tools", "", "Comma-separated tool name globs always sent in tool search mode")
+	embedder := flag.String("embedder", "", "Embedder for semantic recall over chat history (openai or hash)")
+	embeddingURL := flag.String("embedding-url", "", "Base URL of an OpenAI-compatible embeddings API (default OpenAI)")
+	embeddingModel := flag.String("embedding-model", "", "Embedding model (default text-embedding-3-small)")
+	memoryExtract := flag.Bool("memory-extract", false, "Automatically extract long-term user memories after each turn")
+	knowledgeDir := flag.String("kb-dir", "", "Knowledge base directory (default ~/.config/chadbot/knowledge)")
+	blobDir := flag.String("blob-dir", "", "Attachment blob store directory (default ~/.config/chadbot/blobs)")
+	retentionConfig := flag.String("retention-config", "", "Retention policy file (default ~/.config/chadbot/retention.toml)")
+	backupConfig := flag.String("backup-config", "", "Backup settings file (default ~/.config/chadbot/backup.toml)")
+	accessConfig := flag.String("access-config", "", "Skill access policy file (default ~/.config/chadbot/access.toml)")
+	corsOrigins := flag.String("cors-origins", "", "Comma-separated origins allowed to call the API from a browser, or * (default same host)")
+	pluginAuth := flag.String("plugin-auth", plugin.AuthToken, "Comma-separated ways plugins may authenticate: token, peercred or none")
+	pluginUIDs := flag.String("plugin-uids", "", "Comma-separated user IDs allowed by peercred (default the backend's user)")
+	pluginDuplicates := flag.String("plugin-duplicates", plugin.DuplicateReject, "When a plugin registers under a connected plugin's name: reject or replace")
 	flag.Parse()
 
 	// Use /tmp for development if /var/run is not writable
@@ -33,6 +74,58 @@ func main() {
 		OpenAIKey:    *openaiKey,
 		AnthropicKey: *anthropicKey,
 		DefaultLLM:   *defaultLLM,
+		ToolSearch:   *toolSearch,
+
+		Embedder:       *embedder,
+		EmbeddingURL:   *embeddingURL,
+		EmbeddingModel: *embeddingModel,
+
+		MemoryExtract: *memoryExtract,
+		KnowledgeDir:  *knowledgeDir,
+		BlobDir:       *blobDir,
+
+		RetentionConfig: *retentionConfig,
+		BackupConfig:    *backupConfig,
+		AccessConfig:    *accessConfig,
+	}
+	if *corsOrigins != "" {
+		for _, o := range strings.Split(*corsOrigins, ",") {
+			if o = strings.TrimSpace(o); o != "" {
+				config.CORSOrigins = append(config.CORSOrigins, strings.TrimSuffix(o, "/"))
+			}
+		}
+	}
+	mode, err := strconv.ParseUint(*socketMode, 8, 32)
+	if err != nil || mode > 0777 {
+		log.Fatalf("[Main] Invalid -socket-mode %q", *socketMode)
+	}
+	config.SocketMode = os.FileMode(mode)
+	config.SocketGroup = *socketGroup
+	if config.PluginAuth.Methods, err = plugin.ParseAuthMethods(*pluginAuth); err != nil {
+		log.Fatalf("[Main] Invalid -plugin-auth: %v", err)
+	}
+	for _, u := range strings.Split(*pluginUIDs, ",") {
+		if u = strings.TrimSpace(u); u == "" {
+			continue
+		}
+		uid, err := strconv.ParseUint(u, 10, 32)
+		if err != nil {
+			log.Fatalf("[Main] Invalid -plugin-uids entry %q", u)
+		}
+		config.PluginAuth.UIDs = append(config.PluginAuth.UIDs, uint32(uid))
+	}
+	switch *pluginDuplicates {
+	case plugin.DuplicateReject, plugin.DuplicateReplace:
+		config.PluginAuth.Duplicates = *pluginDuplicates
+	default:
+		log.Fatalf("[Main] Invalid -plugin-duplicates %q (reject or replace)", *pluginDuplicates)
+	}
+	if *coreTools != "" {
+		for _, t := range strings.Split(*coreTools, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				config.CoreTools = append(config.CoreTools, t)
+			}
+		}
 	}
 
 	srv := server.New(config)
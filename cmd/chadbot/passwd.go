@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/fipso/chadbot/internal/auth"
+	"github.com/fipso/chadbot/internal/storage"
+)
+
+// runPasswd handles "chadbot passwd <username>": sets a user's password, read from stdin
+func runPasswd(args []string) {
+	fs := flag.NewFlagSet("passwd", flag.ExitOnError)
+	dbPath := fs.String("db", "chadbot.db", "SQLite database path")
+	create := fs.Bool("create", false, "Create the user if it doesn't exist")
+	role := fs.String("role", auth.RoleUser, "Role of a created user (admin or user)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: chadbot passwd [flags] <username> < password\n\nSets a password and signs out the user's sessions.\n\nFlags:")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	username := fs.Arg(0)
+
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && password == "" {
+		log.Fatalf("[Auth] No password on stdin: %v", err)
+	}
+	password = strings.TrimRight(password, "\r\n")
+
+	if err := storage.Init(*dbPath); err != nil {
+		log.Fatalf("[Auth] Failed to open database: %v", err)
+	}
+
+	user, err := storage.GetUserByUsername(username)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound) && *create:
+		if _, err := auth.CreateUser(username, password, *role); err != nil {
+			log.Fatalf("[Auth] %v", err)
+		}
+		fmt.Printf("Created user %s (%s)\n", username, *role)
+		return
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		log.Fatalf("[Auth] User %s does not exist (use -create)", username)
+	case err != nil:
+		log.Fatalf("[Auth] %v", err)
+	}
+
+	if err := auth.SetPassword(user, password, ""); err != nil {
+		log.Fatalf("[Auth] %v", err)
+	}
+	fmt.Printf("Changed the password of %s\n", username)
+}
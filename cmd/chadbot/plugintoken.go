@@ -0,0 +1,30 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	appconfig "github.com/fipso/chadbot/internal/config"
+)
+
+// runPluginToken handles "chadbot plugin-token <name>": prints the token of a plugin started by hand
+func runPluginToken(args []string) {
+	fs := flag.NewFlagSet("plugin-token", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: chadbot plugin-token <plugin name>\n\nPrints the token the plugin registers with, derived from ~/.config/chadbot/%s.\nPass it in %s; chadpm does this for the plugins it starts.\n",
+			appconfig.PluginKeyFile, appconfig.PluginTokenEnv)
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	token, err := appconfig.PluginToken(fs.Arg(0))
+	if err != nil {
+		log.Fatalf("[Plugin] %v", err)
+	}
+	fmt.Println(token)
+}
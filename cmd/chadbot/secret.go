@@ -0,0 +1,32 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	appconfig "github.com/fipso/chadbot/internal/config"
+)
+
+// runSecret handles "chadbot secret": encrypts a value read from stdin for use in config.toml or key flags
+func runSecret(args []string) {
+	fs := flag.NewFlagSet("secret", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: chadbot secret < value\n\nPrints the value encrypted with the key from %s or ~/.config/chadbot/%s.\n",
+			appconfig.SecretKeyEnv, appconfig.SecretKeyFile)
+	}
+	fs.Parse(args)
+
+	value, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && value == "" {
+		log.Fatalf("[Secret] No value on stdin: %v", err)
+	}
+	sealed, err := appconfig.EncryptSecret(strings.TrimRight(value, "\r\n"))
+	if err != nil {
+		log.Fatalf("[Secret] %v", err)
+	}
+	fmt.Println(sealed)
+}
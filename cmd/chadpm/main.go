@@ -16,6 +16,8 @@ import (
 	"time"
 
 	"github.com/fsnotify/fsnotify"
+
+	appconfig "github.com/fipso/chadbot/internal/config"
 )
 
 // ANSI color codes
@@ -208,8 +210,15 @@ func (pm *ProcessManager) StartPlugin(name string) error {
 	defer pm.mu.Unlock()
 
 	binPath := filepath.Join(pm.binDir, "plugins", name)
+
+	// The token lets the backend verify that the plugin is the one chadpm started
+	token, err := appconfig.PluginToken(name)
+	if err != nil {
+		return err
+	}
 	env := []string{
 		fmt.Sprintf("CHADBOT_SOCKET=%s", pm.socketPath),
+		fmt.Sprintf("%s=%s", appconfig.PluginTokenEnv, token),
 	}
 
 	proc, err := pm.startProcess(name, binPath, nil, pm.nextColor(), env)
@@ -320,7 +329,8 @@ func discoverPlugins(pluginsDir string) ([]string, error) {
 // buildChadbot compiles the chadbot binary
 func buildChadbot(binDir string) error {
 	binPath := filepath.Join(binDir, "chadbot")
-	cmd := exec.Command("go", "build", "-o", binPath, "./cmd/chadbot")
+	// FTS5 powers full-text search (knowledge base); without it search falls back to LIKE
+	cmd := exec.Command("go", "build", "-tags", "sqlite_fts5", "-o", binPath, "./cmd/chadbot")
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	return cmd.Run()
@@ -2,14 +2,27 @@ import { createRouter, createWebHistory } from 'vue-router'
 import ChatPage from './pages/ChatPage.vue'
 import StatusPage from './pages/StatusPage.vue'
 import SoulsPage from './pages/SoulsPage.vue'
+import LoginPage from './pages/LoginPage.vue'
+import { useAuthStore } from './stores/auth'
 
 const routes = [
   { path: '/', name: 'chat', component: ChatPage },
   { path: '/status', name: 'status', component: StatusPage },
-  { path: '/souls', name: 'souls', component: SoulsPage }
+  { path: '/souls', name: 'souls', component: SoulsPage },
+  { path: '/login', name: 'login', component: LoginPage, meta: { public: true } }
 ]
 
 export const router = createRouter({
   history: createWebHistory(),
   routes
 })
+
+// Every page but the login page needs a session
+router.beforeEach(async (to) => {
+  if (to.meta.public) return true
+  const auth = useAuthStore()
+  if (!auth.user && !(await auth.load())) {
+    return { name: 'login', query: { redirect: to.fullPath } }
+  }
+  return true
+})
@@ -6,7 +6,16 @@ export interface Chat {
   name: string
   created_at: string
   updated_at: string
-  messages: Message[]
+  last_message?: MessagePreview
+}
+
+export interface MessagePreview {
+  id: string
+  chat_id: string
+  role: 'user' | 'assistant' | 'plugin'
+  content: string  // Truncated
+  has_attachments: boolean
+  created_at: string
 }
 
 export interface Attachment {
@@ -20,6 +29,8 @@ export interface Attachment {
 export interface Message {
   id: string
   chat_id: string
+  parent_id?: string
+  siblings?: string[]  // Alternative versions incl. this one (when branched)
   role: 'user' | 'assistant' | 'plugin'
   content: string
   created_at: string
@@ -30,14 +41,63 @@ export interface Message {
   provider?: string
 }
 
+// Called when a request is rejected because the session ended
+let onUnauthorized: (() => void) | null = null
+
+export function setUnauthorizedHandler(handler: () => void) {
+  onUnauthorized = handler
+}
+
+// fetch with the session cookie, as the API runs on another port than the page
+async function apiFetch(input: string, init: RequestInit = {}): Promise<Response> {
+  const res = await fetch(input, { ...init, credentials: 'include' })
+  if (res.status === 401) onUnauthorized?.()
+  return res
+}
+
+// Auth API
+export interface User {
+  id: string
+  username: string
+  role: 'admin' | 'user'
+  created_at: string
+}
+
+export async function login(username: string, password: string): Promise<User> {
+  const res = await fetch(`${API_BASE}/api/auth/login`, {
+    method: 'POST',
+    credentials: 'include',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ username, password })
+  })
+  if (!res.ok) throw new Error(res.status === 401 ? 'Invalid username or password' : 'Failed to sign in')
+  return res.json()
+}
+
+export async function logout(): Promise<void> {
+  await apiFetch(`${API_BASE}/api/auth/logout`, { method: 'POST' })
+}
+
+// Returns the signed-in user, or null without a valid session
+export async function fetchMe(): Promise<User | null> {
+  const res = await fetch(`${API_BASE}/api/auth/me`, { credentials: 'include' })
+  if (res.status === 401) return null
+  if (!res.ok) throw new Error('Failed to fetch user')
+  return res.json()
+}
+
+export function blobUrl(hash: string): string {
+  return `${API_BASE}/api/blobs/${hash}`
+}
+
 export async function fetchChats(): Promise<Chat[]> {
-  const res = await fetch(`${API_BASE}/api/chats`)
+  const res = await apiFetch(`${API_BASE}/api/chats`)
   if (!res.ok) throw new Error('Failed to fetch chats')
   return res.json()
 }
 
 export async function createChat(name: string = 'New Chat'): Promise<Chat> {
-  const res = await fetch(`${API_BASE}/api/chats`, {
+  const res = await apiFetch(`${API_BASE}/api/chats`, {
     method: 'POST',
     headers: { 'Content-Type': 'application/json' },
     body: JSON.stringify({ name })
@@ -47,20 +107,39 @@ export async function createChat(name: string = 'New Chat'): Promise<Chat> {
 }
 
 export async function deleteChat(chatId: string): Promise<void> {
-  const res = await fetch(`${API_BASE}/api/chats/${chatId}`, {
+  const res = await apiFetch(`${API_BASE}/api/chats/${chatId}`, {
     method: 'DELETE'
   })
   if (!res.ok) throw new Error('Failed to delete chat')
 }
 
 export async function fetchChat(chatId: string): Promise<Chat> {
-  const res = await fetch(`${API_BASE}/api/chats/${chatId}`)
+  const res = await apiFetch(`${API_BASE}/api/chats/${chatId}`)
   if (!res.ok) throw new Error('Failed to fetch chat')
   return res.json()
 }
 
+export interface MessagePage {
+  chat_id: string
+  messages: Message[]  // Chronological order
+  has_more: boolean
+}
+
+export async function fetchMessages(
+  chatId: string,
+  opts: { before?: string; after?: string; limit?: number } = {}
+): Promise<MessagePage> {
+  const params = new URLSearchParams()
+  if (opts.before) params.set('before', opts.before)
+  if (opts.after) params.set('after', opts.after)
+  if (opts.limit) params.set('limit', String(opts.limit))
+  const res = await apiFetch(`${API_BASE}/api/chats/${chatId}/messages?${params}`)
+  if (!res.ok) throw new Error('Failed to fetch messages')
+  return res.json()
+}
+
 export async function renameChat(chatId: string, name: string): Promise<Chat> {
-  const res = await fetch(`${API_BASE}/api/chats/${chatId}`, {
+  const res = await apiFetch(`${API_BASE}/api/chats/${chatId}`, {
     method: 'PUT',
     headers: { 'Content-Type': 'application/json' },
     body: JSON.stringify({ name })
@@ -88,7 +167,7 @@ export interface ConfigField {
   key: string
   label: string
   description: string
-  type: 'bool' | 'string' | 'number' | 'string_array'
+  type: 'bool' | 'string' | 'number' | 'string_array' | 'secret'
   default_value: string
 }
 
@@ -112,13 +191,13 @@ export interface StatusResponse {
 }
 
 export async function fetchStatus(): Promise<StatusResponse> {
-  const res = await fetch(`${API_BASE}/api/status`)
+  const res = await apiFetch(`${API_BASE}/api/status`)
   if (!res.ok) throw new Error('Failed to fetch status')
   return res.json()
 }
 
 export async function setPluginConfig(pluginName: string, key: string, value: string): Promise<void> {
-  const res = await fetch(`${API_BASE}/api/plugins/${pluginName}/config`, {
+  const res = await apiFetch(`${API_BASE}/api/plugins/${pluginName}/config`, {
     method: 'PUT',
     headers: { 'Content-Type': 'application/json' },
     body: JSON.stringify({ key, value })
@@ -126,19 +205,46 @@ export async function setPluginConfig(pluginName: string, key: string, value: st
   if (!res.ok) throw new Error('Failed to set plugin config')
 }
 
+export interface PluginPermissions {
+  requested: string[]
+  approved: string[]
+  pending: string[]
+}
+
+export async function approvePluginPermissions(pluginName: string, approved: string[]): Promise<PluginPermissions> {
+  const res = await apiFetch(`${API_BASE}/api/plugins/${pluginName}/permissions`, {
+    method: 'PUT',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ approved })
+  })
+  if (!res.ok) throw new Error('Failed to approve plugin permissions')
+  return res.json()
+}
+
 export interface Provider {
   name: string
   is_default: boolean
 }
 
 export async function fetchProviders(): Promise<Provider[]> {
-  const res = await fetch(`${API_BASE}/api/providers`)
+  const res = await apiFetch(`${API_BASE}/api/providers`)
   if (!res.ok) throw new Error('Failed to fetch providers')
   return res.json()
 }
 
+// Sets the signed-in user's default provider ('' = instance default)
+export async function setDefaultProvider(name: string): Promise<Provider[]> {
+  const res = await apiFetch(`${API_BASE}/api/providers`, {
+    method: 'PUT',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ default: name })
+  })
+  if (!res.ok) throw new Error('Failed to set default provider')
+  return res.json()
+}
+
 export async function exportConfig(): Promise<Blob> {
-  const res = await fetch(`${API_BASE}/api/config/export`)
+  const res = await apiFetch(`${API_BASE}/api/config/export`)
   if (!res.ok) throw new Error('Failed to export config')
   return res.blob()
 }
@@ -146,51 +252,78 @@ export async function exportConfig(): Promise<Blob> {
 export async function importConfig(file: File): Promise<void> {
   const formData = new FormData()
   formData.append('file', file)
-  const res = await fetch(`${API_BASE}/api/config/import`, {
+  const res = await apiFetch(`${API_BASE}/api/config/import`, {
     method: 'POST',
     body: formData
   })
   if (!res.ok) throw new Error('Failed to import config')
 }
 
+// Chat export/import API
+export type ChatExportFormat = 'json' | 'md' | 'jsonl'
+
+export interface ChatImportResult {
+  imported: string[]
+  skipped: string[]
+}
+
+export async function exportChats(format: ChatExportFormat = 'json'): Promise<Blob> {
+  const res = await apiFetch(`${API_BASE}/api/chats/export?format=${format}`)
+  if (!res.ok) throw new Error('Failed to export chats')
+  return res.blob()
+}
+
+export async function importChats(file: File): Promise<ChatImportResult> {
+  const formData = new FormData()
+  formData.append('file', file)
+  const res = await apiFetch(`${API_BASE}/api/chats/import`, {
+    method: 'POST',
+    body: formData
+  })
+  if (!res.ok) throw new Error(await res.text() || 'Failed to import chats')
+  return res.json()
+}
+
 // Souls API
 export interface Soul {
   name: string
   content: string
+  shared: boolean // Visible to all users, only admins can change it
 }
 
 export async function fetchSouls(): Promise<Soul[]> {
-  const res = await fetch(`${API_BASE}/api/souls`)
+  const res = await apiFetch(`${API_BASE}/api/souls`)
   if (!res.ok) throw new Error('Failed to fetch souls')
   return res.json()
 }
 
 export async function fetchSoul(name: string): Promise<Soul> {
-  const res = await fetch(`${API_BASE}/api/souls/${encodeURIComponent(name)}`)
+  const res = await apiFetch(`${API_BASE}/api/souls/${encodeURIComponent(name)}`)
   if (!res.ok) throw new Error('Failed to fetch soul')
   return res.json()
 }
 
-export async function createSoul(name: string, content: string): Promise<void> {
-  const res = await fetch(`${API_BASE}/api/souls`, {
+export async function createSoul(name: string, content: string, shared = false): Promise<void> {
+  const res = await apiFetch(`${API_BASE}/api/souls`, {
     method: 'POST',
     headers: { 'Content-Type': 'application/json' },
-    body: JSON.stringify({ name, content })
+    body: JSON.stringify({ name, content, shared })
   })
   if (!res.ok) throw new Error('Failed to create soul')
 }
 
-export async function updateSoul(name: string, content: string): Promise<void> {
-  const res = await fetch(`${API_BASE}/api/souls/${encodeURIComponent(name)}`, {
+export async function updateSoul(name: string, content: string, shared = false): Promise<void> {
+  const res = await apiFetch(`${API_BASE}/api/souls/${encodeURIComponent(name)}`, {
     method: 'PUT',
     headers: { 'Content-Type': 'application/json' },
-    body: JSON.stringify({ content })
+    body: JSON.stringify({ content, shared })
   })
   if (!res.ok) throw new Error('Failed to update soul')
 }
 
-export async function deleteSoul(name: string): Promise<void> {
-  const res = await fetch(`${API_BASE}/api/souls/${encodeURIComponent(name)}`, {
+export async function deleteSoul(name: string, shared = false): Promise<void> {
+  const query = shared ? '?shared=true' : ''
+  const res = await apiFetch(`${API_BASE}/api/souls/${encodeURIComponent(name)}${query}`, {
     method: 'DELETE'
   })
   if (!res.ok) throw new Error('Failed to delete soul')
@@ -6,9 +6,11 @@ export interface WSMessage {
 export interface Attachment {
   type: string
   mime_type: string
-  data: string  // base64 encoded
+  hash?: string  // Blob served from /api/blobs/{hash}
+  size?: number
+  data?: string  // base64 encoded (legacy inline attachments)
   url?: string
-  name?: string
+  filename?: string
 }
 
 export interface ToolCallRecord {
@@ -18,6 +20,7 @@ export interface ToolCallRecord {
   result: string
   error?: string
   duration_ms: number
+  children?: ToolCallRecord[]  // Tool calls made by a delegated sub-agent
 }
 
 export interface ToolCallEvent {
@@ -25,15 +28,18 @@ export interface ToolCallEvent {
   chat_id: string
   tool_name: string
   tool_id: string
+  parent_id?: string  // Tool ID of the delegate_task call this belongs to
   arguments?: Record<string, string>
   result?: string
   error?: string
   duration_ms?: number
+  children?: ToolCallEvent[]  // Sub-agent events (client-side only)
 }
 
 export interface ChatMessagePayload {
   id: string
   chat_id: string
+  parent_id?: string
   content: string
   role: 'user' | 'assistant' | 'plugin'
   created_at: string
@@ -117,6 +123,18 @@ class WebSocketService {
     this.send('chat.message', { chat_id: chatId, content, provider, soul })
   }
 
+  editMessage(chatId: string, messageId: string, content: string, provider?: string, soul?: string) {
+    this.send('chat.edit', { chat_id: chatId, message_id: messageId, content, provider, soul })
+  }
+
+  regenerate(chatId: string, messageId: string, provider?: string, soul?: string) {
+    this.send('chat.regenerate', { chat_id: chatId, message_id: messageId, provider, soul })
+  }
+
+  switchBranch(chatId: string, messageId: string) {
+    this.send('chat.switch_branch', { chat_id: chatId, message_id: messageId })
+  }
+
   on(type: string, handler: MessageHandler) {
     if (!this.handlers.has(type)) {
       this.handlers.set(type, new Set())
@@ -0,0 +1,29 @@
+import { defineStore } from 'pinia'
+import { ref } from 'vue'
+import * as api from '../services/api'
+import type { User } from '../services/api'
+
+export const useAuthStore = defineStore('auth', () => {
+  const user = ref<User | null>(null)
+
+  // Loads the signed-in user, returning false without a valid session
+  async function load(): Promise<boolean> {
+    try {
+      user.value = await api.fetchMe()
+    } catch {
+      user.value = null
+    }
+    return user.value !== null
+  }
+
+  async function login(username: string, password: string) {
+    user.value = await api.login(username, password)
+  }
+
+  async function logout() {
+    await api.logout()
+    user.value = null
+  }
+
+  return { user, load, login, logout }
+})
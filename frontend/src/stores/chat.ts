@@ -1,5 +1,5 @@
 import { defineStore } from 'pinia'
-import { ref, computed } from 'vue'
+import { ref, computed, watch } from 'vue'
 import { wsService, type WSMessage, type ChatMessagePayload, type Attachment, type ToolCallRecord, type ToolCallEvent } from '../services/websocket'
 import * as api from '../services/api'
 import type { Provider, Soul } from '../services/api'
@@ -7,6 +7,9 @@ import type { Provider, Soul } from '../services/api'
 export interface ChatMessage {
   id: string
   chat_id: string
+  parent_id?: string
+  siblings?: string[]  // Alternative versions incl. this one (when branched)
+  pending?: boolean    // Sent but not yet confirmed by the server
   content: string
   role: 'user' | 'assistant' | 'plugin'
   created_at: string
@@ -24,10 +27,15 @@ export interface Chat {
   id: string
   name: string
   messages: ChatMessage[]
+  preview?: string       // Last message text for the chat list
+  messagesLoaded: boolean
+  hasMore: boolean       // Older messages exist on the server
   created_at: string
   updated_at: string
 }
 
+const MESSAGE_PAGE_SIZE = 50
+
 export const useChatStore = defineStore('chat', () => {
   const chats = ref<Map<string, Chat>>(new Map())
   const activeChatId = ref<string | null>(null)
@@ -72,27 +80,36 @@ export const useChatStore = defineStore('chat', () => {
     }
   }
 
+  function toChatMessage(m: api.Message): ChatMessage {
+    return {
+      id: m.id,
+      chat_id: m.chat_id,
+      parent_id: m.parent_id,
+      siblings: m.siblings,
+      content: m.content,
+      role: m.role,
+      created_at: m.created_at,
+      display_only: m.display_only,
+      attachments: parseAttachments(m.attachments),
+      tool_calls: parseToolCalls(m.tool_calls),
+      soul: m.soul,
+      provider: m.provider
+    }
+  }
+
   async function loadChats() {
     try {
       const serverChats = await api.fetchChats()
       chats.value.clear()
       for (const chat of serverChats) {
-        const messages: ChatMessage[] = (chat.messages || []).map(m => ({
-          id: m.id,
-          chat_id: m.chat_id,
-          content: m.content,
-          role: m.role,
-          created_at: m.created_at,
-          display_only: m.display_only,
-          attachments: parseAttachments(m.attachments),
-          tool_calls: parseToolCalls(m.tool_calls),
-          soul: m.soul,
-          provider: m.provider
-        }))
+        // Messages are loaded page by page when a chat is opened
         chats.value.set(chat.id, {
           id: chat.id,
           name: chat.name,
-          messages,
+          messages: [],
+          preview: chat.last_message?.content,
+          messagesLoaded: false,
+          hasMore: false,
           created_at: chat.created_at,
           updated_at: chat.updated_at
         })
@@ -101,11 +118,50 @@ export const useChatStore = defineStore('chat', () => {
       if (!activeChatId.value && serverChats.length > 0) {
         activeChatId.value = serverChats[0].id
       }
+      if (activeChatId.value) {
+        await loadMessages(activeChatId.value)
+      }
     } catch (error) {
       console.error('[Chat] Failed to load chats:', error)
     }
   }
 
+  // Load the latest page of messages of a chat (force reloads e.g. after switching branches)
+  async function loadMessages(chatId: string, force = false) {
+    const chat = chats.value.get(chatId)
+    if (!chat || (chat.messagesLoaded && !force)) return
+    try {
+      const page = await api.fetchMessages(chatId, { limit: MESSAGE_PAGE_SIZE })
+      chat.messages = page.messages.map(toChatMessage)
+      chat.hasMore = page.has_more
+      chat.messagesLoaded = true
+    } catch (error) {
+      console.error('[Chat] Failed to load messages:', error)
+    }
+  }
+
+  // Prepend the page of messages before the oldest loaded one
+  async function loadOlderMessages(chatId: string): Promise<boolean> {
+    const chat = chats.value.get(chatId)
+    if (!chat || !chat.hasMore || chat.messages.length === 0) return false
+    try {
+      const page = await api.fetchMessages(chatId, {
+        before: chat.messages[0].id,
+        limit: MESSAGE_PAGE_SIZE
+      })
+      chat.messages = [...page.messages.map(toChatMessage), ...chat.messages]
+      chat.hasMore = page.has_more
+      return page.messages.length > 0
+    } catch (error) {
+      console.error('[Chat] Failed to load older messages:', error)
+      return false
+    }
+  }
+
+  watch(activeChatId, (chatId) => {
+    if (chatId) loadMessages(chatId)
+  })
+
   async function createChat(name: string = 'New Chat'): Promise<Chat | null> {
     try {
       const chat = await api.createChat(name)
@@ -113,6 +169,8 @@ export const useChatStore = defineStore('chat', () => {
         id: chat.id,
         name: chat.name,
         messages: [],
+        messagesLoaded: true,
+        hasMore: false,
         created_at: chat.created_at,
         updated_at: chat.updated_at
       }
@@ -161,6 +219,7 @@ export const useChatStore = defineStore('chat', () => {
     const chat = chats.value.get(chatId)
     if (!chat) return
     chat.messages.push(message)
+    chat.preview = message.content
     chat.updated_at = message.created_at
   }
 
@@ -198,6 +257,16 @@ export const useChatStore = defineStore('chat', () => {
     selectedProvider.value = provider
   }
 
+  // Selects a provider and remembers it as the user's default
+  async function saveDefaultProvider(provider: string) {
+    selectedProvider.value = provider
+    try {
+      providers.value = await api.setDefaultProvider(provider)
+    } catch (error) {
+      console.error('[Chat] Failed to save default provider:', error)
+    }
+  }
+
   function setSoul(soul: string) {
     selectedSoul.value = soul
   }
@@ -214,7 +283,8 @@ export const useChatStore = defineStore('chat', () => {
       chat_id: activeChatId.value,
       content,
       role: 'user',
-      created_at: new Date().toISOString()
+      created_at: new Date().toISOString(),
+      pending: true
     }
     addMessage(activeChatId.value, userMessage)
 
@@ -224,6 +294,35 @@ export const useChatStore = defineStore('chat', () => {
     wsService.sendChatMessage(activeChatId.value, content, selectedProvider.value, selectedSoul.value)
   }
 
+  // Chats whose next answer starts a new branch (reloaded to pick up sibling info)
+  const branchingChats = new Set<string>()
+
+  // Edit a message on a new branch (edited user messages are answered again)
+  function editMessage(messageId: string, content: string) {
+    const chat = activeChat.value
+    const message = chat?.messages.find(m => m.id === messageId)
+    if (!chat || !message || !content.trim()) return
+    if (message.role === 'user') {
+      isLoading.value = true
+      branchingChats.add(chat.id)
+    }
+    wsService.editMessage(chat.id, messageId, content, selectedProvider.value, selectedSoul.value)
+  }
+
+  // Retry an assistant answer with the selected provider and soul
+  function regenerate(messageId: string) {
+    if (!activeChatId.value) return
+    isLoading.value = true
+    branchingChats.add(activeChatId.value)
+    wsService.regenerate(activeChatId.value, messageId, selectedProvider.value, selectedSoul.value)
+  }
+
+  // Show another version of a message
+  function switchBranch(messageId: string) {
+    if (!activeChatId.value) return
+    wsService.switchBranch(activeChatId.value, messageId)
+  }
+
   async function connect() {
     try {
       await wsService.connect()
@@ -232,10 +331,25 @@ export const useChatStore = defineStore('chat', () => {
       // Handle incoming messages
       wsService.on('chat.message', (msg: WSMessage) => {
         const payload = msg.payload as ChatMessagePayload
-        if (payload.role === 'assistant' || payload.role === 'plugin') {
+        if (payload.role === 'user') {
+          // Confirmation of a sent message: take over the server ID
+          const pending = chats.value.get(payload.chat_id)?.messages
+            .find(m => m.pending && m.content === payload.content)
+          if (pending) {
+            pending.id = payload.id
+            pending.parent_id = payload.parent_id
+            pending.created_at = payload.created_at
+            pending.pending = false
+          }
+        } else if (payload.role === 'assistant' && branchingChats.delete(payload.chat_id)) {
+          isLoading.value = false
+          pendingToolCalls.value.delete(payload.chat_id)
+          loadMessages(payload.chat_id, true)
+        } else if (payload.role === 'assistant' || payload.role === 'plugin') {
           addMessage(payload.chat_id, {
             id: payload.id,
             chat_id: payload.chat_id,
+            parent_id: payload.parent_id,
             content: payload.content,
             role: payload.role,
             created_at: payload.created_at,
@@ -261,6 +375,20 @@ export const useChatStore = defineStore('chat', () => {
         }
         const calls = pendingToolCalls.value.get(event.chat_id)!
 
+        // Sub-agent calls are nested under their delegate_task call
+        if (event.parent_id) {
+          const parent = calls.find(c => c.tool_id === event.parent_id)
+          if (!parent) return
+          const children = parent.children || (parent.children = [])
+          const idx = children.findIndex(c => c.tool_id === event.tool_id)
+          if (idx >= 0) {
+            children[idx] = { ...children[idx], ...event }
+          } else {
+            children.push(event)
+          }
+          return
+        }
+
         if (event.type === 'start') {
           calls.push(event)
         } else if (event.type === 'complete' || event.type === 'error') {
@@ -272,9 +400,16 @@ export const useChatStore = defineStore('chat', () => {
         }
       })
 
+      // The active branch changed (edit, regenerate, switch): reload it
+      wsService.on('chat.branch', (msg: WSMessage) => {
+        const payload = msg.payload as { chat_id: string }
+        loadMessages(payload.chat_id, true)
+      })
+
       wsService.on('chat.error', (msg: WSMessage) => {
         console.error('[Chat] Error:', msg.payload)
         isLoading.value = false
+        branchingChats.clear()
       })
 
       // Load chats, providers, and souls from server
@@ -308,6 +443,8 @@ export const useChatStore = defineStore('chat', () => {
     selectedSoul,
     pendingToolCalls,
     loadChats,
+    loadMessages,
+    loadOlderMessages,
     loadProviders,
     loadSouls,
     createChat,
@@ -316,7 +453,11 @@ export const useChatStore = defineStore('chat', () => {
     renameChat,
     addMessage,
     sendMessage,
+    editMessage,
+    regenerate,
+    switchBranch,
     setProvider,
+    saveDefaultProvider,
     setSoul,
     connect,
     disconnect
@@ -28,7 +28,7 @@ type ChatGetOrCreateRequest struct {
 	Platform      string                 `protobuf:"bytes,2,opt,name=platform,proto3" json:"platform,omitempty"`                 // "whatsapp", "telegram", etc.
 	LinkedId      string                 `protobuf:"bytes,3,opt,name=linked_id,json=linkedId,proto3" json:"linked_id,omitempty"` // External chat ID (e.g., WhatsApp JID)
 	Name          string                 `protobuf:"bytes,4,opt,name=name,proto3" json:"name,omitempty"`                         // Chat name
-	UserId        string                 `protobuf:"bytes,5,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`       // Optional user ID
+	UserId        string                 `protobuf:"bytes,5,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`       // Ignored - the owner is the plugin's chat_owner config (default admin)
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -554,7 +554,9 @@ type ChatGetMessagesRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
 	ChatId        string                 `protobuf:"bytes,2,opt,name=chat_id,json=chatId,proto3" json:"chat_id,omitempty"`
-	Limit         int32                  `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"` // Optional limit
+	Limit         int32                  `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`                      // Optional limit (0 = 50, at most 200)
+	BeforeId      string                 `protobuf:"bytes,4,opt,name=before_id,json=beforeId,proto3" json:"before_id,omitempty"` // Optional cursor: only messages older than this message (latest page if no cursor)
+	AfterId       string                 `protobuf:"bytes,5,opt,name=after_id,json=afterId,proto3" json:"after_id,omitempty"`    // Optional cursor: only messages newer than this message
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -610,12 +612,27 @@ func (x *ChatGetMessagesRequest) GetLimit() int32 {
 	return 0
 }
 
+func (x *ChatGetMessagesRequest) GetBeforeId() string {
+	if x != nil {
+		return x.BeforeId
+	}
+	return ""
+}
+
+func (x *ChatGetMessagesRequest) GetAfterId() string {
+	if x != nil {
+		return x.AfterId
+	}
+	return ""
+}
+
 type ChatGetMessagesResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
 	Success       bool                   `protobuf:"varint,2,opt,name=success,proto3" json:"success,omitempty"`
 	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
-	Messages      []*ChatMessage         `protobuf:"bytes,4,rep,name=messages,proto3" json:"messages,omitempty"`
+	Messages      []*ChatMessage         `protobuf:"bytes,4,rep,name=messages,proto3" json:"messages,omitempty"`               // Chronological order
+	HasMore       bool                   `protobuf:"varint,5,opt,name=has_more,json=hasMore,proto3" json:"has_more,omitempty"` // More messages exist in the paging direction
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -678,6 +695,13 @@ func (x *ChatGetMessagesResponse) GetMessages() []*ChatMessage {
 	return nil
 }
 
+func (x *ChatGetMessagesResponse) GetHasMore() bool {
+	if x != nil {
+		return x.HasMore
+	}
+	return false
+}
+
 // Chat message in responses
 type ChatMessage struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -825,18 +849,21 @@ const file_chadbot_chat_proto_rawDesc = "" +
 	"\x05error\x18\x03 \x01(\tR\x05error\x12\x18\n" +
 	"\acontent\x18\x04 \x01(\tR\acontent\x12\x1d\n" +
 	"\n" +
-	"message_id\x18\x05 \x01(\tR\tmessageId\"f\n" +
+	"message_id\x18\x05 \x01(\tR\tmessageId\"\x9e\x01\n" +
 	"\x16ChatGetMessagesRequest\x12\x1d\n" +
 	"\n" +
 	"request_id\x18\x01 \x01(\tR\trequestId\x12\x17\n" +
 	"\achat_id\x18\x02 \x01(\tR\x06chatId\x12\x14\n" +
-	"\x05limit\x18\x03 \x01(\x05R\x05limit\"\x9a\x01\n" +
+	"\x05limit\x18\x03 \x01(\x05R\x05limit\x12\x1b\n" +
+	"\tbefore_id\x18\x04 \x01(\tR\bbeforeId\x12\x19\n" +
+	"\bafter_id\x18\x05 \x01(\tR\aafterId\"\xb5\x01\n" +
 	"\x17ChatGetMessagesResponse\x12\x1d\n" +
 	"\n" +
 	"request_id\x18\x01 \x01(\tR\trequestId\x12\x18\n" +
 	"\asuccess\x18\x02 \x01(\bR\asuccess\x12\x14\n" +
 	"\x05error\x18\x03 \x01(\tR\x05error\x120\n" +
-	"\bmessages\x18\x04 \x03(\v2\x14.chadbot.ChatMessageR\bmessages\"\xdd\x01\n" +
+	"\bmessages\x18\x04 \x03(\v2\x14.chadbot.ChatMessageR\bmessages\x12\x19\n" +
+	"\bhas_more\x18\x05 \x01(\bR\ahasMore\"\xdd\x01\n" +
 	"\vChatMessage\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x17\n" +
 	"\achat_id\x18\x02 \x01(\tR\x06chatId\x12\x12\n" +
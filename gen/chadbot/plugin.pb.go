@@ -284,9 +284,11 @@ func (*PluginMessage_ConfigGet) isPluginMessage_Payload() {}
 func (*PluginMessage_Documentation) isPluginMessage_Payload() {}
 
 // Plugin documentation (PLUGIN.md content)
+// The LLM sees a one-line index entry per plugin and pulls the full docs via read_plugin_docs
 type PluginDocumentation struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Content       string                 `protobuf:"bytes,1,opt,name=content,proto3" json:"content,omitempty"` // Markdown content
+	Content       string                 `protobuf:"bytes,1,opt,name=content,proto3" json:"content,omitempty"`    // Markdown content
+	Required      bool                   `protobuf:"varint,2,opt,name=required,proto3" json:"required,omitempty"` // If true, the docs must be read before the plugin's skills can be called
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -328,6 +330,13 @@ func (x *PluginDocumentation) GetContent() string {
 	return ""
 }
 
+func (x *PluginDocumentation) GetRequired() bool {
+	if x != nil {
+		return x.Required
+	}
+	return false
+}
+
 // Messages from backend to plugin
 type BackendMessage struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
@@ -559,10 +568,14 @@ func (*BackendMessage_ConfigChanged) isBackendMessage_Payload() {}
 
 // Plugin registration
 type RegisterRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
-	Version       string                 `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
-	Description   string                 `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Name        string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Version     string                 `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+	Description string                 `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
+	Token       string                 `protobuf:"bytes,4,opt,name=token,proto3" json:"token,omitempty"` // Plugin token issued by chadpm or "chadbot plugin-token" (CHADBOT_PLUGIN_TOKEN)
+	// Capabilities the plugin needs, e.g. "chat.write", "llm.request", "storage",
+	// "events.subscribe:mqtt.*". A user must approve them before they can be used.
+	Permissions   []string `protobuf:"bytes,5,rep,name=permissions,proto3" json:"permissions,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -618,13 +631,28 @@ func (x *RegisterRequest) GetDescription() string {
 	return ""
 }
 
+func (x *RegisterRequest) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+func (x *RegisterRequest) GetPermissions() []string {
+	if x != nil {
+		return x.Permissions
+	}
+	return nil
+}
+
 type RegisterResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	PluginId      string                 `protobuf:"bytes,1,opt,name=plugin_id,json=pluginId,proto3" json:"plugin_id,omitempty"`
-	Success       bool                   `protobuf:"varint,2,opt,name=success,proto3" json:"success,omitempty"`
-	Message       string                 `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	PluginId           string                 `protobuf:"bytes,1,opt,name=plugin_id,json=pluginId,proto3" json:"plugin_id,omitempty"`
+	Success            bool                   `protobuf:"varint,2,opt,name=success,proto3" json:"success,omitempty"`
+	Message            string                 `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+	PendingPermissions []string               `protobuf:"bytes,4,rep,name=pending_permissions,json=pendingPermissions,proto3" json:"pending_permissions,omitempty"` // Declared permissions not approved yet
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
 }
 
 func (x *RegisterResponse) Reset() {
@@ -678,6 +706,13 @@ func (x *RegisterResponse) GetMessage() string {
 	return ""
 }
 
+func (x *RegisterResponse) GetPendingPermissions() []string {
+	if x != nil {
+		return x.PendingPermissions
+	}
+	return nil
+}
+
 // Error message
 type Error struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -761,9 +796,10 @@ const file_chadbot_plugin_proto_rawDesc = "" +
 	"\n" +
 	"config_get\x18\f \x01(\v2\x19.chadbot.ConfigGetRequestH\x00R\tconfigGet\x12D\n" +
 	"\rdocumentation\x18\r \x01(\v2\x1c.chadbot.PluginDocumentationH\x00R\rdocumentationB\t\n" +
-	"\apayload\"/\n" +
+	"\apayload\"K\n" +
 	"\x13PluginDocumentation\x12\x18\n" +
-	"\acontent\x18\x01 \x01(\tR\acontent\"\xc8\x06\n" +
+	"\acontent\x18\x01 \x01(\tR\acontent\x12\x1a\n" +
+	"\brequired\x18\x02 \x01(\bR\brequired\"\xc8\x06\n" +
 	"\x0eBackendMessage\x12H\n" +
 	"\x11register_response\x18\x01 \x01(\v2\x19.chadbot.RegisterResponseH\x00R\x10registerResponse\x129\n" +
 	"\fskill_invoke\x18\x02 \x01(\v2\x14.chadbot.SkillInvokeH\x00R\vskillInvoke\x12?\n" +
@@ -777,15 +813,18 @@ const file_chadbot_plugin_proto_rawDesc = "" +
 	"\x13config_get_response\x18\n" +
 	" \x01(\v2\x1a.chadbot.ConfigGetResponseH\x00R\x11configGetResponse\x12?\n" +
 	"\x0econfig_changed\x18\v \x01(\v2\x16.chadbot.ConfigChangedH\x00R\rconfigChangedB\t\n" +
-	"\apayload\"a\n" +
+	"\apayload\"\x99\x01\n" +
 	"\x0fRegisterRequest\x12\x12\n" +
 	"\x04name\x18\x01 \x01(\tR\x04name\x12\x18\n" +
 	"\aversion\x18\x02 \x01(\tR\aversion\x12 \n" +
-	"\vdescription\x18\x03 \x01(\tR\vdescription\"c\n" +
+	"\vdescription\x18\x03 \x01(\tR\vdescription\x12\x14\n" +
+	"\x05token\x18\x04 \x01(\tR\x05token\x12 \n" +
+	"\vpermissions\x18\x05 \x03(\tR\vpermissions\"\x94\x01\n" +
 	"\x10RegisterResponse\x12\x1b\n" +
 	"\tplugin_id\x18\x01 \x01(\tR\bpluginId\x12\x18\n" +
 	"\asuccess\x18\x02 \x01(\bR\asuccess\x12\x18\n" +
-	"\amessage\x18\x03 \x01(\tR\amessage\"T\n" +
+	"\amessage\x18\x03 \x01(\tR\amessage\x12/\n" +
+	"\x13pending_permissions\x18\x04 \x03(\tR\x12pendingPermissions\"T\n" +
 	"\x05Error\x12\x12\n" +
 	"\x04code\x18\x01 \x01(\x05R\x04code\x12\x18\n" +
 	"\amessage\x18\x02 \x01(\tR\amessage\x12\x1d\n" +
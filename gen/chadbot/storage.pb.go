@@ -21,6 +21,80 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// Comparison operators of filter conditions
+type FilterOp int32
+
+const (
+	FilterOp_FILTER_OP_EQ          FilterOp = 0
+	FilterOp_FILTER_OP_NE          FilterOp = 1
+	FilterOp_FILTER_OP_LT          FilterOp = 2
+	FilterOp_FILTER_OP_LE          FilterOp = 3
+	FilterOp_FILTER_OP_GT          FilterOp = 4
+	FilterOp_FILTER_OP_GE          FilterOp = 5
+	FilterOp_FILTER_OP_LIKE        FilterOp = 6  // SQL LIKE pattern
+	FilterOp_FILTER_OP_IN          FilterOp = 7  // values
+	FilterOp_FILTER_OP_NOT_IN      FilterOp = 8  // values
+	FilterOp_FILTER_OP_IS_NULL     FilterOp = 9  // No value
+	FilterOp_FILTER_OP_IS_NOT_NULL FilterOp = 10 // No value
+)
+
+// Enum value maps for FilterOp.
+var (
+	FilterOp_name = map[int32]string{
+		0:  "FILTER_OP_EQ",
+		1:  "FILTER_OP_NE",
+		2:  "FILTER_OP_LT",
+		3:  "FILTER_OP_LE",
+		4:  "FILTER_OP_GT",
+		5:  "FILTER_OP_GE",
+		6:  "FILTER_OP_LIKE",
+		7:  "FILTER_OP_IN",
+		8:  "FILTER_OP_NOT_IN",
+		9:  "FILTER_OP_IS_NULL",
+		10: "FILTER_OP_IS_NOT_NULL",
+	}
+	FilterOp_value = map[string]int32{
+		"FILTER_OP_EQ":          0,
+		"FILTER_OP_NE":          1,
+		"FILTER_OP_LT":          2,
+		"FILTER_OP_LE":          3,
+		"FILTER_OP_GT":          4,
+		"FILTER_OP_GE":          5,
+		"FILTER_OP_LIKE":        6,
+		"FILTER_OP_IN":          7,
+		"FILTER_OP_NOT_IN":      8,
+		"FILTER_OP_IS_NULL":     9,
+		"FILTER_OP_IS_NOT_NULL": 10,
+	}
+)
+
+func (x FilterOp) Enum() *FilterOp {
+	p := new(FilterOp)
+	*p = x
+	return p
+}
+
+func (x FilterOp) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (FilterOp) Descriptor() protoreflect.EnumDescriptor {
+	return file_chadbot_storage_proto_enumTypes[0].Descriptor()
+}
+
+func (FilterOp) Type() protoreflect.EnumType {
+	return &file_chadbot_storage_proto_enumTypes[0]
+}
+
+func (x FilterOp) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use FilterOp.Descriptor instead.
+func (FilterOp) EnumDescriptor() ([]byte, []int) {
+	return file_chadbot_storage_proto_rawDescGZIP(), []int{0}
+}
+
 // Storage request from plugin to backend
 type StorageRequest struct {
 	state     protoimpl.MessageState `protogen:"open.v1"`
@@ -33,6 +107,13 @@ type StorageRequest struct {
 	//	*StorageRequest_Update
 	//	*StorageRequest_Delete
 	//	*StorageRequest_Query
+	//	*StorageRequest_Migrate
+	//	*StorageRequest_Batch
+	//	*StorageRequest_Upsert
+	//	*StorageRequest_Count
+	//	*StorageRequest_Aggregate
+	//	*StorageRequest_Kv
+	//	*StorageRequest_Docs
 	Operation     isStorageRequest_Operation `protobuf_oneof:"operation"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
@@ -136,6 +217,69 @@ func (x *StorageRequest) GetQuery() *QueryRequest {
 	return nil
 }
 
+func (x *StorageRequest) GetMigrate() *MigrateRequest {
+	if x != nil {
+		if x, ok := x.Operation.(*StorageRequest_Migrate); ok {
+			return x.Migrate
+		}
+	}
+	return nil
+}
+
+func (x *StorageRequest) GetBatch() *BatchRequest {
+	if x != nil {
+		if x, ok := x.Operation.(*StorageRequest_Batch); ok {
+			return x.Batch
+		}
+	}
+	return nil
+}
+
+func (x *StorageRequest) GetUpsert() *UpsertRequest {
+	if x != nil {
+		if x, ok := x.Operation.(*StorageRequest_Upsert); ok {
+			return x.Upsert
+		}
+	}
+	return nil
+}
+
+func (x *StorageRequest) GetCount() *CountRequest {
+	if x != nil {
+		if x, ok := x.Operation.(*StorageRequest_Count); ok {
+			return x.Count
+		}
+	}
+	return nil
+}
+
+func (x *StorageRequest) GetAggregate() *AggregateRequest {
+	if x != nil {
+		if x, ok := x.Operation.(*StorageRequest_Aggregate); ok {
+			return x.Aggregate
+		}
+	}
+	return nil
+}
+
+func (x *StorageRequest) GetKv() *KVRequest {
+	if x != nil {
+		if x, ok := x.Operation.(*StorageRequest_Kv); ok {
+			return x.Kv
+		}
+	}
+	return nil
+}
+
+func (x *StorageRequest) GetDocs() *DocRequest {
+	if x != nil {
+		if x, ok := x.Operation.(*StorageRequest_Docs); ok {
+			return x.Docs
+		}
+	}
+	return nil
+}
+
 type isStorageRequest_Operation interface {
 	isStorageRequest_Operation()
 }
@@ -164,6 +308,34 @@ type StorageRequest_Query struct {
 	Query *QueryRequest `protobuf:"bytes,7,opt,name=query,proto3,oneof"`
 }
 
+type StorageRequest_Migrate struct {
+	Migrate *MigrateRequest `protobuf:"bytes,8,opt,name=migrate,proto3,oneof"`
+}
+
+type StorageRequest_Batch struct {
+	Batch *BatchRequest `protobuf:"bytes,9,opt,name=batch,proto3,oneof"`
+}
+
+type StorageRequest_Upsert struct {
+	Upsert *UpsertRequest `protobuf:"bytes,10,opt,name=upsert,proto3,oneof"`
+}
+
+type StorageRequest_Count struct {
+	Count *CountRequest `protobuf:"bytes,11,opt,name=count,proto3,oneof"`
+}
+
+type StorageRequest_Aggregate struct {
+	Aggregate *AggregateRequest `protobuf:"bytes,12,opt,name=aggregate,proto3,oneof"`
+}
+
+type StorageRequest_Kv struct {
+	Kv *KVRequest `protobuf:"bytes,13,opt,name=kv,proto3,oneof"`
+}
+
+type StorageRequest_Docs struct {
+	Docs *DocRequest `protobuf:"bytes,14,opt,name=docs,proto3,oneof"`
+}
+
 func (*StorageRequest_CreateTable) isStorageRequest_Operation() {}
 
 func (*StorageRequest_DropTable) isStorageRequest_Operation() {}
@@ -176,15 +348,36 @@ func (*StorageRequest_Delete) isStorageRequest_Operation() {}
 
 func (*StorageRequest_Query) isStorageRequest_Operation() {}
 
+func (*StorageRequest_Migrate) isStorageRequest_Operation() {}
+
+func (*StorageRequest_Batch) isStorageRequest_Operation() {}
+
+func (*StorageRequest_Upsert) isStorageRequest_Operation() {}
+
+func (*StorageRequest_Count) isStorageRequest_Operation() {}
+
+func (*StorageRequest_Aggregate) isStorageRequest_Operation() {}
+
+func (*StorageRequest_Kv) isStorageRequest_Operation() {}
+
+func (*StorageRequest_Docs) isStorageRequest_Operation() {}
+
 // Storage response from backend to plugin
 type StorageResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
 	Success       bool                   `protobuf:"varint,2,opt,name=success,proto3" json:"success,omitempty"`
 	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
-	Rows          []*Row                 `protobuf:"bytes,4,rep,name=rows,proto3" json:"rows,omitempty"`                                        // For query results
-	AffectedRows  int64                  `protobuf:"varint,5,opt,name=affected_rows,json=affectedRows,proto3" json:"affected_rows,omitempty"`   // For insert/update/delete
-	LastInsertId  int64                  `protobuf:"varint,6,opt,name=last_insert_id,json=lastInsertId,proto3" json:"last_insert_id,omitempty"` // For insert
+	Rows          []*Row                 `protobuf:"bytes,4,rep,name=rows,proto3" json:"rows,omitempty"`                                          // For query results
+	AffectedRows  int64                  `protobuf:"varint,5,opt,name=affected_rows,json=affectedRows,proto3" json:"affected_rows,omitempty"`     // For insert/update/delete
+	LastInsertId  int64                  `protobuf:"varint,6,opt,name=last_insert_id,json=lastInsertId,proto3" json:"last_insert_id,omitempty"`   // For insert
+	SchemaVersion int32                  `protobuf:"varint,7,opt,name=schema_version,json=schemaVersion,proto3" json:"schema_version,omitempty"`  // For migrate: highest applied migration version
+	Results       []*OperationResult     `protobuf:"bytes,8,rep,name=results,proto3" json:"results,omitempty"`                                    // For batch: one result per operation
+	Count         int64                  `protobuf:"varint,9,opt,name=count,proto3" json:"count,omitempty"`                                       // For count
+	Entries       []*KVEntry             `protobuf:"bytes,10,rep,name=entries,proto3" json:"entries,omitempty"`                                   // For kv get and list
+	Documents     []*Document            `protobuf:"bytes,11,rep,name=documents,proto3" json:"documents,omitempty"`                               // For docs put, get and find
+	Conflict      bool                   `protobuf:"varint,12,opt,name=conflict,proto3" json:"conflict,omitempty"`                                // A compare-and-swap failed because the version did not match
+	QuotaExceeded bool                   `protobuf:"varint,13,opt,name=quota_exceeded,json=quotaExceeded,proto3" json:"quota_exceeded,omitempty"` // The write was rejected because the plugin's storage quota is used up
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -261,33 +454,85 @@ func (x *StorageResponse) GetLastInsertId() int64 {
 	return 0
 }
 
-// Table column definition
-type ColumnDef struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
-	Type          string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"` // TEXT, INTEGER, REAL, BLOB
-	PrimaryKey    bool                   `protobuf:"varint,3,opt,name=primary_key,json=primaryKey,proto3" json:"primary_key,omitempty"`
-	NotNull       bool                   `protobuf:"varint,4,opt,name=not_null,json=notNull,proto3" json:"not_null,omitempty"`
-	Unique        bool                   `protobuf:"varint,5,opt,name=unique,proto3" json:"unique,omitempty"`
-	DefaultValue  string                 `protobuf:"bytes,6,opt,name=default_value,json=defaultValue,proto3" json:"default_value,omitempty"`
+func (x *StorageResponse) GetSchemaVersion() int32 {
+	if x != nil {
+		return x.SchemaVersion
+	}
+	return 0
+}
+
+func (x *StorageResponse) GetResults() []*OperationResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+func (x *StorageResponse) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *StorageResponse) GetEntries() []*KVEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+func (x *StorageResponse) GetDocuments() []*Document {
+	if x != nil {
+		return x.Documents
+	}
+	return nil
+}
+
+func (x *StorageResponse) GetConflict() bool {
+	if x != nil {
+		return x.Conflict
+	}
+	return false
+}
+
+func (x *StorageResponse) GetQuotaExceeded() bool {
+	if x != nil {
+		return x.QuotaExceeded
+	}
+	return false
+}
+
+// Typed cell value. A Value without kind is NULL.
+type Value struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Kind:
+	//
+	//	*Value_NullValue
+	//	*Value_IntValue
+	//	*Value_FloatValue
+	//	*Value_TextValue
+	//	*Value_BytesValue
+	//	*Value_BoolValue
+	Kind          isValue_Kind `protobuf_oneof:"kind"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ColumnDef) Reset() {
-	*x = ColumnDef{}
+func (x *Value) Reset() {
+	*x = Value{}
 	mi := &file_chadbot_storage_proto_msgTypes[2]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ColumnDef) String() string {
+func (x *Value) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ColumnDef) ProtoMessage() {}
+func (*Value) ProtoMessage() {}
 
-func (x *ColumnDef) ProtoReflect() protoreflect.Message {
+func (x *Value) ProtoReflect() protoreflect.Message {
 	mi := &file_chadbot_storage_proto_msgTypes[2]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -299,77 +544,135 @@ func (x *ColumnDef) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ColumnDef.ProtoReflect.Descriptor instead.
-func (*ColumnDef) Descriptor() ([]byte, []int) {
+// Deprecated: Use Value.ProtoReflect.Descriptor instead.
+func (*Value) Descriptor() ([]byte, []int) {
 	return file_chadbot_storage_proto_rawDescGZIP(), []int{2}
 }
 
-func (x *ColumnDef) GetName() string {
+func (x *Value) GetKind() isValue_Kind {
 	if x != nil {
-		return x.Name
+		return x.Kind
 	}
-	return ""
+	return nil
 }
 
-func (x *ColumnDef) GetType() string {
+func (x *Value) GetNullValue() bool {
 	if x != nil {
-		return x.Type
+		if x, ok := x.Kind.(*Value_NullValue); ok {
+			return x.NullValue
+		}
 	}
-	return ""
+	return false
 }
 
-func (x *ColumnDef) GetPrimaryKey() bool {
+func (x *Value) GetIntValue() int64 {
 	if x != nil {
-		return x.PrimaryKey
+		if x, ok := x.Kind.(*Value_IntValue); ok {
+			return x.IntValue
+		}
 	}
-	return false
+	return 0
 }
 
-func (x *ColumnDef) GetNotNull() bool {
+func (x *Value) GetFloatValue() float64 {
 	if x != nil {
-		return x.NotNull
+		if x, ok := x.Kind.(*Value_FloatValue); ok {
+			return x.FloatValue
+		}
 	}
-	return false
+	return 0
 }
 
-func (x *ColumnDef) GetUnique() bool {
+func (x *Value) GetTextValue() string {
 	if x != nil {
-		return x.Unique
+		if x, ok := x.Kind.(*Value_TextValue); ok {
+			return x.TextValue
+		}
 	}
-	return false
+	return ""
 }
 
-func (x *ColumnDef) GetDefaultValue() string {
+func (x *Value) GetBytesValue() []byte {
 	if x != nil {
-		return x.DefaultValue
+		if x, ok := x.Kind.(*Value_BytesValue); ok {
+			return x.BytesValue
+		}
 	}
-	return ""
+	return nil
 }
 
-// Create table request
-type CreateTableRequest struct {
+func (x *Value) GetBoolValue() bool {
+	if x != nil {
+		if x, ok := x.Kind.(*Value_BoolValue); ok {
+			return x.BoolValue
+		}
+	}
+	return false
+}
+
+type isValue_Kind interface {
+	isValue_Kind()
+}
+
+type Value_NullValue struct {
+	NullValue bool `protobuf:"varint,1,opt,name=null_value,json=nullValue,proto3,oneof"`
+}
+
+type Value_IntValue struct {
+	IntValue int64 `protobuf:"varint,2,opt,name=int_value,json=intValue,proto3,oneof"`
+}
+
+type Value_FloatValue struct {
+	FloatValue float64 `protobuf:"fixed64,3,opt,name=float_value,json=floatValue,proto3,oneof"`
+}
+
+type Value_TextValue struct {
+	TextValue string `protobuf:"bytes,4,opt,name=text_value,json=textValue,proto3,oneof"`
+}
+
+type Value_BytesValue struct {
+	BytesValue []byte `protobuf:"bytes,5,opt,name=bytes_value,json=bytesValue,proto3,oneof"`
+}
+
+type Value_BoolValue struct {
+	BoolValue bool `protobuf:"varint,6,opt,name=bool_value,json=boolValue,proto3,oneof"` // Stored as INTEGER 0/1, read back as int_value (bool_value for BOOLEAN columns)
+}
+
+func (*Value_NullValue) isValue_Kind() {}
+
+func (*Value_IntValue) isValue_Kind() {}
+
+func (*Value_FloatValue) isValue_Kind() {}
+
+func (*Value_TextValue) isValue_Kind() {}
+
+func (*Value_BytesValue) isValue_Kind() {}
+
+func (*Value_BoolValue) isValue_Kind() {}
+
+// Result of a single batch operation
+type OperationResult struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	TableName     string                 `protobuf:"bytes,1,opt,name=table_name,json=tableName,proto3" json:"table_name,omitempty"` // Will be prefixed with plugin namespace
-	Columns       []*ColumnDef           `protobuf:"bytes,2,rep,name=columns,proto3" json:"columns,omitempty"`
-	IfNotExists   bool                   `protobuf:"varint,3,opt,name=if_not_exists,json=ifNotExists,proto3" json:"if_not_exists,omitempty"`
+	AffectedRows  int64                  `protobuf:"varint,1,opt,name=affected_rows,json=affectedRows,proto3" json:"affected_rows,omitempty"`
+	LastInsertId  int64                  `protobuf:"varint,2,opt,name=last_insert_id,json=lastInsertId,proto3" json:"last_insert_id,omitempty"` // For insert and upsert
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *CreateTableRequest) Reset() {
-	*x = CreateTableRequest{}
+func (x *OperationResult) Reset() {
+	*x = OperationResult{}
 	mi := &file_chadbot_storage_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *CreateTableRequest) String() string {
+func (x *OperationResult) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*CreateTableRequest) ProtoMessage() {}
+func (*OperationResult) ProtoMessage() {}
 
-func (x *CreateTableRequest) ProtoReflect() protoreflect.Message {
+func (x *OperationResult) ProtoReflect() protoreflect.Message {
 	mi := &file_chadbot_storage_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -381,55 +684,52 @@ func (x *CreateTableRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use CreateTableRequest.ProtoReflect.Descriptor instead.
-func (*CreateTableRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use OperationResult.ProtoReflect.Descriptor instead.
+func (*OperationResult) Descriptor() ([]byte, []int) {
 	return file_chadbot_storage_proto_rawDescGZIP(), []int{3}
 }
 
-func (x *CreateTableRequest) GetTableName() string {
-	if x != nil {
-		return x.TableName
-	}
-	return ""
-}
-
-func (x *CreateTableRequest) GetColumns() []*ColumnDef {
+func (x *OperationResult) GetAffectedRows() int64 {
 	if x != nil {
-		return x.Columns
+		return x.AffectedRows
 	}
-	return nil
+	return 0
 }
 
-func (x *CreateTableRequest) GetIfNotExists() bool {
+func (x *OperationResult) GetLastInsertId() int64 {
 	if x != nil {
-		return x.IfNotExists
+		return x.LastInsertId
 	}
-	return false
+	return 0
 }
 
-// Drop table request
-type DropTableRequest struct {
+// Table column definition
+type ColumnDef struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	TableName     string                 `protobuf:"bytes,1,opt,name=table_name,json=tableName,proto3" json:"table_name,omitempty"`
-	IfExists      bool                   `protobuf:"varint,2,opt,name=if_exists,json=ifExists,proto3" json:"if_exists,omitempty"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Type          string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"` // TEXT, INTEGER, REAL, BLOB
+	PrimaryKey    bool                   `protobuf:"varint,3,opt,name=primary_key,json=primaryKey,proto3" json:"primary_key,omitempty"`
+	NotNull       bool                   `protobuf:"varint,4,opt,name=not_null,json=notNull,proto3" json:"not_null,omitempty"`
+	Unique        bool                   `protobuf:"varint,5,opt,name=unique,proto3" json:"unique,omitempty"`
+	DefaultValue  string                 `protobuf:"bytes,6,opt,name=default_value,json=defaultValue,proto3" json:"default_value,omitempty"` // Literal (number, 'text', NULL, TRUE, FALSE, CURRENT_TIMESTAMP); expressions need storage_raw_sql
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *DropTableRequest) Reset() {
-	*x = DropTableRequest{}
+func (x *ColumnDef) Reset() {
+	*x = ColumnDef{}
 	mi := &file_chadbot_storage_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *DropTableRequest) String() string {
+func (x *ColumnDef) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*DropTableRequest) ProtoMessage() {}
+func (*ColumnDef) ProtoMessage() {}
 
-func (x *DropTableRequest) ProtoReflect() protoreflect.Message {
+func (x *ColumnDef) ProtoReflect() protoreflect.Message {
 	mi := &file_chadbot_storage_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -441,49 +741,2589 @@ func (x *DropTableRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use DropTableRequest.ProtoReflect.Descriptor instead.
-func (*DropTableRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use ColumnDef.ProtoReflect.Descriptor instead.
+func (*ColumnDef) Descriptor() ([]byte, []int) {
 	return file_chadbot_storage_proto_rawDescGZIP(), []int{4}
 }
 
-func (x *DropTableRequest) GetTableName() string {
+func (x *ColumnDef) GetName() string {
 	if x != nil {
-		return x.TableName
+		return x.Name
 	}
 	return ""
 }
 
-func (x *DropTableRequest) GetIfExists() bool {
+func (x *ColumnDef) GetType() string {
 	if x != nil {
-		return x.IfExists
+		return x.Type
 	}
-	return false
+	return ""
 }
 
-// Insert request
+func (x *ColumnDef) GetPrimaryKey() bool {
+	if x != nil {
+		return x.PrimaryKey
+	}
+	return false
+}
+
+func (x *ColumnDef) GetNotNull() bool {
+	if x != nil {
+		return x.NotNull
+	}
+	return false
+}
+
+func (x *ColumnDef) GetUnique() bool {
+	if x != nil {
+		return x.Unique
+	}
+	return false
+}
+
+func (x *ColumnDef) GetDefaultValue() string {
+	if x != nil {
+		return x.DefaultValue
+	}
+	return ""
+}
+
+// Create table request
+type CreateTableRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TableName     string                 `protobuf:"bytes,1,opt,name=table_name,json=tableName,proto3" json:"table_name,omitempty"` // Will be prefixed with plugin namespace
+	Columns       []*ColumnDef           `protobuf:"bytes,2,rep,name=columns,proto3" json:"columns,omitempty"`
+	IfNotExists   bool                   `protobuf:"varint,3,opt,name=if_not_exists,json=ifNotExists,proto3" json:"if_not_exists,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateTableRequest) Reset() {
+	*x = CreateTableRequest{}
+	mi := &file_chadbot_storage_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateTableRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateTableRequest) ProtoMessage() {}
+
+func (x *CreateTableRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_chadbot_storage_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateTableRequest.ProtoReflect.Descriptor instead.
+func (*CreateTableRequest) Descriptor() ([]byte, []int) {
+	return file_chadbot_storage_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *CreateTableRequest) GetTableName() string {
+	if x != nil {
+		return x.TableName
+	}
+	return ""
+}
+
+func (x *CreateTableRequest) GetColumns() []*ColumnDef {
+	if x != nil {
+		return x.Columns
+	}
+	return nil
+}
+
+func (x *CreateTableRequest) GetIfNotExists() bool {
+	if x != nil {
+		return x.IfNotExists
+	}
+	return false
+}
+
+// Drop table request
+type DropTableRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TableName     string                 `protobuf:"bytes,1,opt,name=table_name,json=tableName,proto3" json:"table_name,omitempty"`
+	IfExists      bool                   `protobuf:"varint,2,opt,name=if_exists,json=ifExists,proto3" json:"if_exists,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DropTableRequest) Reset() {
+	*x = DropTableRequest{}
+	mi := &file_chadbot_storage_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DropTableRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DropTableRequest) ProtoMessage() {}
+
+func (x *DropTableRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_chadbot_storage_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DropTableRequest.ProtoReflect.Descriptor instead.
+func (*DropTableRequest) Descriptor() ([]byte, []int) {
+	return file_chadbot_storage_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *DropTableRequest) GetTableName() string {
+	if x != nil {
+		return x.TableName
+	}
+	return ""
+}
+
+func (x *DropTableRequest) GetIfExists() bool {
+	if x != nil {
+		return x.IfExists
+	}
+	return false
+}
+
+// Insert request
 type InsertRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TableName     string                 `protobuf:"bytes,1,opt,name=table_name,json=tableName,proto3" json:"table_name,omitempty"`
+	Values        map[string]string      `protobuf:"bytes,2,rep,name=values,proto3" json:"values,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`                              // Column -> value
+	TypedValues   map[string]*Value      `protobuf:"bytes,3,rep,name=typed_values,json=typedValues,proto3" json:"typed_values,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // Column -> typed value (may be combined with values)
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *InsertRequest) Reset() {
+	*x = InsertRequest{}
+	mi := &file_chadbot_storage_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InsertRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InsertRequest) ProtoMessage() {}
+
+func (x *InsertRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_chadbot_storage_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InsertRequest.ProtoReflect.Descriptor instead.
+func (*InsertRequest) Descriptor() ([]byte, []int) {
+	return file_chadbot_storage_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *InsertRequest) GetTableName() string {
+	if x != nil {
+		return x.TableName
+	}
+	return ""
+}
+
+func (x *InsertRequest) GetValues() map[string]string {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+func (x *InsertRequest) GetTypedValues() map[string]*Value {
+	if x != nil {
+		return x.TypedValues
+	}
+	return nil
+}
+
+// Update request
+type UpdateRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	TableName     string                 `protobuf:"bytes,1,opt,name=table_name,json=tableName,proto3" json:"table_name,omitempty"`
 	Values        map[string]string      `protobuf:"bytes,2,rep,name=values,proto3" json:"values,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // Column -> value
+	WhereClause   string                 `protobuf:"bytes,3,opt,name=where_clause,json=whereClause,proto3" json:"where_clause,omitempty"`                                              // Raw SQL WHERE clause (without WHERE keyword), needs storage_raw_sql
+	WhereArgs     []string               `protobuf:"bytes,4,rep,name=where_args,json=whereArgs,proto3" json:"where_args,omitempty"`                                                    // Arguments for placeholders
+	TypedValues   map[string]*Value      `protobuf:"bytes,5,rep,name=typed_values,json=typedValues,proto3" json:"typed_values,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Filter        *Filter                `protobuf:"bytes,6,opt,name=filter,proto3" json:"filter,omitempty"` // Rows to update (unset = all rows)
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *InsertRequest) Reset() {
-	*x = InsertRequest{}
-	mi := &file_chadbot_storage_proto_msgTypes[5]
+func (x *UpdateRequest) Reset() {
+	*x = UpdateRequest{}
+	mi := &file_chadbot_storage_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateRequest) ProtoMessage() {}
+
+func (x *UpdateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_chadbot_storage_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateRequest.ProtoReflect.Descriptor instead.
+func (*UpdateRequest) Descriptor() ([]byte, []int) {
+	return file_chadbot_storage_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *UpdateRequest) GetTableName() string {
+	if x != nil {
+		return x.TableName
+	}
+	return ""
+}
+
+func (x *UpdateRequest) GetValues() map[string]string {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+func (x *UpdateRequest) GetWhereClause() string {
+	if x != nil {
+		return x.WhereClause
+	}
+	return ""
+}
+
+func (x *UpdateRequest) GetWhereArgs() []string {
+	if x != nil {
+		return x.WhereArgs
+	}
+	return nil
+}
+
+func (x *UpdateRequest) GetTypedValues() map[string]*Value {
+	if x != nil {
+		return x.TypedValues
+	}
+	return nil
+}
+
+func (x *UpdateRequest) GetFilter() *Filter {
+	if x != nil {
+		return x.Filter
+	}
+	return nil
+}
+
+// Delete request
+type DeleteRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TableName     string                 `protobuf:"bytes,1,opt,name=table_name,json=tableName,proto3" json:"table_name,omitempty"`
+	WhereClause   string                 `protobuf:"bytes,2,opt,name=where_clause,json=whereClause,proto3" json:"where_clause,omitempty"` // Raw SQL, needs storage_raw_sql
+	WhereArgs     []string               `protobuf:"bytes,3,rep,name=where_args,json=whereArgs,proto3" json:"where_args,omitempty"`
+	Filter        *Filter                `protobuf:"bytes,4,opt,name=filter,proto3" json:"filter,omitempty"` // Rows to delete (unset = all rows)
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteRequest) Reset() {
+	*x = DeleteRequest{}
+	mi := &file_chadbot_storage_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteRequest) ProtoMessage() {}
+
+func (x *DeleteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_chadbot_storage_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteRequest.ProtoReflect.Descriptor instead.
+func (*DeleteRequest) Descriptor() ([]byte, []int) {
+	return file_chadbot_storage_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *DeleteRequest) GetTableName() string {
+	if x != nil {
+		return x.TableName
+	}
+	return ""
+}
+
+func (x *DeleteRequest) GetWhereClause() string {
+	if x != nil {
+		return x.WhereClause
+	}
+	return ""
+}
+
+func (x *DeleteRequest) GetWhereArgs() []string {
+	if x != nil {
+		return x.WhereArgs
+	}
+	return nil
+}
+
+func (x *DeleteRequest) GetFilter() *Filter {
+	if x != nil {
+		return x.Filter
+	}
+	return nil
+}
+
+// Upsert request: inserts a row or updates the row it conflicts with
+type UpsertRequest struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	TableName       string                 `protobuf:"bytes,1,opt,name=table_name,json=tableName,proto3" json:"table_name,omitempty"`
+	Values          map[string]string      `protobuf:"bytes,2,rep,name=values,proto3" json:"values,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // Column -> value
+	ConflictColumns []string               `protobuf:"bytes,3,rep,name=conflict_columns,json=conflictColumns,proto3" json:"conflict_columns,omitempty"`                                  // ON CONFLICT target (primary key or unique columns)
+	UpdateColumns   []string               `protobuf:"bytes,4,rep,name=update_columns,json=updateColumns,proto3" json:"update_columns,omitempty"`                                        // Columns updated on conflict (empty = all values except the conflict columns)
+	TypedValues     map[string]*Value      `protobuf:"bytes,5,rep,name=typed_values,json=typedValues,proto3" json:"typed_values,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *UpsertRequest) Reset() {
+	*x = UpsertRequest{}
+	mi := &file_chadbot_storage_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpsertRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpsertRequest) ProtoMessage() {}
+
+func (x *UpsertRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_chadbot_storage_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpsertRequest.ProtoReflect.Descriptor instead.
+func (*UpsertRequest) Descriptor() ([]byte, []int) {
+	return file_chadbot_storage_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *UpsertRequest) GetTableName() string {
+	if x != nil {
+		return x.TableName
+	}
+	return ""
+}
+
+func (x *UpsertRequest) GetValues() map[string]string {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+func (x *UpsertRequest) GetConflictColumns() []string {
+	if x != nil {
+		return x.ConflictColumns
+	}
+	return nil
+}
+
+func (x *UpsertRequest) GetUpdateColumns() []string {
+	if x != nil {
+		return x.UpdateColumns
+	}
+	return nil
+}
+
+func (x *UpsertRequest) GetTypedValues() map[string]*Value {
+	if x != nil {
+		return x.TypedValues
+	}
+	return nil
+}
+
+// Batch request: runs its operations in one transaction, all or nothing
+type BatchRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Operations    []*BatchOperation      `protobuf:"bytes,1,rep,name=operations,proto3" json:"operations,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchRequest) Reset() {
+	*x = BatchRequest{}
+	mi := &file_chadbot_storage_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchRequest) ProtoMessage() {}
+
+func (x *BatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_chadbot_storage_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchRequest.ProtoReflect.Descriptor instead.
+func (*BatchRequest) Descriptor() ([]byte, []int) {
+	return file_chadbot_storage_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *BatchRequest) GetOperations() []*BatchOperation {
+	if x != nil {
+		return x.Operations
+	}
+	return nil
+}
+
+// Single write of a batch
+type BatchOperation struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Operation:
+	//
+	//	*BatchOperation_Insert
+	//	*BatchOperation_Update
+	//	*BatchOperation_Delete
+	//	*BatchOperation_Upsert
+	Operation     isBatchOperation_Operation `protobuf_oneof:"operation"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchOperation) Reset() {
+	*x = BatchOperation{}
+	mi := &file_chadbot_storage_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchOperation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchOperation) ProtoMessage() {}
+
+func (x *BatchOperation) ProtoReflect() protoreflect.Message {
+	mi := &file_chadbot_storage_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchOperation.ProtoReflect.Descriptor instead.
+func (*BatchOperation) Descriptor() ([]byte, []int) {
+	return file_chadbot_storage_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *BatchOperation) GetOperation() isBatchOperation_Operation {
+	if x != nil {
+		return x.Operation
+	}
+	return nil
+}
+
+func (x *BatchOperation) GetInsert() *InsertRequest {
+	if x != nil {
+		if x, ok := x.Operation.(*BatchOperation_Insert); ok {
+			return x.Insert
+		}
+	}
+	return nil
+}
+
+func (x *BatchOperation) GetUpdate() *UpdateRequest {
+	if x != nil {
+		if x, ok := x.Operation.(*BatchOperation_Update); ok {
+			return x.Update
+		}
+	}
+	return nil
+}
+
+func (x *BatchOperation) GetDelete() *DeleteRequest {
+	if x != nil {
+		if x, ok := x.Operation.(*BatchOperation_Delete); ok {
+			return x.Delete
+		}
+	}
+	return nil
+}
+
+func (x *BatchOperation) GetUpsert() *UpsertRequest {
+	if x != nil {
+		if x, ok := x.Operation.(*BatchOperation_Upsert); ok {
+			return x.Upsert
+		}
+	}
+	return nil
+}
+
+type isBatchOperation_Operation interface {
+	isBatchOperation_Operation()
+}
+
+type BatchOperation_Insert struct {
+	Insert *InsertRequest `protobuf:"bytes,1,opt,name=insert,proto3,oneof"`
+}
+
+type BatchOperation_Update struct {
+	Update *UpdateRequest `protobuf:"bytes,2,opt,name=update,proto3,oneof"`
+}
+
+type BatchOperation_Delete struct {
+	Delete *DeleteRequest `protobuf:"bytes,3,opt,name=delete,proto3,oneof"`
+}
+
+type BatchOperation_Upsert struct {
+	Upsert *UpsertRequest `protobuf:"bytes,4,opt,name=upsert,proto3,oneof"`
+}
+
+func (*BatchOperation_Insert) isBatchOperation_Operation() {}
+
+func (*BatchOperation_Update) isBatchOperation_Operation() {}
+
+func (*BatchOperation_Delete) isBatchOperation_Operation() {}
+
+func (*BatchOperation_Upsert) isBatchOperation_Operation() {}
+
+// Query request
+type QueryRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TableName     string                 `protobuf:"bytes,1,opt,name=table_name,json=tableName,proto3" json:"table_name,omitempty"`
+	Columns       []string               `protobuf:"bytes,2,rep,name=columns,proto3" json:"columns,omitempty"`                            // Empty = all columns
+	WhereClause   string                 `protobuf:"bytes,3,opt,name=where_clause,json=whereClause,proto3" json:"where_clause,omitempty"` // Raw SQL, needs storage_raw_sql
+	WhereArgs     []string               `protobuf:"bytes,4,rep,name=where_args,json=whereArgs,proto3" json:"where_args,omitempty"`
+	OrderBy       string                 `protobuf:"bytes,5,opt,name=order_by,json=orderBy,proto3" json:"order_by,omitempty"` // "column [ASC|DESC], ..." (other expressions need storage_raw_sql)
+	Limit         int32                  `protobuf:"varint,6,opt,name=limit,proto3" json:"limit,omitempty"`
+	Offset        int32                  `protobuf:"varint,7,opt,name=offset,proto3" json:"offset,omitempty"`
+	Typed         bool                   `protobuf:"varint,8,opt,name=typed,proto3" json:"typed,omitempty"` // Return typed_values instead of values
+	Filter        *Filter                `protobuf:"bytes,9,opt,name=filter,proto3" json:"filter,omitempty"`
+	Order         []*OrderBy             `protobuf:"bytes,10,rep,name=order,proto3" json:"order,omitempty"` // Takes precedence over order_by
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueryRequest) Reset() {
+	*x = QueryRequest{}
+	mi := &file_chadbot_storage_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryRequest) ProtoMessage() {}
+
+func (x *QueryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_chadbot_storage_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryRequest.ProtoReflect.Descriptor instead.
+func (*QueryRequest) Descriptor() ([]byte, []int) {
+	return file_chadbot_storage_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *QueryRequest) GetTableName() string {
+	if x != nil {
+		return x.TableName
+	}
+	return ""
+}
+
+func (x *QueryRequest) GetColumns() []string {
+	if x != nil {
+		return x.Columns
+	}
+	return nil
+}
+
+func (x *QueryRequest) GetWhereClause() string {
+	if x != nil {
+		return x.WhereClause
+	}
+	return ""
+}
+
+func (x *QueryRequest) GetWhereArgs() []string {
+	if x != nil {
+		return x.WhereArgs
+	}
+	return nil
+}
+
+func (x *QueryRequest) GetOrderBy() string {
+	if x != nil {
+		return x.OrderBy
+	}
+	return ""
+}
+
+func (x *QueryRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *QueryRequest) GetOffset() int32 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *QueryRequest) GetTyped() bool {
+	if x != nil {
+		return x.Typed
+	}
+	return false
+}
+
+func (x *QueryRequest) GetFilter() *Filter {
+	if x != nil {
+		return x.Filter
+	}
+	return nil
+}
+
+func (x *QueryRequest) GetOrder() []*OrderBy {
+	if x != nil {
+		return x.Order
+	}
+	return nil
+}
+
+// Sort column of a query
+type OrderBy struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Column        string                 `protobuf:"bytes,1,opt,name=column,proto3" json:"column,omitempty"`
+	Desc          bool                   `protobuf:"varint,2,opt,name=desc,proto3" json:"desc,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OrderBy) Reset() {
+	*x = OrderBy{}
+	mi := &file_chadbot_storage_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OrderBy) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OrderBy) ProtoMessage() {}
+
+func (x *OrderBy) ProtoReflect() protoreflect.Message {
+	mi := &file_chadbot_storage_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OrderBy.ProtoReflect.Descriptor instead.
+func (*OrderBy) Descriptor() ([]byte, []int) {
+	return file_chadbot_storage_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *OrderBy) GetColumn() string {
+	if x != nil {
+		return x.Column
+	}
+	return ""
+}
+
+func (x *OrderBy) GetDesc() bool {
+	if x != nil {
+		return x.Desc
+	}
+	return false
+}
+
+// Filter expression: a condition or an AND/OR/NOT of filters
+type Filter struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Expr:
+	//
+	//	*Filter_Condition
+	//	*Filter_And
+	//	*Filter_Or
+	//	*Filter_Not
+	Expr          isFilter_Expr `protobuf_oneof:"expr"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Filter) Reset() {
+	*x = Filter{}
+	mi := &file_chadbot_storage_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Filter) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Filter) ProtoMessage() {}
+
+func (x *Filter) ProtoReflect() protoreflect.Message {
+	mi := &file_chadbot_storage_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Filter.ProtoReflect.Descriptor instead.
+func (*Filter) Descriptor() ([]byte, []int) {
+	return file_chadbot_storage_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *Filter) GetExpr() isFilter_Expr {
+	if x != nil {
+		return x.Expr
+	}
+	return nil
+}
+
+func (x *Filter) GetCondition() *Condition {
+	if x != nil {
+		if x, ok := x.Expr.(*Filter_Condition); ok {
+			return x.Condition
+		}
+	}
+	return nil
+}
+
+func (x *Filter) GetAnd() *FilterList {
+	if x != nil {
+		if x, ok := x.Expr.(*Filter_And); ok {
+			return x.And
+		}
+	}
+	return nil
+}
+
+func (x *Filter) GetOr() *FilterList {
+	if x != nil {
+		if x, ok := x.Expr.(*Filter_Or); ok {
+			return x.Or
+		}
+	}
+	return nil
+}
+
+func (x *Filter) GetNot() *Filter {
+	if x != nil {
+		if x, ok := x.Expr.(*Filter_Not); ok {
+			return x.Not
+		}
+	}
+	return nil
+}
+
+type isFilter_Expr interface {
+	isFilter_Expr()
+}
+
+type Filter_Condition struct {
+	Condition *Condition `protobuf:"bytes,1,opt,name=condition,proto3,oneof"`
+}
+
+type Filter_And struct {
+	And *FilterList `protobuf:"bytes,2,opt,name=and,proto3,oneof"`
+}
+
+type Filter_Or struct {
+	Or *FilterList `protobuf:"bytes,3,opt,name=or,proto3,oneof"`
+}
+
+type Filter_Not struct {
+	Not *Filter `protobuf:"bytes,4,opt,name=not,proto3,oneof"`
+}
+
+func (*Filter_Condition) isFilter_Expr() {}
+
+func (*Filter_And) isFilter_Expr() {}
+
+func (*Filter_Or) isFilter_Expr() {}
+
+func (*Filter_Not) isFilter_Expr() {}
+
+// List of filters combined by AND or OR
+type FilterList struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Filters       []*Filter              `protobuf:"bytes,1,rep,name=filters,proto3" json:"filters,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FilterList) Reset() {
+	*x = FilterList{}
+	mi := &file_chadbot_storage_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FilterList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FilterList) ProtoMessage() {}
+
+func (x *FilterList) ProtoReflect() protoreflect.Message {
+	mi := &file_chadbot_storage_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FilterList.ProtoReflect.Descriptor instead.
+func (*FilterList) Descriptor() ([]byte, []int) {
+	return file_chadbot_storage_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *FilterList) GetFilters() []*Filter {
+	if x != nil {
+		return x.Filters
+	}
+	return nil
+}
+
+// Condition on a column
+type Condition struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Column        string                 `protobuf:"bytes,1,opt,name=column,proto3" json:"column,omitempty"`
+	Op            FilterOp               `protobuf:"varint,2,opt,name=op,proto3,enum=chadbot.FilterOp" json:"op,omitempty"`
+	Value         *Value                 `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`   // Operand of comparisons and LIKE
+	Values        []*Value               `protobuf:"bytes,4,rep,name=values,proto3" json:"values,omitempty"` // Operands of IN and NOT IN
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Condition) Reset() {
+	*x = Condition{}
+	mi := &file_chadbot_storage_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Condition) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Condition) ProtoMessage() {}
+
+func (x *Condition) ProtoReflect() protoreflect.Message {
+	mi := &file_chadbot_storage_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Condition.ProtoReflect.Descriptor instead.
+func (*Condition) Descriptor() ([]byte, []int) {
+	return file_chadbot_storage_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *Condition) GetColumn() string {
+	if x != nil {
+		return x.Column
+	}
+	return ""
+}
+
+func (x *Condition) GetOp() FilterOp {
+	if x != nil {
+		return x.Op
+	}
+	return FilterOp_FILTER_OP_EQ
+}
+
+func (x *Condition) GetValue() *Value {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+func (x *Condition) GetValues() []*Value {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+// Count request: number of rows matching a condition
+type CountRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TableName     string                 `protobuf:"bytes,1,opt,name=table_name,json=tableName,proto3" json:"table_name,omitempty"`
+	WhereClause   string                 `protobuf:"bytes,2,opt,name=where_clause,json=whereClause,proto3" json:"where_clause,omitempty"` // Raw SQL, needs storage_raw_sql
+	WhereArgs     []string               `protobuf:"bytes,3,rep,name=where_args,json=whereArgs,proto3" json:"where_args,omitempty"`
+	Filter        *Filter                `protobuf:"bytes,4,opt,name=filter,proto3" json:"filter,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CountRequest) Reset() {
+	*x = CountRequest{}
+	mi := &file_chadbot_storage_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CountRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CountRequest) ProtoMessage() {}
+
+func (x *CountRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_chadbot_storage_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CountRequest.ProtoReflect.Descriptor instead.
+func (*CountRequest) Descriptor() ([]byte, []int) {
+	return file_chadbot_storage_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *CountRequest) GetTableName() string {
+	if x != nil {
+		return x.TableName
+	}
+	return ""
+}
+
+func (x *CountRequest) GetWhereClause() string {
+	if x != nil {
+		return x.WhereClause
+	}
+	return ""
+}
+
+func (x *CountRequest) GetWhereArgs() []string {
+	if x != nil {
+		return x.WhereArgs
+	}
+	return nil
+}
+
+func (x *CountRequest) GetFilter() *Filter {
+	if x != nil {
+		return x.Filter
+	}
+	return nil
+}
+
+// Aggregate request: aggregate functions over rows, optionally grouped.
+// Result rows are typed and hold the group_by columns and one column per aggregate alias.
+type AggregateRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TableName     string                 `protobuf:"bytes,1,opt,name=table_name,json=tableName,proto3" json:"table_name,omitempty"`
+	Aggregates    []*Aggregate           `protobuf:"bytes,2,rep,name=aggregates,proto3" json:"aggregates,omitempty"`
+	WhereClause   string                 `protobuf:"bytes,3,opt,name=where_clause,json=whereClause,proto3" json:"where_clause,omitempty"` // Raw SQL, needs storage_raw_sql
+	WhereArgs     []string               `protobuf:"bytes,4,rep,name=where_args,json=whereArgs,proto3" json:"where_args,omitempty"`
+	GroupBy       []string               `protobuf:"bytes,5,rep,name=group_by,json=groupBy,proto3" json:"group_by,omitempty"`
+	Filter        *Filter                `protobuf:"bytes,6,opt,name=filter,proto3" json:"filter,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AggregateRequest) Reset() {
+	*x = AggregateRequest{}
+	mi := &file_chadbot_storage_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AggregateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AggregateRequest) ProtoMessage() {}
+
+func (x *AggregateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_chadbot_storage_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AggregateRequest.ProtoReflect.Descriptor instead.
+func (*AggregateRequest) Descriptor() ([]byte, []int) {
+	return file_chadbot_storage_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *AggregateRequest) GetTableName() string {
+	if x != nil {
+		return x.TableName
+	}
+	return ""
+}
+
+func (x *AggregateRequest) GetAggregates() []*Aggregate {
+	if x != nil {
+		return x.Aggregates
+	}
+	return nil
+}
+
+func (x *AggregateRequest) GetWhereClause() string {
+	if x != nil {
+		return x.WhereClause
+	}
+	return ""
+}
+
+func (x *AggregateRequest) GetWhereArgs() []string {
+	if x != nil {
+		return x.WhereArgs
+	}
+	return nil
+}
+
+func (x *AggregateRequest) GetGroupBy() []string {
+	if x != nil {
+		return x.GroupBy
+	}
+	return nil
+}
+
+func (x *AggregateRequest) GetFilter() *Filter {
+	if x != nil {
+		return x.Filter
+	}
+	return nil
+}
+
+// Aggregate function over a column
+type Aggregate struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Function      string                 `protobuf:"bytes,1,opt,name=function,proto3" json:"function,omitempty"` // count, sum, avg, min or max
+	Column        string                 `protobuf:"bytes,2,opt,name=column,proto3" json:"column,omitempty"`     // Empty = all rows (count only)
+	Alias         string                 `protobuf:"bytes,3,opt,name=alias,proto3" json:"alias,omitempty"`       // Result column name (default function_column)
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Aggregate) Reset() {
+	*x = Aggregate{}
+	mi := &file_chadbot_storage_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Aggregate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Aggregate) ProtoMessage() {}
+
+func (x *Aggregate) ProtoReflect() protoreflect.Message {
+	mi := &file_chadbot_storage_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Aggregate.ProtoReflect.Descriptor instead.
+func (*Aggregate) Descriptor() ([]byte, []int) {
+	return file_chadbot_storage_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *Aggregate) GetFunction() string {
+	if x != nil {
+		return x.Function
+	}
+	return ""
+}
+
+func (x *Aggregate) GetColumn() string {
+	if x != nil {
+		return x.Column
+	}
+	return ""
+}
+
+func (x *Aggregate) GetAlias() string {
+	if x != nil {
+		return x.Alias
+	}
+	return ""
+}
+
+// Row in query result
+type Row struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Values        map[string]string      `protobuf:"bytes,1,rep,name=values,proto3" json:"values,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	TypedValues   map[string]*Value      `protobuf:"bytes,2,rep,name=typed_values,json=typedValues,proto3" json:"typed_values,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Row) Reset() {
+	*x = Row{}
+	mi := &file_chadbot_storage_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Row) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Row) ProtoMessage() {}
+
+func (x *Row) ProtoReflect() protoreflect.Message {
+	mi := &file_chadbot_storage_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Row.ProtoReflect.Descriptor instead.
+func (*Row) Descriptor() ([]byte, []int) {
+	return file_chadbot_storage_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *Row) GetValues() map[string]string {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+func (x *Row) GetTypedValues() map[string]*Value {
+	if x != nil {
+		return x.TypedValues
+	}
+	return nil
+}
+
+// Migrate request: registers the plugin's ordered schema migrations.
+// Migrations not applied yet run once each, in version order, in their own transaction.
+type MigrateRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Migrations    []*Migration           `protobuf:"bytes,1,rep,name=migrations,proto3" json:"migrations,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MigrateRequest) Reset() {
+	*x = MigrateRequest{}
+	mi := &file_chadbot_storage_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MigrateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MigrateRequest) ProtoMessage() {}
+
+func (x *MigrateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_chadbot_storage_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MigrateRequest.ProtoReflect.Descriptor instead.
+func (*MigrateRequest) Descriptor() ([]byte, []int) {
+	return file_chadbot_storage_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *MigrateRequest) GetMigrations() []*Migration {
+	if x != nil {
+		return x.Migrations
+	}
+	return nil
+}
+
+// Versioned schema migration
+type Migration struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Version       int32                  `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"` // Unique and > 0; applied in ascending order
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Steps         []*MigrationStep       `protobuf:"bytes,3,rep,name=steps,proto3" json:"steps,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Migration) Reset() {
+	*x = Migration{}
+	mi := &file_chadbot_storage_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Migration) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Migration) ProtoMessage() {}
+
+func (x *Migration) ProtoReflect() protoreflect.Message {
+	mi := &file_chadbot_storage_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Migration.ProtoReflect.Descriptor instead.
+func (*Migration) Descriptor() ([]byte, []int) {
+	return file_chadbot_storage_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *Migration) GetVersion() int32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *Migration) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Migration) GetSteps() []*MigrationStep {
+	if x != nil {
+		return x.Steps
+	}
+	return nil
+}
+
+// Single schema change of a migration. Table and index names are prefixed with the plugin namespace.
+type MigrationStep struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Step:
+	//
+	//	*MigrationStep_CreateTable
+	//	*MigrationStep_DropTable
+	//	*MigrationStep_RenameTable
+	//	*MigrationStep_AddColumn
+	//	*MigrationStep_RenameColumn
+	//	*MigrationStep_DropColumn
+	//	*MigrationStep_CreateIndex
+	//	*MigrationStep_DropIndex
+	//	*MigrationStep_Sql
+	//	*MigrationStep_Update
+	Step          isMigrationStep_Step `protobuf_oneof:"step"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MigrationStep) Reset() {
+	*x = MigrationStep{}
+	mi := &file_chadbot_storage_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MigrationStep) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MigrationStep) ProtoMessage() {}
+
+func (x *MigrationStep) ProtoReflect() protoreflect.Message {
+	mi := &file_chadbot_storage_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MigrationStep.ProtoReflect.Descriptor instead.
+func (*MigrationStep) Descriptor() ([]byte, []int) {
+	return file_chadbot_storage_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *MigrationStep) GetStep() isMigrationStep_Step {
+	if x != nil {
+		return x.Step
+	}
+	return nil
+}
+
+func (x *MigrationStep) GetCreateTable() *CreateTableRequest {
+	if x != nil {
+		if x, ok := x.Step.(*MigrationStep_CreateTable); ok {
+			return x.CreateTable
+		}
+	}
+	return nil
+}
+
+func (x *MigrationStep) GetDropTable() *DropTableRequest {
+	if x != nil {
+		if x, ok := x.Step.(*MigrationStep_DropTable); ok {
+			return x.DropTable
+		}
+	}
+	return nil
+}
+
+func (x *MigrationStep) GetRenameTable() *RenameTableStep {
+	if x != nil {
+		if x, ok := x.Step.(*MigrationStep_RenameTable); ok {
+			return x.RenameTable
+		}
+	}
+	return nil
+}
+
+func (x *MigrationStep) GetAddColumn() *AddColumnStep {
+	if x != nil {
+		if x, ok := x.Step.(*MigrationStep_AddColumn); ok {
+			return x.AddColumn
+		}
+	}
+	return nil
+}
+
+func (x *MigrationStep) GetRenameColumn() *RenameColumnStep {
+	if x != nil {
+		if x, ok := x.Step.(*MigrationStep_RenameColumn); ok {
+			return x.RenameColumn
+		}
+	}
+	return nil
+}
+
+func (x *MigrationStep) GetDropColumn() *DropColumnStep {
+	if x != nil {
+		if x, ok := x.Step.(*MigrationStep_DropColumn); ok {
+			return x.DropColumn
+		}
+	}
+	return nil
+}
+
+func (x *MigrationStep) GetCreateIndex() *CreateIndexStep {
+	if x != nil {
+		if x, ok := x.Step.(*MigrationStep_CreateIndex); ok {
+			return x.CreateIndex
+		}
+	}
+	return nil
+}
+
+func (x *MigrationStep) GetDropIndex() *DropIndexStep {
+	if x != nil {
+		if x, ok := x.Step.(*MigrationStep_DropIndex); ok {
+			return x.DropIndex
+		}
+	}
+	return nil
+}
+
+func (x *MigrationStep) GetSql() *SQLStep {
+	if x != nil {
+		if x, ok := x.Step.(*MigrationStep_Sql); ok {
+			return x.Sql
+		}
+	}
+	return nil
+}
+
+func (x *MigrationStep) GetUpdate() *UpdateRequest {
+	if x != nil {
+		if x, ok := x.Step.(*MigrationStep_Update); ok {
+			return x.Update
+		}
+	}
+	return nil
+}
+
+type isMigrationStep_Step interface {
+	isMigrationStep_Step()
+}
+
+type MigrationStep_CreateTable struct {
+	CreateTable *CreateTableRequest `protobuf:"bytes,1,opt,name=create_table,json=createTable,proto3,oneof"`
+}
+
+type MigrationStep_DropTable struct {
+	DropTable *DropTableRequest `protobuf:"bytes,2,opt,name=drop_table,json=dropTable,proto3,oneof"`
+}
+
+type MigrationStep_RenameTable struct {
+	RenameTable *RenameTableStep `protobuf:"bytes,3,opt,name=rename_table,json=renameTable,proto3,oneof"`
+}
+
+type MigrationStep_AddColumn struct {
+	AddColumn *AddColumnStep `protobuf:"bytes,4,opt,name=add_column,json=addColumn,proto3,oneof"`
+}
+
+type MigrationStep_RenameColumn struct {
+	RenameColumn *RenameColumnStep `protobuf:"bytes,5,opt,name=rename_column,json=renameColumn,proto3,oneof"`
+}
+
+type MigrationStep_DropColumn struct {
+	DropColumn *DropColumnStep `protobuf:"bytes,6,opt,name=drop_column,json=dropColumn,proto3,oneof"`
+}
+
+type MigrationStep_CreateIndex struct {
+	CreateIndex *CreateIndexStep `protobuf:"bytes,7,opt,name=create_index,json=createIndex,proto3,oneof"`
+}
+
+type MigrationStep_DropIndex struct {
+	DropIndex *DropIndexStep `protobuf:"bytes,8,opt,name=drop_index,json=dropIndex,proto3,oneof"`
+}
+
+type MigrationStep_Sql struct {
+	Sql *SQLStep `protobuf:"bytes,9,opt,name=sql,proto3,oneof"` // Needs storage_raw_sql
+}
+
+type MigrationStep_Update struct {
+	Update *UpdateRequest `protobuf:"bytes,10,opt,name=update,proto3,oneof"` // Data migration with a filter
+}
+
+func (*MigrationStep_CreateTable) isMigrationStep_Step() {}
+
+func (*MigrationStep_DropTable) isMigrationStep_Step() {}
+
+func (*MigrationStep_RenameTable) isMigrationStep_Step() {}
+
+func (*MigrationStep_AddColumn) isMigrationStep_Step() {}
+
+func (*MigrationStep_RenameColumn) isMigrationStep_Step() {}
+
+func (*MigrationStep_DropColumn) isMigrationStep_Step() {}
+
+func (*MigrationStep_CreateIndex) isMigrationStep_Step() {}
+
+func (*MigrationStep_DropIndex) isMigrationStep_Step() {}
+
+func (*MigrationStep_Sql) isMigrationStep_Step() {}
+
+func (*MigrationStep_Update) isMigrationStep_Step() {}
+
+type RenameTableStep struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TableName     string                 `protobuf:"bytes,1,opt,name=table_name,json=tableName,proto3" json:"table_name,omitempty"`
+	NewName       string                 `protobuf:"bytes,2,opt,name=new_name,json=newName,proto3" json:"new_name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RenameTableStep) Reset() {
+	*x = RenameTableStep{}
+	mi := &file_chadbot_storage_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RenameTableStep) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RenameTableStep) ProtoMessage() {}
+
+func (x *RenameTableStep) ProtoReflect() protoreflect.Message {
+	mi := &file_chadbot_storage_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RenameTableStep.ProtoReflect.Descriptor instead.
+func (*RenameTableStep) Descriptor() ([]byte, []int) {
+	return file_chadbot_storage_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *RenameTableStep) GetTableName() string {
+	if x != nil {
+		return x.TableName
+	}
+	return ""
+}
+
+func (x *RenameTableStep) GetNewName() string {
+	if x != nil {
+		return x.NewName
+	}
+	return ""
+}
+
+type AddColumnStep struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TableName     string                 `protobuf:"bytes,1,opt,name=table_name,json=tableName,proto3" json:"table_name,omitempty"`
+	Column        *ColumnDef             `protobuf:"bytes,2,opt,name=column,proto3" json:"column,omitempty"` // primary_key and unique are not supported by SQLite here
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddColumnStep) Reset() {
+	*x = AddColumnStep{}
+	mi := &file_chadbot_storage_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddColumnStep) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddColumnStep) ProtoMessage() {}
+
+func (x *AddColumnStep) ProtoReflect() protoreflect.Message {
+	mi := &file_chadbot_storage_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddColumnStep.ProtoReflect.Descriptor instead.
+func (*AddColumnStep) Descriptor() ([]byte, []int) {
+	return file_chadbot_storage_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *AddColumnStep) GetTableName() string {
+	if x != nil {
+		return x.TableName
+	}
+	return ""
+}
+
+func (x *AddColumnStep) GetColumn() *ColumnDef {
+	if x != nil {
+		return x.Column
+	}
+	return nil
+}
+
+type RenameColumnStep struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TableName     string                 `protobuf:"bytes,1,opt,name=table_name,json=tableName,proto3" json:"table_name,omitempty"`
+	Column        string                 `protobuf:"bytes,2,opt,name=column,proto3" json:"column,omitempty"`
+	NewName       string                 `protobuf:"bytes,3,opt,name=new_name,json=newName,proto3" json:"new_name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RenameColumnStep) Reset() {
+	*x = RenameColumnStep{}
+	mi := &file_chadbot_storage_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RenameColumnStep) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RenameColumnStep) ProtoMessage() {}
+
+func (x *RenameColumnStep) ProtoReflect() protoreflect.Message {
+	mi := &file_chadbot_storage_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RenameColumnStep.ProtoReflect.Descriptor instead.
+func (*RenameColumnStep) Descriptor() ([]byte, []int) {
+	return file_chadbot_storage_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *RenameColumnStep) GetTableName() string {
+	if x != nil {
+		return x.TableName
+	}
+	return ""
+}
+
+func (x *RenameColumnStep) GetColumn() string {
+	if x != nil {
+		return x.Column
+	}
+	return ""
+}
+
+func (x *RenameColumnStep) GetNewName() string {
+	if x != nil {
+		return x.NewName
+	}
+	return ""
+}
+
+type DropColumnStep struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TableName     string                 `protobuf:"bytes,1,opt,name=table_name,json=tableName,proto3" json:"table_name,omitempty"`
+	Column        string                 `protobuf:"bytes,2,opt,name=column,proto3" json:"column,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DropColumnStep) Reset() {
+	*x = DropColumnStep{}
+	mi := &file_chadbot_storage_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DropColumnStep) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DropColumnStep) ProtoMessage() {}
+
+func (x *DropColumnStep) ProtoReflect() protoreflect.Message {
+	mi := &file_chadbot_storage_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DropColumnStep.ProtoReflect.Descriptor instead.
+func (*DropColumnStep) Descriptor() ([]byte, []int) {
+	return file_chadbot_storage_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *DropColumnStep) GetTableName() string {
+	if x != nil {
+		return x.TableName
+	}
+	return ""
+}
+
+func (x *DropColumnStep) GetColumn() string {
+	if x != nil {
+		return x.Column
+	}
+	return ""
+}
+
+type CreateIndexStep struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	IndexName     string                 `protobuf:"bytes,1,opt,name=index_name,json=indexName,proto3" json:"index_name,omitempty"`
+	TableName     string                 `protobuf:"bytes,2,opt,name=table_name,json=tableName,proto3" json:"table_name,omitempty"`
+	Columns       []string               `protobuf:"bytes,3,rep,name=columns,proto3" json:"columns,omitempty"`
+	Unique        bool                   `protobuf:"varint,4,opt,name=unique,proto3" json:"unique,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateIndexStep) Reset() {
+	*x = CreateIndexStep{}
+	mi := &file_chadbot_storage_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateIndexStep) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateIndexStep) ProtoMessage() {}
+
+func (x *CreateIndexStep) ProtoReflect() protoreflect.Message {
+	mi := &file_chadbot_storage_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateIndexStep.ProtoReflect.Descriptor instead.
+func (*CreateIndexStep) Descriptor() ([]byte, []int) {
+	return file_chadbot_storage_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *CreateIndexStep) GetIndexName() string {
+	if x != nil {
+		return x.IndexName
+	}
+	return ""
+}
+
+func (x *CreateIndexStep) GetTableName() string {
+	if x != nil {
+		return x.TableName
+	}
+	return ""
+}
+
+func (x *CreateIndexStep) GetColumns() []string {
+	if x != nil {
+		return x.Columns
+	}
+	return nil
+}
+
+func (x *CreateIndexStep) GetUnique() bool {
+	if x != nil {
+		return x.Unique
+	}
+	return false
+}
+
+type DropIndexStep struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	IndexName     string                 `protobuf:"bytes,1,opt,name=index_name,json=indexName,proto3" json:"index_name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DropIndexStep) Reset() {
+	*x = DropIndexStep{}
+	mi := &file_chadbot_storage_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DropIndexStep) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DropIndexStep) ProtoMessage() {}
+
+func (x *DropIndexStep) ProtoReflect() protoreflect.Message {
+	mi := &file_chadbot_storage_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DropIndexStep.ProtoReflect.Descriptor instead.
+func (*DropIndexStep) Descriptor() ([]byte, []int) {
+	return file_chadbot_storage_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *DropIndexStep) GetIndexName() string {
+	if x != nil {
+		return x.IndexName
+	}
+	return ""
+}
+
+// Raw SQL, e.g. to backfill data. {name} is replaced with the namespaced table name.
+// Needs storage_raw_sql; prefer update steps with a filter.
+type SQLStep struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Statement     string                 `protobuf:"bytes,1,opt,name=statement,proto3" json:"statement,omitempty"`
+	Args          []string               `protobuf:"bytes,2,rep,name=args,proto3" json:"args,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SQLStep) Reset() {
+	*x = SQLStep{}
+	mi := &file_chadbot_storage_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SQLStep) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SQLStep) ProtoMessage() {}
+
+func (x *SQLStep) ProtoReflect() protoreflect.Message {
+	mi := &file_chadbot_storage_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SQLStep.ProtoReflect.Descriptor instead.
+func (*SQLStep) Descriptor() ([]byte, []int) {
+	return file_chadbot_storage_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *SQLStep) GetStatement() string {
+	if x != nil {
+		return x.Statement
+	}
+	return ""
+}
+
+func (x *SQLStep) GetArgs() []string {
+	if x != nil {
+		return x.Args
+	}
+	return nil
+}
+
+// Key-value request on the plugin's key space
+type KVRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Op:
+	//
+	//	*KVRequest_Get
+	//	*KVRequest_Set
+	//	*KVRequest_Delete
+	//	*KVRequest_List
+	Op            isKVRequest_Op `protobuf_oneof:"op"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *KVRequest) Reset() {
+	*x = KVRequest{}
+	mi := &file_chadbot_storage_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *KVRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KVRequest) ProtoMessage() {}
+
+func (x *KVRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_chadbot_storage_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KVRequest.ProtoReflect.Descriptor instead.
+func (*KVRequest) Descriptor() ([]byte, []int) {
+	return file_chadbot_storage_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *KVRequest) GetOp() isKVRequest_Op {
+	if x != nil {
+		return x.Op
+	}
+	return nil
+}
+
+func (x *KVRequest) GetGet() *KVGet {
+	if x != nil {
+		if x, ok := x.Op.(*KVRequest_Get); ok {
+			return x.Get
+		}
+	}
+	return nil
+}
+
+func (x *KVRequest) GetSet() *KVSet {
+	if x != nil {
+		if x, ok := x.Op.(*KVRequest_Set); ok {
+			return x.Set
+		}
+	}
+	return nil
+}
+
+func (x *KVRequest) GetDelete() *KVDelete {
+	if x != nil {
+		if x, ok := x.Op.(*KVRequest_Delete); ok {
+			return x.Delete
+		}
+	}
+	return nil
+}
+
+func (x *KVRequest) GetList() *KVList {
+	if x != nil {
+		if x, ok := x.Op.(*KVRequest_List); ok {
+			return x.List
+		}
+	}
+	return nil
+}
+
+type isKVRequest_Op interface {
+	isKVRequest_Op()
+}
+
+type KVRequest_Get struct {
+	Get *KVGet `protobuf:"bytes,1,opt,name=get,proto3,oneof"`
+}
+
+type KVRequest_Set struct {
+	Set *KVSet `protobuf:"bytes,2,opt,name=set,proto3,oneof"`
+}
+
+type KVRequest_Delete struct {
+	Delete *KVDelete `protobuf:"bytes,3,opt,name=delete,proto3,oneof"`
+}
+
+type KVRequest_List struct {
+	List *KVList `protobuf:"bytes,4,opt,name=list,proto3,oneof"`
+}
+
+func (*KVRequest_Get) isKVRequest_Op() {}
+
+func (*KVRequest_Set) isKVRequest_Op() {}
+
+func (*KVRequest_Delete) isKVRequest_Op() {}
+
+func (*KVRequest_List) isKVRequest_Op() {}
+
+// Get a key (no entry if missing or expired)
+type KVGet struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *KVGet) Reset() {
+	*x = KVGet{}
+	mi := &file_chadbot_storage_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *KVGet) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KVGet) ProtoMessage() {}
+
+func (x *KVGet) ProtoReflect() protoreflect.Message {
+	mi := &file_chadbot_storage_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KVGet.ProtoReflect.Descriptor instead.
+func (*KVGet) Descriptor() ([]byte, []int) {
+	return file_chadbot_storage_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *KVGet) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+// Set a key. With cas, the write only applies if the entry is at expected_version
+// (0 = the key must not exist); otherwise the response reports a conflict.
+type KVSet struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Key             string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value           []byte                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	TtlSeconds      int64                  `protobuf:"varint,3,opt,name=ttl_seconds,json=ttlSeconds,proto3" json:"ttl_seconds,omitempty"` // 0 = never expires
+	Cas             bool                   `protobuf:"varint,4,opt,name=cas,proto3" json:"cas,omitempty"`
+	ExpectedVersion int64                  `protobuf:"varint,5,opt,name=expected_version,json=expectedVersion,proto3" json:"expected_version,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *KVSet) Reset() {
+	*x = KVSet{}
+	mi := &file_chadbot_storage_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *KVSet) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KVSet) ProtoMessage() {}
+
+func (x *KVSet) ProtoReflect() protoreflect.Message {
+	mi := &file_chadbot_storage_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KVSet.ProtoReflect.Descriptor instead.
+func (*KVSet) Descriptor() ([]byte, []int) {
+	return file_chadbot_storage_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *KVSet) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *KVSet) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+func (x *KVSet) GetTtlSeconds() int64 {
+	if x != nil {
+		return x.TtlSeconds
+	}
+	return 0
+}
+
+func (x *KVSet) GetCas() bool {
+	if x != nil {
+		return x.Cas
+	}
+	return false
+}
+
+func (x *KVSet) GetExpectedVersion() int64 {
+	if x != nil {
+		return x.ExpectedVersion
+	}
+	return 0
+}
+
+// Delete a key, with cas only if the entry is at expected_version
+type KVDelete struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Key             string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Cas             bool                   `protobuf:"varint,2,opt,name=cas,proto3" json:"cas,omitempty"`
+	ExpectedVersion int64                  `protobuf:"varint,3,opt,name=expected_version,json=expectedVersion,proto3" json:"expected_version,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *KVDelete) Reset() {
+	*x = KVDelete{}
+	mi := &file_chadbot_storage_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *KVDelete) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KVDelete) ProtoMessage() {}
+
+func (x *KVDelete) ProtoReflect() protoreflect.Message {
+	mi := &file_chadbot_storage_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KVDelete.ProtoReflect.Descriptor instead.
+func (*KVDelete) Descriptor() ([]byte, []int) {
+	return file_chadbot_storage_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *KVDelete) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *KVDelete) GetCas() bool {
+	if x != nil {
+		return x.Cas
+	}
+	return false
+}
+
+func (x *KVDelete) GetExpectedVersion() int64 {
+	if x != nil {
+		return x.ExpectedVersion
+	}
+	return 0
+}
+
+// List keys by prefix in key order
+type KVList struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Prefix        string                 `protobuf:"bytes,1,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	After         string                 `protobuf:"bytes,2,opt,name=after,proto3" json:"after,omitempty"`  // Only keys after this one, for paging
+	Limit         int32                  `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"` // 0 = 1000
+	KeysOnly      bool                   `protobuf:"varint,4,opt,name=keys_only,json=keysOnly,proto3" json:"keys_only,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *KVList) Reset() {
+	*x = KVList{}
+	mi := &file_chadbot_storage_proto_msgTypes[36]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *KVList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KVList) ProtoMessage() {}
+
+func (x *KVList) ProtoReflect() protoreflect.Message {
+	mi := &file_chadbot_storage_proto_msgTypes[36]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KVList.ProtoReflect.Descriptor instead.
+func (*KVList) Descriptor() ([]byte, []int) {
+	return file_chadbot_storage_proto_rawDescGZIP(), []int{36}
+}
+
+func (x *KVList) GetPrefix() string {
+	if x != nil {
+		return x.Prefix
+	}
+	return ""
+}
+
+func (x *KVList) GetAfter() string {
+	if x != nil {
+		return x.After
+	}
+	return ""
+}
+
+func (x *KVList) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *KVList) GetKeysOnly() bool {
+	if x != nil {
+		return x.KeysOnly
+	}
+	return false
+}
+
+// Key-value entry
+type KVEntry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value         []byte                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	Version       int64                  `protobuf:"varint,3,opt,name=version,proto3" json:"version,omitempty"`                      // Incremented on every write
+	ExpiresAt     int64                  `protobuf:"varint,4,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"` // Unix milliseconds, 0 = never
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *KVEntry) Reset() {
+	*x = KVEntry{}
+	mi := &file_chadbot_storage_proto_msgTypes[37]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *KVEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KVEntry) ProtoMessage() {}
+
+func (x *KVEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_chadbot_storage_proto_msgTypes[37]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KVEntry.ProtoReflect.Descriptor instead.
+func (*KVEntry) Descriptor() ([]byte, []int) {
+	return file_chadbot_storage_proto_rawDescGZIP(), []int{37}
+}
+
+func (x *KVEntry) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *KVEntry) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+func (x *KVEntry) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *KVEntry) GetExpiresAt() int64 {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return 0
+}
+
+// Document request on a collection of JSON documents
+type DocRequest struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Collection string                 `protobuf:"bytes,1,opt,name=collection,proto3" json:"collection,omitempty"`
+	// Types that are valid to be assigned to Op:
+	//
+	//	*DocRequest_Put
+	//	*DocRequest_Get
+	//	*DocRequest_Delete
+	//	*DocRequest_Find
+	//	*DocRequest_EnsureIndex
+	Op            isDocRequest_Op `protobuf_oneof:"op"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DocRequest) Reset() {
+	*x = DocRequest{}
+	mi := &file_chadbot_storage_proto_msgTypes[38]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DocRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DocRequest) ProtoMessage() {}
+
+func (x *DocRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_chadbot_storage_proto_msgTypes[38]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DocRequest.ProtoReflect.Descriptor instead.
+func (*DocRequest) Descriptor() ([]byte, []int) {
+	return file_chadbot_storage_proto_rawDescGZIP(), []int{38}
+}
+
+func (x *DocRequest) GetCollection() string {
+	if x != nil {
+		return x.Collection
+	}
+	return ""
+}
+
+func (x *DocRequest) GetOp() isDocRequest_Op {
+	if x != nil {
+		return x.Op
+	}
+	return nil
+}
+
+func (x *DocRequest) GetPut() *DocPut {
+	if x != nil {
+		if x, ok := x.Op.(*DocRequest_Put); ok {
+			return x.Put
+		}
+	}
+	return nil
+}
+
+func (x *DocRequest) GetGet() *DocGet {
+	if x != nil {
+		if x, ok := x.Op.(*DocRequest_Get); ok {
+			return x.Get
+		}
+	}
+	return nil
+}
+
+func (x *DocRequest) GetDelete() *DocDelete {
+	if x != nil {
+		if x, ok := x.Op.(*DocRequest_Delete); ok {
+			return x.Delete
+		}
+	}
+	return nil
+}
+
+func (x *DocRequest) GetFind() *DocFind {
+	if x != nil {
+		if x, ok := x.Op.(*DocRequest_Find); ok {
+			return x.Find
+		}
+	}
+	return nil
+}
+
+func (x *DocRequest) GetEnsureIndex() *DocEnsureIndex {
+	if x != nil {
+		if x, ok := x.Op.(*DocRequest_EnsureIndex); ok {
+			return x.EnsureIndex
+		}
+	}
+	return nil
+}
+
+type isDocRequest_Op interface {
+	isDocRequest_Op()
+}
+
+type DocRequest_Put struct {
+	Put *DocPut `protobuf:"bytes,2,opt,name=put,proto3,oneof"`
+}
+
+type DocRequest_Get struct {
+	Get *DocGet `protobuf:"bytes,3,opt,name=get,proto3,oneof"`
+}
+
+type DocRequest_Delete struct {
+	Delete *DocDelete `protobuf:"bytes,4,opt,name=delete,proto3,oneof"`
+}
+
+type DocRequest_Find struct {
+	Find *DocFind `protobuf:"bytes,5,opt,name=find,proto3,oneof"`
+}
+
+type DocRequest_EnsureIndex struct {
+	EnsureIndex *DocEnsureIndex `protobuf:"bytes,6,opt,name=ensure_index,json=ensureIndex,proto3,oneof"`
+}
+
+func (*DocRequest_Put) isDocRequest_Op() {}
+
+func (*DocRequest_Get) isDocRequest_Op() {}
+
+func (*DocRequest_Delete) isDocRequest_Op() {}
+
+func (*DocRequest_Find) isDocRequest_Op() {}
+
+func (*DocRequest_EnsureIndex) isDocRequest_Op() {}
+
+// Create or replace a document. With cas, only if it is at expected_version (0 = must not exist).
+type DocPut struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Id              string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`     // Empty = generate
+	Json            string                 `protobuf:"bytes,2,opt,name=json,proto3" json:"json,omitempty"` // JSON object
+	Cas             bool                   `protobuf:"varint,3,opt,name=cas,proto3" json:"cas,omitempty"`
+	ExpectedVersion int64                  `protobuf:"varint,4,opt,name=expected_version,json=expectedVersion,proto3" json:"expected_version,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *DocPut) Reset() {
+	*x = DocPut{}
+	mi := &file_chadbot_storage_proto_msgTypes[39]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *InsertRequest) String() string {
+func (x *DocPut) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*InsertRequest) ProtoMessage() {}
+func (*DocPut) ProtoMessage() {}
 
-func (x *InsertRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_chadbot_storage_proto_msgTypes[5]
+func (x *DocPut) ProtoReflect() protoreflect.Message {
+	mi := &file_chadbot_storage_proto_msgTypes[39]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -494,51 +3334,62 @@ func (x *InsertRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use InsertRequest.ProtoReflect.Descriptor instead.
-func (*InsertRequest) Descriptor() ([]byte, []int) {
-	return file_chadbot_storage_proto_rawDescGZIP(), []int{5}
+// Deprecated: Use DocPut.ProtoReflect.Descriptor instead.
+func (*DocPut) Descriptor() ([]byte, []int) {
+	return file_chadbot_storage_proto_rawDescGZIP(), []int{39}
 }
 
-func (x *InsertRequest) GetTableName() string {
+func (x *DocPut) GetId() string {
 	if x != nil {
-		return x.TableName
+		return x.Id
 	}
 	return ""
 }
 
-func (x *InsertRequest) GetValues() map[string]string {
+func (x *DocPut) GetJson() string {
 	if x != nil {
-		return x.Values
+		return x.Json
 	}
-	return nil
+	return ""
 }
 
-// Update request
-type UpdateRequest struct {
+func (x *DocPut) GetCas() bool {
+	if x != nil {
+		return x.Cas
+	}
+	return false
+}
+
+func (x *DocPut) GetExpectedVersion() int64 {
+	if x != nil {
+		return x.ExpectedVersion
+	}
+	return 0
+}
+
+// Get a document by ID
+type DocGet struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	TableName     string                 `protobuf:"bytes,1,opt,name=table_name,json=tableName,proto3" json:"table_name,omitempty"`
-	Values        map[string]string      `protobuf:"bytes,2,rep,name=values,proto3" json:"values,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // Column -> value
-	WhereClause   string                 `protobuf:"bytes,3,opt,name=where_clause,json=whereClause,proto3" json:"where_clause,omitempty"`                                              // SQL WHERE clause (without WHERE keyword)
-	WhereArgs     []string               `protobuf:"bytes,4,rep,name=where_args,json=whereArgs,proto3" json:"where_args,omitempty"`                                                    // Arguments for placeholders
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *UpdateRequest) Reset() {
-	*x = UpdateRequest{}
-	mi := &file_chadbot_storage_proto_msgTypes[6]
+func (x *DocGet) Reset() {
+	*x = DocGet{}
+	mi := &file_chadbot_storage_proto_msgTypes[40]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *UpdateRequest) String() string {
+func (x *DocGet) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*UpdateRequest) ProtoMessage() {}
+func (*DocGet) ProtoMessage() {}
 
-func (x *UpdateRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_chadbot_storage_proto_msgTypes[6]
+func (x *DocGet) ProtoReflect() protoreflect.Message {
+	mi := &file_chadbot_storage_proto_msgTypes[40]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -549,64 +3400,88 @@ func (x *UpdateRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use UpdateRequest.ProtoReflect.Descriptor instead.
-func (*UpdateRequest) Descriptor() ([]byte, []int) {
-	return file_chadbot_storage_proto_rawDescGZIP(), []int{6}
+// Deprecated: Use DocGet.ProtoReflect.Descriptor instead.
+func (*DocGet) Descriptor() ([]byte, []int) {
+	return file_chadbot_storage_proto_rawDescGZIP(), []int{40}
 }
 
-func (x *UpdateRequest) GetTableName() string {
+func (x *DocGet) GetId() string {
 	if x != nil {
-		return x.TableName
+		return x.Id
 	}
 	return ""
 }
 
-func (x *UpdateRequest) GetValues() map[string]string {
-	if x != nil {
-		return x.Values
-	}
-	return nil
+// Delete a document by ID
+type DocDelete struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *UpdateRequest) GetWhereClause() string {
+func (x *DocDelete) Reset() {
+	*x = DocDelete{}
+	mi := &file_chadbot_storage_proto_msgTypes[41]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DocDelete) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DocDelete) ProtoMessage() {}
+
+func (x *DocDelete) ProtoReflect() protoreflect.Message {
+	mi := &file_chadbot_storage_proto_msgTypes[41]
 	if x != nil {
-		return x.WhereClause
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return ""
+	return mi.MessageOf(x)
 }
 
-func (x *UpdateRequest) GetWhereArgs() []string {
+// Deprecated: Use DocDelete.ProtoReflect.Descriptor instead.
+func (*DocDelete) Descriptor() ([]byte, []int) {
+	return file_chadbot_storage_proto_rawDescGZIP(), []int{41}
+}
+
+func (x *DocDelete) GetId() string {
 	if x != nil {
-		return x.WhereArgs
+		return x.Id
 	}
-	return nil
+	return ""
 }
 
-// Delete request
-type DeleteRequest struct {
+// Find documents whose indexed fields equal the given values (no matches = all documents)
+type DocFind struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	TableName     string                 `protobuf:"bytes,1,opt,name=table_name,json=tableName,proto3" json:"table_name,omitempty"`
-	WhereClause   string                 `protobuf:"bytes,2,opt,name=where_clause,json=whereClause,proto3" json:"where_clause,omitempty"`
-	WhereArgs     []string               `protobuf:"bytes,3,rep,name=where_args,json=whereArgs,proto3" json:"where_args,omitempty"`
+	Matches       []*DocFieldMatch       `protobuf:"bytes,1,rep,name=matches,proto3" json:"matches,omitempty"`
+	Limit         int32                  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"` // 0 = 1000
+	Offset        int32                  `protobuf:"varint,3,opt,name=offset,proto3" json:"offset,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *DeleteRequest) Reset() {
-	*x = DeleteRequest{}
-	mi := &file_chadbot_storage_proto_msgTypes[7]
+func (x *DocFind) Reset() {
+	*x = DocFind{}
+	mi := &file_chadbot_storage_proto_msgTypes[42]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *DeleteRequest) String() string {
+func (x *DocFind) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*DeleteRequest) ProtoMessage() {}
+func (*DocFind) ProtoMessage() {}
 
-func (x *DeleteRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_chadbot_storage_proto_msgTypes[7]
+func (x *DocFind) ProtoReflect() protoreflect.Message {
+	mi := &file_chadbot_storage_proto_msgTypes[42]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -617,61 +3492,56 @@ func (x *DeleteRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use DeleteRequest.ProtoReflect.Descriptor instead.
-func (*DeleteRequest) Descriptor() ([]byte, []int) {
-	return file_chadbot_storage_proto_rawDescGZIP(), []int{7}
+// Deprecated: Use DocFind.ProtoReflect.Descriptor instead.
+func (*DocFind) Descriptor() ([]byte, []int) {
+	return file_chadbot_storage_proto_rawDescGZIP(), []int{42}
 }
 
-func (x *DeleteRequest) GetTableName() string {
+func (x *DocFind) GetMatches() []*DocFieldMatch {
 	if x != nil {
-		return x.TableName
+		return x.Matches
 	}
-	return ""
+	return nil
 }
 
-func (x *DeleteRequest) GetWhereClause() string {
+func (x *DocFind) GetLimit() int32 {
 	if x != nil {
-		return x.WhereClause
+		return x.Limit
 	}
-	return ""
+	return 0
 }
 
-func (x *DeleteRequest) GetWhereArgs() []string {
+func (x *DocFind) GetOffset() int32 {
 	if x != nil {
-		return x.WhereArgs
+		return x.Offset
 	}
-	return nil
+	return 0
 }
 
-// Query request
-type QueryRequest struct {
+// Equality match on an indexed field
+type DocFieldMatch struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	TableName     string                 `protobuf:"bytes,1,opt,name=table_name,json=tableName,proto3" json:"table_name,omitempty"`
-	Columns       []string               `protobuf:"bytes,2,rep,name=columns,proto3" json:"columns,omitempty"` // Empty = all columns
-	WhereClause   string                 `protobuf:"bytes,3,opt,name=where_clause,json=whereClause,proto3" json:"where_clause,omitempty"`
-	WhereArgs     []string               `protobuf:"bytes,4,rep,name=where_args,json=whereArgs,proto3" json:"where_args,omitempty"`
-	OrderBy       string                 `protobuf:"bytes,5,opt,name=order_by,json=orderBy,proto3" json:"order_by,omitempty"`
-	Limit         int32                  `protobuf:"varint,6,opt,name=limit,proto3" json:"limit,omitempty"`
-	Offset        int32                  `protobuf:"varint,7,opt,name=offset,proto3" json:"offset,omitempty"`
+	Field         string                 `protobuf:"bytes,1,opt,name=field,proto3" json:"field,omitempty"`                          // Field path, e.g. "name" or "owner.id"
+	JsonValue     string                 `protobuf:"bytes,2,opt,name=json_value,json=jsonValue,proto3" json:"json_value,omitempty"` // JSON value, e.g. "\"alice\"" or "true"
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *QueryRequest) Reset() {
-	*x = QueryRequest{}
-	mi := &file_chadbot_storage_proto_msgTypes[8]
+func (x *DocFieldMatch) Reset() {
+	*x = DocFieldMatch{}
+	mi := &file_chadbot_storage_proto_msgTypes[43]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *QueryRequest) String() string {
+func (x *DocFieldMatch) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*QueryRequest) ProtoMessage() {}
+func (*DocFieldMatch) ProtoMessage() {}
 
-func (x *QueryRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_chadbot_storage_proto_msgTypes[8]
+func (x *DocFieldMatch) ProtoReflect() protoreflect.Message {
+	mi := &file_chadbot_storage_proto_msgTypes[43]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -682,83 +3552,97 @@ func (x *QueryRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use QueryRequest.ProtoReflect.Descriptor instead.
-func (*QueryRequest) Descriptor() ([]byte, []int) {
-	return file_chadbot_storage_proto_rawDescGZIP(), []int{8}
+// Deprecated: Use DocFieldMatch.ProtoReflect.Descriptor instead.
+func (*DocFieldMatch) Descriptor() ([]byte, []int) {
+	return file_chadbot_storage_proto_rawDescGZIP(), []int{43}
 }
 
-func (x *QueryRequest) GetTableName() string {
+func (x *DocFieldMatch) GetField() string {
 	if x != nil {
-		return x.TableName
+		return x.Field
 	}
 	return ""
 }
 
-func (x *QueryRequest) GetColumns() []string {
+func (x *DocFieldMatch) GetJsonValue() string {
 	if x != nil {
-		return x.Columns
+		return x.JsonValue
 	}
-	return nil
+	return ""
 }
 
-func (x *QueryRequest) GetWhereClause() string {
-	if x != nil {
-		return x.WhereClause
-	}
-	return ""
+// Index fields of the collection (existing documents are indexed too)
+type DocEnsureIndex struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Fields        []string               `protobuf:"bytes,1,rep,name=fields,proto3" json:"fields,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *QueryRequest) GetWhereArgs() []string {
-	if x != nil {
-		return x.WhereArgs
-	}
-	return nil
+func (x *DocEnsureIndex) Reset() {
+	*x = DocEnsureIndex{}
+	mi := &file_chadbot_storage_proto_msgTypes[44]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
-func (x *QueryRequest) GetOrderBy() string {
-	if x != nil {
-		return x.OrderBy
-	}
-	return ""
+func (x *DocEnsureIndex) String() string {
+	return protoimpl.X.MessageStringOf(x)
 }
 
-func (x *QueryRequest) GetLimit() int32 {
+func (*DocEnsureIndex) ProtoMessage() {}
+
+func (x *DocEnsureIndex) ProtoReflect() protoreflect.Message {
+	mi := &file_chadbot_storage_proto_msgTypes[44]
 	if x != nil {
-		return x.Limit
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return 0
+	return mi.MessageOf(x)
 }
 
-func (x *QueryRequest) GetOffset() int32 {
+// Deprecated: Use DocEnsureIndex.ProtoReflect.Descriptor instead.
+func (*DocEnsureIndex) Descriptor() ([]byte, []int) {
+	return file_chadbot_storage_proto_rawDescGZIP(), []int{44}
+}
+
+func (x *DocEnsureIndex) GetFields() []string {
 	if x != nil {
-		return x.Offset
+		return x.Fields
 	}
-	return 0
+	return nil
 }
 
-// Row in query result
-type Row struct {
+// JSON document
+type Document struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Values        map[string]string      `protobuf:"bytes,1,rep,name=values,proto3" json:"values,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Json          string                 `protobuf:"bytes,2,opt,name=json,proto3" json:"json,omitempty"`
+	Version       int64                  `protobuf:"varint,3,opt,name=version,proto3" json:"version,omitempty"`
+	CreatedAt     int64                  `protobuf:"varint,4,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"` // Unix milliseconds
+	UpdatedAt     int64                  `protobuf:"varint,5,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *Row) Reset() {
-	*x = Row{}
-	mi := &file_chadbot_storage_proto_msgTypes[9]
+func (x *Document) Reset() {
+	*x = Document{}
+	mi := &file_chadbot_storage_proto_msgTypes[45]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *Row) String() string {
+func (x *Document) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Row) ProtoMessage() {}
+func (*Document) ProtoMessage() {}
 
-func (x *Row) ProtoReflect() protoreflect.Message {
-	mi := &file_chadbot_storage_proto_msgTypes[9]
+func (x *Document) ProtoReflect() protoreflect.Message {
+	mi := &file_chadbot_storage_proto_msgTypes[45]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -769,23 +3653,51 @@ func (x *Row) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Row.ProtoReflect.Descriptor instead.
-func (*Row) Descriptor() ([]byte, []int) {
-	return file_chadbot_storage_proto_rawDescGZIP(), []int{9}
+// Deprecated: Use Document.ProtoReflect.Descriptor instead.
+func (*Document) Descriptor() ([]byte, []int) {
+	return file_chadbot_storage_proto_rawDescGZIP(), []int{45}
 }
 
-func (x *Row) GetValues() map[string]string {
+func (x *Document) GetId() string {
 	if x != nil {
-		return x.Values
+		return x.Id
 	}
-	return nil
+	return ""
+}
+
+func (x *Document) GetJson() string {
+	if x != nil {
+		return x.Json
+	}
+	return ""
+}
+
+func (x *Document) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *Document) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+func (x *Document) GetUpdatedAt() int64 {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return 0
 }
 
 var File_chadbot_storage_proto protoreflect.FileDescriptor
 
 const file_chadbot_storage_proto_rawDesc = "" +
 	"\n" +
-	"\x15chadbot/storage.proto\x12\achadbot\"\xff\x02\n" +
+	"\x15chadbot/storage.proto\x12\achadbot\"\xd0\x05\n" +
 	"\x0eStorageRequest\x12\x1d\n" +
 	"\n" +
 	"request_id\x18\x01 \x01(\tR\trequestId\x12@\n" +
@@ -795,8 +3707,16 @@ const file_chadbot_storage_proto_rawDesc = "" +
 	"\x06insert\x18\x04 \x01(\v2\x16.chadbot.InsertRequestH\x00R\x06insert\x120\n" +
 	"\x06update\x18\x05 \x01(\v2\x16.chadbot.UpdateRequestH\x00R\x06update\x120\n" +
 	"\x06delete\x18\x06 \x01(\v2\x16.chadbot.DeleteRequestH\x00R\x06delete\x12-\n" +
-	"\x05query\x18\a \x01(\v2\x15.chadbot.QueryRequestH\x00R\x05queryB\v\n" +
-	"\toperation\"\xcd\x01\n" +
+	"\x05query\x18\a \x01(\v2\x15.chadbot.QueryRequestH\x00R\x05query\x123\n" +
+	"\amigrate\x18\b \x01(\v2\x17.chadbot.MigrateRequestH\x00R\amigrate\x12-\n" +
+	"\x05batch\x18\t \x01(\v2\x15.chadbot.BatchRequestH\x00R\x05batch\x120\n" +
+	"\x06upsert\x18\n" +
+	" \x01(\v2\x16.chadbot.UpsertRequestH\x00R\x06upsert\x12-\n" +
+	"\x05count\x18\v \x01(\v2\x15.chadbot.CountRequestH\x00R\x05count\x129\n" +
+	"\taggregate\x18\f \x01(\v2\x19.chadbot.AggregateRequestH\x00R\taggregate\x12$\n" +
+	"\x02kv\x18\r \x01(\v2\x12.chadbot.KVRequestH\x00R\x02kv\x12)\n" +
+	"\x04docs\x18\x0e \x01(\v2\x13.chadbot.DocRequestH\x00R\x04docsB\v\n" +
+	"\toperation\"\xde\x03\n" +
 	"\x0fStorageResponse\x12\x1d\n" +
 	"\n" +
 	"request_id\x18\x01 \x01(\tR\trequestId\x12\x18\n" +
@@ -804,7 +3724,31 @@ const file_chadbot_storage_proto_rawDesc = "" +
 	"\x05error\x18\x03 \x01(\tR\x05error\x12 \n" +
 	"\x04rows\x18\x04 \x03(\v2\f.chadbot.RowR\x04rows\x12#\n" +
 	"\raffected_rows\x18\x05 \x01(\x03R\faffectedRows\x12$\n" +
-	"\x0elast_insert_id\x18\x06 \x01(\x03R\flastInsertId\"\xac\x01\n" +
+	"\x0elast_insert_id\x18\x06 \x01(\x03R\flastInsertId\x12%\n" +
+	"\x0eschema_version\x18\a \x01(\x05R\rschemaVersion\x122\n" +
+	"\aresults\x18\b \x03(\v2\x18.chadbot.OperationResultR\aresults\x12\x14\n" +
+	"\x05count\x18\t \x01(\x03R\x05count\x12*\n" +
+	"\aentries\x18\n" +
+	" \x03(\v2\x10.chadbot.KVEntryR\aentries\x12/\n" +
+	"\tdocuments\x18\v \x03(\v2\x11.chadbot.DocumentR\tdocuments\x12\x1a\n" +
+	"\bconflict\x18\f \x01(\bR\bconflict\x12%\n" +
+	"\x0equota_exceeded\x18\r \x01(\bR\rquotaExceeded\"\xd7\x01\n" +
+	"\x05Value\x12\x1f\n" +
+	"\n" +
+	"null_value\x18\x01 \x01(\bH\x00R\tnullValue\x12\x1d\n" +
+	"\tint_value\x18\x02 \x01(\x03H\x00R\bintValue\x12!\n" +
+	"\vfloat_value\x18\x03 \x01(\x01H\x00R\n" +
+	"floatValue\x12\x1f\n" +
+	"\n" +
+	"text_value\x18\x04 \x01(\tH\x00R\ttextValue\x12!\n" +
+	"\vbytes_value\x18\x05 \x01(\fH\x00R\n" +
+	"bytesValue\x12\x1f\n" +
+	"\n" +
+	"bool_value\x18\x06 \x01(\bH\x00R\tboolValueB\x06\n" +
+	"\x04kind\"\\\n" +
+	"\x0fOperationResult\x12#\n" +
+	"\raffected_rows\x18\x01 \x01(\x03R\faffectedRows\x12$\n" +
+	"\x0elast_insert_id\x18\x02 \x01(\x03R\flastInsertId\"\xac\x01\n" +
 	"\tColumnDef\x12\x12\n" +
 	"\x04name\x18\x01 \x01(\tR\x04name\x12\x12\n" +
 	"\x04type\x18\x02 \x01(\tR\x04type\x12\x1f\n" +
@@ -821,30 +3765,63 @@ const file_chadbot_storage_proto_rawDesc = "" +
 	"\x10DropTableRequest\x12\x1d\n" +
 	"\n" +
 	"table_name\x18\x01 \x01(\tR\ttableName\x12\x1b\n" +
-	"\tif_exists\x18\x02 \x01(\bR\bifExists\"\xa5\x01\n" +
+	"\tif_exists\x18\x02 \x01(\bR\bifExists\"\xc1\x02\n" +
 	"\rInsertRequest\x12\x1d\n" +
 	"\n" +
 	"table_name\x18\x01 \x01(\tR\ttableName\x12:\n" +
-	"\x06values\x18\x02 \x03(\v2\".chadbot.InsertRequest.ValuesEntryR\x06values\x1a9\n" +
+	"\x06values\x18\x02 \x03(\v2\".chadbot.InsertRequest.ValuesEntryR\x06values\x12J\n" +
+	"\ftyped_values\x18\x03 \x03(\v2'.chadbot.InsertRequest.TypedValuesEntryR\vtypedValues\x1a9\n" +
 	"\vValuesEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xe7\x01\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1aN\n" +
+	"\x10TypedValuesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12$\n" +
+	"\x05value\x18\x02 \x01(\v2\x0e.chadbot.ValueR\x05value:\x028\x01\"\xac\x03\n" +
 	"\rUpdateRequest\x12\x1d\n" +
 	"\n" +
 	"table_name\x18\x01 \x01(\tR\ttableName\x12:\n" +
 	"\x06values\x18\x02 \x03(\v2\".chadbot.UpdateRequest.ValuesEntryR\x06values\x12!\n" +
 	"\fwhere_clause\x18\x03 \x01(\tR\vwhereClause\x12\x1d\n" +
 	"\n" +
-	"where_args\x18\x04 \x03(\tR\twhereArgs\x1a9\n" +
+	"where_args\x18\x04 \x03(\tR\twhereArgs\x12J\n" +
+	"\ftyped_values\x18\x05 \x03(\v2'.chadbot.UpdateRequest.TypedValuesEntryR\vtypedValues\x12'\n" +
+	"\x06filter\x18\x06 \x01(\v2\x0f.chadbot.FilterR\x06filter\x1a9\n" +
 	"\vValuesEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"p\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1aN\n" +
+	"\x10TypedValuesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12$\n" +
+	"\x05value\x18\x02 \x01(\v2\x0e.chadbot.ValueR\x05value:\x028\x01\"\x99\x01\n" +
 	"\rDeleteRequest\x12\x1d\n" +
 	"\n" +
 	"table_name\x18\x01 \x01(\tR\ttableName\x12!\n" +
 	"\fwhere_clause\x18\x02 \x01(\tR\vwhereClause\x12\x1d\n" +
 	"\n" +
-	"where_args\x18\x03 \x03(\tR\twhereArgs\"\xd2\x01\n" +
+	"where_args\x18\x03 \x03(\tR\twhereArgs\x12'\n" +
+	"\x06filter\x18\x04 \x01(\v2\x0f.chadbot.FilterR\x06filter\"\x93\x03\n" +
+	"\rUpsertRequest\x12\x1d\n" +
+	"\n" +
+	"table_name\x18\x01 \x01(\tR\ttableName\x12:\n" +
+	"\x06values\x18\x02 \x03(\v2\".chadbot.UpsertRequest.ValuesEntryR\x06values\x12)\n" +
+	"\x10conflict_columns\x18\x03 \x03(\tR\x0fconflictColumns\x12%\n" +
+	"\x0eupdate_columns\x18\x04 \x03(\tR\rupdateColumns\x12J\n" +
+	"\ftyped_values\x18\x05 \x03(\v2'.chadbot.UpsertRequest.TypedValuesEntryR\vtypedValues\x1a9\n" +
+	"\vValuesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1aN\n" +
+	"\x10TypedValuesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12$\n" +
+	"\x05value\x18\x02 \x01(\v2\x0e.chadbot.ValueR\x05value:\x028\x01\"G\n" +
+	"\fBatchRequest\x127\n" +
+	"\n" +
+	"operations\x18\x01 \x03(\v2\x17.chadbot.BatchOperationR\n" +
+	"operations\"\xe5\x01\n" +
+	"\x0eBatchOperation\x120\n" +
+	"\x06insert\x18\x01 \x01(\v2\x16.chadbot.InsertRequestH\x00R\x06insert\x120\n" +
+	"\x06update\x18\x02 \x01(\v2\x16.chadbot.UpdateRequestH\x00R\x06update\x120\n" +
+	"\x06delete\x18\x03 \x01(\v2\x16.chadbot.DeleteRequestH\x00R\x06delete\x120\n" +
+	"\x06upsert\x18\x04 \x01(\v2\x16.chadbot.UpsertRequestH\x00R\x06upsertB\v\n" +
+	"\toperation\"\xb9\x02\n" +
 	"\fQueryRequest\x12\x1d\n" +
 	"\n" +
 	"table_name\x18\x01 \x01(\tR\ttableName\x12\x18\n" +
@@ -854,12 +3831,195 @@ const file_chadbot_storage_proto_rawDesc = "" +
 	"where_args\x18\x04 \x03(\tR\twhereArgs\x12\x19\n" +
 	"\border_by\x18\x05 \x01(\tR\aorderBy\x12\x14\n" +
 	"\x05limit\x18\x06 \x01(\x05R\x05limit\x12\x16\n" +
-	"\x06offset\x18\a \x01(\x05R\x06offset\"r\n" +
+	"\x06offset\x18\a \x01(\x05R\x06offset\x12\x14\n" +
+	"\x05typed\x18\b \x01(\bR\x05typed\x12'\n" +
+	"\x06filter\x18\t \x01(\v2\x0f.chadbot.FilterR\x06filter\x12&\n" +
+	"\x05order\x18\n" +
+	" \x03(\v2\x10.chadbot.OrderByR\x05order\"5\n" +
+	"\aOrderBy\x12\x16\n" +
+	"\x06column\x18\x01 \x01(\tR\x06column\x12\x12\n" +
+	"\x04desc\x18\x02 \x01(\bR\x04desc\"\xb9\x01\n" +
+	"\x06Filter\x122\n" +
+	"\tcondition\x18\x01 \x01(\v2\x12.chadbot.ConditionH\x00R\tcondition\x12'\n" +
+	"\x03and\x18\x02 \x01(\v2\x13.chadbot.FilterListH\x00R\x03and\x12%\n" +
+	"\x02or\x18\x03 \x01(\v2\x13.chadbot.FilterListH\x00R\x02or\x12#\n" +
+	"\x03not\x18\x04 \x01(\v2\x0f.chadbot.FilterH\x00R\x03notB\x06\n" +
+	"\x04expr\"7\n" +
+	"\n" +
+	"FilterList\x12)\n" +
+	"\afilters\x18\x01 \x03(\v2\x0f.chadbot.FilterR\afilters\"\x94\x01\n" +
+	"\tCondition\x12\x16\n" +
+	"\x06column\x18\x01 \x01(\tR\x06column\x12!\n" +
+	"\x02op\x18\x02 \x01(\x0e2\x11.chadbot.FilterOpR\x02op\x12$\n" +
+	"\x05value\x18\x03 \x01(\v2\x0e.chadbot.ValueR\x05value\x12&\n" +
+	"\x06values\x18\x04 \x03(\v2\x0e.chadbot.ValueR\x06values\"\x98\x01\n" +
+	"\fCountRequest\x12\x1d\n" +
+	"\n" +
+	"table_name\x18\x01 \x01(\tR\ttableName\x12!\n" +
+	"\fwhere_clause\x18\x02 \x01(\tR\vwhereClause\x12\x1d\n" +
+	"\n" +
+	"where_args\x18\x03 \x03(\tR\twhereArgs\x12'\n" +
+	"\x06filter\x18\x04 \x01(\v2\x0f.chadbot.FilterR\x06filter\"\xeb\x01\n" +
+	"\x10AggregateRequest\x12\x1d\n" +
+	"\n" +
+	"table_name\x18\x01 \x01(\tR\ttableName\x122\n" +
+	"\n" +
+	"aggregates\x18\x02 \x03(\v2\x12.chadbot.AggregateR\n" +
+	"aggregates\x12!\n" +
+	"\fwhere_clause\x18\x03 \x01(\tR\vwhereClause\x12\x1d\n" +
+	"\n" +
+	"where_args\x18\x04 \x03(\tR\twhereArgs\x12\x19\n" +
+	"\bgroup_by\x18\x05 \x03(\tR\agroupBy\x12'\n" +
+	"\x06filter\x18\x06 \x01(\v2\x0f.chadbot.FilterR\x06filter\"U\n" +
+	"\tAggregate\x12\x1a\n" +
+	"\bfunction\x18\x01 \x01(\tR\bfunction\x12\x16\n" +
+	"\x06column\x18\x02 \x01(\tR\x06column\x12\x14\n" +
+	"\x05alias\x18\x03 \x01(\tR\x05alias\"\x84\x02\n" +
 	"\x03Row\x120\n" +
-	"\x06values\x18\x01 \x03(\v2\x18.chadbot.Row.ValuesEntryR\x06values\x1a9\n" +
+	"\x06values\x18\x01 \x03(\v2\x18.chadbot.Row.ValuesEntryR\x06values\x12@\n" +
+	"\ftyped_values\x18\x02 \x03(\v2\x1d.chadbot.Row.TypedValuesEntryR\vtypedValues\x1a9\n" +
 	"\vValuesEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01B}\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1aN\n" +
+	"\x10TypedValuesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12$\n" +
+	"\x05value\x18\x02 \x01(\v2\x0e.chadbot.ValueR\x05value:\x028\x01\"D\n" +
+	"\x0eMigrateRequest\x122\n" +
+	"\n" +
+	"migrations\x18\x01 \x03(\v2\x12.chadbot.MigrationR\n" +
+	"migrations\"g\n" +
+	"\tMigration\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\x05R\aversion\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12,\n" +
+	"\x05steps\x18\x03 \x03(\v2\x16.chadbot.MigrationStepR\x05steps\"\xdb\x04\n" +
+	"\rMigrationStep\x12@\n" +
+	"\fcreate_table\x18\x01 \x01(\v2\x1b.chadbot.CreateTableRequestH\x00R\vcreateTable\x12:\n" +
+	"\n" +
+	"drop_table\x18\x02 \x01(\v2\x19.chadbot.DropTableRequestH\x00R\tdropTable\x12=\n" +
+	"\frename_table\x18\x03 \x01(\v2\x18.chadbot.RenameTableStepH\x00R\vrenameTable\x127\n" +
+	"\n" +
+	"add_column\x18\x04 \x01(\v2\x16.chadbot.AddColumnStepH\x00R\taddColumn\x12@\n" +
+	"\rrename_column\x18\x05 \x01(\v2\x19.chadbot.RenameColumnStepH\x00R\frenameColumn\x12:\n" +
+	"\vdrop_column\x18\x06 \x01(\v2\x17.chadbot.DropColumnStepH\x00R\n" +
+	"dropColumn\x12=\n" +
+	"\fcreate_index\x18\a \x01(\v2\x18.chadbot.CreateIndexStepH\x00R\vcreateIndex\x127\n" +
+	"\n" +
+	"drop_index\x18\b \x01(\v2\x16.chadbot.DropIndexStepH\x00R\tdropIndex\x12$\n" +
+	"\x03sql\x18\t \x01(\v2\x10.chadbot.SQLStepH\x00R\x03sql\x120\n" +
+	"\x06update\x18\n" +
+	" \x01(\v2\x16.chadbot.UpdateRequestH\x00R\x06updateB\x06\n" +
+	"\x04step\"K\n" +
+	"\x0fRenameTableStep\x12\x1d\n" +
+	"\n" +
+	"table_name\x18\x01 \x01(\tR\ttableName\x12\x19\n" +
+	"\bnew_name\x18\x02 \x01(\tR\anewName\"Z\n" +
+	"\rAddColumnStep\x12\x1d\n" +
+	"\n" +
+	"table_name\x18\x01 \x01(\tR\ttableName\x12*\n" +
+	"\x06column\x18\x02 \x01(\v2\x12.chadbot.ColumnDefR\x06column\"d\n" +
+	"\x10RenameColumnStep\x12\x1d\n" +
+	"\n" +
+	"table_name\x18\x01 \x01(\tR\ttableName\x12\x16\n" +
+	"\x06column\x18\x02 \x01(\tR\x06column\x12\x19\n" +
+	"\bnew_name\x18\x03 \x01(\tR\anewName\"G\n" +
+	"\x0eDropColumnStep\x12\x1d\n" +
+	"\n" +
+	"table_name\x18\x01 \x01(\tR\ttableName\x12\x16\n" +
+	"\x06column\x18\x02 \x01(\tR\x06column\"\x81\x01\n" +
+	"\x0fCreateIndexStep\x12\x1d\n" +
+	"\n" +
+	"index_name\x18\x01 \x01(\tR\tindexName\x12\x1d\n" +
+	"\n" +
+	"table_name\x18\x02 \x01(\tR\ttableName\x12\x18\n" +
+	"\acolumns\x18\x03 \x03(\tR\acolumns\x12\x16\n" +
+	"\x06unique\x18\x04 \x01(\bR\x06unique\".\n" +
+	"\rDropIndexStep\x12\x1d\n" +
+	"\n" +
+	"index_name\x18\x01 \x01(\tR\tindexName\";\n" +
+	"\aSQLStep\x12\x1c\n" +
+	"\tstatement\x18\x01 \x01(\tR\tstatement\x12\x12\n" +
+	"\x04args\x18\x02 \x03(\tR\x04args\"\xad\x01\n" +
+	"\tKVRequest\x12\"\n" +
+	"\x03get\x18\x01 \x01(\v2\x0e.chadbot.KVGetH\x00R\x03get\x12\"\n" +
+	"\x03set\x18\x02 \x01(\v2\x0e.chadbot.KVSetH\x00R\x03set\x12+\n" +
+	"\x06delete\x18\x03 \x01(\v2\x11.chadbot.KVDeleteH\x00R\x06delete\x12%\n" +
+	"\x04list\x18\x04 \x01(\v2\x0f.chadbot.KVListH\x00R\x04listB\x04\n" +
+	"\x02op\"\x19\n" +
+	"\x05KVGet\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\"\x8d\x01\n" +
+	"\x05KVSet\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\fR\x05value\x12\x1f\n" +
+	"\vttl_seconds\x18\x03 \x01(\x03R\n" +
+	"ttlSeconds\x12\x10\n" +
+	"\x03cas\x18\x04 \x01(\bR\x03cas\x12)\n" +
+	"\x10expected_version\x18\x05 \x01(\x03R\x0fexpectedVersion\"Y\n" +
+	"\bKVDelete\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x10\n" +
+	"\x03cas\x18\x02 \x01(\bR\x03cas\x12)\n" +
+	"\x10expected_version\x18\x03 \x01(\x03R\x0fexpectedVersion\"i\n" +
+	"\x06KVList\x12\x16\n" +
+	"\x06prefix\x18\x01 \x01(\tR\x06prefix\x12\x14\n" +
+	"\x05after\x18\x02 \x01(\tR\x05after\x12\x14\n" +
+	"\x05limit\x18\x03 \x01(\x05R\x05limit\x12\x1b\n" +
+	"\tkeys_only\x18\x04 \x01(\bR\bkeysOnly\"j\n" +
+	"\aKVEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\fR\x05value\x12\x18\n" +
+	"\aversion\x18\x03 \x01(\x03R\aversion\x12\x1d\n" +
+	"\n" +
+	"expires_at\x18\x04 \x01(\x03R\texpiresAt\"\x90\x02\n" +
+	"\n" +
+	"DocRequest\x12\x1e\n" +
+	"\n" +
+	"collection\x18\x01 \x01(\tR\n" +
+	"collection\x12#\n" +
+	"\x03put\x18\x02 \x01(\v2\x0f.chadbot.DocPutH\x00R\x03put\x12#\n" +
+	"\x03get\x18\x03 \x01(\v2\x0f.chadbot.DocGetH\x00R\x03get\x12,\n" +
+	"\x06delete\x18\x04 \x01(\v2\x12.chadbot.DocDeleteH\x00R\x06delete\x12&\n" +
+	"\x04find\x18\x05 \x01(\v2\x10.chadbot.DocFindH\x00R\x04find\x12<\n" +
+	"\fensure_index\x18\x06 \x01(\v2\x17.chadbot.DocEnsureIndexH\x00R\vensureIndexB\x04\n" +
+	"\x02op\"i\n" +
+	"\x06DocPut\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04json\x18\x02 \x01(\tR\x04json\x12\x10\n" +
+	"\x03cas\x18\x03 \x01(\bR\x03cas\x12)\n" +
+	"\x10expected_version\x18\x04 \x01(\x03R\x0fexpectedVersion\"\x18\n" +
+	"\x06DocGet\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\x1b\n" +
+	"\tDocDelete\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"i\n" +
+	"\aDocFind\x120\n" +
+	"\amatches\x18\x01 \x03(\v2\x16.chadbot.DocFieldMatchR\amatches\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\x12\x16\n" +
+	"\x06offset\x18\x03 \x01(\x05R\x06offset\"D\n" +
+	"\rDocFieldMatch\x12\x14\n" +
+	"\x05field\x18\x01 \x01(\tR\x05field\x12\x1d\n" +
+	"\n" +
+	"json_value\x18\x02 \x01(\tR\tjsonValue\"(\n" +
+	"\x0eDocEnsureIndex\x12\x16\n" +
+	"\x06fields\x18\x01 \x03(\tR\x06fields\"\x86\x01\n" +
+	"\bDocument\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04json\x18\x02 \x01(\tR\x04json\x12\x18\n" +
+	"\aversion\x18\x03 \x01(\x03R\aversion\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\x04 \x01(\x03R\tcreatedAt\x12\x1d\n" +
+	"\n" +
+	"updated_at\x18\x05 \x01(\x03R\tupdatedAt*\xe4\x01\n" +
+	"\bFilterOp\x12\x10\n" +
+	"\fFILTER_OP_EQ\x10\x00\x12\x10\n" +
+	"\fFILTER_OP_NE\x10\x01\x12\x10\n" +
+	"\fFILTER_OP_LT\x10\x02\x12\x10\n" +
+	"\fFILTER_OP_LE\x10\x03\x12\x10\n" +
+	"\fFILTER_OP_GT\x10\x04\x12\x10\n" +
+	"\fFILTER_OP_GE\x10\x05\x12\x12\n" +
+	"\x0eFILTER_OP_LIKE\x10\x06\x12\x10\n" +
+	"\fFILTER_OP_IN\x10\a\x12\x14\n" +
+	"\x10FILTER_OP_NOT_IN\x10\b\x12\x15\n" +
+	"\x11FILTER_OP_IS_NULL\x10\t\x12\x19\n" +
+	"\x15FILTER_OP_IS_NOT_NULL\x10\n" +
+	"B}\n" +
 	"\vcom.chadbotB\fStorageProtoP\x01Z$github.com/fipso/chadbot/gen/chadbot\xa2\x02\x03CXX\xaa\x02\aChadbot\xca\x02\aChadbot\xe2\x02\x13Chadbot\\GPBMetadata\xea\x02\aChadbotb\x06proto3"
 
 var (
@@ -874,39 +4034,144 @@ func file_chadbot_storage_proto_rawDescGZIP() []byte {
 	return file_chadbot_storage_proto_rawDescData
 }
 
-var file_chadbot_storage_proto_msgTypes = make([]protoimpl.MessageInfo, 13)
+var file_chadbot_storage_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_chadbot_storage_proto_msgTypes = make([]protoimpl.MessageInfo, 54)
 var file_chadbot_storage_proto_goTypes = []any{
-	(*StorageRequest)(nil),     // 0: chadbot.StorageRequest
-	(*StorageResponse)(nil),    // 1: chadbot.StorageResponse
-	(*ColumnDef)(nil),          // 2: chadbot.ColumnDef
-	(*CreateTableRequest)(nil), // 3: chadbot.CreateTableRequest
-	(*DropTableRequest)(nil),   // 4: chadbot.DropTableRequest
-	(*InsertRequest)(nil),      // 5: chadbot.InsertRequest
-	(*UpdateRequest)(nil),      // 6: chadbot.UpdateRequest
-	(*DeleteRequest)(nil),      // 7: chadbot.DeleteRequest
-	(*QueryRequest)(nil),       // 8: chadbot.QueryRequest
-	(*Row)(nil),                // 9: chadbot.Row
-	nil,                        // 10: chadbot.InsertRequest.ValuesEntry
-	nil,                        // 11: chadbot.UpdateRequest.ValuesEntry
-	nil,                        // 12: chadbot.Row.ValuesEntry
+	(FilterOp)(0),              // 0: chadbot.FilterOp
+	(*StorageRequest)(nil),     // 1: chadbot.StorageRequest
+	(*StorageResponse)(nil),    // 2: chadbot.StorageResponse
+	(*Value)(nil),              // 3: chadbot.Value
+	(*OperationResult)(nil),    // 4: chadbot.OperationResult
+	(*ColumnDef)(nil),          // 5: chadbot.ColumnDef
+	(*CreateTableRequest)(nil), // 6: chadbot.CreateTableRequest
+	(*DropTableRequest)(nil),   // 7: chadbot.DropTableRequest
+	(*InsertRequest)(nil),      // 8: chadbot.InsertRequest
+	(*UpdateRequest)(nil),      // 9: chadbot.UpdateRequest
+	(*DeleteRequest)(nil),      // 10: chadbot.DeleteRequest
+	(*UpsertRequest)(nil),      // 11: chadbot.UpsertRequest
+	(*BatchRequest)(nil),       // 12: chadbot.BatchRequest
+	(*BatchOperation)(nil),     // 13: chadbot.BatchOperation
+	(*QueryRequest)(nil),       // 14: chadbot.QueryRequest
+	(*OrderBy)(nil),            // 15: chadbot.OrderBy
+	(*Filter)(nil),             // 16: chadbot.Filter
+	(*FilterList)(nil),         // 17: chadbot.FilterList
+	(*Condition)(nil),          // 18: chadbot.Condition
+	(*CountRequest)(nil),       // 19: chadbot.CountRequest
+	(*AggregateRequest)(nil),   // 20: chadbot.AggregateRequest
+	(*Aggregate)(nil),          // 21: chadbot.Aggregate
+	(*Row)(nil),                // 22: chadbot.Row
+	(*MigrateRequest)(nil),     // 23: chadbot.MigrateRequest
+	(*Migration)(nil),          // 24: chadbot.Migration
+	(*MigrationStep)(nil),      // 25: chadbot.MigrationStep
+	(*RenameTableStep)(nil),    // 26: chadbot.RenameTableStep
+	(*AddColumnStep)(nil),      // 27: chadbot.AddColumnStep
+	(*RenameColumnStep)(nil),   // 28: chadbot.RenameColumnStep
+	(*DropColumnStep)(nil),     // 29: chadbot.DropColumnStep
+	(*CreateIndexStep)(nil),    // 30: chadbot.CreateIndexStep
+	(*DropIndexStep)(nil),      // 31: chadbot.DropIndexStep
+	(*SQLStep)(nil),            // 32: chadbot.SQLStep
+	(*KVRequest)(nil),          // 33: chadbot.KVRequest
+	(*KVGet)(nil),              // 34: chadbot.KVGet
+	(*KVSet)(nil),              // 35: chadbot.KVSet
+	(*KVDelete)(nil),           // 36: chadbot.KVDelete
+	(*KVList)(nil),             // 37: chadbot.KVList
+	(*KVEntry)(nil),            // 38: chadbot.KVEntry
+	(*DocRequest)(nil),         // 39: chadbot.DocRequest
+	(*DocPut)(nil),             // 40: chadbot.DocPut
+	(*DocGet)(nil),             // 41: chadbot.DocGet
+	(*DocDelete)(nil),          // 42: chadbot.DocDelete
+	(*DocFind)(nil),            // 43: chadbot.DocFind
+	(*DocFieldMatch)(nil),      // 44: chadbot.DocFieldMatch
+	(*DocEnsureIndex)(nil),     // 45: chadbot.DocEnsureIndex
+	(*Document)(nil),           // 46: chadbot.Document
+	nil,                        // 47: chadbot.InsertRequest.ValuesEntry
+	nil,                        // 48: chadbot.InsertRequest.TypedValuesEntry
+	nil,                        // 49: chadbot.UpdateRequest.ValuesEntry
+	nil,                        // 50: chadbot.UpdateRequest.TypedValuesEntry
+	nil,                        // 51: chadbot.UpsertRequest.ValuesEntry
+	nil,                        // 52: chadbot.UpsertRequest.TypedValuesEntry
+	nil,                        // 53: chadbot.Row.ValuesEntry
+	nil,                        // 54: chadbot.Row.TypedValuesEntry
 }
 var file_chadbot_storage_proto_depIdxs = []int32{
-	3,  // 0: chadbot.StorageRequest.create_table:type_name -> chadbot.CreateTableRequest
-	4,  // 1: chadbot.StorageRequest.drop_table:type_name -> chadbot.DropTableRequest
-	5,  // 2: chadbot.StorageRequest.insert:type_name -> chadbot.InsertRequest
-	6,  // 3: chadbot.StorageRequest.update:type_name -> chadbot.UpdateRequest
-	7,  // 4: chadbot.StorageRequest.delete:type_name -> chadbot.DeleteRequest
-	8,  // 5: chadbot.StorageRequest.query:type_name -> chadbot.QueryRequest
-	9,  // 6: chadbot.StorageResponse.rows:type_name -> chadbot.Row
-	2,  // 7: chadbot.CreateTableRequest.columns:type_name -> chadbot.ColumnDef
-	10, // 8: chadbot.InsertRequest.values:type_name -> chadbot.InsertRequest.ValuesEntry
-	11, // 9: chadbot.UpdateRequest.values:type_name -> chadbot.UpdateRequest.ValuesEntry
-	12, // 10: chadbot.Row.values:type_name -> chadbot.Row.ValuesEntry
-	11, // [11:11] is the sub-list for method output_type
-	11, // [11:11] is the sub-list for method input_type
-	11, // [11:11] is the sub-list for extension type_name
-	11, // [11:11] is the sub-list for extension extendee
-	0,  // [0:11] is the sub-list for field type_name
+	6,  // 0: chadbot.StorageRequest.create_table:type_name -> chadbot.CreateTableRequest
+	7,  // 1: chadbot.StorageRequest.drop_table:type_name -> chadbot.DropTableRequest
+	8,  // 2: chadbot.StorageRequest.insert:type_name -> chadbot.InsertRequest
+	9,  // 3: chadbot.StorageRequest.update:type_name -> chadbot.UpdateRequest
+	10, // 4: chadbot.StorageRequest.delete:type_name -> chadbot.DeleteRequest
+	14, // 5: chadbot.StorageRequest.query:type_name -> chadbot.QueryRequest
+	23, // 6: chadbot.StorageRequest.migrate:type_name -> chadbot.MigrateRequest
+	12, // 7: chadbot.StorageRequest.batch:type_name -> chadbot.BatchRequest
+	11, // 8: chadbot.StorageRequest.upsert:type_name -> chadbot.UpsertRequest
+	19, // 9: chadbot.StorageRequest.count:type_name -> chadbot.CountRequest
+	20, // 10: chadbot.StorageRequest.aggregate:type_name -> chadbot.AggregateRequest
+	33, // 11: chadbot.StorageRequest.kv:type_name -> chadbot.KVRequest
+	39, // 12: chadbot.StorageRequest.docs:type_name -> chadbot.DocRequest
+	22, // 13: chadbot.StorageResponse.rows:type_name -> chadbot.Row
+	4,  // 14: chadbot.StorageResponse.results:type_name -> chadbot.OperationResult
+	38, // 15: chadbot.StorageResponse.entries:type_name -> chadbot.KVEntry
+	46, // 16: chadbot.StorageResponse.documents:type_name -> chadbot.Document
+	5,  // 17: chadbot.CreateTableRequest.columns:type_name -> chadbot.ColumnDef
+	47, // 18: chadbot.InsertRequest.values:type_name -> chadbot.InsertRequest.ValuesEntry
+	48, // 19: chadbot.InsertRequest.typed_values:type_name -> chadbot.InsertRequest.TypedValuesEntry
+	49, // 20: chadbot.UpdateRequest.values:type_name -> chadbot.UpdateRequest.ValuesEntry
+	50, // 21: chadbot.UpdateRequest.typed_values:type_name -> chadbot.UpdateRequest.TypedValuesEntry
+	16, // 22: chadbot.UpdateRequest.filter:type_name -> chadbot.Filter
+	16, // 23: chadbot.DeleteRequest.filter:type_name -> chadbot.Filter
+	51, // 24: chadbot.UpsertRequest.values:type_name -> chadbot.UpsertRequest.ValuesEntry
+	52, // 25: chadbot.UpsertRequest.typed_values:type_name -> chadbot.UpsertRequest.TypedValuesEntry
+	13, // 26: chadbot.BatchRequest.operations:type_name -> chadbot.BatchOperation
+	8,  // 27: chadbot.BatchOperation.insert:type_name -> chadbot.InsertRequest
+	9,  // 28: chadbot.BatchOperation.update:type_name -> chadbot.UpdateRequest
+	10, // 29: chadbot.BatchOperation.delete:type_name -> chadbot.DeleteRequest
+	11, // 30: chadbot.BatchOperation.upsert:type_name -> chadbot.UpsertRequest
+	16, // 31: chadbot.QueryRequest.filter:type_name -> chadbot.Filter
+	15, // 32: chadbot.QueryRequest.order:type_name -> chadbot.OrderBy
+	18, // 33: chadbot.Filter.condition:type_name -> chadbot.Condition
+	17, // 34: chadbot.Filter.and:type_name -> chadbot.FilterList
+	17, // 35: chadbot.Filter.or:type_name -> chadbot.FilterList
+	16, // 36: chadbot.Filter.not:type_name -> chadbot.Filter
+	16, // 37: chadbot.FilterList.filters:type_name -> chadbot.Filter
+	0,  // 38: chadbot.Condition.op:type_name -> chadbot.FilterOp
+	3,  // 39: chadbot.Condition.value:type_name -> chadbot.Value
+	3,  // 40: chadbot.Condition.values:type_name -> chadbot.Value
+	16, // 41: chadbot.CountRequest.filter:type_name -> chadbot.Filter
+	21, // 42: chadbot.AggregateRequest.aggregates:type_name -> chadbot.Aggregate
+	16, // 43: chadbot.AggregateRequest.filter:type_name -> chadbot.Filter
+	53, // 44: chadbot.Row.values:type_name -> chadbot.Row.ValuesEntry
+	54, // 45: chadbot.Row.typed_values:type_name -> chadbot.Row.TypedValuesEntry
+	24, // 46: chadbot.MigrateRequest.migrations:type_name -> chadbot.Migration
+	25, // 47: chadbot.Migration.steps:type_name -> chadbot.MigrationStep
+	6,  // 48: chadbot.MigrationStep.create_table:type_name -> chadbot.CreateTableRequest
+	7,  // 49: chadbot.MigrationStep.drop_table:type_name -> chadbot.DropTableRequest
+	26, // 50: chadbot.MigrationStep.rename_table:type_name -> chadbot.RenameTableStep
+	27, // 51: chadbot.MigrationStep.add_column:type_name -> chadbot.AddColumnStep
+	28, // 52: chadbot.MigrationStep.rename_column:type_name -> chadbot.RenameColumnStep
+	29, // 53: chadbot.MigrationStep.drop_column:type_name -> chadbot.DropColumnStep
+	30, // 54: chadbot.MigrationStep.create_index:type_name -> chadbot.CreateIndexStep
+	31, // 55: chadbot.MigrationStep.drop_index:type_name -> chadbot.DropIndexStep
+	32, // 56: chadbot.MigrationStep.sql:type_name -> chadbot.SQLStep
+	9,  // 57: chadbot.MigrationStep.update:type_name -> chadbot.UpdateRequest
+	5,  // 58: chadbot.AddColumnStep.column:type_name -> chadbot.ColumnDef
+	34, // 59: chadbot.KVRequest.get:type_name -> chadbot.KVGet
+	35, // 60: chadbot.KVRequest.set:type_name -> chadbot.KVSet
+	36, // 61: chadbot.KVRequest.delete:type_name -> chadbot.KVDelete
+	37, // 62: chadbot.KVRequest.list:type_name -> chadbot.KVList
+	40, // 63: chadbot.DocRequest.put:type_name -> chadbot.DocPut
+	41, // 64: chadbot.DocRequest.get:type_name -> chadbot.DocGet
+	42, // 65: chadbot.DocRequest.delete:type_name -> chadbot.DocDelete
+	43, // 66: chadbot.DocRequest.find:type_name -> chadbot.DocFind
+	45, // 67: chadbot.DocRequest.ensure_index:type_name -> chadbot.DocEnsureIndex
+	44, // 68: chadbot.DocFind.matches:type_name -> chadbot.DocFieldMatch
+	3,  // 69: chadbot.InsertRequest.TypedValuesEntry.value:type_name -> chadbot.Value
+	3,  // 70: chadbot.UpdateRequest.TypedValuesEntry.value:type_name -> chadbot.Value
+	3,  // 71: chadbot.UpsertRequest.TypedValuesEntry.value:type_name -> chadbot.Value
+	3,  // 72: chadbot.Row.TypedValuesEntry.value:type_name -> chadbot.Value
+	73, // [73:73] is the sub-list for method output_type
+	73, // [73:73] is the sub-list for method input_type
+	73, // [73:73] is the sub-list for extension type_name
+	73, // [73:73] is the sub-list for extension extendee
+	0,  // [0:73] is the sub-list for field type_name
 }
 
 func init() { file_chadbot_storage_proto_init() }
@@ -921,19 +4186,72 @@ func file_chadbot_storage_proto_init() {
 		(*StorageRequest_Update)(nil),
 		(*StorageRequest_Delete)(nil),
 		(*StorageRequest_Query)(nil),
+		(*StorageRequest_Migrate)(nil),
+		(*StorageRequest_Batch)(nil),
+		(*StorageRequest_Upsert)(nil),
+		(*StorageRequest_Count)(nil),
+		(*StorageRequest_Aggregate)(nil),
+		(*StorageRequest_Kv)(nil),
+		(*StorageRequest_Docs)(nil),
+	}
+	file_chadbot_storage_proto_msgTypes[2].OneofWrappers = []any{
+		(*Value_NullValue)(nil),
+		(*Value_IntValue)(nil),
+		(*Value_FloatValue)(nil),
+		(*Value_TextValue)(nil),
+		(*Value_BytesValue)(nil),
+		(*Value_BoolValue)(nil),
+	}
+	file_chadbot_storage_proto_msgTypes[12].OneofWrappers = []any{
+		(*BatchOperation_Insert)(nil),
+		(*BatchOperation_Update)(nil),
+		(*BatchOperation_Delete)(nil),
+		(*BatchOperation_Upsert)(nil),
+	}
+	file_chadbot_storage_proto_msgTypes[15].OneofWrappers = []any{
+		(*Filter_Condition)(nil),
+		(*Filter_And)(nil),
+		(*Filter_Or)(nil),
+		(*Filter_Not)(nil),
+	}
+	file_chadbot_storage_proto_msgTypes[24].OneofWrappers = []any{
+		(*MigrationStep_CreateTable)(nil),
+		(*MigrationStep_DropTable)(nil),
+		(*MigrationStep_RenameTable)(nil),
+		(*MigrationStep_AddColumn)(nil),
+		(*MigrationStep_RenameColumn)(nil),
+		(*MigrationStep_DropColumn)(nil),
+		(*MigrationStep_CreateIndex)(nil),
+		(*MigrationStep_DropIndex)(nil),
+		(*MigrationStep_Sql)(nil),
+		(*MigrationStep_Update)(nil),
+	}
+	file_chadbot_storage_proto_msgTypes[32].OneofWrappers = []any{
+		(*KVRequest_Get)(nil),
+		(*KVRequest_Set)(nil),
+		(*KVRequest_Delete)(nil),
+		(*KVRequest_List)(nil),
+	}
+	file_chadbot_storage_proto_msgTypes[38].OneofWrappers = []any{
+		(*DocRequest_Put)(nil),
+		(*DocRequest_Get)(nil),
+		(*DocRequest_Delete)(nil),
+		(*DocRequest_Find)(nil),
+		(*DocRequest_EnsureIndex)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_chadbot_storage_proto_rawDesc), len(file_chadbot_storage_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   13,
+			NumEnums:      1,
+			NumMessages:   54,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
 		GoTypes:           file_chadbot_storage_proto_goTypes,
 		DependencyIndexes: file_chadbot_storage_proto_depIdxs,
+		EnumInfos:         file_chadbot_storage_proto_enumTypes,
 		MessageInfos:      file_chadbot_storage_proto_msgTypes,
 	}.Build()
 	File_chadbot_storage_proto = out.File
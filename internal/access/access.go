@@ -0,0 +1,305 @@
+// Package access decides which skills users may use, by role, user and chat platform
+package access
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/pelletier/go-toml/v2"
+
+	"github.com/fipso/chadbot/internal/config"
+	"github.com/fipso/chadbot/internal/storage"
+)
+
+// Config holds the access policies (~/.config/chadbot/access.toml). Without policies and
+// default roles every user may use every skill.
+type Config struct {
+	DefaultRoles []string        `toml:"default_roles" json:"default_roles"` // Roles of requests no policy matches
+	Roles        map[string]Role `toml:"roles" json:"roles"`
+	Policies     []Policy        `toml:"policies" json:"policies"`
+}
+
+// Role allows skills by name glob and all skills of plugins by name ("*" = all).
+// Built-in skills belong to the plugin "builtin".
+type Role struct {
+	Skills  []string `toml:"skills" json:"skills,omitempty"`
+	Plugins []string `toml:"plugins" json:"plugins,omitempty"`
+}
+
+// Policy gives roles to the requests of matching users on matching platforms.
+// Empty lists match everything.
+type Policy struct {
+	Users        []string `toml:"users" json:"users,omitempty"`                 // Usernames or user IDs
+	AccountRoles []string `toml:"account_roles" json:"account_roles,omitempty"` // "admin" or "user"
+	Platforms    []string `toml:"platforms" json:"platforms,omitempty"`         // Chat platforms ("web", "whatsapp", ...)
+	Roles        []string `toml:"roles" json:"roles"`
+}
+
+// WebPlatform is the platform of chats created in the web app, which store no platform
+const WebPlatform = "web"
+
+// Subject is who asks for a skill: a user chatting on a platform
+type Subject struct {
+	UserID      string
+	Username    string
+	AccountRole string
+	Platform    string
+}
+
+// NewSubject describes a user on a platform, loading the user's name and account role.
+// An empty platform is the web app.
+func NewSubject(userID, platform string) Subject {
+	if platform == "" {
+		platform = WebPlatform
+	}
+	s := Subject{UserID: userID, Username: userID, Platform: platform}
+	if userID == "" {
+		return s
+	}
+	if user, err := storage.GetUser(userID); err == nil {
+		s.Username = user.Username
+		s.AccountRole = user.Role
+	}
+	return s
+}
+
+// String describes the subject for log messages
+func (s Subject) String() string {
+	user := s.Username
+	if user == "" {
+		user = "unknown user"
+	}
+	if s.Platform == "" {
+		return user
+	}
+	return user + " on " + s.Platform
+}
+
+// Path returns the access config path (~/.config/chadbot/access.toml)
+func Path() (string, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "access.toml"), nil
+}
+
+// Load reads access policies from a TOML file (a missing file allows everything)
+func Load(path string) (*Config, error) {
+	cfg := &Config{}
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		if err := toml.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("invalid access config %s: %w", path, err)
+		}
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid access config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Validate checks that policies only use defined roles and valid globs
+func (c *Config) Validate() error {
+	for name, role := range c.Roles {
+		for _, pattern := range role.Skills {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("role %s: invalid skill glob %q", name, pattern)
+			}
+		}
+	}
+	for _, name := range c.DefaultRoles {
+		if _, ok := c.Roles[name]; !ok {
+			return fmt.Errorf("default role %s is not defined", name)
+		}
+	}
+	for i, p := range c.Policies {
+		if len(p.Roles) == 0 {
+			return fmt.Errorf("policy %d has no roles", i+1)
+		}
+		for _, name := range p.Roles {
+			if _, ok := c.Roles[name]; !ok {
+				return fmt.Errorf("policy %d: role %s is not defined", i+1, name)
+			}
+		}
+	}
+	return nil
+}
+
+// restricted reports whether any policy applies
+func (c *Config) restricted() bool {
+	return len(c.Policies) > 0 || len(c.DefaultRoles) > 0
+}
+
+// RolesOf returns the roles of a subject: those of all matching policies, else the default roles
+func (c *Config) RolesOf(s Subject) []string {
+	var roles []string
+	matched := false
+	for _, p := range c.Policies {
+		if p.matches(s) {
+			matched = true
+			roles = append(roles, p.Roles...)
+		}
+	}
+	if !matched {
+		return c.DefaultRoles
+	}
+	return roles
+}
+
+// allows reports whether one of the roles allows a skill of a plugin
+func (c *Config) allows(roles []string, skill, plugin string) bool {
+	for _, name := range roles {
+		role := c.Roles[name]
+		for _, pattern := range role.Skills {
+			if ok, _ := path.Match(pattern, skill); ok {
+				return true
+			}
+		}
+		for _, p := range role.Plugins {
+			if p == "*" || p == plugin {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matches reports whether a policy applies to a subject
+func (p Policy) matches(s Subject) bool {
+	if len(p.Users) > 0 && !slices.Contains(p.Users, s.Username) && !slices.Contains(p.Users, s.UserID) {
+		return false
+	}
+	if len(p.AccountRoles) > 0 && !slices.Contains(p.AccountRoles, s.AccountRole) {
+		return false
+	}
+	if len(p.Platforms) > 0 && !slices.Contains(p.Platforms, s.Platform) {
+		return false
+	}
+	return true
+}
+
+// Manager holds the access policies and reloads them when the file changes
+type Manager struct {
+	path    string
+	mu      sync.RWMutex
+	config  *Config
+	watcher *config.FileWatcher
+}
+
+// NewManager loads the policies in the given file (default ~/.config/chadbot/access.toml) and watches it
+func NewManager(file string) (*Manager, error) {
+	if file == "" {
+		var err error
+		if file, err = Path(); err != nil {
+			return nil, err
+		}
+	}
+	cfg, err := Load(file)
+	if err != nil {
+		return nil, err
+	}
+	m := &Manager{path: file, config: cfg}
+
+	watcher, err := config.NewFileWatcher(config.WatcherConfig{
+		Handler: m.reload,
+		Filter: func(name string) bool {
+			return filepath.Base(name) == filepath.Base(file)
+		},
+		LogPrefix: "Access",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	if err := watcher.Add(filepath.Dir(file)); err != nil {
+		watcher.Stop()
+		return nil, fmt.Errorf("failed to watch access config: %w", err)
+	}
+	m.watcher = watcher
+	log.Printf("[Access] Watching file: %s", file)
+
+	return m, nil
+}
+
+// reload re-reads the policies, keeping the current ones if the file is invalid
+func (m *Manager) reload() {
+	cfg, err := Load(m.path)
+	if err != nil {
+		log.Printf("[Access] Failed to reload, keeping current policies: %v", err)
+		return
+	}
+	m.mu.Lock()
+	m.config = cfg
+	m.mu.Unlock()
+}
+
+// Stop stops the file watcher
+func (m *Manager) Stop() {
+	if m.watcher != nil {
+		m.watcher.Stop()
+	}
+}
+
+// Config returns the current policies
+func (m *Manager) Config() *Config {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.config
+}
+
+// Save validates, applies and writes new policies
+func (m *Manager) Save(cfg *Config) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	data, err := toml.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(m.path, data, 0644); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.config = cfg
+	m.mu.Unlock()
+	log.Printf("[Access] Saved policies to %s", m.path)
+	return nil
+}
+
+// Allowed reports whether a subject may use a skill of a plugin. Once policies apply,
+// a subject without a user (a request outside any chat) may use none.
+func (m *Manager) Allowed(s Subject, skill, plugin string) bool {
+	cfg := m.Config()
+	if !cfg.restricted() {
+		return true
+	}
+	return s.UserID != "" && cfg.allows(cfg.RolesOf(s), skill, plugin)
+}
+
+// Check returns an error if a subject may not use a skill of a plugin (see Allowed). The decision is logged.
+func (m *Manager) Check(s Subject, skill, plugin string) error {
+	cfg := m.Config()
+	if !cfg.restricted() {
+		return nil
+	}
+	if s.UserID == "" {
+		log.Printf("[Access] Denied %s (%s) to a request without a user", skill, plugin)
+		return fmt.Errorf("%s is not allowed without a user", skill)
+	}
+	roles := cfg.RolesOf(s)
+	if !cfg.allows(roles, skill, plugin) {
+		log.Printf("[Access] Denied %s (%s) to %s (roles: %s)", skill, plugin, s, strings.Join(roles, ", "))
+		return fmt.Errorf("%s is not allowed for %s", skill, s)
+	}
+	log.Printf("[Access] Allowed %s (%s) to %s (roles: %s)", skill, plugin, s, strings.Join(roles, ", "))
+	return nil
+}
@@ -0,0 +1,128 @@
+package access
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+const testPolicies = `
+default_roles = ["basic"]
+
+[roles.basic]
+skills = ["memory_*", "chat_search"]
+
+[roles.full]
+skills = ["*"]
+
+[roles.greenhouse]
+plugins = ["mqtt", "vpd"]
+
+[roles.whatsapp]
+skills = ["whatsapp_send_?"]
+
+[[policies]]
+account_roles = ["admin"]
+roles = ["full"]
+
+[[policies]]
+users = ["alice"]
+platforms = ["web"]
+roles = ["basic", "greenhouse"]
+
+[[policies]]
+platforms = ["whatsapp"]
+roles = ["whatsapp"]
+`
+
+func newTestManager(t *testing.T) *Manager {
+	t.Helper()
+	var cfg Config
+	if err := toml.Unmarshal([]byte(testPolicies), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	return &Manager{config: &cfg}
+}
+
+func TestAllowed(t *testing.T) {
+	m := newTestManager(t)
+	admin := Subject{UserID: "1", Username: "root", AccountRole: "admin", Platform: WebPlatform}
+	aliceWeb := Subject{UserID: "2", Username: "alice", AccountRole: "user", Platform: WebPlatform}
+	aliceWhatsApp := Subject{UserID: "2", Username: "alice", AccountRole: "user", Platform: "whatsapp"}
+	bob := Subject{UserID: "3", Username: "bob", AccountRole: "user", Platform: WebPlatform}
+
+	tests := []struct {
+		name    string
+		subject Subject
+		skill   string
+		plugin  string
+		want    bool
+	}{
+		{"admin gets everything", admin, "sandbox_run", "sandbox", true},
+		{"glob prefix", bob, "memory_save", "builtin", true},
+		{"glob needs its prefix", bob, "my_memory_save", "builtin", false},
+		{"exact skill", bob, "chat_search", "builtin", true},
+		{"default roles only", bob, "mqtt_publish", "mqtt", false},
+		{"plugin by name", aliceWeb, "mqtt_publish", "mqtt", true},
+		{"other plugin", aliceWeb, "sandbox_run", "sandbox", false},
+		{"roles of all matching policies", aliceWeb, "memory_save", "builtin", true},
+		{"policy of another platform", aliceWhatsApp, "mqtt_publish", "mqtt", false},
+		{"single character glob", aliceWhatsApp, "whatsapp_send_x", "whatsapp", true},
+		{"single character glob is one character", aliceWhatsApp, "whatsapp_send_xy", "whatsapp", false},
+		{"matching policy replaces default roles", aliceWhatsApp, "memory_save", "builtin", false},
+		{"by user id", Subject{UserID: "alice", Platform: WebPlatform}, "vpd_calc", "vpd", true},
+		{"no user", Subject{}, "memory_save", "builtin", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.Allowed(tt.subject, tt.skill, tt.plugin); got != tt.want {
+				t.Fatalf("Allowed(%s, %s) = %v, want %v", tt.subject, tt.skill, got, tt.want)
+			}
+			if err := m.Check(tt.subject, tt.skill, tt.plugin); (err == nil) != tt.want {
+				t.Fatalf("Check(%s, %s) = %v, want allowed=%v", tt.subject, tt.skill, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnrestricted(t *testing.T) {
+	m := &Manager{config: &Config{Roles: map[string]Role{"basic": {Skills: []string{"memory_*"}}}}}
+	if !m.Allowed(Subject{}, "sandbox_run", "sandbox") {
+		t.Fatal("skill denied without policies")
+	}
+}
+
+func TestNewSubjectPlatform(t *testing.T) {
+	if got := NewSubject("", "").Platform; got != WebPlatform {
+		t.Fatalf("platform of a web chat = %q, want %q", got, WebPlatform)
+	}
+	if got := NewSubject("", "whatsapp").Platform; got != "whatsapp" {
+		t.Fatalf("platform = %q, want whatsapp", got)
+	}
+}
+
+func TestLoadRejectsInvalidPolicies(t *testing.T) {
+	for name, policies := range map[string]string{
+		"invalid glob":         "[roles.a]\nskills = [\"[\"]\n",
+		"undefined role":       "[[policies]]\nroles = [\"missing\"]\n",
+		"undefined default":    "default_roles = [\"missing\"]\n",
+		"policy without roles": "[roles.a]\nskills = [\"*\"]\n[[policies]]\nusers = [\"alice\"]\nroles = []\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			var cfg Config
+			if err := toml.Unmarshal([]byte(policies), &cfg); err != nil {
+				t.Fatal(err)
+			}
+			if err := cfg.Validate(); err == nil {
+				t.Fatal("accepted")
+			}
+		})
+	}
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.toml")); err != nil {
+		t.Fatalf("missing file: %v", err)
+	}
+}
@@ -0,0 +1,290 @@
+// Package auth manages user accounts, browser sessions and API tokens
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+
+	"github.com/fipso/chadbot/internal/storage"
+)
+
+// Roles
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+)
+
+const (
+	// SessionCookie is the name of the session cookie
+	SessionCookie = "chadbot_session"
+
+	// SessionTTL is how long a session stays valid after signing in
+	SessionTTL = 30 * 24 * time.Hour
+
+	// TokenPrefix starts every API token, so leaked tokens are easy to recognize
+	TokenPrefix = "chadbot_"
+
+	// BootstrapUserID is the ID of the admin created on first run. Data created before
+	// accounts existed belongs to the "default" user, so the first admin inherits it.
+	BootstrapUserID = "default"
+
+	// BootstrapUsername is the username of the admin created on first run
+	BootstrapUsername = "admin"
+
+	// AdminPasswordEnv sets the password of the admin created on first run (default random)
+	AdminPasswordEnv = "CHADBOT_ADMIN_PASSWORD"
+
+	// MinPasswordLength is the minimum length of a password
+	MinPasswordLength = 8
+)
+
+var (
+	// ErrInvalidCredentials is returned for an unknown user or wrong password
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	// ErrUnauthenticated is returned when a request carries no valid session or token
+	ErrUnauthenticated = errors.New("authentication required")
+)
+
+// dummyHash is compared against when a username doesn't exist, so the response time doesn't reveal it
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("chadbot"), bcrypt.DefaultCost)
+
+// Bootstrap creates the first admin if there are no users. Its password is taken from
+// AdminPasswordEnv or generated and logged once.
+func Bootstrap() error {
+	n, err := storage.CountUsers()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+
+	password := os.Getenv(AdminPasswordEnv)
+	generated := password == ""
+	if generated {
+		if password, err = randomToken(12); err != nil {
+			return err
+		}
+	}
+	if _, err := CreateUserWithID(BootstrapUserID, BootstrapUsername, password, RoleAdmin); err != nil {
+		return err
+	}
+
+	if generated {
+		log.Printf("[Auth] Created admin user %q with password %q - change it after signing in", BootstrapUsername, password)
+	} else {
+		log.Printf("[Auth] Created admin user %q with the password from %s", BootstrapUsername, AdminPasswordEnv)
+	}
+	return nil
+}
+
+// CreateUser creates a user account
+func CreateUser(username, password, role string) (*storage.User, error) {
+	return CreateUserWithID(uuid.New().String(), username, password, role)
+}
+
+// CreateUserWithID creates a user account with a given ID
+func CreateUserWithID(id, username, password, role string) (*storage.User, error) {
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return nil, errors.New("username is required")
+	}
+	if role != RoleAdmin && role != RoleUser {
+		return nil, fmt.Errorf("invalid role %q", role)
+	}
+	if _, err := storage.GetUserByUsername(username); err == nil {
+		return nil, fmt.Errorf("user %s already exists", username)
+	}
+	hash, err := HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	user := &storage.User{ID: id, Username: username, PasswordHash: hash, Role: role}
+	if err := storage.CreateUser(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// HashPassword checks a password's length and hashes it with bcrypt
+func HashPassword(password string) (string, error) {
+	if len(password) < MinPasswordLength {
+		return "", fmt.Errorf("password must be at least %d characters", MinPasswordLength)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// SetPassword changes a user's password and signs out their other sessions
+func SetPassword(user *storage.User, password, keepSession string) error {
+	hash, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+	user.PasswordHash = hash
+	if err := storage.UpdateUser(user); err != nil {
+		return err
+	}
+	keep := ""
+	if keepSession != "" {
+		keep = hashToken(keepSession)
+	}
+	return storage.DeleteUserSessions(user.ID, keep)
+}
+
+// CheckPassword returns the user with the given credentials
+func CheckPassword(username, password string) (*storage.User, error) {
+	user, err := storage.GetUserByUsername(username)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return user, nil
+}
+
+// Login checks credentials and starts a session, returning the session token for the cookie
+func Login(username, password string) (*storage.User, string, error) {
+	user, err := CheckPassword(username, password)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := storage.DeleteExpiredSessions(); err != nil {
+		log.Printf("[Auth] Failed to delete expired sessions: %v", err)
+	}
+
+	token, err := randomToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+	session := &storage.Session{
+		TokenHash: hashToken(token),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(SessionTTL),
+	}
+	if err := storage.CreateSession(session); err != nil {
+		return nil, "", err
+	}
+	return user, token, nil
+}
+
+// Logout ends a session
+func Logout(token string) error {
+	return storage.DeleteSession(hashToken(token))
+}
+
+// CreateAPIToken creates a bearer token for a user. The token is only returned here;
+// just its hash is stored.
+func CreateAPIToken(userID, name string) (*storage.APIToken, string, error) {
+	secret, err := randomToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+	token := TokenPrefix + secret
+	record := &storage.APIToken{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Name:      name,
+		TokenHash: hashToken(token),
+		Prefix:    token[:len(TokenPrefix)+6],
+	}
+	if err := storage.CreateAPIToken(record); err != nil {
+		return nil, "", err
+	}
+	return record, token, nil
+}
+
+// Authenticate returns the user of a request's bearer token or session cookie
+func Authenticate(r *http.Request) (*storage.User, error) {
+	if header := r.Header.Get("Authorization"); header != "" {
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok {
+			return nil, ErrUnauthenticated
+		}
+		record, err := storage.GetAPITokenByHash(hashToken(strings.TrimSpace(token)))
+		if err != nil {
+			return nil, ErrUnauthenticated
+		}
+		if err := storage.TouchAPIToken(record.ID, time.Now()); err != nil {
+			log.Printf("[Auth] Failed to record token use: %v", err)
+		}
+		return userOrUnauthenticated(record.UserID)
+	}
+
+	if token := SessionToken(r); token != "" {
+		session, err := storage.GetSession(hashToken(token))
+		if err != nil {
+			return nil, ErrUnauthenticated
+		}
+		return userOrUnauthenticated(session.UserID)
+	}
+	return nil, ErrUnauthenticated
+}
+
+// SessionToken returns the session cookie of a request ("" if none)
+func SessionToken(r *http.Request) string {
+	cookie, err := r.Cookie(SessionCookie)
+	if err != nil {
+		return ""
+	}
+	return cookie.Value
+}
+
+// userOrUnauthenticated loads the user a session or token belongs to
+func userOrUnauthenticated(id string) (*storage.User, error) {
+	user, err := storage.GetUser(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUnauthenticated
+	}
+	return user, err
+}
+
+type contextKey struct{}
+
+// WithUser returns a context carrying the authenticated user
+func WithUser(ctx context.Context, user *storage.User) context.Context {
+	return context.WithValue(ctx, contextKey{}, user)
+}
+
+// UserFrom returns the authenticated user of a context (nil if none)
+func UserFrom(ctx context.Context) *storage.User {
+	user, _ := ctx.Value(contextKey{}).(*storage.User)
+	return user
+}
+
+// hashToken returns the hex SHA-256 under which a token is stored
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomToken returns n random bytes, base64url encoded
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
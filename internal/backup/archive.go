@@ -0,0 +1,231 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/fipso/chadbot/internal/storage"
+)
+
+// FormatVersion is the archive layout version. Restore refuses archives with a newer version.
+const FormatVersion = 1
+
+// Archive layout: the database snapshot, the config directory and extra paths,
+// followed by the manifest
+const (
+	manifestName = "manifest.json"
+	databaseName = "chadbot.db"
+	configPrefix = "config/"
+	filesPrefix  = "files/"
+)
+
+// Entry kinds
+const (
+	KindDatabase = "database" // The chadbot database
+	KindConfig   = "config"   // A file in the config directory
+	KindFile     = "file"     // A file from the configured extra paths
+)
+
+// Manifest describes an archive and is stored as its last entry
+type Manifest struct {
+	FormatVersion int       `json:"format_version"`
+	CreatedAt     time.Time `json:"created_at"`
+	SchemaVersion int       `json:"schema_version"` // Core schema version of the database snapshot
+	Entries       []Entry   `json:"entries"`
+}
+
+// Entry is a file in an archive
+type Entry struct {
+	Name   string `json:"name"`           // Path in the archive
+	Kind   string `json:"kind"`           // KindDatabase, KindConfig or KindFile
+	Path   string `json:"path,omitempty"` // Original absolute path (KindFile)
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+	SQLite bool   `json:"sqlite,omitempty"` // Online snapshot of an SQLite database
+}
+
+// sqliteHeader starts every SQLite database file
+var sqliteHeader = []byte("SQLite format 3\x00")
+
+// archiveWriter writes a gzipped tar archive and collects its manifest
+type archiveWriter struct {
+	gz       *gzip.Writer
+	tw       *tar.Writer
+	tmpDir   string // Holds SQLite snapshots until they are archived
+	manifest Manifest
+}
+
+func newArchiveWriter(w io.Writer, tmpDir string) *archiveWriter {
+	gz := gzip.NewWriter(w)
+	return &archiveWriter{
+		gz:       gz,
+		tw:       tar.NewWriter(gz),
+		tmpDir:   tmpDir,
+		manifest: Manifest{FormatVersion: FormatVersion, CreatedAt: time.Now().UTC()},
+	}
+}
+
+// addFile archives a file. SQLite databases are archived as an online snapshot,
+// so a database in use is consistent.
+func (a *archiveWriter) addFile(name, src string, entry Entry) error {
+	isDB, err := isSQLite(src)
+	if err != nil {
+		return err
+	}
+	if isDB {
+		snapshot, err := snapshotSQLite(src, a.tmpDir)
+		if err != nil {
+			return fmt.Errorf("failed to snapshot %s: %w", src, err)
+		}
+		defer os.Remove(snapshot)
+		src = snapshot
+		entry.SQLite = true
+		if entry.Kind == KindDatabase {
+			if a.manifest.SchemaVersion, err = schemaVersion(snapshot); err != nil {
+				return err
+			}
+		}
+	}
+
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	if err := a.tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    int64(info.Mode().Perm()),
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	}); err != nil {
+		return err
+	}
+	// Files that change while being archived must not corrupt the archive, so exactly the stat'ed size is copied
+	hash := sha256.New()
+	if _, err := io.CopyN(io.MultiWriter(a.tw, hash), f, info.Size()); err != nil {
+		return fmt.Errorf("failed to archive %s: %w", src, err)
+	}
+
+	entry.Name = name
+	entry.Size = info.Size()
+	entry.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	a.manifest.Entries = append(a.manifest.Entries, entry)
+	return nil
+}
+
+// addTree archives the regular files below root under prefix, skipping paths for which skip returns true
+func (a *archiveWriter) addTree(root, prefix string, entry func(path string) Entry, skip func(path string) bool) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if skip(path) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		return a.addFile(prefix+filepath.ToSlash(rel), path, entry(path))
+	})
+}
+
+// close writes the manifest and finishes the archive
+func (a *archiveWriter) close() error {
+	data, err := json.MarshalIndent(a.manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := a.tw.WriteHeader(&tar.Header{
+		Name:    manifestName,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: a.manifest.CreatedAt,
+	}); err != nil {
+		return err
+	}
+	if _, err := a.tw.Write(data); err != nil {
+		return err
+	}
+	if err := a.tw.Close(); err != nil {
+		return err
+	}
+	return a.gz.Close()
+}
+
+// isSQLite reports whether a file is an SQLite database
+func isSQLite(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	header := make([]byte, len(sqliteHeader))
+	if _, err := io.ReadFull(f, header); err != nil {
+		return false, nil
+	}
+	return bytes.Equal(header, sqliteHeader), nil
+}
+
+// snapshotSQLite copies a database with VACUUM INTO, which reads it in one
+// transaction, and returns the path of the copy in dir
+func snapshotSQLite(path, dir string) (string, error) {
+	f, err := os.CreateTemp(dir, ".snapshot-*.db")
+	if err != nil {
+		return "", err
+	}
+	target := f.Name()
+	f.Close()
+	os.Remove(target) // VACUUM INTO needs a target that doesn't exist
+
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return "", err
+	}
+	defer db.Close()
+	if _, err := db.Exec("VACUUM INTO ?", target); err != nil {
+		os.Remove(target)
+		return "", err
+	}
+	return target, nil
+}
+
+// schemaVersion returns the core schema version recorded in a database (0 = none)
+func schemaVersion(path string) (int, error) {
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+	var version int
+	err = db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_migrations WHERE namespace = ?", storage.CoreNamespace).Scan(&version)
+	if err != nil && strings.Contains(err.Error(), "no such table") {
+		return 0, nil
+	}
+	return version, err
+}
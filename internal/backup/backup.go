@@ -0,0 +1,291 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fipso/chadbot/internal/config"
+)
+
+const (
+	archivePrefix = "chadbot-"
+	archiveSuffix = ".tar.gz"
+
+	// scheduleCheckInterval is how often the schedule checks whether a backup is due
+	scheduleCheckInterval = time.Minute
+)
+
+// Info describes a backup archive
+type Info struct {
+	Name          string    `json:"name"`
+	Path          string    `json:"path"`
+	Size          int64     `json:"size"`
+	CreatedAt     time.Time `json:"created_at"`
+	SchemaVersion int       `json:"schema_version,omitempty"` // Only known for archives created by this process
+	Files         int       `json:"files,omitempty"`
+}
+
+// Manager creates backup archives of the database, the config directory and
+// extra paths, on demand and on a schedule
+type Manager struct {
+	path   string // backup.toml
+	dbPath string
+
+	mu   sync.Mutex // Serializes backups
+	last *Info
+}
+
+// NewManager creates a manager for the settings in the given file (default ~/.config/chadbot/backup.toml)
+func NewManager(path, dbPath string) (*Manager, error) {
+	if path == "" {
+		var err error
+		if path, err = Path(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := Load(path); err != nil {
+		return nil, err
+	}
+	dbPath, err := filepath.Abs(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	return &Manager{path: path, dbPath: dbPath}, nil
+}
+
+// Config reloads and returns the backup settings
+func (m *Manager) Config() (*Config, error) {
+	return Load(m.path)
+}
+
+// LastBackup returns the archive created last by this process (nil before the first backup)
+func (m *Manager) LastBackup() *Info {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.last
+}
+
+// Run creates a backup whenever the newest archive is older than the configured
+// interval, until ctx is done. The config file is reloaded before each check.
+func (m *Manager) Run(ctx context.Context) {
+	for {
+		if cfg, err := m.Config(); err != nil {
+			log.Printf("[Backup] %v", err)
+		} else if cfg.Interval > 0 {
+			archives, err := m.List()
+			if err != nil {
+				log.Printf("[Backup] %v", err)
+			} else if len(archives) == 0 || time.Since(archives[0].CreatedAt) >= time.Duration(cfg.Interval) {
+				if _, err := m.Create(); err != nil {
+					log.Printf("[Backup] Scheduled backup failed: %v", err)
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(scheduleCheckInterval):
+		}
+	}
+}
+
+// Create writes a new archive and deletes the oldest archives beyond the configured number to keep
+func (m *Manager) Create() (*Info, error) {
+	cfg, err := m.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := os.MkdirAll(cfg.Dir, 0700); err != nil {
+		return nil, err
+	}
+	configDir, err := config.Dir()
+	if err != nil {
+		return nil, err
+	}
+
+	createdAt := time.Now().UTC()
+	name := archivePrefix + createdAt.Format("20060102-150405") + archiveSuffix
+	path := filepath.Join(cfg.Dir, name)
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("backup %s already exists", name)
+	}
+
+	// Written under a temporary name, so a failed backup never looks like an archive
+	f, err := os.CreateTemp(cfg.Dir, ".partial-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	a := newArchiveWriter(f, cfg.Dir)
+	a.manifest.CreatedAt = createdAt
+	if err := a.addFile(databaseName, m.dbPath, Entry{Kind: KindDatabase}); err != nil {
+		return nil, err
+	}
+
+	// The config directory holds config.toml, souls, and by default the knowledge base and attachment
+	// blobs. The archives, the database and what restores leave behind are skipped, and so are the
+	// keys: the secret key, so an archive alone doesn't reveal the secrets in config.toml, and the
+	// plugin key, so it doesn't let anyone mint plugin tokens. Restores keep the local keys.
+	secretKey := filepath.Join(configDir, config.SecretKeyFile)
+	pluginKey := filepath.Join(configDir, config.PluginKeyFile)
+	skip := func(path string) bool {
+		base := filepath.Base(path)
+		return path == cfg.Dir || path == m.dbPath || path == secretKey || path == pluginKey || strings.HasPrefix(path, m.dbPath+"-") ||
+			strings.HasPrefix(base, ".restore-") || strings.Contains(base, preRestoreMarker)
+	}
+	if err := a.addTree(configDir, configPrefix, func(string) Entry { return Entry{Kind: KindConfig} }, skip); err != nil {
+		return nil, err
+	}
+
+	for i, root := range cfg.Paths {
+		prefix := filesPrefix + strconv.Itoa(i) + "/"
+		info, err := os.Stat(root)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			if err := a.addFile(prefix+filepath.Base(root), root, Entry{Kind: KindFile, Path: root}); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		entry := func(path string) Entry { return Entry{Kind: KindFile, Path: path} }
+		if err := a.addTree(root, prefix, entry, skip); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := a.close(); err != nil {
+		return nil, err
+	}
+	if err := f.Sync(); err != nil {
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return nil, err
+	}
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	info := &Info{
+		Name:          name,
+		Path:          path,
+		Size:          stat.Size(),
+		CreatedAt:     createdAt,
+		SchemaVersion: a.manifest.SchemaVersion,
+		Files:         len(a.manifest.Entries),
+	}
+	m.last = info
+	log.Printf("[Backup] Created %s (%d files, %d bytes)", path, info.Files, info.Size)
+
+	if err := m.prune(cfg); err != nil {
+		log.Printf("[Backup] Failed to delete old backups: %v", err)
+	}
+	return info, nil
+}
+
+// List returns the archives in the backup directory, newest first
+func (m *Manager) List() ([]Info, error) {
+	cfg, err := m.Config()
+	if err != nil {
+		return nil, err
+	}
+	return listArchives(cfg.Dir)
+}
+
+// Open returns the path of an archive in the backup directory
+func (m *Manager) Open(name string) (string, error) {
+	cfg, err := m.Config()
+	if err != nil {
+		return "", err
+	}
+	if _, ok := archiveTime(name); !ok || filepath.Base(name) != name {
+		return "", fmt.Errorf("invalid backup name: %s", name)
+	}
+	path := filepath.Join(cfg.Dir, name)
+	if _, err := os.Stat(path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// prune deletes the oldest archives beyond cfg.Keep
+func (m *Manager) prune(cfg *Config) error {
+	archives, err := listArchives(cfg.Dir)
+	if err != nil {
+		return err
+	}
+	for _, info := range archives[min(cfg.Keep, len(archives)):] {
+		if err := os.Remove(info.Path); err != nil {
+			return err
+		}
+		log.Printf("[Backup] Deleted old backup %s", info.Name)
+	}
+	return nil
+}
+
+// listArchives returns the archives in dir, newest first
+func listArchives(dir string) ([]Info, error) {
+	dirEntries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return []Info{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	archives := []Info{}
+	for _, e := range dirEntries {
+		createdAt, ok := archiveTime(e.Name())
+		if !ok || !e.Type().IsRegular() {
+			continue
+		}
+		stat, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		archives = append(archives, Info{
+			Name:      e.Name(),
+			Path:      filepath.Join(dir, e.Name()),
+			Size:      stat.Size(),
+			CreatedAt: createdAt,
+		})
+	}
+	sort.Slice(archives, func(i, j int) bool {
+		return archives[i].CreatedAt.After(archives[j].CreatedAt)
+	})
+	return archives, nil
+}
+
+// archiveTime parses the creation time from an archive name
+func archiveTime(name string) (time.Time, bool) {
+	stamp, ok := strings.CutPrefix(name, archivePrefix)
+	if !ok {
+		return time.Time{}, false
+	}
+	if stamp, ok = strings.CutSuffix(stamp, archiveSuffix); !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse("20060102-150405", stamp)
+	return t, err == nil
+}
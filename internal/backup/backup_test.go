@@ -0,0 +1,53 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fipso/chadbot/internal/config"
+	"github.com/fipso/chadbot/internal/storage"
+)
+
+func TestCreateSkipsKeys(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	configDir, err := config.Dir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dbPath := filepath.Join(configDir, "chadbot.db")
+	if err := storage.Init(dbPath); err != nil {
+		t.Fatalf("init storage: %v", err)
+	}
+	for _, name := range []string{"config.toml", config.SecretKeyFile, config.PluginKeyFile} {
+		if err := os.WriteFile(filepath.Join(configDir, name), []byte(name), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m, err := NewManager("", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := m.Create()
+	if err != nil {
+		t.Fatalf("create backup: %v", err)
+	}
+	manifest, err := Verify(info.Path)
+	if err != nil {
+		t.Fatalf("verify backup: %v", err)
+	}
+
+	archived := make(map[string]bool)
+	for _, e := range manifest.Entries {
+		archived[filepath.Base(e.Name)] = true
+	}
+	if !archived["config.toml"] {
+		t.Errorf("config.toml not archived: %v", manifest.Entries)
+	}
+	for _, key := range []string{config.SecretKeyFile, config.PluginKeyFile} {
+		if archived[key] {
+			t.Errorf("%s archived", key)
+		}
+	}
+}
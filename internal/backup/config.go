@@ -0,0 +1,81 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+
+	"github.com/fipso/chadbot/internal/config"
+	"github.com/fipso/chadbot/internal/retention"
+)
+
+const defaultKeep = 7
+
+// Config holds the backup settings (~/.config/chadbot/backup.toml)
+type Config struct {
+	Dir      string             `toml:"dir" json:"dir"`           // Archive directory (default ~/.config/chadbot/backups)
+	Interval retention.Duration `toml:"interval" json:"interval"` // Scheduled backup interval (0 = manual only)
+	Keep     int                `toml:"keep" json:"keep"`         // Archives kept, oldest are deleted first (default 7)
+	Paths    []string           `toml:"paths" json:"paths"`       // Extra files or directories, e.g. plugin session data
+}
+
+// Path returns the backup config path (~/.config/chadbot/backup.toml)
+func Path() (string, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "backup.toml"), nil
+}
+
+// Load reads backup settings from a TOML file (a missing file means defaults only)
+func Load(path string) (*Config, error) {
+	cfg := &Config{}
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		if err := toml.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("invalid backup config %s: %w", path, err)
+		}
+	}
+
+	if cfg.Dir == "" {
+		dir, err := config.Dir()
+		if err != nil {
+			return nil, err
+		}
+		cfg.Dir = filepath.Join(dir, "backups")
+	}
+	if cfg.Dir, err = absPath(cfg.Dir); err != nil {
+		return nil, err
+	}
+	if cfg.Keep <= 0 {
+		cfg.Keep = defaultKeep
+	}
+	if cfg.Interval < 0 {
+		return nil, fmt.Errorf("invalid backup config %s: interval must not be negative", path)
+	}
+	for i, p := range cfg.Paths {
+		if cfg.Paths[i], err = absPath(p); err != nil {
+			return nil, err
+		}
+	}
+	return cfg, nil
+}
+
+// absPath expands a leading ~/ and makes a path absolute
+func absPath(path string) (string, error) {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(home, rest)
+	}
+	return filepath.Abs(path)
+}
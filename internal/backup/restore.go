@@ -0,0 +1,325 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fipso/chadbot/internal/config"
+	"github.com/fipso/chadbot/internal/storage"
+)
+
+// preRestoreMarker is part of the names under which a restore keeps the state it replaced
+const preRestoreMarker = ".pre-restore-"
+
+// maxManifestSize bounds the manifest read into memory
+const maxManifestSize = 64 << 20
+
+// RestoreOptions configures a restore
+type RestoreOptions struct {
+	DBPath    string // Database to replace
+	ConfigDir string // Config directory to restore into (default ~/.config/chadbot)
+	Force     bool   // Restore a database with a newer schema than this build knows
+}
+
+// stagedFile is an archive entry extracted next to its destination
+type stagedFile struct {
+	path   string
+	size   int64
+	sha256 string
+}
+
+// Verify checks an archive's format version, schema version and checksums without restoring it
+func Verify(archive string) (*Manifest, error) {
+	staging, err := os.MkdirTemp("", "chadbot-verify-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(staging)
+
+	manifest, staged, err := extract(archive, func(name string) string {
+		return filepath.Join(staging, filepath.FromSlash(name))
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := validate(manifest, staged, false); err != nil {
+		return nil, err
+	}
+	return manifest, checkIntegrity(staged[databaseName].path)
+}
+
+// Restore replaces the database, the config directory's contents and the extra paths with those
+// of an archive. chadbot must not be running. The archive is fully extracted and verified before
+// anything is replaced; replaced files are kept next to the originals with a .pre-restore- suffix.
+func Restore(archive string, opts RestoreOptions) (*Manifest, error) {
+	if opts.ConfigDir == "" {
+		var err error
+		if opts.ConfigDir, err = config.Dir(); err != nil {
+			return nil, err
+		}
+	}
+	dbPath, err := filepath.Abs(opts.DBPath)
+	if err != nil {
+		return nil, err
+	}
+
+	stamp := time.Now().UTC().Format("20060102-150405")
+	staging := filepath.Join(opts.ConfigDir, ".restore-"+stamp)
+	stagedDB := dbPath + ".restore-" + stamp
+	defer os.RemoveAll(staging)
+	defer os.Remove(stagedDB)
+
+	// The database and config files are staged on the filesystem they are restored to, so they can be renamed into place
+	manifest, staged, err := extract(archive, func(name string) string {
+		if name == databaseName {
+			return stagedDB
+		}
+		return filepath.Join(staging, filepath.FromSlash(name))
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := validate(manifest, staged, opts.Force); err != nil {
+		return nil, err
+	}
+	if err := checkIntegrity(stagedDB); err != nil {
+		return nil, err
+	}
+
+	aside := func(path string) error {
+		if _, err := os.Lstat(path); os.IsNotExist(err) {
+			return nil
+		}
+		if err := os.Rename(path, path+preRestoreMarker+stamp); err != nil {
+			return fmt.Errorf("failed to move %s aside: %w", path, err)
+		}
+		log.Printf("[Backup] Kept previous %s as %s", path, path+preRestoreMarker+stamp)
+		return nil
+	}
+
+	// Database, without the journal files of the replaced one
+	for _, suffix := range []string{"", "-journal", "-wal", "-shm"} {
+		if err := aside(dbPath + suffix); err != nil {
+			return nil, err
+		}
+	}
+	if err := os.Rename(stagedDB, dbPath); err != nil {
+		return nil, err
+	}
+
+	// Config directory, replacing each top-level file or directory the archive contains
+	configStaging := filepath.Join(staging, strings.TrimSuffix(configPrefix, "/"))
+	items, err := os.ReadDir(configStaging)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, item := range items {
+		target := filepath.Join(opts.ConfigDir, item.Name())
+		if err := aside(target); err != nil {
+			return nil, err
+		}
+		if err := os.Rename(filepath.Join(configStaging, item.Name()), target); err != nil {
+			return nil, err
+		}
+	}
+
+	// Extra paths, copied because they may live on another filesystem
+	for _, e := range manifest.Entries {
+		if e.Kind != KindFile {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(e.Path), 0755); err != nil {
+			return nil, err
+		}
+		tmp := e.Path + ".restore-" + stamp
+		if err := copyFile(staged[e.Name].path, tmp); err != nil {
+			os.Remove(tmp)
+			return nil, err
+		}
+		if err := aside(e.Path); err != nil {
+			return nil, err
+		}
+		if err := os.Rename(tmp, e.Path); err != nil {
+			return nil, err
+		}
+	}
+
+	log.Printf("[Backup] Restored %s (created %s, schema version %d)", archive, manifest.CreatedAt.Format(time.RFC3339), manifest.SchemaVersion)
+	return manifest, nil
+}
+
+// extract writes the files of an archive to the paths chosen by dest and returns its manifest
+func extract(archive string, dest func(name string) string) (*Manifest, map[string]stagedFile, error) {
+	f, err := os.Open(archive)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, nil, fmt.Errorf("not a backup archive: %w", err)
+	}
+	tr := tar.NewReader(gz)
+
+	var manifest *Manifest
+	staged := make(map[string]stagedFile)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("corrupt backup archive: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			return nil, nil, fmt.Errorf("unexpected entry %s in backup archive", hdr.Name)
+		}
+
+		if hdr.Name == manifestName {
+			data, err := io.ReadAll(io.LimitReader(tr, maxManifestSize))
+			if err != nil {
+				return nil, nil, err
+			}
+			manifest = &Manifest{}
+			if err := json.Unmarshal(data, manifest); err != nil {
+				return nil, nil, fmt.Errorf("invalid backup manifest: %w", err)
+			}
+			continue
+		}
+
+		if !validName(hdr.Name) {
+			return nil, nil, fmt.Errorf("invalid entry name %q in backup archive", hdr.Name)
+		}
+		if _, ok := staged[hdr.Name]; ok {
+			return nil, nil, fmt.Errorf("duplicate entry %s in backup archive", hdr.Name)
+		}
+		target := dest(hdr.Name)
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return nil, nil, err
+		}
+		out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, os.FileMode(hdr.Mode).Perm())
+		if err != nil {
+			return nil, nil, err
+		}
+		hash := sha256.New()
+		n, err := io.Copy(io.MultiWriter(out, hash), tr)
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to extract %s: %w", hdr.Name, err)
+		}
+		staged[hdr.Name] = stagedFile{path: target, size: n, sha256: hex.EncodeToString(hash.Sum(nil))}
+	}
+
+	if manifest == nil {
+		return nil, nil, errors.New("backup archive has no manifest")
+	}
+	return manifest, staged, nil
+}
+
+// validName reports whether an archive entry name is one this format writes and stays inside its directory
+func validName(name string) bool {
+	if name == databaseName {
+		return true
+	}
+	if !strings.HasPrefix(name, configPrefix) && !strings.HasPrefix(name, filesPrefix) {
+		return false
+	}
+	return path.Clean(name) == name && !strings.HasPrefix(name, "/") && !strings.Contains("/"+name+"/", "/../")
+}
+
+// validate checks the manifest's versions and that it matches the extracted files
+func validate(manifest *Manifest, staged map[string]stagedFile, force bool) error {
+	if manifest.FormatVersion < 1 || manifest.FormatVersion > FormatVersion {
+		return fmt.Errorf("unsupported backup format version %d (this build supports up to %d)", manifest.FormatVersion, FormatVersion)
+	}
+	if latest := storage.LatestCoreVersion(); manifest.SchemaVersion > latest && !force {
+		return fmt.Errorf("backup has schema version %d, newer than this build's %d; upgrade chadbot or force the restore", manifest.SchemaVersion, latest)
+	}
+
+	hasDB := false
+	listed := make(map[string]bool, len(manifest.Entries))
+	for _, e := range manifest.Entries {
+		file, ok := staged[e.Name]
+		if !ok {
+			return fmt.Errorf("backup archive is missing %s", e.Name)
+		}
+		if file.size != e.Size || file.sha256 != e.SHA256 {
+			return fmt.Errorf("checksum mismatch for %s in backup archive", e.Name)
+		}
+		switch e.Kind {
+		case KindDatabase:
+			hasDB = e.Name == databaseName
+		case KindConfig:
+		case KindFile:
+			if !filepath.IsAbs(e.Path) {
+				return fmt.Errorf("invalid path %q for %s in backup manifest", e.Path, e.Name)
+			}
+		default:
+			return fmt.Errorf("unknown kind %q for %s in backup manifest", e.Kind, e.Name)
+		}
+		listed[e.Name] = true
+	}
+	for name := range staged {
+		if !listed[name] {
+			return fmt.Errorf("%s is not listed in the backup manifest", name)
+		}
+	}
+	if !hasDB {
+		return errors.New("backup archive has no database")
+	}
+	return nil
+}
+
+// checkIntegrity runs SQLite's integrity check on a database file
+func checkIntegrity(path string) error {
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	var result string
+	if err := db.QueryRow("PRAGMA integrity_check").Scan(&result); err != nil {
+		return fmt.Errorf("database in backup is unreadable: %w", err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("database in backup failed the integrity check: %s", result)
+	}
+	return nil
+}
+
+// copyFile copies a file, keeping its permissions
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,153 @@
+package blob
+
+import (
+	"encoding/json"
+	"log"
+
+	pb "github.com/fipso/chadbot/gen/chadbot"
+	"github.com/fipso/chadbot/internal/storage"
+)
+
+// storedAttachment is an attachment as stored in messages: a blob reference,
+// or the legacy format with inline data (a serialized pb.Attachment)
+type storedAttachment struct {
+	storage.AttachmentRef
+	Data []byte `json:"data,omitempty"`
+}
+
+// SaveAttachments moves attachment data into the store and returns the JSON stored with the message
+func (s *Store) SaveAttachments(attachments []*pb.Attachment) (string, error) {
+	if len(attachments) == 0 {
+		return "", nil
+	}
+
+	refs := make([]storage.AttachmentRef, 0, len(attachments))
+	for _, a := range attachments {
+		ref := storage.AttachmentRef{
+			Type:     a.Type,
+			MimeType: a.MimeType,
+			URL:      a.Url,
+			Filename: a.Filename,
+		}
+		if len(a.Data) > 0 {
+			blob, err := s.Put(a.Data, a.MimeType)
+			if err != nil {
+				return "", err
+			}
+			ref.Hash, ref.Size = blob.Hash, blob.Size
+			if ref.MimeType == "" {
+				ref.MimeType = blob.MimeType
+			}
+		}
+		refs = append(refs, ref)
+	}
+
+	data, err := json.Marshal(refs)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// ParseAttachments returns the blob references of a message's attachments JSON
+func ParseAttachments(attachmentsJSON string) []storage.AttachmentRef {
+	if attachmentsJSON == "" {
+		return nil
+	}
+	var stored []storedAttachment
+	if err := json.Unmarshal([]byte(attachmentsJSON), &stored); err != nil {
+		return nil
+	}
+	refs := make([]storage.AttachmentRef, len(stored))
+	for i, a := range stored {
+		refs[i] = a.AttachmentRef
+	}
+	return refs
+}
+
+// LoadAttachments returns a message's attachments with their data read from the store
+func (s *Store) LoadAttachments(attachmentsJSON string) []*pb.Attachment {
+	if attachmentsJSON == "" {
+		return nil
+	}
+	var stored []storedAttachment
+	if err := json.Unmarshal([]byte(attachmentsJSON), &stored); err != nil {
+		return nil
+	}
+
+	attachments := make([]*pb.Attachment, 0, len(stored))
+	for _, a := range stored {
+		data := a.Data
+		if a.Hash != "" {
+			var err error
+			if data, err = s.Read(a.Hash); err != nil {
+				log.Printf("[Blob] Failed to read blob %s: %v", a.Hash, err)
+			}
+		}
+		attachments = append(attachments, &pb.Attachment{
+			Type:     a.Type,
+			MimeType: a.MimeType,
+			Data:     data,
+			Url:      a.URL,
+			Filename: a.Filename,
+		})
+	}
+	return attachments
+}
+
+// MigrateInlineAttachments moves attachment data embedded in messages into the store
+func (s *Store) MigrateInlineAttachments() (int, error) {
+	const batchSize = 100
+
+	moved := 0
+	lastID := ""
+	for {
+		messages, err := storage.GetMessagesWithInlineAttachments(lastID, batchSize)
+		if err != nil {
+			return moved, err
+		}
+		if len(messages) == 0 {
+			break
+		}
+
+		for _, m := range messages {
+			lastID = m.ID
+
+			var stored []storedAttachment
+			if err := json.Unmarshal([]byte(m.Attachments), &stored); err != nil {
+				log.Printf("[Blob] Skipping message %s with invalid attachments: %v", m.ID, err)
+				continue
+			}
+
+			refs := make([]storage.AttachmentRef, len(stored))
+			for i, a := range stored {
+				refs[i] = a.AttachmentRef
+				if len(a.Data) == 0 {
+					continue
+				}
+				blob, err := s.Put(a.Data, a.MimeType)
+				if err != nil {
+					return moved, err
+				}
+				refs[i].Hash, refs[i].Size = blob.Hash, blob.Size
+				if refs[i].MimeType == "" {
+					refs[i].MimeType = blob.MimeType
+				}
+				moved++
+			}
+
+			data, err := json.Marshal(refs)
+			if err != nil {
+				return moved, err
+			}
+			if err := storage.SetMessageAttachments(m.ID, string(data)); err != nil {
+				return moved, err
+			}
+		}
+	}
+
+	if moved > 0 {
+		log.Printf("[Blob] Moved %d inline attachments into the blob store", moved)
+	}
+	return moved, nil
+}
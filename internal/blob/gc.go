@@ -0,0 +1,76 @@
+package blob
+
+import (
+	"context"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fipso/chadbot/internal/storage"
+)
+
+// gcGracePeriod protects new blobs whose message may not be saved yet
+const gcGracePeriod = time.Hour
+
+// GC removes blobs that no message references and returns how many were removed
+func (s *Store) GC() (removed int, freed int64, err error) {
+	referenced, err := storage.ReferencedBlobHashes()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	err = filepath.WalkDir(s.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		name := d.Name()
+		if referenced[name] {
+			return nil
+		}
+		if !ValidHash(name) && !strings.HasPrefix(name, ".tmp-") {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil || time.Since(info.ModTime()) < gcGracePeriod {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			log.Printf("[Blob] Failed to remove %s: %v", path, err)
+			return nil
+		}
+		if ValidHash(name) {
+			if err := storage.DeleteBlob(name); err != nil {
+				log.Printf("[Blob] Failed to delete record of %s: %v", name, err)
+			}
+			removed++
+			freed += info.Size()
+		}
+		return nil
+	})
+	return removed, freed, err
+}
+
+// Run collects garbage at startup and then every interval until ctx is done
+func (s *Store) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		removed, freed, err := s.GC()
+		if err != nil {
+			log.Printf("[Blob] Garbage collection failed: %v", err)
+		} else if removed > 0 {
+			log.Printf("[Blob] Removed %d unreferenced blobs (%d bytes)", removed, freed)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package blob
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fipso/chadbot/internal/config"
+	"github.com/fipso/chadbot/internal/storage"
+)
+
+// Store is a content-addressed blob store on the filesystem, deduplicated by SHA-256.
+// Files live in <dir>/<first two hash chars>/<hash>, metadata in the blobs table.
+type Store struct {
+	dir string
+}
+
+// New creates a blob store in dir (default ~/.config/chadbot/blobs)
+func New(dir string) (*Store, error) {
+	if dir == "" {
+		var err error
+		if dir, err = config.BlobsDir(); err != nil {
+			return nil, err
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Store{dir: dir}, nil
+}
+
+// Dir returns the directory of the store
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// ValidHash reports whether hash is a lowercase hex SHA-256
+func ValidHash(hash string) bool {
+	if len(hash) != sha256.Size*2 {
+		return false
+	}
+	for _, c := range hash {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// path returns the file path of a blob
+func (s *Store) path(hash string) string {
+	return filepath.Join(s.dir, hash[:2], hash)
+}
+
+// Put stores data and returns its blob record (identical content is stored once)
+func (s *Store) Put(data []byte, mimeType string) (*storage.Blob, error) {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+
+	path := s.path(hash)
+	if _, err := os.Stat(path); err == nil {
+		// Refresh the modification time so the garbage collector's grace period starts again
+		now := time.Now()
+		os.Chtimes(path, now, now)
+	} else if err := s.write(path, data); err != nil {
+		return nil, err
+	}
+
+	blob := &storage.Blob{
+		Hash:      hash,
+		Size:      int64(len(data)),
+		MimeType:  mimeType,
+		CreatedAt: time.Now(),
+	}
+	if err := storage.CreateBlob(blob); err != nil {
+		return nil, err
+	}
+	return blob, nil
+}
+
+// write atomically writes a blob file
+func (s *Store) write(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Open opens a blob for reading and returns its record
+func (s *Store) Open(hash string) (*os.File, *storage.Blob, error) {
+	if !ValidHash(hash) {
+		return nil, nil, fmt.Errorf("invalid blob hash")
+	}
+	f, err := os.Open(s.path(hash))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	blob, err := storage.GetBlob(hash)
+	if err != nil {
+		// File without record (e.g. copied in by hand): derive what we can
+		info, statErr := f.Stat()
+		if statErr != nil {
+			f.Close()
+			return nil, nil, statErr
+		}
+		blob = &storage.Blob{Hash: hash, Size: info.Size(), CreatedAt: info.ModTime()}
+	}
+	return f, blob, nil
+}
+
+// Read returns the content of a blob
+func (s *Store) Read(hash string) ([]byte, error) {
+	if !ValidHash(hash) {
+		return nil, fmt.Errorf("invalid blob hash")
+	}
+	return os.ReadFile(s.path(hash))
+}
@@ -0,0 +1,409 @@
+package chat
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/fipso/chadbot/internal/blob"
+	"github.com/fipso/chadbot/internal/storage"
+)
+
+// Export formats
+const (
+	FormatJSON     = "json"  // All branches, tool calls and attachments
+	FormatMarkdown = "md"    // Readable transcript of the active branch
+	FormatJSONL    = "jsonl" // OpenAI fine-tuning format of the active branch, one chat per line (metadata keeps all branches)
+)
+
+// ExportVersion is the version of the chat export format
+const ExportVersion = 1
+
+// ExportOptions configures a chat export
+type ExportOptions struct {
+	Format            string
+	InlineAttachments bool // Embed attachment data instead of blob references (JSON only)
+	NoMetadata        bool // Omit chadbot metadata from JSONL (plain fine-tuning data, imports as new chats)
+}
+
+// ChatExport is the JSON export of one or more chats
+type ChatExport struct {
+	Version    int            `json:"version"`
+	ExportedAt time.Time      `json:"exported_at"`
+	Chats      []ExportedChat `json:"chats"`
+}
+
+// ExportedChat is a chat with the messages of all its branches
+type ExportedChat struct {
+	ID         string            `json:"id"`
+	UserID     string            `json:"user_id"`
+	Name       string            `json:"name"`
+	Platform   string            `json:"platform,omitempty"`
+	LinkedID   string            `json:"linked_id,omitempty"`
+	ActiveLeaf string            `json:"active_leaf,omitempty"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+	Messages   []ExportedMessage `json:"messages,omitempty"`
+}
+
+// ExportedMessage is a message with decoded tool calls and attachments
+type ExportedMessage struct {
+	ID          string                   `json:"id"`
+	ParentID    string                   `json:"parent_id,omitempty"`
+	Role        string                   `json:"role"`
+	Content     string                   `json:"content,omitempty"`
+	DisplayOnly bool                     `json:"display_only,omitempty"`
+	Attachments []ExportedAttachment     `json:"attachments,omitempty"`
+	ToolCalls   []storage.ToolCallRecord `json:"tool_calls,omitempty"`
+	Soul        string                   `json:"soul,omitempty"`
+	Provider    string                   `json:"provider,omitempty"`
+	CreatedAt   time.Time                `json:"created_at"`
+}
+
+// ExportedAttachment references a blob, with its data if attachments are inlined
+type ExportedAttachment struct {
+	storage.AttachmentRef
+	Data []byte `json:"data,omitempty"`
+}
+
+// ImportResult lists the chats created by an import
+type ImportResult struct {
+	Imported []string `json:"imported"`
+	Skipped  []string `json:"skipped"` // Chat IDs that already exist
+}
+
+// ExportChats writes chats in the given format
+func ExportChats(w io.Writer, chatIDs []string, opts ExportOptions, blobs *blob.Store) error {
+	chats := make([]ExportedChat, 0, len(chatIDs))
+	for _, id := range chatIDs {
+		chat, err := loadExportedChat(id, opts.InlineAttachments, blobs)
+		if err != nil {
+			return err
+		}
+		chats = append(chats, *chat)
+	}
+
+	switch opts.Format {
+	case FormatJSON, "":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(ChatExport{Version: ExportVersion, ExportedAt: time.Now(), Chats: chats})
+	case FormatMarkdown:
+		for _, chat := range chats {
+			if err := writeMarkdown(w, activeBranch(chat)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatJSONL:
+		enc := json.NewEncoder(w)
+		for _, chat := range chats {
+			if err := enc.Encode(toFineTuning(chat, !opts.NoMetadata)); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown export format %q", opts.Format)
+	}
+}
+
+// loadExportedChat loads a chat with all messages of all branches
+func loadExportedChat(chatID string, inline bool, blobs *blob.Store) (*ExportedChat, error) {
+	chat, err := storage.GetChat(chatID)
+	if err != nil {
+		return nil, fmt.Errorf("chat %s not found", chatID)
+	}
+	messages, err := storage.GetAllChatMessages(chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	exported := &ExportedChat{
+		ID:         chat.ID,
+		UserID:     chat.UserID,
+		Name:       chat.Name,
+		Platform:   chat.Platform,
+		LinkedID:   chat.LinkedID,
+		ActiveLeaf: chat.ActiveLeaf,
+		CreatedAt:  chat.CreatedAt,
+		UpdatedAt:  chat.UpdatedAt,
+		Messages:   make([]ExportedMessage, len(messages)),
+	}
+	for i, m := range messages {
+		em := ExportedMessage{
+			ID:          m.ID,
+			ParentID:    m.ParentID,
+			Role:        m.Role,
+			Content:     m.Content,
+			DisplayOnly: m.DisplayOnly,
+			Soul:        m.Soul,
+			Provider:    m.Provider,
+			CreatedAt:   m.CreatedAt,
+		}
+		if m.ToolCalls != "" {
+			if err := json.Unmarshal([]byte(m.ToolCalls), &em.ToolCalls); err != nil {
+				log.Printf("[Chat] Ignoring invalid tool calls of message %s: %v", m.ID, err)
+			}
+		}
+		for _, ref := range blob.ParseAttachments(m.Attachments) {
+			a := ExportedAttachment{AttachmentRef: ref}
+			if inline && ref.Hash != "" {
+				if a.Data, err = blobs.Read(ref.Hash); err != nil {
+					return nil, fmt.Errorf("attachment %s of message %s: %w", ref.Hash, m.ID, err)
+				}
+			}
+			em.Attachments = append(em.Attachments, a)
+		}
+		exported.Messages[i] = em
+	}
+	return exported, nil
+}
+
+// activeBranch returns a copy of a chat reduced to the messages of its active branch
+func activeBranch(chat ExportedChat) ExportedChat {
+	byID := make(map[string]ExportedMessage, len(chat.Messages))
+	for _, m := range chat.Messages {
+		byID[m.ID] = m
+	}
+
+	var branch []ExportedMessage
+	for id := chat.ActiveLeaf; id != "" && len(branch) < len(chat.Messages); {
+		m, ok := byID[id]
+		if !ok {
+			break
+		}
+		branch = append(branch, m)
+		id = m.ParentID
+	}
+	for i, j := 0, len(branch)-1; i < j; i, j = i+1, j-1 {
+		branch[i], branch[j] = branch[j], branch[i]
+	}
+	chat.Messages = branch
+	return chat
+}
+
+// ImportChats recreates exported chats owned by userID, whoever owned them in the export.
+// Chats whose ID already exists are skipped; all other chats are stored in one transaction.
+func ImportChats(data []byte, format, userID string, blobs *blob.Store) (*ImportResult, error) {
+	if format == "" {
+		format = detectFormat(data)
+	}
+
+	var chats []ExportedChat
+	var err error
+	switch format {
+	case FormatJSON:
+		var export ChatExport
+		if err = json.Unmarshal(data, &export); err != nil {
+			return nil, fmt.Errorf("invalid JSON export: %w", err)
+		}
+		if export.Version > ExportVersion {
+			return nil, fmt.Errorf("export version %d is newer than supported version %d", export.Version, ExportVersion)
+		}
+		chats = export.Chats
+	case FormatMarkdown:
+		chats, err = parseMarkdown(string(data))
+	case FormatJSONL:
+		chats, err = parseFineTuning(data)
+	default:
+		return nil, fmt.Errorf("unknown import format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ImportResult{Imported: []string{}, Skipped: []string{}}
+	var stored []storage.Chat
+	seen := make(map[string]bool)
+	for i := range chats {
+		chat := &chats[i]
+		chat.UserID = userID
+		exists, err := storage.ChatExists(chat.ID)
+		if err != nil {
+			return nil, err
+		}
+		if exists || seen[chat.ID] {
+			result.Skipped = append(result.Skipped, chat.ID)
+			continue
+		}
+		seen[chat.ID] = true
+		if err := remapMessageIDs(chat, seen); err != nil {
+			return nil, err
+		}
+		s, err := toStoredChat(chat, blobs)
+		if err != nil {
+			return nil, fmt.Errorf("import chat %s: %w", chat.ID, err)
+		}
+		stored = append(stored, *s)
+		result.Imported = append(result.Imported, chat.ID)
+	}
+	if err := storage.ImportChats(stored); err != nil {
+		return nil, fmt.Errorf("import chats: %w", err)
+	}
+	log.Printf("[Chat] Imported %d chats (%d skipped)", len(result.Imported), len(result.Skipped))
+	return result, nil
+}
+
+// remapMessageIDs gives messages whose ID is already stored or used earlier in the import
+// (seen) a new ID, so a chat imported under a new ID does not collide with its original
+func remapMessageIDs(chat *ExportedChat, seen map[string]bool) error {
+	ids := make([]string, len(chat.Messages))
+	for i, m := range chat.Messages {
+		ids[i] = m.ID
+	}
+	taken, err := storage.TakenMessageIDs(ids)
+	if err != nil {
+		return err
+	}
+
+	remapped := make(map[string]string)
+	for i := range chat.Messages {
+		m := &chat.Messages[i]
+		if m.ID == "" || taken[m.ID] || seen[m.ID] {
+			id := uuid.New().String()
+			if m.ID != "" {
+				remapped[m.ID] = id
+			}
+			m.ID = id
+		}
+		seen[m.ID] = true
+	}
+	if len(remapped) == 0 {
+		return nil
+	}
+	for i := range chat.Messages {
+		if id, ok := remapped[chat.Messages[i].ParentID]; ok {
+			chat.Messages[i].ParentID = id
+		}
+	}
+	if id, ok := remapped[chat.ActiveLeaf]; ok {
+		chat.ActiveLeaf = id
+	}
+	log.Printf("[Chat] Import of chat %s: %d message IDs already taken, assigned new ones", chat.ID, len(remapped))
+	return nil
+}
+
+// detectFormat guesses the format of an export from its content
+func detectFormat(data []byte) string {
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("{\"messages\"")) || bytes.HasPrefix(trimmed, []byte("{\"chadbot\"")):
+		return FormatJSONL
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		return FormatJSON
+	default:
+		return FormatMarkdown
+	}
+}
+
+// toStoredChat converts an exported chat to a chat with messages, storing inlined attachments.
+// Imported chats are web chats: a platform link would hand them to the linking plugin.
+func toStoredChat(chat *ExportedChat, blobs *blob.Store) (*storage.Chat, error) {
+	chat.Platform, chat.LinkedID = "web", ""
+	stored := &storage.Chat{
+		ID:         chat.ID,
+		UserID:     chat.UserID,
+		Name:       chat.Name,
+		Platform:   chat.Platform,
+		ActiveLeaf: chat.ActiveLeaf,
+		CreatedAt:  chat.CreatedAt,
+		UpdatedAt:  chat.UpdatedAt,
+	}
+
+	owned := make(map[string]bool) // Blobs the user references or this import stored
+	messages := make([]storage.Message, len(chat.Messages))
+	for i, m := range chat.Messages {
+		msg := storage.Message{
+			ID:          m.ID,
+			ChatID:      chat.ID,
+			ParentID:    m.ParentID,
+			Role:        m.Role,
+			Content:     m.Content,
+			DisplayOnly: m.DisplayOnly,
+			Soul:        m.Soul,
+			Provider:    m.Provider,
+			CreatedAt:   m.CreatedAt,
+		}
+		if len(m.ToolCalls) > 0 {
+			data, err := json.Marshal(m.ToolCalls)
+			if err != nil {
+				return nil, err
+			}
+			msg.ToolCalls = string(data)
+		}
+		if len(m.Attachments) > 0 {
+			refs := make([]storage.AttachmentRef, 0, len(m.Attachments))
+			for _, a := range m.Attachments {
+				ref := a.AttachmentRef
+				if len(a.Data) > 0 {
+					b, err := blobs.Put(a.Data, a.MimeType)
+					if err != nil {
+						return nil, err
+					}
+					ref.Hash, ref.Size = b.Hash, b.Size
+					owned[b.Hash] = true
+				} else if ref.Hash != "" {
+					// A reference without data would grant access to the blob, keep it only if the user has it
+					ok, err := ownsBlob(chat.UserID, ref.Hash, owned)
+					if err != nil {
+						return nil, err
+					}
+					if !ok {
+						log.Printf("[Chat] Import of chat %s: dropping attachment %s the user has no access to", chat.ID, ref.Hash)
+						continue
+					}
+				}
+				refs = append(refs, ref)
+			}
+			if len(refs) > 0 {
+				data, err := json.Marshal(refs)
+				if err != nil {
+					return nil, err
+				}
+				msg.Attachments = string(data)
+			}
+		}
+		messages[i] = msg
+	}
+	if stored.ActiveLeaf == "" && len(messages) > 0 {
+		stored.ActiveLeaf = messages[len(messages)-1].ID
+	}
+	stored.Messages = messages
+	return stored, nil
+}
+
+// ownsBlob reports whether a user references a blob, remembering the answer in owned
+func ownsBlob(userID, hash string, owned map[string]bool) (bool, error) {
+	if ok, checked := owned[hash]; checked {
+		return ok, nil
+	}
+	ok := false
+	if blob.ValidHash(hash) {
+		var err error
+		if ok, err = storage.UserReferencesBlob(userID, hash); err != nil {
+			return false, err
+		}
+	}
+	owned[hash] = ok
+	return ok, nil
+}
+
+// roleLabel returns the display name of a message role
+func roleLabel(role string) string {
+	switch role {
+	case "user":
+		return "User"
+	case "assistant":
+		return "Assistant"
+	case "plugin":
+		return "Plugin"
+	default:
+		return role
+	}
+}
@@ -0,0 +1,229 @@
+package chat
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fipso/chadbot/internal/storage"
+)
+
+// initStorage opens a fresh database for a test
+func initStorage(t *testing.T) {
+	t.Helper()
+	if err := storage.Init(filepath.Join(t.TempDir(), "chadbot.db")); err != nil {
+		t.Fatalf("init storage: %v", err)
+	}
+}
+
+// seedBranchedChat stores a chat whose first answer was regenerated:
+// q -> a1 (inactive) and q -> a2 -> q2 -> a3 (active)
+func seedBranchedChat(t *testing.T) storage.Chat {
+	t.Helper()
+	at := time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC)
+	msg := func(i int, id, parent, role, content string) storage.Message {
+		return storage.Message{ID: id, ChatID: "c1", ParentID: parent, Role: role, Content: content, CreatedAt: at.Add(time.Duration(i) * time.Second)}
+	}
+	chat := storage.Chat{
+		ID: "c1", UserID: "u1", Name: "Branched", Platform: "web", ActiveLeaf: "a3",
+		CreatedAt: at, UpdatedAt: at.Add(time.Minute),
+		Messages: []storage.Message{
+			msg(0, "q", "", "user", "question"),
+			msg(1, "a1", "q", "assistant", "first answer"),
+			msg(2, "a2", "q", "assistant", "second answer"),
+			msg(3, "q2", "a2", "user", "follow-up"),
+			msg(4, "a3", "q2", "assistant", "final answer"),
+		},
+	}
+	if err := storage.ImportChats([]storage.Chat{chat}); err != nil {
+		t.Fatalf("seed chat: %v", err)
+	}
+	return chat
+}
+
+// treeOf summarizes the stored messages of a chat as "id<parent:role:content" lines
+func treeOf(t *testing.T, chatID string) string {
+	t.Helper()
+	messages, err := storage.GetAllChatMessages(chatID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := make([]string, len(messages))
+	for i, m := range messages {
+		lines[i] = m.ID + "<" + m.ParentID + ":" + m.Role + ":" + m.Content
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	fullTree := "q<:user:question\na1<q:assistant:first answer\na2<q:assistant:second answer\n" +
+		"q2<a2:user:follow-up\na3<q2:assistant:final answer"
+	activeOnly := "q<:user:question\na2<q:assistant:second answer\nq2<a2:user:follow-up\na3<q2:assistant:final answer"
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{FormatJSON, fullTree},
+		{FormatJSONL, fullTree},
+		{FormatMarkdown, activeOnly},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			initStorage(t)
+			seedBranchedChat(t)
+			var buf bytes.Buffer
+			if err := ExportChats(&buf, []string{"c1"}, ExportOptions{Format: tt.format}, nil); err != nil {
+				t.Fatalf("export: %v", err)
+			}
+
+			initStorage(t)
+			result, err := ImportChats(buf.Bytes(), "", "u2", nil)
+			if err != nil {
+				t.Fatalf("import: %v", err)
+			}
+			if len(result.Imported) != 1 || result.Imported[0] != "c1" {
+				t.Fatalf("imported %v, want [c1]", result.Imported)
+			}
+			if got := treeOf(t, "c1"); got != tt.want {
+				t.Fatalf("imported messages:\n%s\nwant:\n%s", got, tt.want)
+			}
+			chat, err := storage.GetChat("c1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if chat.UserID != "u2" || chat.Name != "Branched" || chat.ActiveLeaf != "a3" {
+				t.Fatalf("imported chat user=%q name=%q active_leaf=%q", chat.UserID, chat.Name, chat.ActiveLeaf)
+			}
+
+			// Importing again skips the existing chat
+			result, err = ImportChats(buf.Bytes(), "", "u2", nil)
+			if err != nil {
+				t.Fatalf("second import: %v", err)
+			}
+			if len(result.Imported) != 0 || len(result.Skipped) != 1 {
+				t.Fatalf("second import imported %v skipped %v, want one skipped", result.Imported, result.Skipped)
+			}
+		})
+	}
+}
+
+func TestImportRemapsTakenMessageIDs(t *testing.T) {
+	initStorage(t)
+	seedBranchedChat(t)
+	var buf bytes.Buffer
+	if err := ExportChats(&buf, []string{"c1"}, ExportOptions{Format: FormatJSON}, nil); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+
+	// The same chat under a new ID: all message IDs are taken by the original
+	data := bytes.Replace(buf.Bytes(), []byte(`"id": "c1"`), []byte(`"id": "c2"`), 1)
+	if _, err := ImportChats(data, FormatJSON, "u1", nil); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+
+	chat, err := storage.GetChat("c2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	branch, err := storage.GetChatMessages("c2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var contents []string
+	for _, m := range branch {
+		if m.ID == "q" || m.ID == "a2" || m.ID == "q2" || m.ID == "a3" {
+			t.Fatalf("message kept the taken ID %s", m.ID)
+		}
+		contents = append(contents, m.Content)
+	}
+	if got := strings.Join(contents, ", "); got != "question, second answer, follow-up, final answer" {
+		t.Fatalf("active branch of the copy: %s (active leaf %s)", got, chat.ActiveLeaf)
+	}
+	if got := treeOf(t, "c1"); !strings.HasPrefix(got, "q<:user:question") {
+		t.Fatalf("original chat changed:\n%s", got)
+	}
+}
+
+func TestImportIsAtomic(t *testing.T) {
+	initStorage(t)
+	seedBranchedChat(t)
+	var buf bytes.Buffer
+	if err := ExportChats(&buf, []string{"c1"}, ExportOptions{Format: FormatJSONL}, nil); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+
+	// Two chats, the second of which fails to store
+	first := strings.Replace(buf.String(), `"id":"c1"`, `"id":"ok"`, 1)
+	second := strings.Replace(buf.String(), `"id":"c1"`, `"id":"bad"`, 1)
+	second = strings.Replace(second, "final answer", "fail", 1)
+	if err := storage.DB.Exec(`CREATE TRIGGER fail_insert BEFORE INSERT ON messages
+		WHEN NEW.content = 'fail' BEGIN SELECT RAISE(ABORT, 'refused'); END`).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ImportChats([]byte(first+second), FormatJSONL, "u1", nil); err == nil {
+		t.Fatal("import succeeded, want an error")
+	}
+	for _, id := range []string{"ok", "bad"} {
+		exists, err := storage.ChatExists(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exists {
+			t.Fatalf("chat %s stored by a failed import", id)
+		}
+	}
+}
+
+func TestImportDropsForeignLinks(t *testing.T) {
+	initStorage(t)
+	hash := strings.Repeat("ab", 32)
+	refs := `[{"type":"image","mime_type":"image/png","hash":"` + hash + `","size":3}]`
+	if err := storage.CreateChat(&storage.Chat{ID: "mine", UserID: "u1", Name: "Photo"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.AddMessage(&storage.Message{ID: "m1", ChatID: "mine", Role: "user", Attachments: refs, CreatedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+
+	export := func(chatID string) []byte {
+		return []byte(`{"version":1,"chats":[{"id":"` + chatID + `","name":"Copy","platform":"whatsapp","linked_id":"jid",
+			"messages":[{"id":"` + chatID + `-m","role":"user","content":"look","attachments":` + refs + `}]}]}`)
+	}
+	attachments := func(chatID string) string {
+		messages, err := storage.GetAllChatMessages(chatID)
+		if err != nil || len(messages) != 1 {
+			t.Fatalf("messages of %s: %v, %v", chatID, messages, err)
+		}
+		return messages[0].Attachments
+	}
+
+	// Another user can't reach the blob by naming its hash
+	if _, err := ImportChats(export("theirs"), FormatJSON, "u2", nil); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if got := attachments("theirs"); got != "" {
+		t.Fatalf("foreign blob reference imported: %s", got)
+	}
+	if ok, _ := storage.UserReferencesBlob("u2", hash); ok {
+		t.Fatal("importer gained access to the blob")
+	}
+	chat, err := storage.GetChat("theirs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chat.Platform != "web" || chat.LinkedID != "" {
+		t.Fatalf("imported chat linked to %q/%q, want an unlinked web chat", chat.Platform, chat.LinkedID)
+	}
+
+	// The blob's owner keeps the reference
+	if _, err := ImportChats(export("copy"), FormatJSON, "u1", nil); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if got := attachments("copy"); !strings.Contains(got, hash) {
+		t.Fatalf("own blob reference dropped: %q", got)
+	}
+}
@@ -0,0 +1,222 @@
+package chat
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/fipso/chadbot/internal/storage"
+)
+
+// fineTuningLine is a chat in OpenAI chat fine-tuning format
+type fineTuningLine struct {
+	Messages []fineTuningMessage `json:"messages"`
+	Chadbot  *ExportedChat       `json:"chadbot,omitempty"` // All branches for lossless import (contents of the active branch are in Messages)
+}
+
+// fineTuningMessage is a message in OpenAI chat format
+type fineTuningMessage struct {
+	Role       string               `json:"role"`
+	Content    *string              `json:"content,omitempty"`
+	ToolCalls  []fineTuningToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string               `json:"tool_call_id,omitempty"`
+}
+
+// fineTuningToolCall is a tool call in OpenAI function-calling format
+type fineTuningToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"` // JSON object
+	} `json:"function"`
+}
+
+// trainable reports whether a message is part of the conversation sent to the LLM
+func trainable(m ExportedMessage) bool {
+	return (m.Role == "user" || m.Role == "assistant") && !m.DisplayOnly
+}
+
+// toFineTuning converts the active branch of a chat to fine-tuning format. An assistant message
+// with tool calls becomes a tool_calls message, one tool message per result and the final answer.
+func toFineTuning(chat ExportedChat, withMetadata bool) fineTuningLine {
+	var line fineTuningLine
+	branch := activeBranch(chat).Messages
+	calls := 0
+	for _, m := range branch {
+		if !trainable(m) {
+			continue
+		}
+		if m.Role == "assistant" && len(m.ToolCalls) > 0 {
+			request := fineTuningMessage{Role: "assistant"}
+			var results []fineTuningMessage
+			for _, tc := range m.ToolCalls {
+				calls++
+				call := fineTuningToolCall{ID: tc.ID, Type: "function"}
+				if call.ID == "" {
+					call.ID = fmt.Sprintf("call_%d", calls)
+				}
+				call.Function.Name = tc.Name
+				call.Function.Arguments = "{}"
+				if len(tc.Arguments) > 0 {
+					args, _ := json.Marshal(tc.Arguments)
+					call.Function.Arguments = string(args)
+				}
+				request.ToolCalls = append(request.ToolCalls, call)
+
+				result := tc.Result
+				if tc.Error != "" {
+					result = "Error: " + tc.Error
+				}
+				results = append(results, fineTuningMessage{Role: "tool", ToolCallID: call.ID, Content: &result})
+			}
+			line.Messages = append(line.Messages, request)
+			line.Messages = append(line.Messages, results...)
+		}
+		content := m.Content
+		line.Messages = append(line.Messages, fineTuningMessage{Role: m.Role, Content: &content})
+	}
+
+	if withMetadata {
+		inLine := make(map[string]bool, len(branch))
+		for _, m := range branch {
+			inLine[m.ID] = trainable(m)
+		}
+		meta := chat
+		meta.Messages = make([]ExportedMessage, len(chat.Messages))
+		for i, m := range chat.Messages {
+			if inLine[m.ID] {
+				m.Content = ""
+			}
+			meta.Messages[i] = m
+		}
+		line.Chadbot = &meta
+	}
+	return line
+}
+
+// parseFineTuning reads chats in fine-tuning format. Lines with chadbot metadata are restored
+// exactly, plain lines become new chats.
+func parseFineTuning(data []byte) ([]ExportedChat, error) {
+	var chats []ExportedChat
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for n := 1; dec.More(); n++ {
+		var line fineTuningLine
+		if err := dec.Decode(&line); err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+
+		var chat ExportedChat
+		var err error
+		if line.Chadbot != nil {
+			chat, err = restoreFineTuning(line)
+		} else {
+			chat, err = newChatFromFineTuning(line.Messages)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		chats = append(chats, chat)
+	}
+	return chats, nil
+}
+
+// restoreFineTuning fills the active branch in the metadata of a line with the message contents
+func restoreFineTuning(line fineTuningLine) (ExportedChat, error) {
+	chat := *line.Chadbot
+	index := make(map[string]int, len(chat.Messages))
+	for i, m := range chat.Messages {
+		index[m.ID] = i
+	}
+	next := 0
+	for _, m := range activeBranch(chat).Messages {
+		if !trainable(m) {
+			continue
+		}
+		i := index[m.ID]
+		if m.Role == "assistant" && len(m.ToolCalls) > 0 {
+			next += 1 + len(m.ToolCalls) // Tool call request and results
+		}
+		if next >= len(line.Messages) {
+			return chat, fmt.Errorf("metadata does not match messages")
+		}
+		if c := line.Messages[next].Content; c != nil {
+			chat.Messages[i].Content = *c
+		}
+		next++
+	}
+	return chat, nil
+}
+
+// newChatFromFineTuning creates a chat from plain fine-tuning messages
+func newChatFromFineTuning(messages []fineTuningMessage) (ExportedChat, error) {
+	now := time.Now()
+	chat := ExportedChat{
+		ID:        uuid.New().String(),
+		Name:      "Imported chat",
+		Platform:  "web",
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	var pending *ExportedMessage // Assistant message waiting for tool results and its answer
+	add := func(m ExportedMessage) {
+		m.ID = uuid.New().String()
+		m.CreatedAt = now.Add(time.Duration(len(chat.Messages)) * time.Millisecond)
+		if n := len(chat.Messages); n > 0 {
+			m.ParentID = chat.Messages[n-1].ID
+		}
+		chat.Messages = append(chat.Messages, m)
+		chat.ActiveLeaf = m.ID
+	}
+
+	for _, fm := range messages {
+		content := ""
+		if fm.Content != nil {
+			content = *fm.Content
+		}
+		switch {
+		case fm.Role == "assistant" && len(fm.ToolCalls) > 0:
+			if pending == nil {
+				pending = &ExportedMessage{Role: "assistant"}
+			}
+			for _, call := range fm.ToolCalls {
+				record := storage.ToolCallRecord{ID: call.ID, Name: call.Function.Name}
+				if err := json.Unmarshal([]byte(call.Function.Arguments), &record.Arguments); err != nil {
+					record.Arguments = map[string]string{"arguments": call.Function.Arguments}
+				}
+				pending.ToolCalls = append(pending.ToolCalls, record)
+			}
+		case fm.Role == "tool":
+			if pending == nil {
+				return chat, fmt.Errorf("tool message without tool call")
+			}
+			for i := range pending.ToolCalls {
+				if pending.ToolCalls[i].ID == fm.ToolCallID {
+					pending.ToolCalls[i].Result = content
+				}
+			}
+		case fm.Role == "assistant":
+			m := ExportedMessage{Role: "assistant", Content: content}
+			if pending != nil {
+				m.ToolCalls = pending.ToolCalls
+				pending = nil
+			}
+			add(m)
+		case fm.Role == "user":
+			add(ExportedMessage{Role: "user", Content: content})
+		}
+		// System messages are not stored (souls provide the system prompt)
+	}
+	if pending != nil {
+		add(*pending)
+	}
+	if len(chat.Messages) == 0 {
+		return chat, fmt.Errorf("no messages")
+	}
+	chat.UpdatedAt = chat.Messages[len(chat.Messages)-1].CreatedAt
+	return chat, nil
+}
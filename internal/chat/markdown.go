@@ -0,0 +1,160 @@
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Markdown exports carry the chat and message metadata in HTML comments, so they
+// render as a plain transcript and import back the active branch without loss
+// (other branches are not exported):
+//
+//	# Chat name
+//
+//	<!-- chadbot:chat {...} -->
+//
+//	## User · 2025-01-02 15:04:05
+//	<!-- chadbot:message {...} -->
+//
+//	content
+//
+// The content of a message is everything between its metadata line (plus one blank
+// line) and the blank line before the next heading. Content lines that look like
+// metadata are escaped with a backslash.
+
+const (
+	mdChatMarker    = "<!-- chadbot:chat "
+	mdMessageMarker = "<!-- chadbot:message "
+	mdMarkerEnd     = " -->"
+)
+
+var (
+	mdMarkerLine  = regexp.MustCompile(`(?m)^<!-- chadbot:(chat|message) (.*) -->$`)
+	mdEscapedLine = regexp.MustCompile(`(?m)^(\\*)<!-- chadbot:`)
+)
+
+// writeMarkdown writes a chat (reduced to its active branch) as Markdown
+func writeMarkdown(w io.Writer, chat ExportedChat) error {
+	messages := chat.Messages
+	chat.Messages = nil
+	meta, err := json.Marshal(chat)
+	if err != nil {
+		return err
+	}
+
+	var sb strings.Builder
+	title := strings.Join(strings.Fields(chat.Name), " ") // The heading must stay on one line
+	fmt.Fprintf(&sb, "# %s\n\n%s%s%s\n", title, mdChatMarker, meta, mdMarkerEnd)
+	for _, m := range messages {
+		content := m.Content
+		m.Content = ""
+		meta, err := json.Marshal(m)
+		if err != nil {
+			return err
+		}
+		content = mdEscapedLine.ReplaceAllString(content, `\$1<!-- chadbot:`)
+		fmt.Fprintf(&sb, "\n## %s\n%s%s%s\n\n%s\n", markdownHeading(m), mdMessageMarker, meta, mdMarkerEnd, content)
+	}
+	sb.WriteString("\n")
+
+	_, err = io.WriteString(w, sb.String())
+	return err
+}
+
+// markdownHeading describes a message for readers of the transcript
+func markdownHeading(m ExportedMessage) string {
+	parts := []string{roleLabel(m.Role)}
+	if m.Soul != "" {
+		parts = append(parts, m.Soul)
+	}
+	if m.Provider != "" {
+		parts = append(parts, m.Provider)
+	}
+	parts = append(parts, m.CreatedAt.Format("2006-01-02 15:04:05"))
+	if len(m.ToolCalls) > 0 {
+		names := make([]string, len(m.ToolCalls))
+		for i, tc := range m.ToolCalls {
+			names[i] = tc.Name
+		}
+		parts = append(parts, "tools: "+strings.Join(names, ", "))
+	}
+	if n := len(m.Attachments); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d attachment(s)", n))
+	}
+	if m.DisplayOnly {
+		parts = append(parts, "display only")
+	}
+	return strings.Join(parts, " · ")
+}
+
+// parseMarkdown reads chats written by writeMarkdown
+func parseMarkdown(text string) ([]ExportedChat, error) {
+	markers := mdMarkerLine.FindAllStringSubmatchIndex(text, -1)
+	if len(markers) == 0 {
+		return nil, fmt.Errorf("no chadbot metadata found in Markdown")
+	}
+
+	var chats []ExportedChat
+	for i, mk := range markers {
+		kind, meta := text[mk[2]:mk[3]], text[mk[4]:mk[5]]
+
+		if kind == "chat" {
+			var chat ExportedChat
+			if err := json.Unmarshal([]byte(meta), &chat); err != nil {
+				return nil, fmt.Errorf("invalid chat metadata: %w", err)
+			}
+			chats = append(chats, chat)
+			continue
+		}
+
+		if len(chats) == 0 {
+			return nil, fmt.Errorf("message before chat metadata")
+		}
+		var msg ExportedMessage
+		if err := json.Unmarshal([]byte(meta), &msg); err != nil {
+			return nil, fmt.Errorf("invalid message metadata: %w", err)
+		}
+
+		// Content runs up to the heading lines of the next marker
+		end, headingLines := len(text), 0
+		if i+1 < len(markers) {
+			end = markers[i+1][0]
+			headingLines = 1 // "## heading"
+			if text[markers[i+1][2]:markers[i+1][3]] == "chat" {
+				headingLines = 2 // "# name" and a blank line
+			}
+		}
+		start := min(mk[1]+1, end) // Skip the newline ending the marker line
+		msg.Content = unescapeMarkdown(markdownContent(text[start:end], headingLines))
+
+		last := &chats[len(chats)-1]
+		last.Messages = append(last.Messages, msg)
+	}
+	return chats, nil
+}
+
+// markdownContent strips the layout around a message's content
+func markdownContent(section string, headingLines int) string {
+	for ; headingLines > 0; headingLines-- {
+		trimmed := strings.TrimSuffix(section, "\n")
+		i := strings.LastIndex(trimmed, "\n")
+		if i < 0 {
+			break
+		}
+		section = section[:i+1]
+	}
+	if strings.HasPrefix(section, "\n") && strings.HasSuffix(section, "\n\n") && len(section) >= 3 {
+		return section[1 : len(section)-2]
+	}
+	return strings.TrimSpace(section) // Edited by hand
+}
+
+// unescapeMarkdown removes the backslash writeMarkdown adds to content lines that look like metadata
+func unescapeMarkdown(content string) string {
+	return mdEscapedLine.ReplaceAllStringFunc(content, func(line string) string {
+		return strings.TrimPrefix(line, `\`)
+	})
+}
@@ -2,13 +2,15 @@ package chat
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"log"
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 
 	pb "github.com/fipso/chadbot/gen/chadbot"
+	"github.com/fipso/chadbot/internal/blob"
 	"github.com/fipso/chadbot/internal/storage"
 )
 
@@ -19,7 +21,7 @@ type LLMProvider interface {
 
 // MessageBroadcaster broadcasts new messages to connected clients
 type MessageBroadcaster interface {
-	BroadcastMessage(chatID string, msg *storage.Message, attachments []*pb.Attachment)
+	BroadcastMessage(chatID string, msg *storage.Message)
 }
 
 // Message for LLM
@@ -37,6 +39,7 @@ type Response struct {
 type Service struct {
 	llm         LLMProvider
 	broadcaster MessageBroadcaster
+	blobs       *blob.Store
 }
 
 // NewService creates a new chat service
@@ -49,9 +52,18 @@ func (s *Service) SetBroadcaster(b MessageBroadcaster) {
 	s.broadcaster = b
 }
 
+// SetBlobStore sets the store that holds attachment data
+func (s *Service) SetBlobStore(blobs *blob.Store) {
+	s.blobs = blobs
+}
+
 // HandleGetOrCreate handles ChatGetOrCreateRequest
 func (s *Service) HandleGetOrCreate(req *pb.ChatGetOrCreateRequest) *pb.ChatGetOrCreateResponse {
 	resp := &pb.ChatGetOrCreateResponse{RequestId: req.RequestId}
+	if req.Platform == "" || req.LinkedId == "" {
+		resp.Error = "platform and linked_id are required"
+		return resp
+	}
 
 	userID := req.UserId
 	if userID == "" {
@@ -71,19 +83,32 @@ func (s *Service) HandleGetOrCreate(req *pb.ChatGetOrCreateRequest) *pb.ChatGetO
 	return resp
 }
 
-// HandleAddMessage handles ChatAddMessageRequest
-func (s *Service) HandleAddMessage(req *pb.ChatAddMessageRequest) *pb.ChatAddMessageResponse {
+// linkedTo reports whether a chat is linked to a platform. Plugins only
+// reach the chats of their own platform.
+func linkedTo(platform, chatID string) bool {
+	if platform == "" {
+		return false
+	}
+	_, err := storage.GetLinkedChat(platform, chatID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Printf("[Chat] Failed to look up chat %s: %v", chatID, err)
+	}
+	return err == nil
+}
+
+// HandleAddMessage handles ChatAddMessageRequest of a plugin's platform
+func (s *Service) HandleAddMessage(platform string, req *pb.ChatAddMessageRequest) *pb.ChatAddMessageResponse {
 	resp := &pb.ChatAddMessageResponse{RequestId: req.RequestId}
+	if !linkedTo(platform, req.ChatId) {
+		resp.Error = "Chat not found"
+		return resp
+	}
 
-	// Serialize attachments to JSON if present
-	var attachmentsJSON string
-	if len(req.Attachments) > 0 {
-		data, err := json.Marshal(req.Attachments)
-		if err != nil {
-			resp.Error = "Failed to serialize attachments: " + err.Error()
-			return resp
-		}
-		attachmentsJSON = string(data)
+	// Move attachment data into the blob store
+	attachmentsJSON, err := s.blobs.SaveAttachments(req.Attachments)
+	if err != nil {
+		resp.Error = "Failed to store attachments: " + err.Error()
+		return resp
 	}
 
 	msg := &storage.Message{
@@ -103,7 +128,7 @@ func (s *Service) HandleAddMessage(req *pb.ChatAddMessageRequest) *pb.ChatAddMes
 
 	// Broadcast to connected WebSocket clients
 	if s.broadcaster != nil {
-		s.broadcaster.BroadcastMessage(req.ChatId, msg, req.Attachments)
+		s.broadcaster.BroadcastMessage(req.ChatId, msg)
 	}
 
 	resp.Success = true
@@ -111,11 +136,15 @@ func (s *Service) HandleAddMessage(req *pb.ChatAddMessageRequest) *pb.ChatAddMes
 	return resp
 }
 
-// HandleLLMRequest handles ChatLLMRequest - gets LLM response for chat
-func (s *Service) HandleLLMRequest(req *pb.ChatLLMRequest) *pb.ChatLLMResponse {
+// HandleLLMRequest handles ChatLLMRequest - gets LLM response for a chat of a plugin's platform
+func (s *Service) HandleLLMRequest(platform string, req *pb.ChatLLMRequest) *pb.ChatLLMResponse {
 	resp := &pb.ChatLLMResponse{RequestId: req.RequestId}
+	if !linkedTo(platform, req.ChatId) {
+		resp.Error = "Chat not found"
+		return resp
+	}
 
-	// Load chat history
+	// Load the active branch of the chat history
 	dbMessages, err := storage.GetChatMessages(req.ChatId)
 	if err != nil {
 		resp.Error = "Failed to load chat history: " + err.Error()
@@ -145,7 +174,7 @@ func (s *Service) HandleLLMRequest(req *pb.ChatLLMRequest) *pb.ChatLLMResponse {
 		return resp
 	}
 
-	// Save assistant response
+	// Save assistant response below the answered message (the active branch may have changed meanwhile)
 	assistantMsg := &storage.Message{
 		ID:        uuid.New().String(),
 		ChatID:    req.ChatId,
@@ -153,7 +182,13 @@ func (s *Service) HandleLLMRequest(req *pb.ChatLLMRequest) *pb.ChatLLMResponse {
 		Content:   llmResp.Content,
 		CreatedAt: time.Now(),
 	}
-	if err := storage.AddMessage(assistantMsg); err != nil {
+	if len(dbMessages) > 0 {
+		assistantMsg.ParentID = dbMessages[len(dbMessages)-1].ID
+		err = storage.AddBranchMessage(assistantMsg)
+	} else {
+		err = storage.AddMessage(assistantMsg)
+	}
+	if err != nil {
 		log.Printf("[Chat] Failed to save assistant message: %v", err)
 	}
 
@@ -163,17 +198,36 @@ func (s *Service) HandleLLMRequest(req *pb.ChatLLMRequest) *pb.ChatLLMResponse {
 	return resp
 }
 
-// HandleGetMessages handles ChatGetMessagesRequest
-func (s *Service) HandleGetMessages(req *pb.ChatGetMessagesRequest) *pb.ChatGetMessagesResponse {
+// Page sizes of plugin message requests, which also carry attachment data
+const (
+	defaultMessagePageSize = 50
+	maxMessagePageSize     = 200
+)
+
+// HandleGetMessages handles ChatGetMessagesRequest of a plugin's platform
+func (s *Service) HandleGetMessages(platform string, req *pb.ChatGetMessagesRequest) *pb.ChatGetMessagesResponse {
 	resp := &pb.ChatGetMessagesResponse{RequestId: req.RequestId}
+	if !linkedTo(platform, req.ChatId) {
+		resp.Error = "Chat not found"
+		return resp
+	}
 
-	messages, err := storage.GetChatMessages(req.ChatId)
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = defaultMessagePageSize
+	}
+	messages, hasMore, err := storage.GetChatMessagesPage(req.ChatId, storage.MessagePage{
+		BeforeID: req.BeforeId,
+		AfterID:  req.AfterId,
+		Limit:    min(limit, maxMessagePageSize),
+	})
 	if err != nil {
 		resp.Error = err.Error()
 		return resp
 	}
 
 	resp.Success = true
+	resp.HasMore = hasMore
 	resp.Messages = make([]*pb.ChatMessage, len(messages))
 	for i, m := range messages {
 		msg := &pb.ChatMessage{
@@ -185,13 +239,8 @@ func (s *Service) HandleGetMessages(req *pb.ChatGetMessagesRequest) *pb.ChatGetM
 			DisplayOnly: m.DisplayOnly,
 		}
 
-		// Deserialize attachments from JSON if present
-		if m.Attachments != "" {
-			var attachments []*pb.Attachment
-			if err := json.Unmarshal([]byte(m.Attachments), &attachments); err == nil {
-				msg.Attachments = attachments
-			}
-		}
+		// Load attachment data from the blob store
+		msg.Attachments = s.blobs.LoadAttachments(m.Attachments)
 
 		resp.Messages[i] = msg
 	}
@@ -0,0 +1,84 @@
+package chat
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	pb "github.com/fipso/chadbot/gen/chadbot"
+	"github.com/fipso/chadbot/internal/storage"
+)
+
+func TestPluginChatsStayOnTheirPlatform(t *testing.T) {
+	initStorage(t)
+	s := NewService(nil)
+	if err := storage.CreateChat(&storage.Chat{ID: "web1", UserID: "u1", Name: "Web"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Web chats store no platform and no linked ID
+	if resp := s.HandleGetOrCreate(&pb.ChatGetOrCreateRequest{UserId: "u2"}); resp.Success {
+		t.Fatalf("empty platform and linked_id linked chat %s", resp.ChatId)
+	}
+
+	linked := s.HandleGetOrCreate(&pb.ChatGetOrCreateRequest{Platform: "whatsapp", LinkedId: "jid", Name: "Group", UserId: "u1"})
+	if !linked.Success || !linked.Created {
+		t.Fatalf("create linked chat: %+v", linked)
+	}
+	if again := s.HandleGetOrCreate(&pb.ChatGetOrCreateRequest{Platform: "whatsapp", LinkedId: "jid", UserId: "u1"}); again.ChatId != linked.ChatId || again.Created {
+		t.Fatalf("lookup of linked chat: %+v, want %s", again, linked.ChatId)
+	}
+
+	// Another owner gets its own chat, the first one keeps its owner
+	other := s.HandleGetOrCreate(&pb.ChatGetOrCreateRequest{Platform: "whatsapp", LinkedId: "jid", UserId: "u2"})
+	if !other.Success || !other.Created || other.ChatId == linked.ChatId {
+		t.Fatalf("linked chat of another owner: %+v", other)
+	}
+	if chat, err := storage.GetChat(linked.ChatId); err != nil || chat.UserID != "u1" {
+		t.Fatalf("owner of the first linked chat changed: %+v, %v", chat, err)
+	}
+
+	if resp := s.HandleGetMessages("whatsapp", &pb.ChatGetMessagesRequest{ChatId: linked.ChatId}); !resp.Success {
+		t.Fatalf("read own linked chat: %q", resp.Error)
+	}
+	for _, platform := range []string{"whatsapp", "telegram", ""} {
+		if resp := s.HandleGetMessages(platform, &pb.ChatGetMessagesRequest{ChatId: "web1"}); resp.Success {
+			t.Fatalf("platform %q read a web chat", platform)
+		}
+	}
+	if resp := s.HandleAddMessage("telegram", &pb.ChatAddMessageRequest{ChatId: linked.ChatId, Role: "user", Content: "hi"}); resp.Success {
+		t.Fatal("another platform wrote to a linked chat")
+	}
+	if resp := s.HandleLLMRequest("telegram", &pb.ChatLLMRequest{ChatId: linked.ChatId}); resp.Success {
+		t.Fatal("another platform requested an answer in a linked chat")
+	}
+}
+
+func TestPluginMessagePageSize(t *testing.T) {
+	initStorage(t)
+	s := NewService(nil)
+	chat := s.HandleGetOrCreate(&pb.ChatGetOrCreateRequest{Platform: "whatsapp", LinkedId: "jid", UserId: "u1"})
+	at := time.Now()
+	for i := range maxMessagePageSize + 1 {
+		msg := &storage.Message{ID: fmt.Sprint("m", i), ChatID: chat.ChatId, Role: "user", Content: "hi", CreatedAt: at.Add(time.Duration(i) * time.Second)}
+		if err := storage.AddMessage(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		limit int32
+		want  int
+	}{
+		{0, defaultMessagePageSize},
+		{-1, defaultMessagePageSize},
+		{10, 10},
+		{1000, maxMessagePageSize},
+	}
+	for _, tt := range tests {
+		resp := s.HandleGetMessages("whatsapp", &pb.ChatGetMessagesRequest{ChatId: chat.ChatId, Limit: tt.limit})
+		if !resp.Success || len(resp.Messages) != tt.want || !resp.HasMore {
+			t.Fatalf("limit %d: %d messages, has_more=%v (%q), want %d and more", tt.limit, len(resp.Messages), resp.HasMore, resp.Error, tt.want)
+		}
+	}
+}
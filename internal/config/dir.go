@@ -36,3 +36,35 @@ func SoulsDir() (string, error) {
 
 	return soulsDir, nil
 }
+
+// KnowledgeDir returns the knowledge base directory (~/.config/chadbot/knowledge)
+// Creates it if it doesn't exist
+func KnowledgeDir() (string, error) {
+	configDir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+
+	knowledgeDir := filepath.Join(configDir, "knowledge")
+	if err := os.MkdirAll(knowledgeDir, 0755); err != nil {
+		return "", err
+	}
+
+	return knowledgeDir, nil
+}
+
+// BlobsDir returns the blob store directory (~/.config/chadbot/blobs)
+// Creates it if it doesn't exist
+func BlobsDir() (string, error) {
+	configDir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+
+	blobsDir := filepath.Join(configDir, "blobs")
+	if err := os.MkdirAll(blobsDir, 0755); err != nil {
+		return "", err
+	}
+
+	return blobsDir, nil
+}
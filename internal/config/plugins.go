@@ -16,6 +16,7 @@ type PluginConfigManager struct {
 	path          string
 	mu            sync.RWMutex
 	data          map[string]map[string]string // plugin -> key -> value
+	secrets       map[string]map[string]bool   // plugin -> keys declared as secret fields
 	watcher       *FileWatcher
 	changeHandler func(pluginName, key, value string)
 }
@@ -32,8 +33,9 @@ func NewPluginConfigManager() (*PluginConfigManager, error) {
 
 	path := filepath.Join(configDir, "config.toml")
 	m := &PluginConfigManager{
-		path: path,
-		data: make(map[string]map[string]string),
+		path:    path,
+		data:    make(map[string]map[string]string),
+		secrets: make(map[string]map[string]bool),
 	}
 
 	// Load existing config
@@ -49,6 +51,12 @@ func NewPluginConfigManager() (*PluginConfigManager, error) {
 				log.Printf("[PluginConfig] Failed to reload: %v", err)
 				return
 			}
+			// Secrets entered in plaintext by editing the file are encrypted in place
+			if m.sealAll() {
+				if err := m.save(); err != nil {
+					log.Printf("[PluginConfig] Failed to save encrypted secrets: %v", err)
+				}
+			}
 			m.notifyChanges(oldData)
 		},
 		Filter: func(name string) bool {
@@ -169,42 +177,80 @@ func (m *PluginConfigManager) save() error {
 	return os.WriteFile(m.path, data, 0644)
 }
 
-// GetPluginConfig gets a single config value for a plugin
+// GetPluginConfig gets a single config value for a plugin, with secrets redacted
 func (m *PluginConfigManager) GetPluginConfig(pluginName, key string) string {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 	if values, ok := m.data[pluginName]; ok {
-		return values[key]
+		return m.redact(pluginName, key, values[key])
 	}
 	return ""
 }
 
-// GetPluginConfigs gets all config values for a plugin
+// GetPluginConfigs gets all config values for a plugin, with secrets redacted
 func (m *PluginConfigManager) GetPluginConfigs(pluginName string) map[string]string {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 	result := make(map[string]string)
 	if values, ok := m.data[pluginName]; ok {
 		for k, v := range values {
+			result[k] = m.redact(pluginName, k, v)
+		}
+	}
+	return result
+}
+
+// ResolvedPluginConfigs gets all config values for a plugin with secrets decrypted and
+// references resolved. Only the plugin itself may see these values.
+func (m *PluginConfigManager) ResolvedPluginConfigs(pluginName string) map[string]string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	result := make(map[string]string)
+	for k, v := range m.data[pluginName] {
+		if !m.isSecret(pluginName, k, v) {
 			result[k] = v
+			continue
+		}
+		resolved, err := ResolveSecret(v)
+		if err != nil {
+			log.Printf("[PluginConfig] Failed to resolve secret %s.%s: %v", pluginName, k, err)
+			resolved = ""
 		}
+		result[k] = resolved
 	}
 	return result
 }
 
-// SetPluginConfig sets a config value for a plugin
-func (m *PluginConfigManager) SetPluginConfig(pluginName, key, value string) error {
+// IsSecret reports whether a config key holds a secret
+func (m *PluginConfigManager) IsSecret(pluginName, key string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.isSecret(pluginName, key, m.data[pluginName][key])
+}
+
+// MarkSecret records the keys a plugin declared as secret fields and encrypts
+// any of their values still stored in plaintext
+func (m *PluginConfigManager) MarkSecret(pluginName string, keys []string) error {
 	m.mu.Lock()
-	if m.data[pluginName] == nil {
-		m.data[pluginName] = make(map[string]string)
+	secrets := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		secrets[key] = true
 	}
-	m.data[pluginName][key] = value
+	m.secrets[pluginName] = secrets
 	m.mu.Unlock()
 
-	return m.save()
+	if m.sealAll() {
+		return m.save()
+	}
+	return nil
+}
+
+// SetPluginConfig sets a config value for a plugin
+func (m *PluginConfigManager) SetPluginConfig(pluginName, key, value string) error {
+	return m.SetPluginConfigs(pluginName, map[string]string{key: value})
 }
 
-// GetAllPluginConfigs returns all plugin configs
+// GetAllPluginConfigs returns all plugin configs, with secrets redacted
 func (m *PluginConfigManager) GetAllPluginConfigs() map[string]map[string]string {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -212,27 +258,35 @@ func (m *PluginConfigManager) GetAllPluginConfigs() map[string]map[string]string
 	for plugin, values := range m.data {
 		result[plugin] = make(map[string]string)
 		for k, v := range values {
-			result[plugin][k] = v
+			result[plugin][k] = m.redact(plugin, k, v)
 		}
 	}
 	return result
 }
 
-// SetPluginConfigs batch sets config values for a plugin
+// SetPluginConfigs batch sets config values for a plugin. Secret values are encrypted;
+// setting a secret to RedactedSecret keeps its current value.
 func (m *PluginConfigManager) SetPluginConfigs(pluginName string, values map[string]string) error {
 	m.mu.Lock()
 	if m.data[pluginName] == nil {
 		m.data[pluginName] = make(map[string]string)
 	}
 	for k, v := range values {
-		m.data[pluginName][k] = v
+		sealed, keep, err := m.seal(pluginName, k, v)
+		if err != nil {
+			m.mu.Unlock()
+			return fmt.Errorf("failed to encrypt %s.%s: %w", pluginName, k, err)
+		}
+		if !keep {
+			m.data[pluginName][k] = sealed
+		}
 	}
 	m.mu.Unlock()
 
 	return m.save()
 }
 
-// ExportToTOML exports all plugin configs to TOML format
+// ExportToTOML exports all plugin configs to TOML format, with secrets redacted
 func (m *PluginConfigManager) ExportToTOML() ([]byte, error) {
 	m.mu.RLock()
 	cfg := TOMLConfig{
@@ -241,7 +295,7 @@ func (m *PluginConfigManager) ExportToTOML() ([]byte, error) {
 	for pluginName, values := range m.data {
 		cfg.Plugins[pluginName] = make(map[string]any)
 		for key, value := range values {
-			cfg.Plugins[pluginName][key] = convertValueForTOML(value)
+			cfg.Plugins[pluginName][key] = convertValueForTOML(m.redact(pluginName, key, value))
 		}
 	}
 	m.mu.RUnlock()
@@ -249,25 +303,77 @@ func (m *PluginConfigManager) ExportToTOML() ([]byte, error) {
 	return toml.Marshal(cfg)
 }
 
-// ImportFromTOML imports plugin configs from TOML data
+// ImportFromTOML imports plugin configs from TOML data. Redacted secrets keep their current values.
 func (m *PluginConfigManager) ImportFromTOML(data []byte) error {
 	var cfg TOMLConfig
 	if err := toml.Unmarshal(data, &cfg); err != nil {
 		return err
 	}
 
-	m.mu.Lock()
 	for pluginName, values := range cfg.Plugins {
-		if m.data[pluginName] == nil {
-			m.data[pluginName] = make(map[string]string)
-		}
+		converted := make(map[string]string, len(values))
 		for key, value := range values {
-			m.data[pluginName][key] = convertValueFromTOML(value)
+			converted[key] = convertValueFromTOML(value)
+		}
+		if err := m.SetPluginConfigs(pluginName, converted); err != nil {
+			return err
 		}
 	}
-	m.mu.Unlock()
+	return nil
+}
 
-	return m.save()
+// isSecret reports whether a value is a secret: encrypted, or of a key declared secret.
+// The caller must hold m.mu.
+func (m *PluginConfigManager) isSecret(pluginName, key, value string) bool {
+	return m.secrets[pluginName][key] || IsEncryptedSecret(value)
+}
+
+// redact hides a secret value. References to environment variables and files are shown,
+// as they name where the secret lives rather than the secret. The caller must hold m.mu.
+func (m *PluginConfigManager) redact(pluginName, key, value string) string {
+	if value == "" || IsSecretReference(value) || !m.isSecret(pluginName, key, value) {
+		return value
+	}
+	return RedactedSecret
+}
+
+// seal returns the value to store for a key, encrypting plaintext secrets. keep is true
+// when the value is RedactedSecret and the current value should stay. The caller must hold m.mu.
+func (m *PluginConfigManager) seal(pluginName, key, value string) (sealed string, keep bool, err error) {
+	current, exists := m.data[pluginName][key]
+	if value == RedactedSecret && m.isSecret(pluginName, key, current) {
+		return "", exists, nil
+	}
+	if !m.secrets[pluginName][key] || value == "" || IsEncryptedSecret(value) || IsSecretReference(value) {
+		return value, false, nil
+	}
+	sealed, err = EncryptSecret(value)
+	return sealed, false, err
+}
+
+// sealAll encrypts plaintext values of secret keys and reports whether any changed
+func (m *PluginConfigManager) sealAll() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	changed := false
+	for pluginName, keys := range m.secrets {
+		for key := range keys {
+			value, ok := m.data[pluginName][key]
+			if !ok {
+				continue
+			}
+			sealed, _, err := m.seal(pluginName, key, value)
+			if err != nil {
+				log.Printf("[PluginConfig] Failed to encrypt %s.%s: %v", pluginName, key, err)
+				continue
+			}
+			if sealed != value {
+				m.data[pluginName][key] = sealed
+				changed = true
+			}
+		}
+	}
+	return changed
 }
 
 // convertValueForTOML converts a stored string value to appropriate TOML type
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PluginTokenEnv passes a plugin its token (see PluginToken)
+const PluginTokenEnv = "CHADBOT_PLUGIN_TOKEN"
+
+// PluginKeyFile is the name of the key file in the config directory that plugin tokens
+// are derived from. Deleting it revokes all tokens.
+const PluginKeyFile = "plugin.key"
+
+// PluginToken returns the token a plugin registers the given name with. It is derived
+// from the plugin key, so chadpm and the backend agree on it without sharing state.
+func PluginToken(name string) (string, error) {
+	key, err := loadPluginKey()
+	if err != nil {
+		return "", fmt.Errorf("plugin key unavailable: %w", err)
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("chadbot-plugin:" + name))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// VerifyPluginToken reports whether a token was issued for the given plugin name
+func VerifyPluginToken(name, token string) (bool, error) {
+	if token == "" {
+		return false, nil
+	}
+	want, err := PluginToken(name)
+	if err != nil {
+		return false, err
+	}
+	return hmac.Equal([]byte(token), []byte(want)), nil
+}
+
+// loadPluginKey reads the plugin key file, creating it if needed
+func loadPluginKey() ([]byte, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, PluginKeyFile)
+
+	for {
+		data, err := os.ReadFile(path)
+		if err == nil {
+			key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+			if err != nil || len(key) != 32 {
+				return nil, fmt.Errorf("invalid key file %s", path)
+			}
+			return key, nil
+		}
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if errors.Is(err, os.ErrExist) {
+			// chadpm and the backend raced to create it; read the winner's key
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if _, err := f.WriteString(base64.StdEncoding.EncodeToString(key) + "\n"); err != nil {
+			f.Close()
+			return nil, err
+		}
+		return key, f.Close()
+	}
+}
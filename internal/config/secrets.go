@@ -0,0 +1,171 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// SecretKeyEnv holds the key that encrypts secrets at rest: 32 bytes in base64, or any
+// passphrase. Without it a random key is kept in ~/.config/chadbot/secret.key.
+const SecretKeyEnv = "CHADBOT_SECRET_KEY"
+
+// SecretKeyFile is the name of the key file in the config directory
+const SecretKeyFile = "secret.key"
+
+// RedactedSecret replaces secret values in API responses and exports. Setting or importing
+// it keeps the stored value.
+const RedactedSecret = "********"
+
+// Secret value prefixes: encrypted values and references resolved when the value is used
+const (
+	encryptedPrefix = "enc:v1:"
+	envPrefix       = "env:"
+	filePrefix      = "file:"
+)
+
+var secretCipher struct {
+	once sync.Once
+	aead cipher.AEAD
+	err  error
+}
+
+// secretAEAD returns the cipher for secrets, creating the key file on first use
+func secretAEAD() (cipher.AEAD, error) {
+	secretCipher.once.Do(func() {
+		key, err := loadSecretKey()
+		if err != nil {
+			secretCipher.err = fmt.Errorf("secret key unavailable: %w", err)
+			return
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			secretCipher.err = err
+			return
+		}
+		secretCipher.aead, secretCipher.err = cipher.NewGCM(block)
+	})
+	return secretCipher.aead, secretCipher.err
+}
+
+// loadSecretKey reads the key from SecretKeyEnv or the key file, creating the file if needed
+func loadSecretKey() ([]byte, error) {
+	if v := os.Getenv(SecretKeyEnv); v != "" {
+		if key, err := base64.StdEncoding.DecodeString(v); err == nil && len(key) == 32 {
+			return key, nil
+		}
+		sum := sha256.Sum256([]byte(v))
+		return sum[:], nil
+	}
+
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, SecretKeyFile)
+	data, err := os.ReadFile(path)
+	if err == nil {
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("invalid key file %s", path)
+		}
+		return key, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.WriteString(base64.StdEncoding.EncodeToString(key) + "\n"); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return key, f.Close()
+}
+
+// EncryptSecret encrypts a value for storage
+func EncryptSecret(plaintext string) (string, error) {
+	aead, err := secretAEAD()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// IsEncryptedSecret reports whether a stored value was encrypted by EncryptSecret
+func IsEncryptedSecret(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
+
+// IsSecretReference reports whether a value refers to an environment variable or file
+func IsSecretReference(value string) bool {
+	return strings.HasPrefix(value, envPrefix) || strings.HasPrefix(value, filePrefix)
+}
+
+// CheckSchemaDefault refuses default values a plugin declares in its config schema that
+// would be resolved as secrets. Plugins choose their defaults, so an env: or file:
+// reference would let them read any variable or file of the host; only the user may
+// point a field at a secret.
+func CheckSchemaDefault(value string) error {
+	if IsSecretReference(value) || IsEncryptedSecret(value) {
+		return fmt.Errorf("default value %q refers to a secret", value)
+	}
+	return nil
+}
+
+// ResolveSecret returns the plaintext of an encrypted value or a reference
+// ("env:NAME", "file:/path"); other values are returned unchanged
+func ResolveSecret(value string) (string, error) {
+	switch {
+	case IsEncryptedSecret(value):
+		aead, err := secretAEAD()
+		if err != nil {
+			return "", err
+		}
+		sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+		if err != nil || len(sealed) < aead.NonceSize() {
+			return "", errors.New("malformed encrypted secret")
+		}
+		plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+		if err != nil {
+			return "", errors.New("failed to decrypt secret (wrong key?)")
+		}
+		return string(plaintext), nil
+
+	case strings.HasPrefix(value, envPrefix):
+		name := strings.TrimPrefix(value, envPrefix)
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return v, nil
+
+	case strings.HasPrefix(value, filePrefix):
+		data, err := os.ReadFile(strings.TrimPrefix(value, filePrefix))
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+	return value, nil
+}
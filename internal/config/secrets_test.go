@@ -0,0 +1,21 @@
+package config
+
+import "testing"
+
+func TestCheckSchemaDefault(t *testing.T) {
+	for _, tc := range []struct {
+		value string
+		ok    bool
+	}{
+		{"", true},
+		{"localhost:1883", true},
+		{"https://example.com/file:x", true},
+		{"env:OPENAI_API_KEY", false},
+		{"file:/root/.config/chadbot/plugin.key", false},
+		{"enc:v1:AAAA", false},
+	} {
+		if err := CheckSchemaDefault(tc.value); (err == nil) != tc.ok {
+			t.Errorf("CheckSchemaDefault(%q) = %v, want ok=%v", tc.value, err, tc.ok)
+		}
+	}
+}
@@ -73,17 +73,17 @@ func (b *Bus) Publish(event *pb.Event) {
 // matches checks if an event type matches any of the patterns
 func (b *Bus) matches(eventType string, patterns []string) bool {
 	for _, pattern := range patterns {
-		if matchPattern(pattern, eventType) {
+		if MatchPattern(pattern, eventType) {
 			return true
 		}
 	}
 	return false
 }
 
-// matchPattern checks if an event type matches a pattern
+// MatchPattern checks if an event type matches a pattern
 // Supports wildcards: "chat.*" matches "chat.message", "chat.typing"
 // "chat.message.*" matches "chat.message.received", "chat.message.sent"
-func matchPattern(pattern, eventType string) bool {
+func MatchPattern(pattern, eventType string) bool {
 	if pattern == "*" {
 		return true
 	}
@@ -0,0 +1,149 @@
+package knowledge
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+const maxChunkChars = 1200
+
+// chunk is a passage of a document before it is stored
+type chunk struct {
+	Location string
+	Content  string
+}
+
+// supportedFile reports whether a file can be ingested
+func supportedFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".md", ".markdown", ".txt", ".pdf":
+		return true
+	}
+	return false
+}
+
+// chunkFile reads a file and splits it into passages
+func chunkFile(path string) ([]chunk, error) {
+	if strings.EqualFold(filepath.Ext(path), ".pdf") {
+		return chunkPDF(path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown":
+		return chunkMarkdown(string(data)), nil
+	default:
+		return splitText("", string(data)), nil
+	}
+}
+
+// chunkMarkdown splits Markdown into sections by heading, then into passages by paragraph
+func chunkMarkdown(text string) []chunk {
+	var chunks []chunk
+	var headings []string
+	var section strings.Builder
+	inFence := false
+
+	flush := func() {
+		chunks = append(chunks, splitText(strings.Join(headings, " > "), section.String())...)
+		section.Reset()
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inFence = !inFence
+		}
+		if !inFence && strings.HasPrefix(trimmed, "#") {
+			level := len(trimmed) - len(strings.TrimLeft(trimmed, "#"))
+			title := strings.TrimSpace(trimmed[level:])
+			if level <= 6 && title != "" {
+				flush()
+				if level-1 < len(headings) {
+					headings = headings[:level-1]
+				}
+				for len(headings) < level-1 {
+					headings = append(headings, "")
+				}
+				headings = append(headings, title)
+				continue
+			}
+		}
+		section.WriteString(line)
+		section.WriteString("\n")
+	}
+	flush()
+	return chunks
+}
+
+// chunkPDF extracts text with pdftotext (poppler-utils) and splits it per page
+func chunkPDF(path string) ([]chunk, error) {
+	if _, err := exec.LookPath("pdftotext"); err != nil {
+		return nil, fmt.Errorf("pdftotext not found (install poppler-utils to index PDFs)")
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("pdftotext", "-enc", "UTF-8", path, "-")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("pdftotext failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var chunks []chunk
+	for i, page := range strings.Split(stdout.String(), "\f") {
+		chunks = append(chunks, splitText(fmt.Sprintf("page %d", i+1), page)...)
+	}
+	return chunks, nil
+}
+
+// splitText splits text into passages of at most maxChunkChars, breaking at paragraphs where possible
+func splitText(location, text string) []chunk {
+	var chunks []chunk
+	var current strings.Builder
+
+	flush := func() {
+		if content := strings.TrimSpace(current.String()); content != "" {
+			chunks = append(chunks, chunk{Location: location, Content: content})
+		}
+		current.Reset()
+	}
+
+	for _, para := range strings.Split(text, "\n\n") {
+		para = strings.TrimSpace(para)
+		if para == "" {
+			continue
+		}
+		if current.Len() > 0 && current.Len()+len(para)+2 > maxChunkChars {
+			flush()
+		}
+		// Hard-split paragraphs that are longer than a chunk on their own
+		for len(para) > maxChunkChars {
+			cut := strings.LastIndexAny(para[:maxChunkChars], ".!?\n ")
+			if cut <= 0 {
+				// No break character: cut at the last rune boundary
+				cut = maxChunkChars - 1
+				for cut > 0 && !utf8.RuneStart(para[cut+1]) {
+					cut--
+				}
+			}
+			current.WriteString(para[:cut+1])
+			flush()
+			para = strings.TrimSpace(para[cut+1:])
+		}
+		if current.Len() > 0 {
+			current.WriteString("\n\n")
+		}
+		current.WriteString(para)
+	}
+	flush()
+	return chunks
+}
@@ -0,0 +1,441 @@
+package knowledge
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fipso/chadbot/internal/config"
+	"github.com/fipso/chadbot/internal/llm"
+	"github.com/fipso/chadbot/internal/storage"
+)
+
+const (
+	// SearchSkillName is the built-in skill that searches the knowledge base
+	SearchSkillName = "kb_search"
+
+	defaultSearchLimit = 5
+	maxSearchLimit     = 15
+	embedBatchSize     = 32
+	rrfK               = 60 // Reciprocal rank fusion constant
+)
+
+// Status is the ingestion status of the knowledge base
+type Status struct {
+	Dir       string          `json:"dir"`
+	Documents int             `json:"documents"`
+	Chunks    int             `json:"chunks"`
+	Embedded  int             `json:"embedded"` // Chunks with an embedding of the current embedder
+	Indexing  bool            `json:"indexing"`
+	FTS       bool            `json:"fts"` // SQLite FTS5 in use (otherwise LIKE search)
+	LastScan  time.Time       `json:"last_scan"`
+	Errors    []DocumentError `json:"errors,omitempty"`
+}
+
+// DocumentError is a document that failed to ingest
+type DocumentError struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// Passage is a cited search result
+type Passage struct {
+	Path     string  `json:"path"`
+	Soul     string  `json:"soul"`
+	Location string  `json:"location"`
+	Content  string  `json:"content"`
+	Score    float64 `json:"score"`
+}
+
+// Manager ingests a directory of documents and searches it.
+// Files in a top-level subdirectory named after a soul are only visible to that soul,
+// files directly in the directory are visible to all souls.
+type Manager struct {
+	dir      string
+	embedder llm.Embedder
+	watcher  *config.FileWatcher
+
+	scanMu sync.Mutex // Serializes scans
+
+	mu     sync.RWMutex
+	status Status
+}
+
+// NewManager creates a knowledge base for a directory (empty = ~/.config/chadbot/knowledge).
+// The embedder is optional; without it only full-text search is used.
+func NewManager(dir string, embedder llm.Embedder) (*Manager, error) {
+	if dir == "" {
+		var err error
+		if dir, err = config.KnowledgeDir(); err != nil {
+			return nil, fmt.Errorf("failed to get knowledge directory: %w", err)
+		}
+	} else if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create knowledge directory: %w", err)
+	}
+
+	m := &Manager{
+		dir:      dir,
+		embedder: embedder,
+		status:   Status{Dir: dir, FTS: storage.FTSEnabled()},
+	}
+
+	watcher, err := config.NewFileWatcher(config.WatcherConfig{
+		Handler:       m.Scan,
+		DebounceDelay: time.Second,
+		LogPrefix:     "Knowledge",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	m.watcher = watcher
+
+	return m, nil
+}
+
+// Start runs the initial scan in the background
+func (m *Manager) Start() {
+	log.Printf("[Knowledge] Watching directory: %s", m.dir)
+	go m.Scan()
+}
+
+// Stop stops the file watcher
+func (m *Manager) Stop() {
+	if m.watcher != nil {
+		m.watcher.Stop()
+	}
+}
+
+// Status returns the current ingestion status
+func (m *Manager) Status() Status {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	status := m.status
+	status.Errors = append([]DocumentError(nil), m.status.Errors...)
+	return status
+}
+
+// Scan synchronizes the index with the directory: new and changed files are (re)ingested,
+// removed files are dropped, then missing embeddings are computed
+func (m *Manager) Scan() {
+	m.scanMu.Lock()
+	defer m.scanMu.Unlock()
+
+	m.setIndexing(true)
+	defer m.setIndexing(false)
+
+	indexed := make(map[string]storage.KBDocument)
+	if docs, err := storage.ListKBDocuments(); err == nil {
+		for _, d := range docs {
+			indexed[d.Path] = d
+		}
+	}
+
+	seen := make(map[string]bool)
+	err := filepath.WalkDir(m.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") && path != m.dir {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			// fsnotify is not recursive, so every directory is watched (re-adding is a no-op)
+			if err := m.watcher.Add(path); err != nil {
+				log.Printf("[Knowledge] Failed to watch %s: %v", path, err)
+			}
+			return nil
+		}
+		if !supportedFile(path) {
+			return nil
+		}
+
+		rel, _ := filepath.Rel(m.dir, path)
+		rel = filepath.ToSlash(rel)
+		seen[rel] = true
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		if doc, ok := indexed[rel]; ok && doc.Size == info.Size() && doc.ModTime.Equal(info.ModTime()) {
+			return nil
+		}
+		m.ingest(path, rel, info)
+		return nil
+	})
+	if err != nil {
+		log.Printf("[Knowledge] Scan failed: %v", err)
+	}
+
+	for path := range indexed {
+		if !seen[path] {
+			if err := storage.DeleteKBDocument(path); err != nil {
+				log.Printf("[Knowledge] Failed to remove %s: %v", path, err)
+			} else {
+				log.Printf("[Knowledge] Removed %s", path)
+			}
+		}
+	}
+
+	m.embedChunks()
+	m.refreshStatus()
+}
+
+// ingest chunks a file and replaces its index entries
+func (m *Manager) ingest(path, rel string, info fs.FileInfo) {
+	doc := &storage.KBDocument{
+		Path:      rel,
+		Soul:      soulForPath(rel),
+		Size:      info.Size(),
+		ModTime:   info.ModTime(),
+		IndexedAt: time.Now(),
+	}
+
+	parts, err := chunkFile(path)
+	if err != nil {
+		doc.Error = err.Error()
+		log.Printf("[Knowledge] Failed to ingest %s: %v", rel, err)
+	}
+
+	chunks := make([]storage.KBChunk, len(parts))
+	for i, p := range parts {
+		chunks[i] = storage.KBChunk{
+			DocumentPath: rel,
+			Soul:         doc.Soul,
+			Ordinal:      i,
+			Location:     p.Location,
+			Content:      p.Content,
+		}
+	}
+	doc.Chunks = len(chunks)
+
+	if err := storage.ReplaceKBDocument(doc, chunks); err != nil {
+		log.Printf("[Knowledge] Failed to store %s: %v", rel, err)
+		return
+	}
+	if doc.Error == "" {
+		log.Printf("[Knowledge] Indexed %s (%d chunks)", rel, len(chunks))
+	}
+}
+
+// embedChunks computes embeddings for chunks that don't have one for the current embedder
+func (m *Manager) embedChunks() {
+	if m.embedder == nil {
+		return
+	}
+	model := m.embedder.Name()
+	ctx := context.Background()
+
+	for {
+		chunks, err := storage.GetKBChunksWithoutVector(model, embedBatchSize)
+		if err != nil || len(chunks) == 0 {
+			return
+		}
+
+		texts := make([]string, len(chunks))
+		for i, c := range chunks {
+			texts[i] = c.Location + "\n" + c.Content
+		}
+		vectors, err := m.embedder.Embed(ctx, texts)
+		if err != nil || len(vectors) != len(chunks) {
+			log.Printf("[Knowledge] Embedding failed: %v", err)
+			return
+		}
+		for i, c := range chunks {
+			if err := storage.SetKBChunkVector(c.ID, model, storage.EncodeVector(vectors[i])); err != nil {
+				log.Printf("[Knowledge] Failed to store embedding: %v", err)
+				return
+			}
+		}
+	}
+}
+
+// setIndexing updates the indexing flag
+func (m *Manager) setIndexing(indexing bool) {
+	m.mu.Lock()
+	m.status.Indexing = indexing
+	m.mu.Unlock()
+}
+
+// refreshStatus recomputes document and chunk counts
+func (m *Manager) refreshStatus() {
+	docs, err := storage.ListKBDocuments()
+	if err != nil {
+		return
+	}
+
+	status := Status{Dir: m.dir, FTS: storage.FTSEnabled(), LastScan: time.Now(), Documents: len(docs)}
+	for _, d := range docs {
+		status.Chunks += d.Chunks
+		if d.Error != "" {
+			status.Errors = append(status.Errors, DocumentError{Path: d.Path, Error: d.Error})
+		}
+	}
+	if m.embedder != nil {
+		if pending, err := storage.CountKBChunksWithoutVector(m.embedder.Name()); err == nil {
+			status.Embedded = status.Chunks - int(pending)
+		}
+	}
+
+	m.mu.Lock()
+	status.Indexing = m.status.Indexing
+	m.status = status
+	m.mu.Unlock()
+}
+
+// soulForPath returns the soul a document belongs to (its top-level directory, "" for shared files)
+func soulForPath(rel string) string {
+	if i := strings.Index(rel, "/"); i > 0 {
+		return rel[:i]
+	}
+	return ""
+}
+
+// Search returns the passages most relevant to the query for a soul, fusing full-text and vector rankings
+func (m *Manager) Search(ctx context.Context, soul, query string, limit int) ([]Passage, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+	if soul == "" {
+		soul = "default"
+	}
+	souls := []string{soul, ""}
+	pool := limit * 4
+
+	scores := make(map[uint]float64)
+	chunks := make(map[uint]storage.KBChunk)
+
+	textMatches, err := storage.SearchKBChunks(query, souls, pool)
+	if err != nil {
+		return nil, err
+	}
+	for rank, c := range textMatches {
+		scores[c.ID] += 1.0 / float64(rrfK+rank+1)
+		chunks[c.ID] = c.KBChunk
+	}
+
+	for rank, c := range m.vectorSearch(ctx, query, souls, pool) {
+		scores[c.ID] += 1.0 / float64(rrfK+rank+1)
+		chunks[c.ID] = c
+	}
+
+	passages := make([]Passage, 0, len(scores))
+	for id, score := range scores {
+		c := chunks[id]
+		passages = append(passages, Passage{
+			Path:     c.DocumentPath,
+			Soul:     c.Soul,
+			Location: c.Location,
+			Content:  c.Content,
+			Score:    score,
+		})
+	}
+	sort.Slice(passages, func(i, j int) bool {
+		return passages[i].Score > passages[j].Score
+	})
+	if len(passages) > limit {
+		passages = passages[:limit]
+	}
+	return passages, nil
+}
+
+// vectorSearch ranks chunks by cosine similarity to the query (nil without embedder)
+func (m *Manager) vectorSearch(ctx context.Context, query string, souls []string, limit int) []storage.KBChunk {
+	if m.embedder == nil {
+		return nil
+	}
+	vectors, err := m.embedder.Embed(ctx, []string{query})
+	if err != nil || len(vectors) != 1 {
+		log.Printf("[Knowledge] Query embedding failed, using full-text only: %v", err)
+		return nil
+	}
+	all, err := storage.GetKBChunks(souls)
+	if err != nil {
+		return nil
+	}
+
+	model := m.embedder.Name()
+	type scored struct {
+		chunk storage.KBChunk
+		sim   float64
+	}
+	var ranked []scored
+	for _, c := range all {
+		if c.Model != model || len(c.Vector) == 0 {
+			continue
+		}
+		if sim := llm.CosineSimilarity(vectors[0], storage.DecodeVector(c.Vector)); sim > 0 {
+			ranked = append(ranked, scored{chunk: c, sim: sim})
+		}
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		return ranked[i].sim > ranked[j].sim
+	})
+
+	result := make([]storage.KBChunk, 0, min(limit, len(ranked)))
+	for i := 0; i < len(ranked) && i < limit; i++ {
+		result = append(result, ranked[i].chunk)
+	}
+	return result
+}
+
+// Skill returns the kb_search built-in skill
+func (m *Manager) Skill() *llm.BuiltinSkill {
+	return &llm.BuiltinSkill{
+		Tool: llm.Tool{
+			Name: SearchSkillName,
+			Description: "Search the local knowledge base (guides, runbooks, manuals) and return cited passages. " +
+				"Cite the passages you use with their [n] reference and source.",
+			Parameters: llm.ObjectSchema(map[string]string{
+				"query": "What to look for",
+				"limit": fmt.Sprintf("Optional number of passages (default %d, max %d)", defaultSearchLimit, maxSearchLimit),
+			}, "query"),
+		},
+		Handler: m.handleSearch,
+	}
+}
+
+// handleSearch runs kb_search for the soul of the current chat
+func (m *Manager) handleSearch(ctx context.Context, call *llm.BuiltinCall) (string, error) {
+	query := strings.TrimSpace(call.Args["query"])
+	if query == "" {
+		return "", fmt.Errorf("%s: argument \"query\" is required", SearchSkillName)
+	}
+	limit := defaultSearchLimit
+	if n, err := strconv.Atoi(call.Args["limit"]); err == nil && n > 0 {
+		limit = min(n, maxSearchLimit)
+	}
+	soul := ""
+	if call.ChatCtx != nil {
+		soul = call.ChatCtx.Soul
+	}
+
+	passages, err := m.Search(ctx, soul, query, limit)
+	if err != nil {
+		return "", err
+	}
+	if len(passages) == 0 {
+		return "No matching passages in the knowledge base.", nil
+	}
+
+	var sb strings.Builder
+	for i, p := range passages {
+		source := p.Path
+		if p.Location != "" {
+			source += " - " + p.Location
+		}
+		fmt.Fprintf(&sb, "[%d] %s\n%s\n\n", i+1, source, p.Content)
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
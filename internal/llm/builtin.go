@@ -0,0 +1,118 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"sort"
+)
+
+// BuiltinPluginName is reported as the owner of skills implemented inside the backend
+const BuiltinPluginName = "builtin"
+
+// BuiltinCall contains the context of a built-in skill invocation
+type BuiltinCall struct {
+	ToolID  string
+	Args    map[string]string
+	ChatCtx *ChatContext
+
+	// Children can be filled by the handler with tool calls it made on its own
+	// (e.g. a delegated sub-agent); they are stored under the parent record
+	Children []ToolCallRecord
+	// DeferredAttachments produced while handling the call
+	DeferredAttachments []DeferredAttachment
+	// ActivateTools lists tools to offer to the LLM for the rest of the chat (tool search mode)
+	ActivateTools []string
+}
+
+// BuiltinHandler executes a built-in skill
+type BuiltinHandler func(ctx context.Context, call *BuiltinCall) (string, error)
+
+// BuiltinSkill is a skill implemented inside the backend instead of a plugin
+type BuiltinSkill struct {
+	Tool    Tool
+	Handler BuiltinHandler
+}
+
+// RegisterBuiltin registers a built-in skill that is offered to the LLM next to plugin skills
+func (r *Router) RegisterBuiltin(skill *BuiltinSkill) {
+	r.builtinsMu.Lock()
+	defer r.builtinsMu.Unlock()
+	r.builtins[skill.Tool.Name] = skill
+	log.Printf("[LLM Router] Registered built-in skill: %s", skill.Tool.Name)
+}
+
+// GetBuiltin returns a built-in skill by name
+func (r *Router) GetBuiltin(name string) (*BuiltinSkill, bool) {
+	r.builtinsMu.RLock()
+	defer r.builtinsMu.RUnlock()
+	skill, ok := r.builtins[name]
+	return skill, ok
+}
+
+// ListBuiltins returns all built-in skills sorted by name
+func (r *Router) ListBuiltins() []*BuiltinSkill {
+	r.builtinsMu.RLock()
+	defer r.builtinsMu.RUnlock()
+
+	skills := make([]*BuiltinSkill, 0, len(r.builtins))
+	for _, s := range r.builtins {
+		skills = append(skills, s)
+	}
+	sort.Slice(skills, func(i, j int) bool {
+		return skills[i].Tool.Name < skills[j].Tool.Name
+	})
+	return skills
+}
+
+// invokeBuiltin runs a built-in skill; the returned call holds any nested records and attachments
+func (r *Router) invokeBuiltin(ctx context.Context, skill *BuiltinSkill, toolID string, args map[string]string, chatCtx *ChatContext) (string, *BuiltinCall, error) {
+	call := &BuiltinCall{
+		ToolID:  toolID,
+		Args:    args,
+		ChatCtx: chatCtx,
+	}
+	if err := r.checkAccess(skill.Tool.Name, BuiltinPluginName, chatCtx); err != nil {
+		return "", call, err
+	}
+	result, err := skill.Handler(ctx, call)
+	return result, call, err
+}
+
+// toolAllowed reports whether a tool name passes the chat's allowlist (glob patterns)
+func toolAllowed(name string, chatCtx *ChatContext) bool {
+	if chatCtx == nil || len(chatCtx.AllowedTools) == 0 {
+		return true
+	}
+	for _, pattern := range chatCtx.AllowedTools {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ObjectSchema builds a JSON schema object for string parameters (for built-in skills)
+func ObjectSchema(properties map[string]string, required ...string) map[string]interface{} {
+	props := make(map[string]interface{}, len(properties))
+	for name, desc := range properties {
+		props[name] = map[string]interface{}{
+			"type":        "string",
+			"description": desc,
+		}
+	}
+	if required == nil {
+		required = []string{}
+	}
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": props,
+		"required":   required,
+	}
+}
+
+// errBuiltinArg builds a consistent error for missing/invalid built-in skill arguments
+func errBuiltinArg(skill, arg string) error {
+	return fmt.Errorf("%s: argument %q is required", skill, arg)
+}
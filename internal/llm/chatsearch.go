@@ -0,0 +1,87 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fipso/chadbot/internal/storage"
+)
+
+// ChatSearchSkillName is the built-in skill for full-text search over past conversations
+const ChatSearchSkillName = "chat_search"
+
+// newChatSearchSkill creates the chat_search built-in skill
+func newChatSearchSkill() *BuiltinSkill {
+	return &BuiltinSkill{
+		Tool: Tool{
+			Name: ChatSearchSkillName,
+			Description: "Full-text search over your past conversations with the user (messages, tool results and attachment names). " +
+				"All words must match. Returns snippets with chat and message IDs.",
+			Parameters: ObjectSchema(map[string]string{
+				"query":    "Words to search for",
+				"role":     "Optional: only \"user\" or \"assistant\" messages",
+				"platform": "Optional: only chats of a platform, e.g. \"web\" or \"whatsapp\"",
+				"since":    "Optional start date (YYYY-MM-DD)",
+				"until":    "Optional end date, inclusive (YYYY-MM-DD)",
+				"limit":    "Optional number of results (default 10)",
+			}, "query"),
+		},
+		Handler: handleChatSearch,
+	}
+}
+
+// handleChatSearch searches the messages of the chat owner
+func handleChatSearch(ctx context.Context, call *BuiltinCall) (string, error) {
+	q := storage.MessageSearchQuery{
+		Query:          strings.TrimSpace(call.Args["query"]),
+		Role:           call.Args["role"],
+		Platform:       call.Args["platform"],
+		Limit:          10,
+		HighlightStart: "**",
+		HighlightEnd:   "**",
+	}
+	if q.Query == "" {
+		return "", errBuiltinArg(ChatSearchSkillName, "query")
+	}
+	// An empty user ID searches everyone's messages, which no skill call may do
+	if call.ChatCtx == nil || call.ChatCtx.UserID == "" {
+		return "", fmt.Errorf("%s is not available without a user", ChatSearchSkillName)
+	}
+	q.UserID = call.ChatCtx.UserID
+	if n, err := strconv.Atoi(call.Args["limit"]); err == nil && n > 0 {
+		q.Limit = min(n, 50)
+	}
+	if v := call.Args["since"]; v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return "", fmt.Errorf("%s: invalid since date %q (use YYYY-MM-DD)", ChatSearchSkillName, v)
+		}
+		q.Since = t
+	}
+	if v := call.Args["until"]; v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return "", fmt.Errorf("%s: invalid until date %q (use YYYY-MM-DD)", ChatSearchSkillName, v)
+		}
+		q.Until = t.AddDate(0, 0, 1)
+	}
+
+	results, err := storage.SearchMessages(q)
+	if err != nil {
+		return "", err
+	}
+	if len(results) == 0 {
+		return "No matching messages found.", nil
+	}
+
+	var sb strings.Builder
+	for _, res := range results {
+		fmt.Fprintf(&sb, "[%s] %s in chat %q (chat_id=%s, message_id=%s): %s\n",
+			res.CreatedAt.Format("2006-01-02 15:04"), res.Role, res.ChatName, res.ChatID, res.MessageID,
+			strings.ReplaceAll(res.Snippet, "\n", " "))
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,22 @@
+package llm
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fipso/chadbot/internal/storage"
+)
+
+func TestChatSearchNeedsUser(t *testing.T) {
+	if err := storage.Init(filepath.Join(t.TempDir(), "chadbot.db")); err != nil {
+		t.Fatalf("init storage: %v", err)
+	}
+	for _, chatCtx := range []*ChatContext{nil, {ChatID: "c1"}} {
+		call := &BuiltinCall{Args: map[string]string{"query": "hello"}, ChatCtx: chatCtx}
+		if _, err := handleChatSearch(context.Background(), call); err == nil || !strings.Contains(err.Error(), "without a user") {
+			t.Fatalf("chat search with chat context %+v: %v, want an error for the missing user", chatCtx, err)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DelegateSkillName is the built-in skill that runs a task in a nested sub-agent
+	DelegateSkillName = "delegate_task"
+
+	defaultDelegateMaxSteps = 8
+	maxDelegateMaxSteps     = 25
+	defaultDelegateTimeout  = 5 * time.Minute
+	maxDelegateTimeout      = 15 * time.Minute
+	maxDelegateDepth        = 1
+)
+
+// newDelegateSkill creates the delegate_task built-in skill
+func newDelegateSkill(r *Router) *BuiltinSkill {
+	return &BuiltinSkill{
+		Tool: Tool{
+			Name: DelegateSkillName,
+			Description: "Delegate a self-contained task to a sub-agent with its own context. " +
+				"The sub-agent can use tools (e.g. research, run code) and only its final answer is returned. " +
+				"Use this for multi-step work whose intermediate tool output is not needed in this conversation.",
+			Parameters: ObjectSchema(map[string]string{
+				"task":            "Complete description of the task, including everything the sub-agent needs to know",
+				"soul":            "Optional soul (system prompt profile) for the sub-agent",
+				"provider":        "Optional LLM provider for the sub-agent (defaults to the current one)",
+				"tools":           "Optional comma-separated allowlist of tool names or glob patterns (e.g. \"http_*,sandbox_run\")",
+				"max_steps":       fmt.Sprintf("Optional maximum number of LLM round-trips (default %d, max %d)", defaultDelegateMaxSteps, maxDelegateMaxSteps),
+				"timeout_seconds": fmt.Sprintf("Optional time budget in seconds (default %d, max %d)", int(defaultDelegateTimeout.Seconds()), int(maxDelegateTimeout.Seconds())),
+			}, "task"),
+		},
+		Handler: r.handleDelegate,
+	}
+}
+
+// handleDelegate runs a nested Router.Chat and returns only its final answer
+func (r *Router) handleDelegate(ctx context.Context, call *BuiltinCall) (string, error) {
+	task := strings.TrimSpace(call.Args["task"])
+	if task == "" {
+		return "", errBuiltinArg(DelegateSkillName, "task")
+	}
+
+	parent := call.ChatCtx
+	if parent == nil {
+		parent = &ChatContext{}
+	}
+	if parent.Depth >= maxDelegateDepth {
+		return "", fmt.Errorf("delegation depth limit reached")
+	}
+
+	maxSteps := defaultDelegateMaxSteps
+	if v := call.Args["max_steps"]; v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return "", fmt.Errorf("invalid max_steps: %s", v)
+		}
+		maxSteps = min(n, maxDelegateMaxSteps)
+	}
+
+	timeout := defaultDelegateTimeout
+	if v := call.Args["timeout_seconds"]; v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return "", fmt.Errorf("invalid timeout_seconds: %s", v)
+		}
+		timeout = time.Duration(min(n, int(maxDelegateTimeout.Seconds()))) * time.Second
+	}
+
+	var allowed []string
+	if v := call.Args["tools"]; v != "" {
+		for _, t := range strings.Split(v, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				allowed = append(allowed, t)
+			}
+		}
+	}
+	// Narrow the parent's allowlist, never widen it
+	if len(parent.AllowedTools) > 0 {
+		if len(allowed) == 0 {
+			allowed = parent.AllowedTools
+		} else {
+			var narrowed []string
+			for _, t := range allowed {
+				if toolAllowed(t, parent) {
+					narrowed = append(narrowed, t)
+				}
+			}
+			if len(narrowed) == 0 {
+				return "", fmt.Errorf("none of the requested tools are allowed")
+			}
+			allowed = narrowed
+		}
+	}
+
+	soul := call.Args["soul"]
+	if soul == "" {
+		soul = parent.Soul
+	}
+	provider := call.Args["provider"]
+	if provider == "" {
+		provider = parent.Provider
+	}
+
+	childCtx := &ChatContext{
+		ChatID:       parent.ChatID,
+		UserID:       parent.UserID,
+		Platform:     parent.Platform,
+		Soul:         soul,
+		AllowedTools: allowed,
+		MaxSteps:     maxSteps,
+		ParentToolID: call.ToolID,
+		Depth:        parent.Depth + 1,
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	log.Printf("[LLM Router] Delegating task (soul=%q provider=%q tools=%v max_steps=%d)", soul, provider, allowed, maxSteps)
+
+	resp, err := r.Chat(ctx, []Message{{Role: "user", Content: task}}, provider, childCtx)
+	if err != nil {
+		return "", fmt.Errorf("sub-agent failed: %w", err)
+	}
+
+	call.Children = resp.ToolCallRecords
+	call.DeferredAttachments = resp.DeferredAttachments
+	return resp.Content, nil
+}
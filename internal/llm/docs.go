@@ -0,0 +1,83 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// ReadDocsSkillName is the built-in skill that returns a plugin's documentation
+const ReadDocsSkillName = "read_plugin_docs"
+
+// newReadDocsSkill creates the read_plugin_docs built-in skill
+func newReadDocsSkill(r *Router) *BuiltinSkill {
+	return &BuiltinSkill{
+		Tool: Tool{
+			Name: ReadDocsSkillName,
+			Description: "Read the full documentation (PLUGIN.md) of a plugin. " +
+				"Check the plugin documentation index in the system prompt for available plugins.",
+			Parameters: ObjectSchema(map[string]string{
+				"plugin": "Plugin name from the documentation index",
+			}, "plugin"),
+		},
+		Handler: r.handleReadDocs,
+	}
+}
+
+// handleReadDocs returns a plugin's documentation and marks it as read for this chat request
+func (r *Router) handleReadDocs(ctx context.Context, call *BuiltinCall) (string, error) {
+	pluginName := strings.TrimSpace(call.Args["plugin"])
+	if pluginName == "" {
+		return "", errBuiltinArg(ReadDocsSkillName, "plugin")
+	}
+
+	doc := r.manager.GetDocumentationByName(pluginName)
+	if doc == "" {
+		return "", fmt.Errorf("no documentation available for plugin %s", pluginName)
+	}
+
+	if call.ChatCtx != nil && call.ChatCtx.docsRead != nil {
+		call.ChatCtx.docsRead[pluginName] = true
+	}
+	log.Printf("[LLM Router] Plugin documentation read: %s", pluginName)
+	return doc, nil
+}
+
+// buildDocsIndex returns the one-line-per-plugin documentation index for the system prompt
+func (r *Router) buildDocsIndex() string {
+	entries := r.manager.DocumentationIndex()
+	if len(entries) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("\n\n---\n## Plugin documentation\n")
+	sb.WriteString("Call read_plugin_docs(plugin) to read a plugin's documentation. ")
+	sb.WriteString("Plugins marked (required) must have their docs read before their tools can be used.\n")
+	for _, e := range entries {
+		required := ""
+		if e.Required {
+			required = " (required)"
+		}
+		fmt.Fprintf(&sb, "- %s%s: %s\n", e.PluginName, required, e.Description)
+	}
+	return sb.String()
+}
+
+// checkDocsRead returns an error if a skill's plugin requires docs that haven't been read yet
+func (r *Router) checkDocsRead(skillName string, chatCtx *ChatContext) error {
+	skill, ok := r.registry.GetSkill(skillName)
+	if !ok {
+		return nil
+	}
+	plugin, ok := r.manager.Get(skill.PluginID)
+	if !ok || !plugin.DocsRequired || plugin.Documentation == "" {
+		return nil
+	}
+	if chatCtx.docsRead[plugin.Name] {
+		return nil
+	}
+	return fmt.Errorf("the documentation of plugin %s must be read first: call %s with plugin=%q, then retry",
+		plugin.Name, ReadDocsSkillName, plugin.Name)
+}
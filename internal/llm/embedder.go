@@ -0,0 +1,199 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Embedder turns texts into embedding vectors
+type Embedder interface {
+	Name() string
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// SetEmbedder sets the embedder used to rank tool search results and memories (optional)
+func (r *Router) SetEmbedder(e Embedder) {
+	r.embedder = e
+}
+
+// CosineSimilarity returns the cosine similarity of two vectors (0 if either is empty)
+func CosineSimilarity(a, b []float32) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+const (
+	openAIEmbeddingsBaseURL = "https://api.openai.com/v1"
+	defaultEmbeddingModel   = "text-embedding-3-small"
+	defaultHashDims         = 256
+)
+
+// OpenAIEmbedder implements Embedder for OpenAI-compatible /embeddings endpoints
+type OpenAIEmbedder struct {
+	apiKey  string
+	baseURL string
+	model   string
+	client  *http.Client
+}
+
+// NewOpenAIEmbedder creates an embedder for an OpenAI-compatible API (empty baseURL = OpenAI)
+func NewOpenAIEmbedder(apiKey, baseURL, model string) *OpenAIEmbedder {
+	if apiKey == "" {
+		apiKey = os.Getenv("OPENAI_API_KEY")
+	}
+	if baseURL == "" {
+		baseURL = openAIEmbeddingsBaseURL
+	}
+	if model == "" {
+		model = defaultEmbeddingModel
+	}
+	return &OpenAIEmbedder{
+		apiKey:  apiKey,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		model:   model,
+		client:  &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// Name returns the embedding model identifier (vectors of different models are not comparable)
+func (e *OpenAIEmbedder) Name() string {
+	return "openai:" + e.model
+}
+
+// Embed sends the texts to the embeddings endpoint
+func (e *OpenAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"model": e.model,
+		"input": texts,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", e.baseURL+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("embeddings API error: %s", string(respBody))
+	}
+
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Data) != len(texts) {
+		return nil, fmt.Errorf("embeddings API returned %d vectors for %d inputs", len(result.Data), len(texts))
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, d := range result.Data {
+		if d.Index < 0 || d.Index >= len(texts) {
+			return nil, fmt.Errorf("embeddings API returned invalid index %d", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	return vectors, nil
+}
+
+// HashEmbedder is a local, dependency-free embedder based on feature hashing of words and word pairs.
+// It only captures lexical overlap, but works offline and is deterministic (useful for tests).
+type HashEmbedder struct {
+	dims int
+}
+
+// NewHashEmbedder creates a hashing embedder (dims <= 0 uses 256)
+func NewHashEmbedder(dims int) *HashEmbedder {
+	if dims <= 0 {
+		dims = defaultHashDims
+	}
+	return &HashEmbedder{dims: dims}
+}
+
+// Name returns the embedder identifier
+func (e *HashEmbedder) Name() string {
+	return fmt.Sprintf("hash-%d", e.dims)
+}
+
+// Embed hashes each text into a normalized vector
+func (e *HashEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vec := make([]float32, e.dims)
+		words := tokenize(text)
+		for j, w := range words {
+			e.add(vec, w, 1)
+			if j > 0 {
+				e.add(vec, words[j-1]+" "+w, 0.5)
+			}
+		}
+
+		var norm float64
+		for _, v := range vec {
+			norm += float64(v) * float64(v)
+		}
+		if norm > 0 {
+			scale := float32(1 / math.Sqrt(norm))
+			for j := range vec {
+				vec[j] *= scale
+			}
+		}
+		vectors[i] = vec
+	}
+	return vectors, nil
+}
+
+// add hashes a feature into the vector (the hash also picks the sign to reduce collision bias)
+func (e *HashEmbedder) add(vec []float32, feature string, weight float32) {
+	h := fnv.New64a()
+	h.Write([]byte(feature))
+	sum := h.Sum64()
+	if sum&(1<<63) != 0 {
+		weight = -weight
+	}
+	vec[sum%uint64(e.dims)] += weight
+}
@@ -0,0 +1,357 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/fipso/chadbot/internal/storage"
+)
+
+const (
+	// MemorySaveSkillName is the built-in skill that stores a long-term memory
+	MemorySaveSkillName = "memory_save"
+	// MemorySearchSkillName is the built-in skill that searches long-term memories
+	MemorySearchSkillName = "memory_search"
+	// MemoryForgetSkillName is the built-in skill that deletes a long-term memory
+	MemoryForgetSkillName = "memory_forget"
+
+	defaultMaxInjectedMemories = 8
+	memoryExtractTimeout       = 60 * time.Second
+)
+
+// memoryExtractPrompt instructs the LLM to extract durable facts from the last exchange
+const memoryExtractPrompt = `You maintain long-term memory about the user of an assistant.
+Extract durable facts worth remembering across conversations from the last exchange: preferences, personal details, projects, devices, recurring needs.
+Ignore small talk, one-off requests and anything already known.
+Reply with a JSON array of short, self-contained statements in third person (e.g. ["Prefers metric units"]) or [] if there is nothing new. Reply with JSON only.`
+
+// MemoryConfig configures long-term user memory
+type MemoryConfig struct {
+	AutoExtract bool // Extract facts from each top-level turn in the background
+	MaxInjected int  // Max memories injected into the system prompt (default 8)
+}
+
+// ScoredMemory is a memory with its relevance to a query
+type ScoredMemory struct {
+	storage.Memory
+	Score float64 `json:"score"`
+}
+
+// SetMemoryConfig configures long-term memory injection and extraction
+func (r *Router) SetMemoryConfig(cfg MemoryConfig) {
+	if cfg.MaxInjected <= 0 {
+		cfg.MaxInjected = defaultMaxInjectedMemories
+	}
+	r.memory = cfg
+}
+
+// SaveMemory stores a memory for a user (an identical memory is refreshed instead of duplicated)
+func (r *Router) SaveMemory(userID, content, source, chatID string) (*storage.Memory, error) {
+	content = strings.TrimSpace(content)
+	if userID == "" || content == "" {
+		return nil, fmt.Errorf("user ID and content are required")
+	}
+
+	existing, err := storage.GetUserMemories(userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range existing {
+		if strings.EqualFold(existing[i].Content, content) {
+			existing[i].UpdatedAt = time.Now()
+			return &existing[i], storage.UpdateMemory(&existing[i])
+		}
+	}
+
+	m := &storage.Memory{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Content:   content,
+		Source:    source,
+		ChatID:    chatID,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := storage.CreateMemory(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// SearchMemories ranks a user's memories against a query (keywords, blended with embeddings if available)
+func (r *Router) SearchMemories(ctx context.Context, userID, query string, limit int) ([]ScoredMemory, error) {
+	memories, err := storage.GetUserMemories(userID)
+	if err != nil || len(memories) == 0 {
+		return nil, err
+	}
+
+	terms := tokenize(query)
+	semantic := r.memoryScores(ctx, query, memories)
+
+	scored := make([]ScoredMemory, len(memories))
+	for i, m := range memories {
+		score := keywordCoverage(terms, m.Content)
+		if semantic != nil {
+			score = 0.5*score + 0.5*semantic[i]
+		}
+		scored[i] = ScoredMemory{Memory: m, Score: score}
+	}
+	sort.SliceStable(scored, func(i, j int) bool {
+		return scored[i].Score > scored[j].Score
+	})
+
+	var result []ScoredMemory
+	for _, m := range scored {
+		if m.Score <= 0 || len(result) >= limit {
+			break
+		}
+		result = append(result, m)
+	}
+	return result, nil
+}
+
+// memoryScores returns cosine similarities between the query and each memory (nil without embedder).
+// Missing memory vectors are computed and stored.
+func (r *Router) memoryScores(ctx context.Context, query string, memories []storage.Memory) []float64 {
+	if r.embedder == nil || strings.TrimSpace(query) == "" {
+		return nil
+	}
+	model := r.embedder.Name()
+
+	texts := []string{query}
+	var missing []int
+	for i, m := range memories {
+		if m.Model != model || len(m.Vector) == 0 {
+			missing = append(missing, i)
+			texts = append(texts, m.Content)
+		}
+	}
+
+	vectors, err := r.embedder.Embed(ctx, texts)
+	if err != nil || len(vectors) != len(texts) {
+		log.Printf("[Memory] Embedding failed, using keywords only: %v", err)
+		return nil
+	}
+	for j, i := range missing {
+		memories[i].Model = model
+		memories[i].Vector = storage.EncodeVector(vectors[j+1])
+		if err := storage.SetMemoryVector(memories[i].ID, model, memories[i].Vector); err != nil {
+			log.Printf("[Memory] Failed to store vector for memory %s: %v", memories[i].ID, err)
+		}
+	}
+
+	scores := make([]float64, len(memories))
+	for i, m := range memories {
+		scores[i] = max(0, CosineSimilarity(vectors[0], storage.DecodeVector(m.Vector)))
+	}
+	return scores
+}
+
+// buildMemoryPrompt returns the memories relevant to the latest user message for the system prompt
+func (r *Router) buildMemoryPrompt(ctx context.Context, chatCtx *ChatContext, messages []Message) string {
+	if chatCtx.UserID == "" {
+		return ""
+	}
+
+	memories, err := storage.GetUserMemories(chatCtx.UserID)
+	if err != nil || len(memories) == 0 {
+		return ""
+	}
+
+	// Small memory sets are injected completely, larger ones are ranked by the latest user message
+	limit := r.memory.MaxInjected
+	if limit <= 0 {
+		limit = defaultMaxInjectedMemories
+	}
+	selected := memories
+	if len(memories) > limit {
+		scored, err := r.SearchMemories(ctx, chatCtx.UserID, lastUserMessage(messages), limit)
+		if err != nil {
+			return ""
+		}
+		selected = make([]storage.Memory, len(scored))
+		for i, m := range scored {
+			selected[i] = m.Memory
+		}
+	}
+	if len(selected) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("\n\n---\n## What you remember about the user\n")
+	sb.WriteString("Long-term memories from earlier conversations (manage them with memory_save, memory_search and memory_forget):\n")
+	for _, m := range selected {
+		fmt.Fprintf(&sb, "- %s (id: %s)\n", m.Content, m.ID)
+	}
+	return sb.String()
+}
+
+// lastUserMessage returns the content of the most recent user message
+func lastUserMessage(messages []Message) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			return messages[i].Content
+		}
+	}
+	return ""
+}
+
+// extractMemories asks the LLM for durable facts from the last exchange and saves them
+func (r *Router) extractMemories(provider Provider, chatCtx *ChatContext, userMsg, answer string) {
+	ctx, cancel := context.WithTimeout(context.Background(), memoryExtractTimeout)
+	defer cancel()
+
+	var known strings.Builder
+	if memories, err := storage.GetUserMemories(chatCtx.UserID); err == nil {
+		for _, m := range memories {
+			fmt.Fprintf(&known, "- %s\n", m.Content)
+		}
+	}
+
+	prompt := fmt.Sprintf("Already known:\n%s\nUser: %s\n\nAssistant: %s", known.String(), userMsg, answer)
+	resp, err := provider.Chat(ctx, []Message{
+		{Role: "system", Content: memoryExtractPrompt},
+		{Role: "user", Content: prompt},
+	}, nil)
+	if err != nil {
+		log.Printf("[Memory] Extraction failed: %v", err)
+		return
+	}
+
+	content := strings.TrimSpace(resp.Content)
+	if i, j := strings.Index(content, "["), strings.LastIndex(content, "]"); i >= 0 && j > i {
+		content = content[i : j+1]
+	}
+	var facts []string
+	if err := json.Unmarshal([]byte(content), &facts); err != nil {
+		log.Printf("[Memory] Extraction returned invalid JSON: %v", err)
+		return
+	}
+
+	for _, fact := range facts {
+		if _, err := r.SaveMemory(chatCtx.UserID, fact, "auto", chatCtx.ChatID); err != nil {
+			log.Printf("[Memory] Failed to save extracted memory: %v", err)
+		}
+	}
+	if len(facts) > 0 {
+		log.Printf("[Memory] Extracted %d memories for user %s", len(facts), chatCtx.UserID)
+	}
+}
+
+// newMemorySaveSkill creates the memory_save built-in skill
+func newMemorySaveSkill(r *Router) *BuiltinSkill {
+	return &BuiltinSkill{
+		Tool: Tool{
+			Name: MemorySaveSkillName,
+			Description: "Remember a durable fact about the user across all chats (preferences, personal details, projects). " +
+				"Write a short, self-contained statement.",
+			Parameters: ObjectSchema(map[string]string{
+				"content": "The fact to remember, e.g. \"Prefers answers in German\"",
+			}, "content"),
+		},
+		Handler: func(ctx context.Context, call *BuiltinCall) (string, error) {
+			userID, err := memoryUser(call)
+			if err != nil {
+				return "", err
+			}
+			content := strings.TrimSpace(call.Args["content"])
+			if content == "" {
+				return "", errBuiltinArg(MemorySaveSkillName, "content")
+			}
+			m, err := r.SaveMemory(userID, content, "skill", call.ChatCtx.ChatID)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Remembered (id: %s)", m.ID), nil
+		},
+	}
+}
+
+// newMemorySearchSkill creates the memory_search built-in skill
+func newMemorySearchSkill(r *Router) *BuiltinSkill {
+	return &BuiltinSkill{
+		Tool: Tool{
+			Name:        MemorySearchSkillName,
+			Description: "Search the long-term memories about the user.",
+			Parameters: ObjectSchema(map[string]string{
+				"query": "What to look for",
+				"limit": "Optional max number of memories (default 10)",
+			}, "query"),
+		},
+		Handler: func(ctx context.Context, call *BuiltinCall) (string, error) {
+			userID, err := memoryUser(call)
+			if err != nil {
+				return "", err
+			}
+			query := strings.TrimSpace(call.Args["query"])
+			if query == "" {
+				return "", errBuiltinArg(MemorySearchSkillName, "query")
+			}
+			limit := 10
+			if n, err := strconv.Atoi(call.Args["limit"]); err == nil && n > 0 {
+				limit = n
+			}
+
+			memories, err := r.SearchMemories(ctx, userID, query, limit)
+			if err != nil {
+				return "", err
+			}
+			if len(memories) == 0 {
+				return "No matching memories.", nil
+			}
+			var sb strings.Builder
+			for _, m := range memories {
+				fmt.Fprintf(&sb, "- %s (id: %s, saved %s)\n", m.Content, m.ID, m.UpdatedAt.Format("2006-01-02"))
+			}
+			return sb.String(), nil
+		},
+	}
+}
+
+// newMemoryForgetSkill creates the memory_forget built-in skill
+func newMemoryForgetSkill(r *Router) *BuiltinSkill {
+	return &BuiltinSkill{
+		Tool: Tool{
+			Name:        MemoryForgetSkillName,
+			Description: "Delete a long-term memory about the user, e.g. when it is outdated or the user asks you to forget it.",
+			Parameters: ObjectSchema(map[string]string{
+				"id": "ID of the memory to delete",
+			}, "id"),
+		},
+		Handler: func(ctx context.Context, call *BuiltinCall) (string, error) {
+			userID, err := memoryUser(call)
+			if err != nil {
+				return "", err
+			}
+			id := strings.TrimSpace(call.Args["id"])
+			if id == "" {
+				return "", errBuiltinArg(MemoryForgetSkillName, "id")
+			}
+			m, err := storage.GetMemory(id)
+			if err != nil || m.UserID != userID {
+				return "", fmt.Errorf("memory %s not found", id)
+			}
+			if err := storage.DeleteMemory(id); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Forgot: %s", m.Content), nil
+		},
+	}
+}
+
+// memoryUser returns the user whose memories a skill call operates on
+func memoryUser(call *BuiltinCall) (string, error) {
+	if call.ChatCtx == nil || call.ChatCtx.UserID == "" {
+		return "", fmt.Errorf("memory is not available without a user")
+	}
+	return call.ChatCtx.UserID, nil
+}
@@ -0,0 +1,379 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fipso/chadbot/internal/storage"
+)
+
+const (
+	// RecallSkillName is the built-in skill for semantic search over chat history
+	RecallSkillName = "semantic_recall"
+
+	defaultRecallLimit    = 5
+	maxRecallLimit        = 25
+	recallIndexInterval   = 15 * time.Second
+	recallMaxBackoff      = 10 * time.Minute
+	recallIndexBatchSize  = 32
+	recallMaxEmbedChars   = 8000
+	recallKeywordPoolSize = 200
+)
+
+// Recall embeds chat messages in the background and retrieves them by meaning
+type Recall struct {
+	embedder Embedder
+}
+
+// RecallQuery is a semantic recall request
+type RecallQuery struct {
+	Query         string
+	UserID        string  // Only search this user's chats (empty = all)
+	ChatID        string  // Only search this chat (empty = all)
+	Limit         int     // Max results (default 5)
+	KeywordWeight float64 // 0..1 share of the keyword score in the final score
+}
+
+// RecallResult is a message found by semantic recall
+type RecallResult struct {
+	ChatID       string    `json:"chat_id"`
+	ChatName     string    `json:"chat_name"`
+	MessageID    string    `json:"message_id"`
+	Role         string    `json:"role"`
+	Content      string    `json:"content"`
+	CreatedAt    time.Time `json:"created_at"`
+	Score        float64   `json:"score"`
+	VectorScore  float64   `json:"vector_score"`
+	KeywordScore float64   `json:"keyword_score"`
+}
+
+// NewRecall creates a recall index using an embedder
+func NewRecall(embedder Embedder) *Recall {
+	return &Recall{embedder: embedder}
+}
+
+// Run embeds new messages until the context is cancelled. While the embedder fails,
+// the interval doubles up to recallMaxBackoff.
+func (rc *Recall) Run(ctx context.Context) {
+	log.Printf("[Recall] Background indexing started (embedder: %s)", rc.embedder.Name())
+	var backoff time.Duration
+
+	for {
+		for {
+			n, err := rc.indexBatch(ctx)
+			if err != nil {
+				if backoff == 0 {
+					log.Printf("[Recall] Indexing failed, retrying with backoff: %v", err)
+				}
+				backoff = min(max(2*backoff, recallIndexInterval), recallMaxBackoff)
+				break
+			}
+			if backoff > 0 {
+				log.Printf("[Recall] Indexing recovered")
+				backoff = 0
+			}
+			if n < recallIndexBatchSize {
+				break
+			}
+		}
+
+		wait := recallIndexInterval
+		if backoff > 0 {
+			wait = backoff
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// indexBatch embeds one batch of unindexed messages and returns how many were processed
+func (rc *Recall) indexBatch(ctx context.Context) (int, error) {
+	messages, err := storage.GetMessagesWithoutEmbedding(rc.embedder.Name(), recallIndexBatchSize)
+	if err != nil || len(messages) == 0 {
+		return 0, err
+	}
+
+	texts := make([]string, len(messages))
+	for i, m := range messages {
+		texts[i] = truncateRunes(m.Content, recallMaxEmbedChars)
+	}
+
+	vectors, err := rc.embedder.Embed(ctx, texts)
+	if err == nil && len(vectors) != len(messages) {
+		err = fmt.Errorf("embedder returned %d vectors for %d messages", len(vectors), len(messages))
+	}
+	if err != nil {
+		if len(messages) == 1 || ctx.Err() != nil {
+			return 0, err
+		}
+		return rc.indexSingly(ctx, messages, err)
+	}
+
+	embeddings := make([]storage.MessageEmbedding, len(messages))
+	for i, m := range messages {
+		embeddings[i] = rc.newEmbedding(m, vectors[i])
+	}
+	if err := storage.SaveMessageEmbeddings(embeddings); err != nil {
+		return 0, err
+	}
+	return len(messages), nil
+}
+
+// indexSingly embeds the messages of a failed batch one at a time. If some of them succeed,
+// the embedder works and rejects the others, which are stored without a vector so they no
+// longer block the messages after them. If none succeed, the batch error is returned.
+func (rc *Recall) indexSingly(ctx context.Context, messages []storage.Message, batchErr error) (int, error) {
+	embeddings := make([]storage.MessageEmbedding, len(messages))
+	var rejected []int
+	for i, m := range messages {
+		vectors, err := rc.embedder.Embed(ctx, []string{truncateRunes(m.Content, recallMaxEmbedChars)})
+		if err == nil && len(vectors) != 1 {
+			err = fmt.Errorf("embedder returned %d vectors for 1 message", len(vectors))
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return 0, err
+			}
+			embeddings[i] = rc.newEmbedding(m, nil)
+			rejected = append(rejected, i)
+			continue
+		}
+		embeddings[i] = rc.newEmbedding(m, vectors[0])
+	}
+	if len(rejected) == len(messages) {
+		return 0, batchErr
+	}
+
+	for _, i := range rejected {
+		log.Printf("[Recall] Skipping message %s, the embedder rejects it", messages[i].ID)
+	}
+	if err := storage.SaveMessageEmbeddings(embeddings); err != nil {
+		return 0, err
+	}
+	return len(messages), nil
+}
+
+// newEmbedding returns the embedding of a message (a nil vector marks it as skipped)
+func (rc *Recall) newEmbedding(m storage.Message, vector []float32) storage.MessageEmbedding {
+	e := storage.MessageEmbedding{
+		MessageID: m.ID,
+		Model:     rc.embedder.Name(),
+		ChatID:    m.ChatID,
+		CreatedAt: time.Now(),
+	}
+	if vector != nil {
+		e.Vector = storage.EncodeVector(vector)
+	}
+	return e
+}
+
+// Search returns the messages most similar to the query, optionally blended with keyword matches
+func (rc *Recall) Search(ctx context.Context, q RecallQuery) ([]RecallResult, error) {
+	if strings.TrimSpace(q.Query) == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+	if q.Limit <= 0 {
+		q.Limit = defaultRecallLimit
+	}
+	q.Limit = min(q.Limit, maxRecallLimit)
+	q.KeywordWeight = min(max(q.KeywordWeight, 0), 1)
+
+	vectors, err := rc.embedder.Embed(ctx, []string{q.Query})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("embedder returned no vector for query")
+	}
+
+	embeddings, err := storage.GetMessageEmbeddings(rc.embedder.Name(), q.UserID, q.ChatID)
+	if err != nil {
+		return nil, err
+	}
+
+	scores := make(map[string]*RecallResult)
+	for _, e := range embeddings {
+		sim := CosineSimilarity(vectors[0], storage.DecodeVector(e.Vector))
+		if sim <= 0 {
+			continue
+		}
+		scores[e.MessageID] = &RecallResult{MessageID: e.MessageID, ChatID: e.ChatID, VectorScore: sim}
+	}
+
+	// Keyword candidates are scored by the share of query terms they contain
+	var keywordMsgs []storage.Message
+	terms := tokenize(q.Query)
+	if q.KeywordWeight > 0 {
+		keywordMsgs, err = storage.SearchMessagesByKeywords(terms, q.UserID, q.ChatID, recallKeywordPoolSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range keywordMsgs {
+			res, ok := scores[m.ID]
+			if !ok {
+				res = &RecallResult{MessageID: m.ID, ChatID: m.ChatID}
+				scores[m.ID] = res
+			}
+			res.KeywordScore = keywordCoverage(terms, m.Content)
+		}
+	}
+
+	ranked := make([]*RecallResult, 0, len(scores))
+	for _, res := range scores {
+		res.Score = (1-q.KeywordWeight)*res.VectorScore + q.KeywordWeight*res.KeywordScore
+		if res.Score > 0 {
+			ranked = append(ranked, res)
+		}
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		return ranked[i].Score > ranked[j].Score
+	})
+	if len(ranked) > q.Limit {
+		ranked = ranked[:q.Limit]
+	}
+
+	return rc.hydrate(ranked)
+}
+
+// hydrate loads message content and chat names for ranked results
+func (rc *Recall) hydrate(ranked []*RecallResult) ([]RecallResult, error) {
+	ids := make([]string, len(ranked))
+	chatIDs := make([]string, 0, len(ranked))
+	for i, res := range ranked {
+		ids[i] = res.MessageID
+		chatIDs = append(chatIDs, res.ChatID)
+	}
+
+	messages, err := storage.GetMessagesByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]storage.Message, len(messages))
+	for _, m := range messages {
+		byID[m.ID] = m
+	}
+	chatNames, err := storage.GetChatNames(chatIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]RecallResult, 0, len(ranked))
+	for _, res := range ranked {
+		m, ok := byID[res.MessageID]
+		if !ok {
+			continue
+		}
+		res.Role = m.Role
+		res.Content = m.Content
+		res.CreatedAt = m.CreatedAt
+		res.ChatName = chatNames[res.ChatID]
+		results = append(results, *res)
+	}
+	return results, nil
+}
+
+// keywordCoverage returns the fraction of query terms found in the text
+func keywordCoverage(terms []string, text string) float64 {
+	if len(terms) == 0 {
+		return 0
+	}
+	words := tokenize(text)
+	hits := 0.0
+	for _, t := range terms {
+		hits += termHits(t, words)
+	}
+	return hits / float64(len(terms))
+}
+
+// truncateRunes shortens text to at most n runes
+func truncateRunes(text string, n int) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return string(runes[:n])
+}
+
+// SetRecall enables semantic recall over chat history and registers the semantic_recall skill
+func (r *Router) SetRecall(rc *Recall) {
+	r.recall = rc
+	if rc != nil {
+		r.RegisterBuiltin(newRecallSkill(r))
+	}
+}
+
+// Recall returns the semantic recall index (nil if disabled)
+func (r *Router) Recall() *Recall {
+	return r.recall
+}
+
+// newRecallSkill creates the semantic_recall built-in skill
+func newRecallSkill(r *Router) *BuiltinSkill {
+	return &BuiltinSkill{
+		Tool: Tool{
+			Name: RecallSkillName,
+			Description: "Search past conversations (across all chats) by meaning, e.g. \"what did we discuss about the grow tent\". " +
+				"Returns matching messages with their chat and message IDs.",
+			Parameters: ObjectSchema(map[string]string{
+				"query":          "What to look for, in natural language",
+				"limit":          fmt.Sprintf("Optional number of messages to return (default %d, max %d)", defaultRecallLimit, maxRecallLimit),
+				"current_chat":   "Optional: \"true\" to only search the current chat",
+				"keyword_weight": "Optional 0..1 weight of exact keyword matches (default 0.3)",
+			}, "query"),
+		},
+		Handler: r.handleRecall,
+	}
+}
+
+// handleRecall runs a semantic recall query scoped to the chat owner's history
+func (r *Router) handleRecall(ctx context.Context, call *BuiltinCall) (string, error) {
+	q := RecallQuery{
+		Query:         strings.TrimSpace(call.Args["query"]),
+		KeywordWeight: 0.3,
+	}
+	if q.Query == "" {
+		return "", errBuiltinArg(RecallSkillName, "query")
+	}
+	if v := call.Args["limit"]; v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			q.Limit = n
+		}
+	}
+	if v := call.Args["keyword_weight"]; v != "" {
+		if w, err := strconv.ParseFloat(v, 64); err == nil {
+			q.KeywordWeight = w
+		}
+	}
+	// An empty user ID searches everyone's history, which no skill call may do
+	if call.ChatCtx == nil || call.ChatCtx.UserID == "" {
+		return "", fmt.Errorf("%s is not available without a user", RecallSkillName)
+	}
+	q.UserID = call.ChatCtx.UserID
+	if call.Args["current_chat"] == "true" {
+		q.ChatID = call.ChatCtx.ChatID
+	}
+
+	results, err := r.recall.Search(ctx, q)
+	if err != nil {
+		return "", err
+	}
+	if len(results) == 0 {
+		return "No matching messages found.", nil
+	}
+
+	var sb strings.Builder
+	for _, res := range results {
+		fmt.Fprintf(&sb, "[%s] %s in chat %q (chat_id=%s, message_id=%s, score=%.2f):\n%s\n\n",
+			res.CreatedAt.Format("2006-01-02 15:04"), res.Role, res.ChatName, res.ChatID, res.MessageID, res.Score,
+			truncateRunes(res.Content, 1000))
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
@@ -0,0 +1,99 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fipso/chadbot/internal/storage"
+)
+
+// testEmbedder fails on texts in reject, or on everything while down
+type testEmbedder struct {
+	reject map[string]bool
+	down   bool
+}
+
+func (e *testEmbedder) Name() string { return "test" }
+
+func (e *testEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	if e.down {
+		return nil, errors.New("embedder unavailable")
+	}
+	vectors := make([][]float32, len(texts))
+	for i, t := range texts {
+		if e.reject[t] {
+			return nil, fmt.Errorf("text %q rejected", t)
+		}
+		vectors[i] = []float32{1, float32(len(t))}
+	}
+	return vectors, nil
+}
+
+func TestIndexBatchSkipsRejectedMessages(t *testing.T) {
+	if err := storage.Init(filepath.Join(t.TempDir(), "chadbot.db")); err != nil {
+		t.Fatalf("init storage: %v", err)
+	}
+	chat := &storage.Chat{ID: "c1", UserID: "u1", Name: "Recall", Platform: "web"}
+	if err := storage.CreateChat(chat); err != nil {
+		t.Fatal(err)
+	}
+	at := time.Now()
+	for i, content := range []string{"poison", "hello", "world"} {
+		msg := &storage.Message{ID: content, ChatID: chat.ID, Role: "user", Content: content, CreatedAt: at.Add(time.Duration(i) * time.Second)}
+		if err := storage.AddMessage(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	embedder := &testEmbedder{reject: map[string]bool{"poison": true}, down: true}
+	rc := NewRecall(embedder)
+
+	// An unavailable embedder skips nothing
+	if _, err := rc.indexBatch(context.Background()); err == nil {
+		t.Fatal("indexing with an unavailable embedder succeeded")
+	}
+	pending, err := storage.GetMessagesWithoutEmbedding("test", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 3 {
+		t.Fatalf("%d messages pending after a failed batch, want 3", len(pending))
+	}
+
+	// A message the embedder rejects no longer blocks the ones after it
+	embedder.down = false
+	n, err := rc.indexBatch(context.Background())
+	if err != nil || n != 3 {
+		t.Fatalf("indexBatch = %d, %v, want 3 processed", n, err)
+	}
+	if pending, _ = storage.GetMessagesWithoutEmbedding("test", 10); len(pending) != 0 {
+		t.Fatalf("%d messages still pending", len(pending))
+	}
+
+	embeddings, err := storage.GetMessageEmbeddings("test", "u1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(embeddings) != 2 {
+		t.Fatalf("%d searchable embeddings, want 2 (the rejected message has none)", len(embeddings))
+	}
+	for _, e := range embeddings {
+		if e.MessageID == "poison" {
+			t.Fatal("rejected message is searchable")
+		}
+	}
+}
+
+func TestRecallNeedsUser(t *testing.T) {
+	r := &Router{}
+	for _, chatCtx := range []*ChatContext{nil, {ChatID: "c1"}} {
+		call := &BuiltinCall{Args: map[string]string{"query": "hello"}, ChatCtx: chatCtx}
+		if _, err := r.handleRecall(context.Background(), call); err == nil {
+			t.Fatalf("recall with chat context %+v searched, want an error", chatCtx)
+		}
+	}
+}
@@ -6,13 +6,18 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 
 	pb "github.com/fipso/chadbot/gen/chadbot"
+	"github.com/fipso/chadbot/internal/access"
 	"github.com/fipso/chadbot/internal/plugin"
 	"github.com/fipso/chadbot/internal/souls"
+	"github.com/fipso/chadbot/internal/storage"
 )
 
 // Provider represents an LLM provider
@@ -60,6 +65,7 @@ type ToolCallRecord struct {
 	Result    string            `json:"result"`
 	Error     string            `json:"error,omitempty"`
 	Duration  int64             `json:"duration_ms"`
+	Children  []ToolCallRecord  `json:"children,omitempty"` // Tool calls made by a delegated sub-agent
 }
 
 // ToolCallCallback is called when a tool call starts or completes
@@ -71,6 +77,7 @@ type ToolCallEvent struct {
 	ChatID    string            `json:"chat_id"`
 	ToolName  string            `json:"tool_name"`
 	ToolID    string            `json:"tool_id"`
+	ParentID  string            `json:"parent_id,omitempty"` // Tool ID of the delegate_task call this belongs to
 	Arguments map[string]string `json:"arguments,omitempty"`
 	Result    string            `json:"result,omitempty"`
 	Error     string            `json:"error,omitempty"`
@@ -94,6 +101,20 @@ type Router struct {
 	souls            *souls.Manager
 	defaultProvider  string
 	toolCallCallback ToolCallCallback
+
+	builtinsMu sync.RWMutex
+	builtins   map[string]*BuiltinSkill
+
+	toolSearch toolSearch
+	recall     *Recall
+	embedder   Embedder
+	memory     MemoryConfig
+	access     *access.Manager
+}
+
+// SetAccess sets the access policies that decide which skills a chat's user may use
+func (r *Router) SetAccess(m *access.Manager) {
+	r.access = m
 }
 
 // SetToolCallCallback sets a callback for tool call events
@@ -103,12 +124,22 @@ func (r *Router) SetToolCallCallback(cb ToolCallCallback) {
 
 // NewRouter creates a new LLM router
 func NewRouter(manager *plugin.Manager, registry *plugin.Registry, soulsManager *souls.Manager) *Router {
-	return &Router{
+	r := &Router{
 		providers: make(map[string]Provider),
 		manager:   manager,
 		registry:  registry,
 		souls:     soulsManager,
+		builtins:  make(map[string]*BuiltinSkill),
+	}
+	r.RegisterBuiltin(newDelegateSkill(r))
+	r.RegisterBuiltin(newReadDocsSkill(r))
+	r.RegisterBuiltin(newMemorySaveSkill(r))
+	r.RegisterBuiltin(newMemorySearchSkill(r))
+	r.RegisterBuiltin(newMemoryForgetSkill(r))
+	if storage.FTSEnabled() {
+		r.RegisterBuiltin(newChatSearchSkill())
 	}
+	return r
 }
 
 // RegisterProvider adds an LLM provider
@@ -129,6 +160,20 @@ func (r *Router) SetDefaultProvider(name string) error {
 	return nil
 }
 
+// HasProvider reports whether a provider is registered
+func (r *Router) HasProvider(name string) bool {
+	_, ok := r.providers[name]
+	return ok
+}
+
+// DefaultProviderFor returns a user's preferred provider if it is registered, else the default provider
+func (r *Router) DefaultProviderFor(preferred string) string {
+	if r.HasProvider(preferred) {
+		return preferred
+	}
+	return r.defaultProvider
+}
+
 // ProviderInfo contains information about an LLM provider
 type ProviderInfo struct {
 	Name      string `json:"name"`
@@ -137,11 +182,17 @@ type ProviderInfo struct {
 
 // ListProviders returns all registered providers
 func (r *Router) ListProviders() []ProviderInfo {
+	return r.ListProvidersFor("")
+}
+
+// ListProvidersFor returns all registered providers, marking a user's preferred provider as default
+func (r *Router) ListProvidersFor(preferred string) []ProviderInfo {
+	def := r.DefaultProviderFor(preferred)
 	result := make([]ProviderInfo, 0, len(r.providers))
 	for name := range r.providers {
 		result = append(result, ProviderInfo{
 			Name:      name,
-			IsDefault: name == r.defaultProvider,
+			IsDefault: name == def,
 		})
 	}
 	return result
@@ -158,28 +209,31 @@ When using tools to extract or gather data, be efficient with token usage:
 - Avoid redundant tool calls - plan your approach before executing
 - If a tool returns a large response, focus on the relevant parts in your answer`
 
-// buildSystemPrompt creates the base system prompt (without plugin docs)
-func (r *Router) buildSystemPrompt(soulName string) string {
+// buildSystemPrompt creates the system prompt for a user's soul followed by the plugin documentation index
+func (r *Router) buildSystemPrompt(userID, soulName string) string {
+	prompt := DefaultSystemPrompt
 	if r.souls != nil {
-		return r.souls.GetSystemPrompt(soulName)
+		prompt = r.souls.GetSystemPrompt(userID, soulName)
 	}
-	return DefaultSystemPrompt
-}
-
-// getPluginDocumentation returns formatted documentation for a plugin
-func (r *Router) getPluginDocumentation(pluginName string) string {
-	doc := r.manager.GetDocumentationByName(pluginName)
-	if doc == "" {
-		return ""
-	}
-	return fmt.Sprintf("\n\n---\n## Plugin: %s\n\n%s", pluginName, doc)
+	return prompt + r.buildDocsIndex()
 }
 
 // ChatContext contains context information for the chat session
 type ChatContext struct {
-	ChatID string
-	UserID string
-	Soul   string // Soul name for system prompt
+	ChatID   string
+	UserID   string
+	Platform string // Platform of the chat ("web", "whatsapp", ...), loaded by Chat if empty
+	Soul     string // Soul name for system prompt
+	Provider string // Provider handling the chat (set by Chat)
+
+	// Sub-agent settings (used by delegate_task)
+	AllowedTools []string // Tool name glob patterns; empty = all tools
+	MaxSteps     int      // Max LLM round-trips; 0 = unlimited
+	ParentToolID string   // Tool call ID of the parent delegate_task call
+	Depth        int      // Delegation depth (0 = top-level chat)
+
+	docsRead map[string]bool // Plugins whose docs were read during this request
+	subject  access.Subject  // Who the access policies are checked for
 }
 
 // Chat processes a chat request with tool calling loop
@@ -192,16 +246,40 @@ func (r *Router) Chat(ctx context.Context, messages []Message, providerName stri
 		return nil, fmt.Errorf("no LLM provider available")
 	}
 
-	// Prepend system prompt (without plugin docs - those are added on-demand)
-	soulName := ""
-	if chatCtx != nil {
-		soulName = chatCtx.Soul
+	// Work on a copy so the caller's context is not modified
+	if chatCtx == nil {
+		chatCtx = &ChatContext{}
+	} else {
+		cc := *chatCtx
+		chatCtx = &cc
+	}
+	chatCtx.Provider = provider.Name()
+	chatCtx.docsRead = make(map[string]bool)
+	if chatCtx.ChatID != "" && (chatCtx.UserID == "" || chatCtx.Platform == "") {
+		if chat, err := storage.GetChat(chatCtx.ChatID); err == nil {
+			if chatCtx.UserID == "" {
+				chatCtx.UserID = chat.UserID
+			}
+			if chatCtx.Platform == "" {
+				chatCtx.Platform = chat.Platform
+			}
+		}
+	}
+	if r.access != nil {
+		chatCtx.subject = access.NewSubject(chatCtx.UserID, chatCtx.Platform)
 	}
-	systemPrompt := r.buildSystemPrompt(soulName)
+
+	// Prepend system prompt (plugin docs are only indexed - the LLM pulls them via read_plugin_docs)
+	systemPrompt := r.buildSystemPrompt(chatCtx.UserID, chatCtx.Soul) + r.buildMemoryPrompt(ctx, chatCtx, messages)
+	userMsg := lastUserMessage(messages)
 	messages = append([]Message{{Role: "system", Content: systemPrompt}}, messages...)
 
-	// Convert skills to tools
-	tools := r.getTools()
+	// Convert skills to tools (in tool search mode only core and previously activated tools)
+	var activeTools map[string]bool
+	if r.toolSearch.config.Enabled {
+		activeTools = r.loadActiveTools(chatCtx)
+	}
+	tools := r.getTools(chatCtx, activeTools)
 
 	// Track deferred attachments from skill results
 	var deferredAttachments []DeferredAttachment
@@ -209,12 +287,15 @@ func (r *Router) Chat(ctx context.Context, messages []Message, providerName stri
 	// Track tool call records for the response
 	var toolCallRecords []ToolCallRecord
 
-	// Track which plugins have had their documentation injected
-	loadedPluginDocs := make(map[string]bool)
-
 	// Main conversation loop with tool calls
-	for {
-		resp, err := provider.Chat(ctx, messages, tools)
+	for step := 1; ; step++ {
+		// On the last allowed step, withhold tools so the model has to answer
+		stepTools := tools
+		if chatCtx.MaxSteps > 0 && step >= chatCtx.MaxSteps {
+			stepTools = nil
+		}
+
+		resp, err := provider.Chat(ctx, messages, stepTools)
 		if err != nil {
 			return nil, err
 		}
@@ -223,47 +304,10 @@ func (r *Router) Chat(ctx context.Context, messages []Message, providerName stri
 		if len(resp.ToolCalls) == 0 {
 			resp.DeferredAttachments = deferredAttachments
 			resp.ToolCallRecords = toolCallRecords
-			return resp, nil
-		}
-
-		// Check if any tool calls need plugin documentation injected first
-		needsDocInjection := false
-		var docsToInject []string
-		for _, tc := range resp.ToolCalls {
-			skill, ok := r.registry.GetSkill(tc.Name)
-			if !ok {
-				continue
+			if r.memory.AutoExtract && chatCtx.Depth == 0 && chatCtx.UserID != "" && userMsg != "" {
+				go r.extractMemories(provider, chatCtx, userMsg, resp.Content)
 			}
-			plugin, ok := r.manager.Get(skill.PluginID)
-			if !ok {
-				continue
-			}
-			pluginName := plugin.Name
-			if !loadedPluginDocs[pluginName] {
-				doc := r.getPluginDocumentation(pluginName)
-				if doc != "" {
-					docsToInject = append(docsToInject, doc)
-					loadedPluginDocs[pluginName] = true
-					needsDocInjection = true
-					log.Printf("[LLM Router] Injecting documentation for plugin: %s", pluginName)
-				}
-			}
-		}
-
-		// If we need to inject docs, add them to the system message and re-query
-		if needsDocInjection {
-			// Append plugin docs to system message
-			for i, m := range messages {
-				if m.Role == "system" {
-					for _, doc := range docsToInject {
-						messages[i].Content += doc
-					}
-					break
-				}
-			}
-			// Re-query the LLM with the documentation - don't add the tool calls yet
-			log.Printf("[LLM Router] Re-querying LLM with plugin documentation")
-			continue
+			return resp, nil
 		}
 
 		// Add assistant message with tool calls
@@ -278,22 +322,40 @@ func (r *Router) Chat(ctx context.Context, messages []Message, providerName stri
 			log.Printf("[LLM Router] Tool call: %s with args: %+v", tc.Name, tc.Arguments)
 
 			// Emit tool call start event
-			chatID := ""
-			if chatCtx != nil {
-				chatID = chatCtx.ChatID
-			}
 			if r.toolCallCallback != nil {
 				r.toolCallCallback(ToolCallEvent{
 					Type:      "start",
-					ChatID:    chatID,
+					ChatID:    chatCtx.ChatID,
 					ToolName:  tc.Name,
 					ToolID:    tc.ID,
+					ParentID:  chatCtx.ParentToolID,
 					Arguments: tc.Arguments,
 				})
 			}
 
 			startTime := time.Now()
-			result, err := r.invokeSkill(ctx, tc.Name, tc.Arguments, chatCtx)
+			var result string
+			var children []ToolCallRecord
+			if !toolAllowed(tc.Name, chatCtx) {
+				err = fmt.Errorf("tool %s is not available in this context", tc.Name)
+			} else if docsErr := r.checkDocsRead(tc.Name, chatCtx); docsErr != nil {
+				err = docsErr
+			} else if builtin, ok := r.GetBuiltin(tc.Name); ok {
+				var call *BuiltinCall
+				result, call, err = r.invokeBuiltin(ctx, builtin, tc.ID, tc.Arguments, chatCtx)
+				children = call.Children
+				deferredAttachments = append(deferredAttachments, call.DeferredAttachments...)
+				if len(call.ActivateTools) > 0 && activeTools != nil {
+					for _, name := range call.ActivateTools {
+						activeTools[name] = true
+					}
+					tools = r.getTools(chatCtx, activeTools)
+					r.saveActiveTools(chatCtx, activeTools)
+					log.Printf("[LLM Router] Activated tools: %v", call.ActivateTools)
+				}
+			} else {
+				result, err = r.invokeSkill(ctx, tc.Name, tc.Arguments, chatCtx)
+			}
 			duration := time.Since(startTime).Milliseconds()
 
 			// Record tool call
@@ -302,6 +364,7 @@ func (r *Router) Chat(ctx context.Context, messages []Message, providerName stri
 				Name:      tc.Name,
 				Arguments: tc.Arguments,
 				Duration:  duration,
+				Children:  children,
 			}
 
 			if err != nil {
@@ -316,9 +379,10 @@ func (r *Router) Chat(ctx context.Context, messages []Message, providerName stri
 			if r.toolCallCallback != nil {
 				r.toolCallCallback(ToolCallEvent{
 					Type:     "complete",
-					ChatID:   chatID,
+					ChatID:   chatCtx.ChatID,
 					ToolName: tc.Name,
 					ToolID:   tc.ID,
+					ParentID: chatCtx.ParentToolID,
 					Result:   result,
 					Error:    record.Error,
 					Duration: duration,
@@ -471,12 +535,26 @@ func pruneToolHistory(messages []Message, maxExchanges int) []Message {
 	return keptMessages
 }
 
-// getTools converts registered skills to LLM tools
-func (r *Router) getTools() []Tool {
-	skills := r.registry.GetSkillsForLLM()
-	tools := make([]Tool, len(skills))
+// getTools converts registered skills and built-in skills to LLM tools.
+// If active is non-nil (tool search mode), only core tools and active tools are included.
+func (r *Router) getTools(chatCtx *ChatContext, active map[string]bool) []Tool {
+	skills := r.registry.ListSkills()
+	tools := make([]Tool, 0, len(skills))
+	var hidden []string
+
+	for _, rs := range skills {
+		skill := rs.Skill
+		if !toolAllowed(skill.Name, chatCtx) {
+			continue
+		}
+		if !r.skillPermitted(skill.Name, rs.PluginName, chatCtx) {
+			hidden = append(hidden, skill.Name)
+			continue
+		}
+		if active != nil && !active[skill.Name] && !r.isCoreTool(skill.Name) {
+			continue
+		}
 
-	for i, skill := range skills {
 		params := make(map[string]interface{})
 		properties := make(map[string]interface{})
 		required := []string{}
@@ -495,22 +573,69 @@ func (r *Router) getTools() []Tool {
 		params["properties"] = properties
 		params["required"] = required
 
-		tools[i] = Tool{
+		tools = append(tools, Tool{
 			Name:        skill.Name,
 			Description: skill.Description,
 			Parameters:  params,
+		})
+	}
+
+	for _, b := range r.ListBuiltins() {
+		if !toolAllowed(b.Tool.Name, chatCtx) {
+			continue
 		}
+		if !r.skillPermitted(b.Tool.Name, BuiltinPluginName, chatCtx) {
+			hidden = append(hidden, b.Tool.Name)
+			continue
+		}
+		// Sub-agents cannot delegate further than maxDelegateDepth
+		if b.Tool.Name == DelegateSkillName && chatCtx != nil && chatCtx.Depth >= maxDelegateDepth {
+			continue
+		}
+		tools = append(tools, b.Tool)
 	}
 
+	if len(hidden) > 0 {
+		sort.Strings(hidden)
+		log.Printf("[Access] Hid %d skills from %s: %s", len(hidden), chatSubject(chatCtx), strings.Join(hidden, ", "))
+	}
 	return tools
 }
 
+// chatSubject returns who the access policies are checked for. Without a chat it is a
+// subject without a user, which policies deny.
+func chatSubject(chatCtx *ChatContext) access.Subject {
+	if chatCtx == nil {
+		return access.Subject{}
+	}
+	return chatCtx.subject
+}
+
+// skillPermitted reports whether the access policies let the chat's user see a skill
+func (r *Router) skillPermitted(name, pluginName string, chatCtx *ChatContext) bool {
+	if r.access == nil {
+		return true
+	}
+	return r.access.Allowed(chatSubject(chatCtx), name, pluginName)
+}
+
+// checkAccess enforces the access policies on a skill invocation
+func (r *Router) checkAccess(name, pluginName string, chatCtx *ChatContext) error {
+	if r.access == nil {
+		return nil
+	}
+	return r.access.Check(chatSubject(chatCtx), name, pluginName)
+}
+
 // invokeSkill invokes a skill on a plugin
 func (r *Router) invokeSkill(ctx context.Context, skillName string, args map[string]string, chatCtx *ChatContext) (string, error) {
 	skill, ok := r.registry.GetSkill(skillName)
 	if !ok {
 		return "", fmt.Errorf("skill %s not found", skillName)
 	}
+	if err := r.checkAccess(skillName, skill.PluginName, chatCtx); err != nil {
+		return "", err
+	}
 
 	plugin, ok := r.manager.Get(skill.PluginID)
 	if !ok {
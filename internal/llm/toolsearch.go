@@ -0,0 +1,284 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/fipso/chadbot/internal/storage"
+)
+
+const (
+	// ToolSearchSkillName is the meta-skill used to discover tools in tool search mode
+	ToolSearchSkillName = "search_tools"
+
+	defaultToolSearchResults = 5
+	maxToolSearchResults     = 15
+)
+
+// ToolSearchConfig configures lazy tool discovery
+type ToolSearchConfig struct {
+	Enabled    bool
+	CoreTools  []string // Tool name glob patterns that are always sent
+	MaxResults int      // Tools activated per search (default 5)
+}
+
+// toolSearch holds the tool search state of a Router
+type toolSearch struct {
+	config ToolSearchConfig
+
+	mu         sync.Mutex
+	embeddings map[string][]float32 // "name\x00description" -> vector
+}
+
+// toolCandidate is a searchable tool from the catalogue
+type toolCandidate struct {
+	Name        string
+	Description string
+	PluginName  string
+}
+
+// toolMatch is a scored search result
+type toolMatch struct {
+	toolCandidate
+	Score float64
+}
+
+// SetToolSearch enables or disables lazy tool discovery.
+// When enabled only core tools, built-ins and tools found via search_tools are sent to the LLM.
+func (r *Router) SetToolSearch(cfg ToolSearchConfig) {
+	if cfg.MaxResults <= 0 {
+		cfg.MaxResults = defaultToolSearchResults
+	}
+	r.toolSearch.config = cfg
+	if cfg.Enabled {
+		r.RegisterBuiltin(newToolSearchSkill(r))
+		log.Printf("[LLM Router] Tool search enabled (core tools: %v)", cfg.CoreTools)
+	}
+}
+
+// newToolSearchSkill creates the search_tools built-in skill
+func newToolSearchSkill(r *Router) *BuiltinSkill {
+	return &BuiltinSkill{
+		Tool: Tool{
+			Name: ToolSearchSkillName,
+			Description: "Search the catalogue of available tools by keyword. Only a few core tools are loaded by default; " +
+				"call this whenever you need a capability you don't currently have (e.g. \"send whatsapp message\", \"mqtt publish\"). " +
+				"Matching tools become available for the rest of the conversation.",
+			Parameters: ObjectSchema(map[string]string{
+				"query": "What you want to do, in a few keywords",
+				"limit": fmt.Sprintf("Optional number of tools to load (default %d, max %d)", defaultToolSearchResults, maxToolSearchResults),
+			}, "query"),
+		},
+		Handler: r.handleToolSearch,
+	}
+}
+
+// handleToolSearch scores the catalogue and activates the best matches
+func (r *Router) handleToolSearch(ctx context.Context, call *BuiltinCall) (string, error) {
+	query := strings.TrimSpace(call.Args["query"])
+	if query == "" {
+		return "", errBuiltinArg(ToolSearchSkillName, "query")
+	}
+
+	limit := r.toolSearch.config.MaxResults
+	if v := call.Args["limit"]; v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			limit = min(n, maxToolSearchResults)
+		}
+	}
+
+	matches := r.searchTools(ctx, query, call.ChatCtx, limit)
+	if len(matches) == 0 {
+		return "No matching tools found. Try different keywords.", nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString("The following tools are now available:\n")
+	for _, m := range matches {
+		call.ActivateTools = append(call.ActivateTools, m.Name)
+		fmt.Fprintf(&sb, "- %s (%s): %s\n", m.Name, m.PluginName, m.Description)
+	}
+	return sb.String(), nil
+}
+
+// searchTools ranks all tools not in the core set by keyword score, blended with embeddings if available
+func (r *Router) searchTools(ctx context.Context, query string, chatCtx *ChatContext, limit int) []toolMatch {
+	var candidates []toolCandidate
+	for _, rs := range r.registry.ListSkills() {
+		if r.isCoreTool(rs.Skill.Name) || !toolAllowed(rs.Skill.Name, chatCtx) || !r.skillPermitted(rs.Skill.Name, rs.PluginName, chatCtx) {
+			continue
+		}
+		candidates = append(candidates, toolCandidate{
+			Name:        rs.Skill.Name,
+			Description: rs.Skill.Description,
+			PluginName:  rs.PluginName,
+		})
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	terms := tokenize(query)
+	matches := make([]toolMatch, len(candidates))
+	maxScore := 0.0
+	for i, c := range candidates {
+		matches[i] = toolMatch{toolCandidate: c, Score: keywordScore(terms, c)}
+		maxScore = max(maxScore, matches[i].Score)
+	}
+
+	// Normalize keyword scores to 0..1 and blend with semantic similarity
+	semantic := r.semanticScores(ctx, query, candidates)
+	for i := range matches {
+		kw := 0.0
+		if maxScore > 0 {
+			kw = matches[i].Score / maxScore
+		}
+		if semantic != nil {
+			matches[i].Score = 0.5*kw + 0.5*semantic[i]
+		} else {
+			matches[i].Score = kw
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+
+	var result []toolMatch
+	for _, m := range matches {
+		if m.Score <= 0 || len(result) >= limit {
+			break
+		}
+		result = append(result, m)
+	}
+	return result
+}
+
+// semanticScores returns cosine similarities between the query and each candidate (nil without embedder)
+func (r *Router) semanticScores(ctx context.Context, query string, candidates []toolCandidate) []float64 {
+	ts := &r.toolSearch
+	if r.embedder == nil {
+		return nil
+	}
+
+	ts.mu.Lock()
+	if ts.embeddings == nil {
+		ts.embeddings = make(map[string][]float32)
+	}
+	var missingKeys, missingTexts []string
+	for _, c := range candidates {
+		key := c.Name + "\x00" + c.Description
+		if _, ok := ts.embeddings[key]; !ok {
+			missingKeys = append(missingKeys, key)
+			missingTexts = append(missingTexts, c.Name+": "+c.Description)
+		}
+	}
+	ts.mu.Unlock()
+
+	vectors, err := r.embedder.Embed(ctx, append([]string{query}, missingTexts...))
+	if err != nil || len(vectors) != len(missingTexts)+1 {
+		log.Printf("[LLM Router] Tool search embedding failed, using keywords only: %v", err)
+		return nil
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	for i, key := range missingKeys {
+		ts.embeddings[key] = vectors[i+1]
+	}
+
+	scores := make([]float64, len(candidates))
+	for i, c := range candidates {
+		scores[i] = max(0, CosineSimilarity(vectors[0], ts.embeddings[c.Name+"\x00"+c.Description]))
+	}
+	return scores
+}
+
+// keywordScore scores a tool against query terms (name matches weigh more than description matches)
+func keywordScore(terms []string, c toolCandidate) float64 {
+	nameTerms := tokenize(c.Name)
+	pluginTerms := tokenize(c.PluginName)
+	descTerms := tokenize(c.Description)
+
+	score := 0.0
+	for _, t := range terms {
+		score += 3 * termHits(t, nameTerms)
+		score += 2 * termHits(t, pluginTerms)
+		score += termHits(t, descTerms)
+	}
+	return score
+}
+
+// termHits returns 1 for an exact term match, 0.5 for a prefix match and 0 otherwise
+func termHits(term string, words []string) float64 {
+	best := 0.0
+	for _, w := range words {
+		if w == term {
+			return 1
+		}
+		if len(term) >= 3 && (strings.HasPrefix(w, term) || (strings.HasPrefix(term, w) && len(w) >= 3)) {
+			best = 0.5
+		}
+	}
+	return best
+}
+
+// tokenize lowercases and splits text into words (underscores and punctuation separate words)
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	})
+}
+
+// isCoreTool reports whether a tool is always sent in tool search mode
+func (r *Router) isCoreTool(name string) bool {
+	for _, pattern := range r.toolSearch.config.CoreTools {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// loadActiveTools loads the tools a chat has activated via search_tools
+func (r *Router) loadActiveTools(chatCtx *ChatContext) map[string]bool {
+	active := make(map[string]bool)
+	if chatCtx.ChatID == "" {
+		return active
+	}
+	data, err := storage.GetChatActiveTools(chatCtx.ChatID)
+	if err != nil || data == "" {
+		return active
+	}
+	var names []string
+	if err := json.Unmarshal([]byte(data), &names); err != nil {
+		return active
+	}
+	for _, name := range names {
+		active[name] = true
+	}
+	return active
+}
+
+// saveActiveTools remembers the activated tools for a chat (sub-agents don't persist theirs)
+func (r *Router) saveActiveTools(chatCtx *ChatContext, active map[string]bool) {
+	if chatCtx.ChatID == "" || chatCtx.Depth > 0 {
+		return
+	}
+	names := make([]string, 0, len(active))
+	for name := range active {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	data, _ := json.Marshal(names)
+	if err := storage.SetChatActiveTools(chatCtx.ChatID, string(data)); err != nil {
+		log.Printf("[LLM Router] Failed to save active tools for chat %s: %v", chatCtx.ChatID, err)
+	}
+}
@@ -0,0 +1,123 @@
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+
+	pb "github.com/fipso/chadbot/gen/chadbot"
+	"github.com/fipso/chadbot/internal/config"
+)
+
+// Ways a plugin can prove its identity when it registers
+const (
+	AuthToken    = "token"    // Token derived from the plugin name (config.PluginToken)
+	AuthPeerCred = "peercred" // Unix user of the plugin process, checked against an allowlist
+	AuthNone     = "none"     // Any process that can open the socket
+)
+
+// What happens when a plugin registers under the name of a connected plugin
+const (
+	DuplicateReject  = "reject"  // The new connection is refused
+	DuplicateReplace = "replace" // The old connection is closed
+)
+
+// AuthConfig controls which processes may register as plugins
+type AuthConfig struct {
+	Methods    []string // Accepted methods, any of which suffices (default token)
+	UIDs       []uint32 // Users allowed by peercred (default the backend's own user)
+	Duplicates string   // DuplicateReject (default) or DuplicateReplace
+}
+
+// ParseAuthMethods splits and validates a comma-separated list of methods
+func ParseAuthMethods(s string) ([]string, error) {
+	var methods []string
+	for _, m := range strings.Split(s, ",") {
+		switch m = strings.TrimSpace(m); m {
+		case "":
+		case AuthToken, AuthPeerCred, AuthNone:
+			methods = append(methods, m)
+		default:
+			return nil, fmt.Errorf("unknown plugin auth method %q (token, peercred or none)", m)
+		}
+	}
+	return methods, nil
+}
+
+// PeerCred is the gRPC auth info of a Unix socket connection: the plugin process's credentials
+type PeerCred struct {
+	credentials.CommonAuthInfo
+	PID int32
+	UID uint32
+	GID uint32
+}
+
+// AuthType implements credentials.AuthInfo
+func (PeerCred) AuthType() string {
+	return AuthPeerCred
+}
+
+// SetAuth sets how plugins are authenticated
+func (h *Handler) SetAuth(cfg AuthConfig) {
+	if len(cfg.Methods) == 0 {
+		cfg.Methods = []string{AuthToken}
+	}
+	if len(cfg.UIDs) == 0 {
+		cfg.UIDs = []uint32{uint32(os.Getuid())}
+	}
+	if cfg.Duplicates == "" {
+		cfg.Duplicates = DuplicateReject
+	}
+	h.auth = cfg
+}
+
+// authenticate checks that the process behind a stream may register under the requested name
+func (h *Handler) authenticate(req *pb.RegisterRequest, stream pb.PluginService_ConnectServer) error {
+	var reasons []string
+	for _, method := range h.auth.Methods {
+		switch method {
+		case AuthNone:
+			return nil
+
+		case AuthToken:
+			ok, err := config.VerifyPluginToken(req.Name, req.Token)
+			if err != nil {
+				return err
+			}
+			if ok {
+				return nil
+			}
+			if req.Token == "" {
+				reasons = append(reasons, "no token")
+			} else {
+				reasons = append(reasons, "invalid token")
+			}
+
+		case AuthPeerCred:
+			cred, ok := peerCred(stream)
+			if !ok {
+				reasons = append(reasons, "no peer credentials")
+				continue
+			}
+			if slices.Contains(h.auth.UIDs, cred.UID) {
+				return nil
+			}
+			reasons = append(reasons, fmt.Sprintf("uid %d (pid %d) not allowed", cred.UID, cred.PID))
+		}
+	}
+	return fmt.Errorf("plugin %s not authenticated: %s", req.Name, strings.Join(reasons, ", "))
+}
+
+// peerCred returns the credentials of the process behind a stream
+func peerCred(stream pb.PluginService_ConnectServer) (PeerCred, bool) {
+	p, ok := peer.FromContext(stream.Context())
+	if !ok {
+		return PeerCred{}, false
+	}
+	cred, ok := p.AuthInfo.(PeerCred)
+	return cred, ok
+}
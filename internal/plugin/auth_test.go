@@ -0,0 +1,107 @@
+package plugin
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/peer"
+
+	pb "github.com/fipso/chadbot/gen/chadbot"
+	"github.com/fipso/chadbot/internal/config"
+	"github.com/fipso/chadbot/internal/event"
+)
+
+// testStream is a plugin stream whose context carries the given peer
+type testStream struct {
+	pb.PluginService_ConnectServer
+	ctx context.Context
+}
+
+func (s testStream) Context() context.Context {
+	return s.ctx
+}
+
+func streamFrom(cred *PeerCred) testStream {
+	ctx := context.Background()
+	if cred != nil {
+		ctx = peer.NewContext(ctx, &peer.Peer{AuthInfo: *cred})
+	}
+	return testStream{ctx: ctx}
+}
+
+func TestAuthenticate(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	token, err := config.PluginToken("notes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherToken, err := config.PluginToken("other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	allowed := &PeerCred{PID: 10, UID: 1000}
+	stranger := &PeerCred{PID: 11, UID: 1001}
+
+	tests := []struct {
+		name    string
+		methods []string
+		token   string
+		cred    *PeerCred
+		ok      bool
+	}{
+		{"valid token", []string{AuthToken}, token, nil, true},
+		{"no token", []string{AuthToken}, "", nil, false},
+		{"token of another plugin", []string{AuthToken}, otherToken, nil, false},
+		{"garbage token", []string{AuthToken}, "not-a-token", nil, false},
+		{"allowed uid", []string{AuthPeerCred}, "", allowed, true},
+		{"other uid", []string{AuthPeerCred}, "", stranger, false},
+		{"no peer credentials", []string{AuthPeerCred}, "", nil, false},
+		{"token or peercred, token only", []string{AuthPeerCred, AuthToken}, token, stranger, true},
+		{"token or peercred, uid only", []string{AuthToken, AuthPeerCred}, "", allowed, true},
+		{"token or peercred, neither", []string{AuthToken, AuthPeerCred}, otherToken, stranger, false},
+		{"none", []string{AuthNone}, "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &Handler{}
+			h.SetAuth(AuthConfig{Methods: tt.methods, UIDs: []uint32{1000}})
+			err := h.authenticate(&pb.RegisterRequest{Name: "notes", Token: tt.token}, streamFrom(tt.cred))
+			if (err == nil) != tt.ok {
+				t.Fatalf("authenticate: %v, want ok=%v", err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestParseAuthMethods(t *testing.T) {
+	methods, err := ParseAuthMethods(" token, peercred ,")
+	if err != nil || len(methods) != 2 || methods[0] != AuthToken || methods[1] != AuthPeerCred {
+		t.Fatalf("got %v, %v", methods, err)
+	}
+	if _, err := ParseAuthMethods("token,password"); err == nil {
+		t.Fatal("unknown method accepted")
+	}
+}
+
+func TestRegisterDuplicates(t *testing.T) {
+	m := NewManager(NewRegistry(), event.NewBus())
+	first, err := m.Register("1", "notes", "1.0", "", nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Register("2", "notes", "1.0", "", nil, false); err == nil {
+		t.Fatal("second connection under the same name accepted")
+	}
+
+	if _, err := m.Register("3", "notes", "1.0", "", nil, true); err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+	select {
+	case <-first.Closed():
+	default:
+		t.Fatal("replaced connection not closed")
+	}
+	if p, ok := m.GetByName("notes"); !ok || p.ID != "3" {
+		t.Fatalf("plugin notes is %+v, want the new connection", p)
+	}
+}
@@ -4,31 +4,45 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"slices"
+	"strings"
 
 	"github.com/google/uuid"
 
 	pb "github.com/fipso/chadbot/gen/chadbot"
+	"github.com/fipso/chadbot/internal/access"
+	"github.com/fipso/chadbot/internal/auth"
 	"github.com/fipso/chadbot/internal/chat"
 	"github.com/fipso/chadbot/internal/config"
 	"github.com/fipso/chadbot/internal/storage"
 )
 
+// ChatOwnerConfigKey is the plugin config key (config.toml) naming the user, by username
+// or ID, who owns the chats a plugin links. The bootstrap admin owns them if it is unset:
+//
+//	[plugins.whatsapp]
+//	chat_owner = "alice"
+const ChatOwnerConfigKey = "chat_owner"
+
 // Handler handles gRPC stream connections from plugins
 type Handler struct {
 	manager        *Manager
 	chatService    *chat.Service
 	pluginStorages map[string]*storage.PluginStorage
 	pluginConfig   *config.PluginConfigManager
+	auth           AuthConfig
 }
 
 // NewHandler creates a new plugin handler
 func NewHandler(manager *Manager, chatService *chat.Service, pluginConfig *config.PluginConfigManager) *Handler {
-	return &Handler{
+	h := &Handler{
 		manager:        manager,
 		chatService:    chatService,
 		pluginStorages: make(map[string]*storage.PluginStorage),
 		pluginConfig:   pluginConfig,
 	}
+	h.SetAuth(AuthConfig{})
+	return h
 }
 
 // HandleConnection processes a plugin's bidirectional stream
@@ -42,8 +56,37 @@ func (h *Handler) HandleConnection(stream pb.PluginService_ConnectServer) error
 		}
 	}()
 
+	// Receive in the background so the connection can be closed when it is replaced
+	type received struct {
+		msg *pb.PluginMessage
+		err error
+	}
+	recv := make(chan received)
+	go func() {
+		for {
+			msg, err := stream.Recv()
+			select {
+			case recv <- received{msg, err}:
+			case <-stream.Context().Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	var replaced <-chan struct{}
 	for {
-		msg, err := stream.Recv()
+		var r received
+		select {
+		case r = <-recv:
+		case <-replaced:
+			log.Printf("[Handler] Closing replaced connection of plugin %s (id: %s)", plugin.Name, pluginID)
+			pluginID = ""
+			return fmt.Errorf("plugin %s connected again", plugin.Name)
+		}
+		msg, err := r.msg, r.err
 		if err == io.EOF {
 			log.Printf("[Handler] Plugin stream closed (id: %s)", pluginID)
 			return nil
@@ -55,10 +98,15 @@ func (h *Handler) HandleConnection(stream pb.PluginService_ConnectServer) error
 
 		switch payload := msg.Payload.(type) {
 		case *pb.PluginMessage_Register:
+			if plugin != nil {
+				h.sendError(stream, 1, "Already registered", "")
+				continue
+			}
 			pluginID, plugin, err = h.handleRegister(payload.Register, stream)
 			if err != nil {
 				return err
 			}
+			replaced = plugin.Closed()
 
 		case *pb.PluginMessage_SkillRegister:
 			if plugin == nil {
@@ -72,13 +120,17 @@ func (h *Handler) HandleConnection(stream pb.PluginService_ConnectServer) error
 				h.sendError(stream, 1, "Must register before subscribing to events", "")
 				continue
 			}
-			h.handleEventSubscribe(pluginID, payload.EventSubscribe)
+			h.handleEventSubscribe(plugin, payload.EventSubscribe, stream)
 
 		case *pb.PluginMessage_EventEmit:
 			if plugin == nil {
 				h.sendError(stream, 1, "Must register before emitting events", "")
 				continue
 			}
+			if err := h.checkPermission(plugin, PermEventsEmit, payload.EventEmit.GetEvent().GetEventType()); err != nil {
+				h.sendError(stream, 403, err.Error(), "")
+				continue
+			}
 			h.handleEventEmit(pluginID, payload.EventEmit)
 
 		case *pb.PluginMessage_SkillResponse:
@@ -93,6 +145,14 @@ func (h *Handler) HandleConnection(stream pb.PluginService_ConnectServer) error
 				h.sendError(stream, 1, "Must register before using storage", "")
 				continue
 			}
+			if err := h.checkPermission(plugin, PermStorage, ""); err != nil {
+				stream.Send(&pb.BackendMessage{
+					Payload: &pb.BackendMessage_StorageResponse{StorageResponse: &pb.StorageResponse{
+						RequestId: payload.StorageRequest.RequestId, Error: err.Error(),
+					}},
+				})
+				continue
+			}
 			h.handleStorageRequest(pluginID, payload.StorageRequest, stream)
 
 		case *pb.PluginMessage_ChatGetOrCreate:
@@ -100,6 +160,18 @@ func (h *Handler) HandleConnection(stream pb.PluginService_ConnectServer) error
 				h.sendError(stream, 1, "Must register before using chat service", "")
 				continue
 			}
+			if err := h.checkPermission(plugin, PermChatWrite, ""); err != nil {
+				stream.Send(&pb.BackendMessage{
+					Payload: &pb.BackendMessage_ChatGetOrCreateResponse{ChatGetOrCreateResponse: &pb.ChatGetOrCreateResponse{
+						RequestId: payload.ChatGetOrCreate.RequestId, Error: err.Error(),
+					}},
+				})
+				continue
+			}
+			// The owner is configured by the user, whatever user the plugin asks for,
+			// and plugins only link chats of their own platform
+			payload.ChatGetOrCreate.UserId = h.chatOwner(plugin.Name)
+			payload.ChatGetOrCreate.Platform = plugin.Name
 			resp := h.chatService.HandleGetOrCreate(payload.ChatGetOrCreate)
 			stream.Send(&pb.BackendMessage{
 				Payload: &pb.BackendMessage_ChatGetOrCreateResponse{ChatGetOrCreateResponse: resp},
@@ -110,7 +182,15 @@ func (h *Handler) HandleConnection(stream pb.PluginService_ConnectServer) error
 				h.sendError(stream, 1, "Must register before using chat service", "")
 				continue
 			}
-			resp := h.chatService.HandleAddMessage(payload.ChatAddMessage)
+			if err := h.checkPermission(plugin, PermChatWrite, ""); err != nil {
+				stream.Send(&pb.BackendMessage{
+					Payload: &pb.BackendMessage_ChatAddMessageResponse{ChatAddMessageResponse: &pb.ChatAddMessageResponse{
+						RequestId: payload.ChatAddMessage.RequestId, Error: err.Error(),
+					}},
+				})
+				continue
+			}
+			resp := h.chatService.HandleAddMessage(plugin.Name, payload.ChatAddMessage)
 			stream.Send(&pb.BackendMessage{
 				Payload: &pb.BackendMessage_ChatAddMessageResponse{ChatAddMessageResponse: resp},
 			})
@@ -120,20 +200,36 @@ func (h *Handler) HandleConnection(stream pb.PluginService_ConnectServer) error
 				h.sendError(stream, 1, "Must register before using chat service", "")
 				continue
 			}
+			if err := h.checkPermission(plugin, PermLLMRequest, ""); err != nil {
+				stream.Send(&pb.BackendMessage{
+					Payload: &pb.BackendMessage_ChatLlmResponse{ChatLlmResponse: &pb.ChatLLMResponse{
+						RequestId: payload.ChatLlmRequest.RequestId, Error: err.Error(),
+					}},
+				})
+				continue
+			}
 			// Run LLM request in goroutine to not block stream
-			go func(req *pb.ChatLLMRequest) {
-				resp := h.chatService.HandleLLMRequest(req)
+			go func(platform string, req *pb.ChatLLMRequest) {
+				resp := h.chatService.HandleLLMRequest(platform, req)
 				stream.Send(&pb.BackendMessage{
 					Payload: &pb.BackendMessage_ChatLlmResponse{ChatLlmResponse: resp},
 				})
-			}(payload.ChatLlmRequest)
+			}(plugin.Name, payload.ChatLlmRequest)
 
 		case *pb.PluginMessage_ChatGetMessages:
 			if plugin == nil {
 				h.sendError(stream, 1, "Must register before using chat service", "")
 				continue
 			}
-			resp := h.chatService.HandleGetMessages(payload.ChatGetMessages)
+			if err := h.checkPermission(plugin, PermChatRead, ""); err != nil {
+				stream.Send(&pb.BackendMessage{
+					Payload: &pb.BackendMessage_ChatGetMessagesResponse{ChatGetMessagesResponse: &pb.ChatGetMessagesResponse{
+						RequestId: payload.ChatGetMessages.RequestId, Error: err.Error(),
+					}},
+				})
+				continue
+			}
+			resp := h.chatService.HandleGetMessages(plugin.Name, payload.ChatGetMessages)
 			stream.Send(&pb.BackendMessage{
 				Payload: &pb.BackendMessage_ChatGetMessagesResponse{ChatGetMessagesResponse: resp},
 			})
@@ -150,14 +246,14 @@ func (h *Handler) HandleConnection(stream pb.PluginService_ConnectServer) error
 				h.sendError(stream, 1, "Must register before getting config", "")
 				continue
 			}
-			h.handleConfigGet(plugin.Name, payload.ConfigGet, stream)
+			h.handleConfigGet(plugin, payload.ConfigGet, stream)
 
 		case *pb.PluginMessage_Documentation:
 			if plugin == nil {
 				h.sendError(stream, 1, "Must register before setting documentation", "")
 				continue
 			}
-			h.manager.SetDocumentation(pluginID, payload.Documentation.Content)
+			h.manager.SetDocumentation(pluginID, payload.Documentation.Content, payload.Documentation.Required)
 
 		default:
 			log.Printf("[Handler] Unknown message type from plugin %s", pluginID)
@@ -165,26 +261,75 @@ func (h *Handler) HandleConnection(stream pb.PluginService_ConnectServer) error
 	}
 }
 
+// handleRegister authenticates a plugin and registers it, telling the plugin the outcome
 func (h *Handler) handleRegister(req *pb.RegisterRequest, stream pb.PluginService_ConnectServer) (string, *Plugin, error) {
 	pluginID := uuid.New().String()
-	plugin := h.manager.Register(pluginID, req.Name, req.Version, req.Description, stream)
+	plugin, err := h.register(pluginID, req, stream)
+	if err != nil {
+		log.Printf("[Handler] Rejected registration: %v", err)
+		stream.Send(&pb.BackendMessage{
+			Payload: &pb.BackendMessage_RegisterResponse{
+				RegisterResponse: &pb.RegisterResponse{Success: false, Message: err.Error()},
+			},
+		})
+		return "", nil, err
+	}
 
-	err := stream.Send(&pb.BackendMessage{
+	// Declared permissions are only granted once a user approves them
+	approved, err := storage.GetPluginGrants(req.Name)
+	if err != nil {
+		log.Printf("[Handler] Failed to load permissions of plugin %s: %v", req.Name, err)
+	}
+	pending := h.manager.SetPermissions(pluginID, req.Permissions, approved)
+	if len(pending) > 0 {
+		log.Printf("[Handler] Plugin %s awaits approval of: %s", req.Name, strings.Join(pending, ", "))
+	}
+
+	err = stream.Send(&pb.BackendMessage{
 		Payload: &pb.BackendMessage_RegisterResponse{
 			RegisterResponse: &pb.RegisterResponse{
-				PluginId: pluginID,
-				Success:  true,
-				Message:  fmt.Sprintf("Plugin %s registered successfully", req.Name),
+				PluginId:           pluginID,
+				Success:            true,
+				Message:            fmt.Sprintf("Plugin %s registered successfully", req.Name),
+				PendingPermissions: pending,
 			},
 		},
 	})
 	if err != nil {
+		h.manager.Unregister(pluginID)
 		return "", nil, err
 	}
 
 	return pluginID, plugin, nil
 }
 
+// reservedPluginNames are the platforms of chats created by the app. Plugins reach
+// the chats of the platform named like them, so none may take these names.
+var reservedPluginNames = []string{access.WebPlatform, "pwa"}
+
+// register checks a plugin's identity and adds it to the manager
+func (h *Handler) register(pluginID string, req *pb.RegisterRequest, stream pb.PluginService_ConnectServer) (*Plugin, error) {
+	if req.Name == "" {
+		return nil, fmt.Errorf("plugin name is required")
+	}
+	if slices.Contains(reservedPluginNames, req.Name) {
+		return nil, fmt.Errorf("plugin name %q is reserved", req.Name)
+	}
+	if err := h.authenticate(req, stream); err != nil {
+		return nil, err
+	}
+	for _, p := range req.Permissions {
+		if err := ValidatePermission(p); err != nil {
+			return nil, err
+		}
+	}
+	// Tables left under the prefix of an older version would be shadowed by new ones
+	if err := storage.MigrateLegacyPluginPrefix(req.Name); err != nil {
+		return nil, fmt.Errorf("move storage tables: %w", err)
+	}
+	return h.manager.Register(pluginID, req.Name, req.Version, req.Description, stream, h.auth.Duplicates == DuplicateReplace)
+}
+
 func (h *Handler) handleSkillRegister(pluginID string, req *pb.SkillRegister) {
 	registry := h.manager.Registry()
 	plugin, ok := h.manager.Get(pluginID)
@@ -199,9 +344,21 @@ func (h *Handler) handleSkillRegister(pluginID string, req *pb.SkillRegister) {
 	}
 }
 
-func (h *Handler) handleEventSubscribe(pluginID string, req *pb.EventSubscribe) {
-	h.manager.SubscribePlugin(pluginID, req.EventTypes)
-	log.Printf("[Handler] Plugin %s subscribed to: %v", pluginID, req.EventTypes)
+// handleEventSubscribe subscribes a plugin to the requested patterns it was granted
+func (h *Handler) handleEventSubscribe(plugin *Plugin, req *pb.EventSubscribe, stream pb.PluginService_ConnectServer) {
+	var patterns []string
+	for _, pattern := range req.EventTypes {
+		if err := h.checkPermission(plugin, PermEventsSubscribe, pattern); err != nil {
+			h.sendError(stream, 403, err.Error(), "")
+			continue
+		}
+		patterns = append(patterns, pattern)
+	}
+	if len(patterns) == 0 {
+		return
+	}
+	h.manager.SubscribePlugin(plugin.ID, patterns)
+	log.Printf("[Handler] Plugin %s subscribed to: %v", plugin.ID, patterns)
 }
 
 func (h *Handler) handleEventEmit(pluginID string, req *pb.EventEmit) {
@@ -241,6 +398,10 @@ func (h *Handler) handleStorageRequest(pluginID string, req *pb.StorageRequest,
 		ps = storage.NewPluginStorage(plugin.Name)
 		h.pluginStorages[plugin.Name] = ps
 	}
+	// Raw SQL escapes the plugin's namespace and quotas limit it, so only the user can set them
+	configs := h.pluginConfig.GetPluginConfigs(plugin.Name)
+	ps.SetRawSQL(configs[storage.RawSQLConfigKey] == "true")
+	ps.SetQuota(storage.QuotaFromConfig(configs))
 
 	// Process the request
 	resp := ps.HandleRequest(req)
@@ -253,6 +414,22 @@ func (h *Handler) handleStorageRequest(pluginID string, req *pb.StorageRequest,
 	})
 }
 
+// chatOwner returns the ID of the user owning the chats a plugin links
+func (h *Handler) chatOwner(pluginName string) string {
+	owner := strings.TrimSpace(h.pluginConfig.GetPluginConfigs(pluginName)[ChatOwnerConfigKey])
+	if owner == "" {
+		return auth.BootstrapUserID
+	}
+	if user, err := storage.GetUserByUsername(owner); err == nil {
+		return user.ID
+	}
+	if user, err := storage.GetUser(owner); err == nil {
+		return user.ID
+	}
+	log.Printf("[Handler] Unknown %s %q of plugin %s, using %s", ChatOwnerConfigKey, owner, pluginName, auth.BootstrapUserID)
+	return auth.BootstrapUserID
+}
+
 func (h *Handler) sendError(stream pb.PluginService_ConnectServer, code int32, message, requestID string) {
 	stream.Send(&pb.BackendMessage{
 		Payload: &pb.BackendMessage_Error{
@@ -266,9 +443,36 @@ func (h *Handler) sendError(stream pb.PluginService_ConnectServer, code int32, m
 }
 
 func (h *Handler) handleConfigSchema(pluginID, pluginName string, schema *pb.ConfigSchema, stream pb.PluginService_ConnectServer) {
+	// Plugins must not grant themselves raw SQL, lift their quota or pick the owner of
+	// their chats through a default value
+	schema.Fields = slices.DeleteFunc(schema.Fields, func(field *pb.ConfigField) bool {
+		if slices.Contains(storage.ReservedConfigKeys, field.Key) || field.Key == ChatOwnerConfigKey {
+			log.Printf("[Handler] Plugin %s declared reserved config key %s, ignoring it", pluginName, field.Key)
+			return true
+		}
+		return false
+	})
+	for _, field := range schema.Fields {
+		if err := config.CheckSchemaDefault(field.DefaultValue); err != nil {
+			log.Printf("[Handler] Plugin %s field %s: %v, ignoring it", pluginName, field.Key, err)
+			field.DefaultValue = ""
+		}
+	}
+
 	// Store schema in manager
 	h.manager.SetConfigSchema(pluginID, schema)
 
+	// Secret fields are stored encrypted and redacted everywhere but in what the plugin receives
+	var secretKeys []string
+	for _, field := range schema.Fields {
+		if field.Type == pb.ConfigFieldType_CONFIG_FIELD_TYPE_SECRET {
+			secretKeys = append(secretKeys, field.Key)
+		}
+	}
+	if err := h.pluginConfig.MarkSecret(pluginName, secretKeys); err != nil {
+		log.Printf("[Handler] Failed to encrypt secrets of plugin %s: %v", pluginName, err)
+	}
+
 	// Initialize all config fields from schema if they don't exist
 	// This ensures the config.toml file is created with all available options
 	existingConfigs := h.pluginConfig.GetPluginConfigs(pluginName)
@@ -287,7 +491,7 @@ func (h *Handler) handleConfigSchema(pluginID, pluginName string, schema *pb.Con
 	}
 
 	// Send current config values back to plugin
-	values := h.pluginConfig.GetPluginConfigs(pluginName)
+	values := h.pluginConfig.ResolvedPluginConfigs(pluginName)
 	stream.Send(&pb.BackendMessage{
 		Payload: &pb.BackendMessage_ConfigGetResponse{
 			ConfigGetResponse: &pb.ConfigGetResponse{
@@ -298,12 +502,21 @@ func (h *Handler) handleConfigSchema(pluginID, pluginName string, schema *pb.Con
 	})
 }
 
-func (h *Handler) handleConfigGet(pluginName string, req *pb.ConfigGetRequest, stream pb.PluginService_ConnectServer) {
-	values := h.pluginConfig.GetPluginConfigs(pluginName)
-	resp := &pb.ConfigGetResponse{
-		RequestId: req.RequestId,
-		Success:   true,
-		Config:    &pb.ConfigValues{Values: values},
+// handleConfigGet returns a plugin's own config, or with config.read another plugin's
+// config with secrets redacted
+func (h *Handler) handleConfigGet(plugin *Plugin, req *pb.ConfigGetRequest, stream pb.PluginService_ConnectServer) {
+	resp := &pb.ConfigGetResponse{RequestId: req.RequestId}
+	switch target := h.configTarget(req.PluginId); target {
+	case "", plugin.Name:
+		resp.Success = true
+		resp.Config = &pb.ConfigValues{Values: h.pluginConfig.ResolvedPluginConfigs(plugin.Name)}
+	default:
+		if err := h.checkPermission(plugin, PermConfigRead, target); err != nil {
+			resp.Error = err.Error()
+			break
+		}
+		resp.Success = true
+		resp.Config = &pb.ConfigValues{Values: h.pluginConfig.GetPluginConfigs(target)}
 	}
 
 	stream.Send(&pb.BackendMessage{
@@ -311,22 +524,33 @@ func (h *Handler) handleConfigGet(pluginName string, req *pb.ConfigGetRequest, s
 	})
 }
 
+// configTarget resolves the plugin_id of a ConfigGetRequest, a connected plugin's ID or a name, to a name
+func (h *Handler) configTarget(pluginID string) string {
+	if p, ok := h.manager.Get(pluginID); ok {
+		return p.Name
+	}
+	return pluginID
+}
+
 // SetPluginConfig sets a config value and notifies the plugin
 func (h *Handler) SetPluginConfig(pluginName, key, value string) error {
 	// Save to config file
 	if err := h.pluginConfig.SetPluginConfig(pluginName, key, value); err != nil {
 		return err
 	}
+	return h.NotifyConfigChanged(pluginName, key)
+}
 
+// NotifyConfigChanged sends a plugin its current config after key changed, with secrets resolved
+func (h *Handler) NotifyConfigChanged(pluginName, key string) error {
 	// Find plugin by name and notify if connected
 	plugin, ok := h.manager.GetByName(pluginName)
 	if !ok {
 		return nil // Plugin not connected, just saved to file
 	}
 
-	// Notify plugin
-	allValues := h.pluginConfig.GetPluginConfigs(pluginName)
-	return h.manager.NotifyConfigChanged(plugin.ID, key, value, allValues)
+	allValues := h.pluginConfig.ResolvedPluginConfigs(pluginName)
+	return h.manager.NotifyConfigChanged(plugin.ID, key, allValues[key], allValues)
 }
 
 // Manager returns the plugin manager
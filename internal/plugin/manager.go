@@ -1,7 +1,9 @@
 package plugin
 
 import (
+	"fmt"
 	"log"
+	"sort"
 	"sync"
 
 	pb "github.com/fipso/chadbot/gen/chadbot"
@@ -17,7 +19,17 @@ type Plugin struct {
 	Stream        pb.PluginService_ConnectServer
 	Subscribed    []string // Event patterns subscribed to
 	ConfigSchema  *pb.ConfigSchema
-	Documentation string // PLUGIN.md content
+	Documentation string   // PLUGIN.md content
+	DocsRequired  bool     // Docs must be read before the plugin's skills can be called
+	Permissions   []string // Permissions declared on registration
+
+	granted []string      // Declared permissions a user approved
+	closed  chan struct{} // Closed when another connection replaces this one
+}
+
+// Closed returns a channel that is closed when another connection replaces the plugin
+func (p *Plugin) Closed() <-chan struct{} {
+	return p.closed
 }
 
 // Manager handles plugin lifecycle and communication
@@ -42,22 +54,74 @@ func NewManager(registry *Registry, eventBus *event.Bus) *Manager {
 	}
 }
 
-// Register adds a new plugin
-func (m *Manager) Register(id, name, version, description string, stream pb.PluginService_ConnectServer) *Plugin {
+// Register adds a new plugin. If a plugin with the same name is connected, the
+// registration fails, or with replace the old plugin is unregistered and closed.
+func (m *Manager) Register(id, name, version, description string, stream pb.PluginService_ConnectServer, replace bool) (*Plugin, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	for oldID, old := range m.plugins {
+		if old.Name != name {
+			continue
+		}
+		if !replace {
+			return nil, fmt.Errorf("plugin %s is already connected", name)
+		}
+		log.Printf("[Manager] Plugin %s replaced by a new connection (old id: %s)", name, oldID)
+		delete(m.plugins, oldID)
+		m.registry.UnregisterPluginSkills(oldID)
+		close(old.closed)
+	}
+
 	plugin := &Plugin{
 		ID:          id,
 		Name:        name,
 		Version:     version,
 		Description: description,
 		Stream:      stream,
+		closed:      make(chan struct{}),
 	}
 
 	m.plugins[id] = plugin
 	log.Printf("[Manager] Plugin registered: %s (id: %s, version: %s)", name, id, version)
-	return plugin
+	return plugin, nil
+}
+
+// SetPermissions sets the permissions a plugin declared and grants those that are approved.
+// It returns the declared permissions awaiting approval.
+func (m *Manager) SetPermissions(id string, requested, approved []string) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	plugin, ok := m.plugins[id]
+	if !ok {
+		return nil
+	}
+	plugin.Permissions = requested
+	granted, pending := effective(requested, approved)
+	plugin.granted = granted
+	return pending
+}
+
+// SetApproved grants a connected plugin the declared permissions that are now approved
+func (m *Manager) SetApproved(name string, approved []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, p := range m.plugins {
+		if p.Name == name {
+			p.granted, _ = effective(p.Permissions, approved)
+		}
+	}
+}
+
+// Permitted reports whether a plugin was granted a permission (see permits)
+func (m *Manager) Permitted(id, name, arg string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	plugin, ok := m.plugins[id]
+	return ok && permits(plugin.granted, name, arg)
 }
 
 // Unregister removes a plugin
@@ -226,13 +290,14 @@ func (m *Manager) NotifyConfigChanged(pluginID, key, value string, allValues map
 }
 
 // SetDocumentation sets the documentation for a plugin
-func (m *Manager) SetDocumentation(pluginID, content string) {
+func (m *Manager) SetDocumentation(pluginID, content string, required bool) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	if plugin, ok := m.plugins[pluginID]; ok {
 		plugin.Documentation = content
-		log.Printf("[Manager] Documentation set for plugin %s", plugin.Name)
+		plugin.DocsRequired = required
+		log.Printf("[Manager] Documentation set for plugin %s (required: %v)", plugin.Name, required)
 	}
 }
 
@@ -259,3 +324,32 @@ func (m *Manager) GetDocumentationByName(name string) string {
 	}
 	return ""
 }
+
+// DocumentationIndexEntry describes a plugin that has documentation
+type DocumentationIndexEntry struct {
+	PluginName  string
+	Description string
+	Required    bool
+}
+
+// DocumentationIndex returns one entry per connected plugin with documentation, sorted by name
+func (m *Manager) DocumentationIndex() []DocumentationIndexEntry {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var entries []DocumentationIndexEntry
+	for _, p := range m.plugins {
+		if p.Documentation == "" {
+			continue
+		}
+		entries = append(entries, DocumentationIndexEntry{
+			PluginName:  p.Name,
+			Description: p.Description,
+			Required:    p.DocsRequired,
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].PluginName < entries[j].PluginName
+	})
+	return entries
+}
@@ -0,0 +1,133 @@
+package plugin
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/fipso/chadbot/internal/event"
+	"github.com/fipso/chadbot/internal/storage"
+)
+
+// Permissions a plugin declares in RegisterRequest.permissions. Scoped permissions take an
+// argument after a colon. Registering skills, answering skill calls, describing its config
+// and reading its own config need no permission.
+const (
+	PermChatRead        = "chat.read"        // Read chat history
+	PermChatWrite       = "chat.write"       // Link chats and add messages
+	PermLLMRequest      = "llm.request"      // Ask the LLM to reply in a chat
+	PermStorage         = "storage"          // Use the plugin's storage namespace
+	PermEventsSubscribe = "events.subscribe" // events.subscribe:<pattern>
+	PermEventsEmit      = "events.emit"      // events.emit:<pattern>
+	PermConfigRead      = "config.read"      // config.read:<plugin>, with secrets redacted
+)
+
+// scopedPermissions are the permissions that need an argument
+var scopedPermissions = []string{PermEventsSubscribe, PermEventsEmit, PermConfigRead}
+
+// ValidatePermission checks that a permission is known and has an argument if it needs one
+func ValidatePermission(p string) error {
+	name, arg, scoped := strings.Cut(p, ":")
+	switch name {
+	case PermChatRead, PermChatWrite, PermLLMRequest, PermStorage:
+		if scoped {
+			return fmt.Errorf("permission %s takes no argument", name)
+		}
+	case PermEventsSubscribe, PermEventsEmit, PermConfigRead:
+		if arg == "" {
+			return fmt.Errorf("permission %s needs an argument (%s:...)", name, name)
+		}
+	default:
+		return fmt.Errorf("unknown permission %q", p)
+	}
+	return nil
+}
+
+// Permissions describes what a plugin asked for and what a user approved
+type Permissions struct {
+	Requested []string `json:"requested"` // Declared by the connected plugin
+	Approved  []string `json:"approved"`  // Approved by a user
+	Pending   []string `json:"pending"`   // Requested but not approved
+}
+
+// effective returns the requested permissions that are approved
+func effective(requested, approved []string) (granted, pending []string) {
+	for _, p := range requested {
+		if slices.Contains(approved, p) {
+			granted = append(granted, p)
+		} else {
+			pending = append(pending, p)
+		}
+	}
+	return granted, pending
+}
+
+// permits reports whether granted permissions allow a permission. For scoped permissions
+// the argument must match: event types and patterns against granted patterns, plugin names exactly.
+func permits(granted []string, name, arg string) bool {
+	if !slices.Contains(scopedPermissions, name) {
+		return slices.Contains(granted, name)
+	}
+	for _, g := range granted {
+		gName, gArg, ok := strings.Cut(g, ":")
+		if !ok || gName != name {
+			continue
+		}
+		if name == PermConfigRead {
+			if gArg == arg {
+				return true
+			}
+		} else if event.MatchPattern(gArg, arg) {
+			return true
+		}
+	}
+	return false
+}
+
+// Permissions returns the permissions of a plugin, connected or not
+func (h *Handler) Permissions(pluginName string) (*Permissions, error) {
+	approved, err := storage.GetPluginGrants(pluginName)
+	if err != nil {
+		return nil, err
+	}
+	perms := &Permissions{Requested: []string{}, Approved: []string{}, Pending: []string{}}
+	perms.Approved = append(perms.Approved, approved...)
+	if plugin, ok := h.manager.GetByName(pluginName); ok {
+		perms.Requested = append(perms.Requested, plugin.Permissions...)
+		_, pending := effective(plugin.Permissions, approved)
+		perms.Pending = append(perms.Pending, pending...)
+	}
+	return perms, nil
+}
+
+// Approve replaces the permissions approved for a plugin and applies them to its connection
+func (h *Handler) Approve(pluginName string, permissions []string, userID string) error {
+	for _, p := range permissions {
+		if err := ValidatePermission(p); err != nil {
+			return err
+		}
+	}
+	permissions = slices.Clone(permissions)
+	slices.Sort(permissions)
+	permissions = slices.Compact(permissions)
+	if err := storage.SetPluginGrants(pluginName, permissions, userID); err != nil {
+		return err
+	}
+	h.manager.SetApproved(pluginName, permissions)
+	log.Printf("[Handler] Permissions of plugin %s approved by %s: %s", pluginName, userID, strings.Join(permissions, ", "))
+	return nil
+}
+
+// checkPermission returns an error, and logs the denial, if a plugin lacks a permission
+func (h *Handler) checkPermission(plugin *Plugin, name, arg string) error {
+	if h.manager.Permitted(plugin.ID, name, arg) {
+		return nil
+	}
+	perm := name
+	if arg != "" {
+		perm += ":" + arg
+	}
+	log.Printf("[Handler] Denied %s to plugin %s (not granted)", perm, plugin.Name)
+	return fmt.Errorf("permission %s not granted", perm)
+}
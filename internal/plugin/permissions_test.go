@@ -0,0 +1,111 @@
+package plugin
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/fipso/chadbot/internal/event"
+	"github.com/fipso/chadbot/internal/storage"
+)
+
+func TestPermits(t *testing.T) {
+	granted := []string{
+		PermStorage,
+		"events.subscribe:chat.*",
+		"events.emit:notes.saved",
+		"config.read:mail",
+	}
+	tests := []struct {
+		name, arg string
+		want      bool
+	}{
+		{PermStorage, "", true},
+		{PermChatRead, "", false},
+		{PermLLMRequest, "", false},
+
+		{PermEventsSubscribe, "chat.message.received", true},
+		{PermEventsSubscribe, "chat.*", true},
+		{PermEventsSubscribe, "*", false},
+		{PermEventsSubscribe, "plugin.notes.saved", false},
+		{PermEventsEmit, "notes.saved", true},
+		{PermEventsEmit, "notes.deleted", false},
+		{PermEventsEmit, "chat.message.received", false},
+
+		{PermConfigRead, "mail", true},
+		{PermConfigRead, "mailer", false},
+		{PermConfigRead, "*", false},
+		{PermConfigRead, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name+":"+tt.arg, func(t *testing.T) {
+			if got := permits(granted, tt.name, tt.arg); got != tt.want {
+				t.Fatalf("permits(%s, %q) = %v, want %v", tt.name, tt.arg, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidatePermission(t *testing.T) {
+	for _, p := range []string{"storage", "chat.read", "events.subscribe:chat.*", "config.read:mail"} {
+		if err := ValidatePermission(p); err != nil {
+			t.Errorf("%s: %v", p, err)
+		}
+	}
+	for _, p := range []string{"", "root", "storage:all", "events.emit", "events.emit:", "config.read"} {
+		if err := ValidatePermission(p); err == nil {
+			t.Errorf("%q accepted", p)
+		}
+	}
+}
+
+func TestApprove(t *testing.T) {
+	if err := storage.Init(filepath.Join(t.TempDir(), "chadbot.db")); err != nil {
+		t.Fatalf("init storage: %v", err)
+	}
+	m := NewManager(NewRegistry(), event.NewBus())
+	h := NewHandler(m, nil, nil)
+	p, err := m.Register("1", "notes", "1.0", "", nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.SetPermissions(p.ID, []string{PermStorage, PermChatRead}, nil)
+	if m.Permitted(p.ID, PermStorage, "") {
+		t.Fatal("storage permitted before approval")
+	}
+
+	if err := h.Approve("notes", []string{PermStorage, PermStorage, "events.emit:notes.*"}, "admin"); err != nil {
+		t.Fatal(err)
+	}
+	if !m.Permitted(p.ID, PermStorage, "") {
+		t.Fatal("storage not permitted after approval")
+	}
+	// Approved but not requested by the connected plugin
+	if m.Permitted(p.ID, PermEventsEmit, "notes.saved") {
+		t.Fatal("unrequested permission granted")
+	}
+
+	perms, err := h.Permissions("notes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(perms.Approved, []string{"events.emit:notes.*", PermStorage}) || !slices.Equal(perms.Pending, []string{PermChatRead}) {
+		t.Fatalf("permissions %+v", perms)
+	}
+
+	// Approvals persist and replace the previous ones
+	if err := h.Approve("notes", []string{PermChatRead}, "admin"); err != nil {
+		t.Fatal(err)
+	}
+	approved, err := storage.GetPluginGrants("notes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(approved, []string{PermChatRead}) || m.Permitted(p.ID, PermStorage, "") {
+		t.Fatalf("approved %v after replacing the approval", approved)
+	}
+
+	if err := h.Approve("notes", []string{"everything"}, "admin"); err == nil {
+		t.Fatal("invalid permission approved")
+	}
+}
@@ -0,0 +1,278 @@
+package retention
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/fipso/chadbot/gen/chadbot"
+	"github.com/fipso/chadbot/internal/blob"
+	"github.com/fipso/chadbot/internal/event"
+	"github.com/fipso/chadbot/internal/storage"
+)
+
+// TypeChatForgotten is published when a chat was forgotten, so plugins can drop their own data for it
+const TypeChatForgotten = "chat.forgotten"
+
+// Report lists what a janitor run or a forgotten chat removed
+type Report struct {
+	StartedAt       time.Time        `json:"started_at"`
+	FinishedAt      time.Time        `json:"finished_at"`
+	PurgedChats     int64            `json:"purged_chats"`     // Hard-deleted chats
+	ExpiredMessages int64            `json:"expired_messages"` // Removed by max_age
+	TrimmedMessages int64            `json:"trimmed_messages"` // Removed by max_messages
+	Messages        int64            `json:"messages"`         // All removed messages (incl. those of purged chats)
+	Embeddings      int64            `json:"embeddings"`
+	Memories        int64            `json:"memories"`
+	PluginRows      map[string]int64 `json:"plugin_rows"` // "plugin.table" -> removed rows
+	KVEntries       int64            `json:"kv_entries"`  // Expired plugin key-value entries
+	Blobs           int              `json:"blobs"`
+	BlobBytes       int64            `json:"blob_bytes"`
+	Errors          []string         `json:"errors,omitempty"`
+}
+
+// addPurge adds the counts of a storage purge
+func (r *Report) addPurge(p storage.PurgeResult) {
+	r.PurgedChats += p.Chats
+	r.Messages += p.Messages
+	r.Embeddings += p.Embeddings
+	r.Memories += p.Memories
+}
+
+// fail records an error that did not stop the run
+func (r *Report) fail(format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	log.Printf("[Retention] %s", msg)
+	r.Errors = append(r.Errors, msg)
+}
+
+// empty reports whether nothing was removed
+func (r *Report) empty() bool {
+	return r.PurgedChats == 0 && r.Messages == 0 && r.Memories == 0 && pluginRowCount(r) == 0 &&
+		r.KVEntries == 0 && r.Blobs == 0
+}
+
+// Janitor enforces retention policies and purges deleted chats
+type Janitor struct {
+	path     string
+	blobs    *blob.Store
+	eventBus *event.Bus
+
+	mu   sync.Mutex // Serializes runs
+	last *Report
+}
+
+// NewJanitor creates a janitor for the policies in the given file (default ~/.config/chadbot/retention.toml)
+func NewJanitor(path string, blobs *blob.Store, eventBus *event.Bus) (*Janitor, error) {
+	if path == "" {
+		var err error
+		if path, err = Path(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := Load(path); err != nil {
+		return nil, err
+	}
+	return &Janitor{path: path, blobs: blobs, eventBus: eventBus}, nil
+}
+
+// Config reloads and returns the retention policies
+func (j *Janitor) Config() (*Config, error) {
+	return Load(j.path)
+}
+
+// LastReport returns the report of the last run (nil before the first run)
+func (j *Janitor) LastReport() *Report {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.last
+}
+
+// Run enforces the policies at startup and then every configured interval until ctx is done.
+// The config file is reloaded before each run.
+func (j *Janitor) Run(ctx context.Context) {
+	for {
+		interval := defaultInterval
+		if cfg, err := j.Config(); err != nil {
+			log.Printf("[Retention] %v", err)
+		} else {
+			interval = time.Duration(cfg.Interval)
+			j.RunOnce()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// RunOnce enforces the policies now and returns what was removed
+func (j *Janitor) RunOnce() (*Report, error) {
+	cfg, err := j.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	report := &Report{StartedAt: time.Now(), PluginRows: map[string]int64{}}
+
+	// Hard-purge chats deleted before the grace period
+	ids, err := storage.DeletedChatsBefore(time.Now().Add(-time.Duration(cfg.PurgeDeletedAfter)))
+	if err != nil {
+		report.fail("Listing deleted chats failed: %v", err)
+	}
+	for _, id := range ids {
+		purged, err := storage.PurgeChat(id, false)
+		if err != nil {
+			report.fail("Purging chat %s failed: %v", id, err)
+			continue
+		}
+		report.addPurge(purged)
+	}
+
+	for _, p := range cfg.Chats {
+		if p.MaxAge > 0 {
+			removed, err := storage.DeleteMessagesBefore(p.scope(), time.Now().Add(-time.Duration(p.MaxAge)))
+			if err != nil {
+				report.fail("Max age of %s failed: %v", p, err)
+			}
+			report.addPurge(removed)
+			report.ExpiredMessages += removed.Messages
+		}
+		if p.MaxMessages > 0 {
+			removed, err := storage.TrimMessages(p.scope(), p.MaxMessages)
+			if err != nil {
+				report.fail("Max messages of %s failed: %v", p, err)
+			}
+			report.addPurge(removed)
+			report.TrimmedMessages += removed.Messages
+		}
+	}
+
+	for _, p := range cfg.PluginTables {
+		if p.TTL <= 0 {
+			continue
+		}
+		n, err := storage.DeleteExpiredPluginRows(p.Plugin, p.Table, p.TimestampColumn, p.TimestampFormat,
+			time.Now().Add(-time.Duration(p.TTL)))
+		if err != nil {
+			report.fail("TTL of %s.%s failed: %v", p.Plugin, p.Table, err)
+			continue
+		}
+		report.PluginRows[p.Plugin+"."+p.Table] += n
+	}
+
+	if report.KVEntries, err = storage.DeleteExpiredKV(); err != nil {
+		report.fail("Deleting expired key-value entries failed: %v", err)
+	}
+
+	j.collectBlobs(report)
+	report.FinishedAt = time.Now()
+	j.last = report
+
+	if !report.empty() {
+		log.Printf("[Retention] Removed %d chats, %d messages (%d expired, %d trimmed), %d plugin rows, %d expired keys and %d blobs",
+			report.PurgedChats, report.Messages, report.ExpiredMessages, report.TrimmedMessages,
+			pluginRowCount(report), report.KVEntries, report.Blobs)
+	}
+	return report, nil
+}
+
+// ForgetChat hard-deletes a chat with its messages, embeddings, memories learned in it,
+// plugin rows linked to it and unreferenced blobs, and tells plugins it is gone
+func (j *Janitor) ForgetChat(chatID string) (*Report, error) {
+	chat, err := storage.GetChatUnscoped(chatID)
+	if err != nil {
+		return nil, fmt.Errorf("chat %s not found", chatID)
+	}
+	cfg, err := j.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	report := &Report{StartedAt: time.Now(), PluginRows: map[string]int64{}}
+	purged, err := storage.PurgeChat(chatID, true)
+	if err != nil {
+		return nil, err
+	}
+	report.addPurge(purged)
+
+	// Plugin rows may reference the chat by its ID or by the platform's chat ID
+	keys := []string{chat.ID}
+	if chat.LinkedID != "" {
+		keys = append(keys, chat.LinkedID)
+	}
+	for _, p := range cfg.PluginTables {
+		if p.ChatColumn == "" {
+			continue
+		}
+		n, err := storage.DeletePluginChatRows(p.Plugin, p.Table, p.ChatColumn, keys)
+		if err != nil {
+			report.fail("Forgetting chat in %s.%s failed: %v", p.Plugin, p.Table, err)
+			continue
+		}
+		report.PluginRows[p.Plugin+"."+p.Table] += n
+	}
+
+	j.collectBlobs(report)
+	report.FinishedAt = time.Now()
+	j.publishForgotten(chat)
+
+	log.Printf("[Retention] Forgot chat %s: %d messages, %d memories, %d plugin rows, %d blobs",
+		chatID, report.Messages, report.Memories, pluginRowCount(report), report.Blobs)
+	return report, nil
+}
+
+// collectBlobs removes attachment blobs that purged messages referenced
+func (j *Janitor) collectBlobs(report *Report) {
+	if j.blobs == nil || report.Messages == 0 {
+		return
+	}
+	removed, freed, err := j.blobs.GC()
+	if err != nil {
+		report.fail("Blob garbage collection failed: %v", err)
+	}
+	report.Blobs += removed
+	report.BlobBytes += freed
+}
+
+// publishForgotten publishes chat.forgotten with the chat's platform and linked ID
+func (j *Janitor) publishForgotten(chat *storage.Chat) {
+	if j.eventBus == nil {
+		return
+	}
+	payload, err := structpb.NewStruct(map[string]any{
+		"chat_id":   chat.ID,
+		"platform":  chat.Platform,
+		"linked_id": chat.LinkedID,
+	})
+	if err != nil {
+		return
+	}
+	j.eventBus.Publish(&pb.Event{
+		EventType: TypeChatForgotten,
+		Timestamp: timestamppb.Now(),
+		Data:      &pb.Event_Generic{Generic: &pb.GenericEvent{Payload: payload}},
+	})
+}
+
+// pluginRowCount sums the removed plugin rows of a report
+func pluginRowCount(r *Report) int64 {
+	var n int64
+	for _, rows := range r.PluginRows {
+		n += rows
+	}
+	return n
+}
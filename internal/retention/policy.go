@@ -0,0 +1,129 @@
+package retention
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+
+	"github.com/fipso/chadbot/internal/config"
+	"github.com/fipso/chadbot/internal/storage"
+)
+
+const (
+	defaultInterval          = time.Hour
+	defaultPurgeDeletedAfter = 24 * time.Hour
+)
+
+// Config holds the retention policies (~/.config/chadbot/retention.toml)
+type Config struct {
+	Interval          Duration            `toml:"interval" json:"interval"`                       // Janitor interval (default 1h)
+	PurgeDeletedAfter Duration            `toml:"purge_deleted_after" json:"purge_deleted_after"` // Grace period before deleted chats are purged (default 24h)
+	Chats             []ChatPolicy        `toml:"chats" json:"chats"`
+	PluginTables      []PluginTablePolicy `toml:"plugin_tables" json:"plugin_tables"`
+}
+
+// ChatPolicy limits the messages kept in chats of a platform or a single chat (neither = all chats)
+type ChatPolicy struct {
+	Platform    string   `toml:"platform" json:"platform,omitempty"`
+	ChatID      string   `toml:"chat_id" json:"chat_id,omitempty"`
+	MaxAge      Duration `toml:"max_age" json:"max_age,omitempty"`           // Delete older messages (0 = keep)
+	MaxMessages int      `toml:"max_messages" json:"max_messages,omitempty"` // Keep only the newest messages (0 = all)
+}
+
+// PluginTablePolicy applies retention to a plugin storage table
+type PluginTablePolicy struct {
+	Plugin          string   `toml:"plugin" json:"plugin"`
+	Table           string   `toml:"table" json:"table"`                                 // Table name without plugin prefix
+	TimestampColumn string   `toml:"timestamp_column" json:"timestamp_column,omitempty"` // Column the TTL applies to
+	TimestampFormat string   `toml:"timestamp_format" json:"timestamp_format,omitempty"` // unix (default), unix_ms or datetime
+	TTL             Duration `toml:"ttl" json:"ttl,omitempty"`                           // Delete older rows (0 = keep)
+	ChatColumn      string   `toml:"chat_column" json:"chat_column,omitempty"`           // Column holding a chat ID or linked ID, for forgetting chats
+}
+
+// Duration is a time.Duration that also accepts days ("30d") in config files
+type Duration time.Duration
+
+// UnmarshalText parses durations like "90m", "12h" or "30d"
+func (d *Duration) UnmarshalText(text []byte) error {
+	s := strings.TrimSpace(string(text))
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.ParseFloat(days, 64)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", text)
+		}
+		*d = Duration(n * float64(24*time.Hour))
+		return nil
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalText formats the duration like time.Duration
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// Path returns the retention config path (~/.config/chadbot/retention.toml)
+func Path() (string, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "retention.toml"), nil
+}
+
+// Load reads retention policies from a TOML file (a missing file means defaults only)
+func Load(path string) (*Config, error) {
+	cfg := &Config{}
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		if err := toml.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("invalid retention config %s: %w", path, err)
+		}
+	}
+
+	if cfg.Interval <= 0 {
+		cfg.Interval = Duration(defaultInterval)
+	}
+	if cfg.PurgeDeletedAfter <= 0 {
+		cfg.PurgeDeletedAfter = Duration(defaultPurgeDeletedAfter)
+	}
+	for _, p := range cfg.PluginTables {
+		if p.Plugin == "" || p.Table == "" {
+			return nil, fmt.Errorf("invalid retention config %s: plugin table policy needs plugin and table", path)
+		}
+		if p.TTL > 0 && p.TimestampColumn == "" {
+			return nil, fmt.Errorf("invalid retention config %s: ttl of %s.%s needs timestamp_column", path, p.Plugin, p.Table)
+		}
+	}
+	return cfg, nil
+}
+
+// scope returns the chats a policy applies to
+func (p ChatPolicy) scope() storage.ChatScope {
+	return storage.ChatScope{Platform: p.Platform, ChatID: p.ChatID}
+}
+
+// String describes the policy's scope for reports
+func (p ChatPolicy) String() string {
+	switch {
+	case p.ChatID != "":
+		return "chat " + p.ChatID
+	case p.Platform != "":
+		return "platform " + p.Platform
+	default:
+		return "all chats"
+	}
+}
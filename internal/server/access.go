@@ -0,0 +1,38 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/fipso/chadbot/internal/access"
+)
+
+// handleAccess handles GET/PUT /api/access - the skill access policies (admin only)
+func (s *WebSocketServer) handleAccess(w http.ResponseWriter, r *http.Request) {
+	if s.access == nil {
+		http.Error(w, "Access policies are not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+
+	case http.MethodPut:
+		var cfg access.Config
+		if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := s.access.Save(&cfg); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.access.Config())
+}
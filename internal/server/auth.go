@@ -0,0 +1,390 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/fipso/chadbot/internal/auth"
+	"github.com/fipso/chadbot/internal/storage"
+)
+
+// LoginRequest signs in with a username and password
+type LoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// ChangePasswordRequest changes the signed-in user's password
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// CreateTokenRequest creates an API token
+type CreateTokenRequest struct {
+	Name string `json:"name"`
+}
+
+// CreateTokenResponse returns a new API token, which is shown only once
+type CreateTokenResponse struct {
+	*storage.APIToken
+	Token string `json:"token"`
+}
+
+// UserRequest creates a user or changes a user's password or role
+type UserRequest struct {
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	Role     string `json:"role,omitempty"`
+}
+
+// publicPaths are reachable without signing in
+var publicPaths = []string{"/health", "/api/auth/login"}
+
+// authMiddleware rejects requests without a valid session or API token and puts the
+// user into the request context. Admin-only routes are rejected for other users.
+func (s *WebSocketServer) authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if slices.Contains(publicPaths, r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		user, err := auth.Authenticate(r)
+		if errors.Is(err, auth.ErrUnauthenticated) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if adminOnly(r) && user.Role != auth.RoleAdmin {
+			http.Error(w, "Admin role required", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(auth.WithUser(r.Context(), user)))
+	})
+}
+
+// adminOnly reports whether a request changes instance-wide state
+func adminOnly(r *http.Request) bool {
+	path := r.URL.Path
+	switch {
+	case strings.HasPrefix(path, "/api/admin/"), strings.HasPrefix(path, "/api/config/"),
+		path == "/api/users", strings.HasPrefix(path, "/api/users/"), path == "/api/access":
+		return true
+	case strings.HasPrefix(path, "/api/plugins/") && strings.HasSuffix(path, "/config"):
+		return true
+	case strings.HasPrefix(path, "/api/plugins/"), path == "/api/retention", path == "/api/retention/run":
+		return r.Method != http.MethodGet
+	}
+	return false
+}
+
+// requestUser returns the authenticated user of a request
+func requestUser(r *http.Request) *storage.User {
+	return auth.UserFrom(r.Context())
+}
+
+// requestUserID returns the ID of the authenticated user of a request
+func requestUserID(r *http.Request) string {
+	if user := requestUser(r); user != nil {
+		return user.ID
+	}
+	return ""
+}
+
+// corsMiddleware allows credentialed requests from the allowed origins
+func (s *WebSocketServer) corsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if origin := r.Header.Get("Origin"); origin != "" && s.allowedOrigin(r) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+			w.Header().Add("Vary", "Origin")
+		}
+
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowedOrigin reports whether a request's Origin may use the API. Without configured
+// origins, pages served from the same host on any port (such as the frontend dev server) are allowed.
+func (s *WebSocketServer) allowedOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true // Not a browser cross-origin request
+	}
+	if len(s.corsOrigins) > 0 {
+		return slices.Contains(s.corsOrigins, "*") || slices.Contains(s.corsOrigins, origin)
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	host, _, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		host = strings.Trim(r.Host, "[]") // No port
+	}
+	return strings.EqualFold(u.Hostname(), host)
+}
+
+// handleLogin handles POST /api/auth/login - starts a session
+func (s *WebSocketServer) handleLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req LoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, token, err := auth.Login(req.Username, req.Password)
+	if errors.Is(err, auth.ErrInvalidCredentials) {
+		log.Printf("[Auth] Failed sign-in for %q from %s", req.Username, r.RemoteAddr)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     auth.SessionCookie,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(auth.SessionTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// handleLogout handles POST /api/auth/logout - ends the current session
+func (s *WebSocketServer) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if token := auth.SessionToken(r); token != "" {
+		if err := auth.Logout(token); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	http.SetCookie(w, &http.Cookie{Name: auth.SessionCookie, Path: "/", MaxAge: -1, HttpOnly: true})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleMe handles GET /api/auth/me - returns the signed-in user
+func (s *WebSocketServer) handleMe(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(requestUser(r))
+}
+
+// handleChangePassword handles PUT /api/auth/password - changes the signed-in user's password
+func (s *WebSocketServer) handleChangePassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, err := auth.CheckPassword(requestUser(r).Username, req.CurrentPassword)
+	if errors.Is(err, auth.ErrInvalidCredentials) {
+		http.Error(w, "Current password is wrong", http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := auth.SetPassword(user, req.NewPassword, auth.SessionToken(r)); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleTokens handles GET /api/auth/tokens (list) and POST /api/auth/tokens (create)
+func (s *WebSocketServer) handleTokens(w http.ResponseWriter, r *http.Request) {
+	userID := requestUserID(r)
+
+	switch r.Method {
+	case http.MethodGet:
+		tokens, err := storage.GetUserAPITokens(userID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(tokens)
+
+	case http.MethodPost:
+		var req CreateTokenRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if strings.TrimSpace(req.Name) == "" {
+			http.Error(w, "Name is required", http.StatusBadRequest)
+			return
+		}
+		record, token, err := auth.CreateAPIToken(userID, req.Name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(CreateTokenResponse{APIToken: record, Token: token})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleTokenByID handles DELETE /api/auth/tokens/{id} - revokes an API token
+func (s *WebSocketServer) handleTokenByID(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/auth/tokens/"), "/")
+	deleted, err := storage.DeleteAPIToken(requestUserID(r), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !deleted {
+		http.Error(w, "Token not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleUsers handles GET /api/users (list) and POST /api/users (create) - admin only
+func (s *WebSocketServer) handleUsers(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		users, err := storage.ListUsers()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(users)
+
+	case http.MethodPost:
+		var req UserRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Role == "" {
+			req.Role = auth.RoleUser
+		}
+		user, err := auth.CreateUser(req.Username, req.Password, req.Role)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("[Auth] %s created user %s (%s)", requestUser(r).Username, user.Username, user.Role)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(user)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleUserByID handles PUT /api/users/{id} (password, role) and DELETE /api/users/{id} - admin only
+func (s *WebSocketServer) handleUserByID(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/users/"), "/")
+	user, err := storage.GetUser(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		var req UserRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Role != "" && req.Role != user.Role {
+			if req.Role != auth.RoleAdmin && req.Role != auth.RoleUser {
+				http.Error(w, "Invalid role", http.StatusBadRequest)
+				return
+			}
+			if user.ID == requestUserID(r) {
+				http.Error(w, "You cannot change your own role", http.StatusBadRequest)
+				return
+			}
+			user.Role = req.Role
+			if err := storage.UpdateUser(user); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		if req.Password != "" {
+			if err := auth.SetPassword(user, req.Password, ""); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(user)
+
+	case http.MethodDelete:
+		if user.ID == requestUserID(r) {
+			http.Error(w, "You cannot delete your own account", http.StatusBadRequest)
+			return
+		}
+		if err := storage.DeleteUser(user.ID); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		log.Printf("[Auth] %s deleted user %s", requestUser(r).Username, user.Username)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/fipso/chadbot/internal/backup"
+)
+
+// BackupResponse shows the backup settings and the archives in the backup directory
+type BackupResponse struct {
+	Config     *backup.Config `json:"config"`
+	Backups    []backup.Info  `json:"backups"`
+	LastBackup *backup.Info   `json:"last_backup"`
+}
+
+// handleBackup handles GET /api/admin/backup (list archives) and POST /api/admin/backup (create one now)
+func (s *WebSocketServer) handleBackup(w http.ResponseWriter, r *http.Request) {
+	if s.backups == nil {
+		http.Error(w, "Backups are not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		cfg, err := s.backups.Config()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		archives, err := s.backups.List()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(BackupResponse{Config: cfg, Backups: archives, LastBackup: s.backups.LastBackup()})
+
+	case http.MethodPost:
+		info, err := s.backups.Create()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(info)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleBackupDownload handles GET /api/admin/backup/{name} - downloads an archive
+func (s *WebSocketServer) handleBackupDownload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.backups == nil {
+		http.Error(w, "Backups are not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/admin/backup/"), "/")
+	path, err := s.backups.Open(name)
+	if err != nil {
+		http.Error(w, "Backup not found", http.StatusNotFound)
+		return
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+	http.ServeContent(w, r, name, info.ModTime(), f)
+}
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/fipso/chadbot/internal/blob"
+	"github.com/fipso/chadbot/internal/storage"
+)
+
+// handleBlob handles GET /api/blobs/{hash} (supports range requests)
+func (s *WebSocketServer) handleBlob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	hash := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/blobs/"), "/")
+	if !blob.ValidHash(hash) {
+		http.Error(w, "Blob not found", http.StatusNotFound)
+		return
+	}
+	// Only attachments of the user's own chats; other blobs answer 404 like missing ones
+	owned, err := storage.UserReferencesBlob(requestUserID(r), hash)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !owned {
+		http.Error(w, "Blob not found", http.StatusNotFound)
+		return
+	}
+	f, info, err := s.blobs.Open(hash)
+	if err != nil {
+		http.Error(w, "Blob not found", http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+
+	// Blobs are immutable: their name is the hash of their content. They are private to
+	// their chats' owners, so shared caches must not keep them.
+	if info.MimeType != "" {
+		w.Header().Set("Content-Type", info.MimeType)
+	}
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; sandbox")
+	w.Header().Set("ETag", `"`+info.Hash+`"`)
+	w.Header().Set("Cache-Control", "private, max-age=31536000, immutable")
+	http.ServeContent(w, r, "", info.CreatedAt, f)
+}
@@ -0,0 +1,254 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/fipso/chadbot/internal/storage"
+)
+
+// EditMessageRequest edits a message on a new branch (user messages are answered again)
+type EditMessageRequest struct {
+	ChatID    string `json:"chat_id"`
+	MessageID string `json:"message_id"`
+	Content   string `json:"content"`
+	Provider  string `json:"provider,omitempty"`
+	Soul      string `json:"soul,omitempty"`
+}
+
+// RegenerateRequest retries an assistant answer on a new branch
+type RegenerateRequest struct {
+	ChatID    string `json:"chat_id"`
+	MessageID string `json:"message_id"`
+	Provider  string `json:"provider,omitempty"` // Provider of the original answer if empty
+	Soul      string `json:"soul,omitempty"`     // Soul of the original answer if empty
+}
+
+// SwitchBranchRequest activates the branch through a message
+type SwitchBranchRequest struct {
+	ChatID    string `json:"chat_id"`
+	MessageID string `json:"message_id"`
+}
+
+// BranchResponse reports the active branch after a branch operation
+type BranchResponse struct {
+	ChatID     string           `json:"chat_id"`
+	ActiveLeaf string           `json:"active_leaf"`
+	Message    *storage.Message `json:"message,omitempty"` // Edited message
+	Reply      *storage.Message `json:"reply,omitempty"`   // Generated answer (REST only)
+}
+
+// editMessage stores an edited copy of a message next to the original
+func editMessage(chatID, messageID, content string) (*storage.Message, error) {
+	if strings.TrimSpace(content) == "" {
+		return nil, fmt.Errorf("content is required")
+	}
+	orig, err := storage.GetChatMessage(chatID, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &storage.Message{
+		ID:          uuid.New().String(),
+		ChatID:      chatID,
+		ParentID:    orig.ParentID,
+		Role:        orig.Role,
+		Content:     content,
+		DisplayOnly: orig.DisplayOnly,
+		Attachments: orig.Attachments,
+		Soul:        orig.Soul,
+		Provider:    orig.Provider,
+		CreatedAt:   time.Now(),
+	}
+	if err := storage.AddBranchMessage(msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// prepareRegenerate returns the request for a new answer to the message an answer replies to.
+// The original answer stays active until the new one is stored.
+func prepareRegenerate(req RegenerateRequest) (replyRequest, error) {
+	orig, err := storage.GetChatMessage(req.ChatID, req.MessageID)
+	if err != nil {
+		return replyRequest{}, err
+	}
+	if orig.Role != "assistant" || orig.ParentID == "" {
+		return replyRequest{}, fmt.Errorf("only assistant answers can be regenerated")
+	}
+
+	reply := replyRequest{
+		ChatID:   req.ChatID,
+		ParentID: orig.ParentID,
+		Provider: req.Provider,
+		Soul:     req.Soul,
+	}
+	if reply.Provider == "" {
+		reply.Provider = orig.Provider
+	}
+	if reply.Soul == "" {
+		reply.Soul = orig.Soul
+	}
+	return reply, nil
+}
+
+// handleEditMessage handles chat.edit from a PWA client
+func (c *WSClient) handleEditMessage(req EditMessageRequest) {
+	if !c.ownsChat(req.ChatID) {
+		return
+	}
+	msg, err := editMessage(req.ChatID, req.MessageID, req.Content)
+	if err != nil {
+		c.send("chat.error", map[string]string{"chat_id": req.ChatID, "error": err.Error()})
+		return
+	}
+	c.send("chat.branch", BranchResponse{ChatID: req.ChatID, ActiveLeaf: msg.ID, Message: msg})
+
+	if msg.Role == "user" && c.Server.llmRouter != nil {
+		c.Server.emitReceived(msg, c.UserID)
+		go c.processWithLLM(replyRequest{
+			ChatID:   req.ChatID,
+			ParentID: msg.ID,
+			UserID:   c.UserID,
+			Provider: req.Provider,
+			Soul:     req.Soul,
+		})
+	}
+}
+
+// handleRegenerate handles chat.regenerate from a PWA client
+func (c *WSClient) handleRegenerate(req RegenerateRequest) {
+	if !c.ownsChat(req.ChatID) {
+		return
+	}
+	if c.Server.llmRouter == nil {
+		c.send("chat.error", map[string]string{"chat_id": req.ChatID, "error": "no LLM provider available"})
+		return
+	}
+	reply, err := prepareRegenerate(req)
+	if err != nil {
+		c.send("chat.error", map[string]string{"chat_id": req.ChatID, "error": err.Error()})
+		return
+	}
+
+	// The new answer arrives as chat.message once stored, on the branch it starts
+	reply.UserID = c.UserID
+	go c.processWithLLM(reply)
+}
+
+// handleSwitchBranch handles chat.switch_branch from a PWA client
+func (c *WSClient) handleSwitchBranch(req SwitchBranchRequest) {
+	if !c.ownsChat(req.ChatID) {
+		return
+	}
+	leaf, err := storage.SwitchBranch(req.ChatID, req.MessageID)
+	if err != nil {
+		c.send("chat.error", map[string]string{"chat_id": req.ChatID, "error": err.Error()})
+		return
+	}
+	c.send("chat.branch", BranchResponse{ChatID: req.ChatID, ActiveLeaf: leaf})
+}
+
+// handleMessageAction handles POST /api/chats/{id}/messages/{messageId}/{edit,regenerate}.
+// The answer is generated before the response is sent.
+func (s *WebSocketServer) handleMessageAction(w http.ResponseWriter, r *http.Request, chatID, messageID, action string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var resp BranchResponse
+	var reply replyRequest
+	switch action {
+	case "edit":
+		req := EditMessageRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		msg, err := editMessage(chatID, messageID, req.Content)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		resp = BranchResponse{ChatID: chatID, ActiveLeaf: msg.ID, Message: msg}
+		if msg.Role == "user" {
+			reply = replyRequest{ChatID: chatID, ParentID: msg.ID, UserID: requestUserID(r), Provider: req.Provider, Soul: req.Soul}
+		}
+
+	case "regenerate":
+		req := RegenerateRequest{}
+		// The body is optional
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		req.ChatID, req.MessageID = chatID, messageID
+		var err error
+		if reply, err = prepareRegenerate(req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		reply.UserID = requestUserID(r)
+		resp = BranchResponse{ChatID: chatID, ActiveLeaf: messageID}
+
+	default:
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	if reply.ParentID != "" && s.llmRouter != nil {
+		ctx, cancel := context.WithTimeout(r.Context(), 15*time.Minute)
+		defer cancel()
+
+		assistantMsg, llmResp, err := s.generateReply(ctx, reply)
+		if err != nil {
+			log.Printf("[WebSocket] Failed to answer %s: %v", reply.ParentID, err)
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		s.BroadcastMessage(chatID, assistantMsg)
+		s.finishReply(assistantMsg, llmResp)
+		resp.Reply = assistantMsg
+		resp.ActiveLeaf = assistantMsg.ID
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleSwitchBranchHTTP handles POST /api/chats/{id}/branch
+func (s *WebSocketServer) handleSwitchBranchHTTP(w http.ResponseWriter, r *http.Request, chatID string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req SwitchBranchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	leaf, err := storage.SwitchBranch(chatID, req.MessageID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(BranchResponse{ChatID: chatID, ActiveLeaf: leaf})
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/fipso/chadbot/internal/event"
+	"github.com/fipso/chadbot/internal/llm"
+	"github.com/fipso/chadbot/internal/plugin"
+	"github.com/fipso/chadbot/internal/storage"
+)
+
+// failingProvider is an LLM provider whose requests fail
+type failingProvider struct{}
+
+func (failingProvider) Name() string { return "failing" }
+
+func (failingProvider) Chat(context.Context, []llm.Message, []llm.Tool) (*llm.Response, error) {
+	return nil, errors.New("provider unavailable")
+}
+
+func TestFailedRegenerateKeepsOriginalAnswer(t *testing.T) {
+	s, h, alice, _ := newIsolationServer(t)
+	registry := plugin.NewRegistry()
+	router := llm.NewRouter(plugin.NewManager(registry, event.NewBus()), registry, nil)
+	router.RegisterProvider(failingProvider{})
+	s.llmRouter = router
+
+	chatID := createChat(t, h, alice, "alice")
+	answer := &storage.Message{ID: "answer", ChatID: chatID, ParentID: "alice-msg", Role: "assistant", Content: "first answer", CreatedAt: time.Now()}
+	if err := storage.AddBranchMessage(answer); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := do(t, h, alice, http.MethodPost, "/api/chats/"+chatID+"/messages/answer/regenerate", nil)
+	if rec.Code != http.StatusBadGateway {
+		t.Fatalf("regenerate: got %d %s, want 502", rec.Code, rec.Body)
+	}
+	chat, err := storage.GetChat(chatID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chat.ActiveLeaf != "answer" {
+		t.Fatalf("active leaf %q after a failed regenerate, want the original answer", chat.ActiveLeaf)
+	}
+}
+
+func TestSwitchBranchErrors(t *testing.T) {
+	_, h, alice, _ := newIsolationServer(t)
+	chatID := createChat(t, h, alice, "alice")
+
+	rec := do(t, h, alice, http.MethodPost, "/api/chats/"+chatID+"/branch", SwitchBranchRequest{MessageID: "missing"})
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown message: got %d, want 404", rec.Code)
+	}
+
+	// Database failures are not reported as missing messages
+	if err := storage.DB.Exec("DROP TABLE messages").Error; err != nil {
+		t.Fatal(err)
+	}
+	rec = do(t, h, alice, http.MethodPost, "/api/chats/"+chatID+"/branch", SwitchBranchRequest{MessageID: "alice-msg"})
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("messages table missing: got %d, want 500", rec.Code)
+	}
+}
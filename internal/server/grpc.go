@@ -1,9 +1,12 @@
 package server
 
 import (
+	"fmt"
 	"log"
 	"net"
 	"os"
+	"os/user"
+	"strconv"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
@@ -12,12 +15,20 @@ import (
 	"github.com/fipso/chadbot/internal/plugin"
 )
 
+// maxPluginMessageSize is the largest message a plugin may send
+const maxPluginMessageSize = 64 << 20
+
+// DefaultSocketMode lets the backend's user and group connect to the plugin socket
+const DefaultSocketMode os.FileMode = 0660
+
 // GRPCServer wraps the gRPC server for plugin communication
 type GRPCServer struct {
 	pb.UnimplementedPluginServiceServer
 	server  *grpc.Server
 	handler *plugin.Handler
 	socket  string
+	mode    os.FileMode
+	group   string
 }
 
 // NewGRPCServer creates a new gRPC server
@@ -25,9 +36,16 @@ func NewGRPCServer(handler *plugin.Handler, socket string) *GRPCServer {
 	return &GRPCServer{
 		handler: handler,
 		socket:  socket,
+		mode:    DefaultSocketMode,
 	}
 }
 
+// SetSocketPermissions sets the socket's file mode and, if not empty, its group
+func (s *GRPCServer) SetSocketPermissions(mode os.FileMode, group string) {
+	s.mode = mode
+	s.group = group
+}
+
 // Start starts the gRPC server on the Unix socket
 func (s *GRPCServer) Start() error {
 	// Remove existing socket file if it exists
@@ -40,12 +58,14 @@ func (s *GRPCServer) Start() error {
 		return err
 	}
 
-	// Set socket permissions
-	if err := os.Chmod(s.socket, 0666); err != nil {
-		log.Printf("[GRPC] Warning: failed to set socket permissions: %v", err)
+	// Only processes that can open the socket may connect; plugins are authenticated on registration
+	if err := s.setPermissions(); err != nil {
+		listener.Close()
+		return err
 	}
 
-	s.server = grpc.NewServer()
+	// Batches such as a WhatsApp history sync exceed the 4 MB default
+	s.server = grpc.NewServer(grpc.MaxRecvMsgSize(maxPluginMessageSize), grpc.Creds(peerCredentials{}))
 	pb.RegisterPluginServiceServer(s.server, s)
 
 	// Enable reflection for debugging with grpcurl
@@ -55,6 +75,28 @@ func (s *GRPCServer) Start() error {
 	return s.server.Serve(listener)
 }
 
+// setPermissions applies the socket's file mode and group
+func (s *GRPCServer) setPermissions() error {
+	if s.group != "" {
+		group, err := user.LookupGroup(s.group)
+		if err != nil {
+			return fmt.Errorf("socket group: %w", err)
+		}
+		gid, err := strconv.Atoi(group.Gid)
+		if err != nil {
+			return fmt.Errorf("socket group %s: invalid gid %s", s.group, group.Gid)
+		}
+		if err := os.Chown(s.socket, -1, gid); err != nil {
+			return fmt.Errorf("failed to set socket group: %w", err)
+		}
+	}
+	if err := os.Chmod(s.socket, s.mode); err != nil {
+		return fmt.Errorf("failed to set socket permissions: %w", err)
+	}
+	log.Printf("[GRPC] Socket mode %04o", s.mode)
+	return nil
+}
+
 // Stop gracefully stops the gRPC server
 func (s *GRPCServer) Stop() {
 	if s.server != nil {
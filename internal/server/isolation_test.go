@@ -0,0 +1,234 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fipso/chadbot/internal/auth"
+	"github.com/fipso/chadbot/internal/blob"
+	"github.com/fipso/chadbot/internal/event"
+	"github.com/fipso/chadbot/internal/storage"
+)
+
+// testUser is a signed-in user of the test server
+type testUser struct {
+	id      string
+	session string
+}
+
+// newIsolationServer starts a server on a fresh database with the users alice and bob
+func newIsolationServer(t *testing.T) (*WebSocketServer, http.Handler, testUser, testUser) {
+	t.Helper()
+	if err := storage.Init(filepath.Join(t.TempDir(), "chadbot.db")); err != nil {
+		t.Fatalf("init storage: %v", err)
+	}
+
+	signIn := func(username string) testUser {
+		if _, err := auth.CreateUser(username, "password123", auth.RoleUser); err != nil {
+			t.Fatalf("create %s: %v", username, err)
+		}
+		user, session, err := auth.Login(username, "password123")
+		if err != nil {
+			t.Fatalf("login %s: %v", username, err)
+		}
+		return testUser{id: user.ID, session: session}
+	}
+	alice, bob := signIn("alice"), signIn("bob")
+
+	s := NewWebSocketServer("", event.NewBus(), nil, nil, nil, nil, nil)
+	return s, s.Handler(), alice, bob
+}
+
+// do sends a request as a user and returns the recorded response
+func do(t *testing.T, h http.Handler, user testUser, method, path string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.AddCookie(&http.Cookie{Name: auth.SessionCookie, Value: user.session})
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+// createChat creates a chat with one message as a user
+func createChat(t *testing.T, h http.Handler, user testUser, name string) string {
+	t.Helper()
+	rec := do(t, h, user, http.MethodPost, "/api/chats", CreateChatRequest{Name: name})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create chat: %d %s", rec.Code, rec.Body)
+	}
+	var chat storage.Chat
+	if err := json.NewDecoder(rec.Body).Decode(&chat); err != nil {
+		t.Fatal(err)
+	}
+	msg := &storage.Message{ID: name + "-msg", ChatID: chat.ID, Role: "user", Content: "secret of " + name, CreatedAt: time.Now()}
+	if err := storage.AddMessage(msg); err != nil {
+		t.Fatal(err)
+	}
+	return chat.ID
+}
+
+// fakeClient registers a WebSocket client without a connection
+func fakeClient(s *WebSocketServer, id, userID string) *WSClient {
+	c := &WSClient{ID: id, UserID: userID, Send: make(chan []byte, 16), Server: s}
+	s.mu.Lock()
+	s.clients[id] = c
+	s.mu.Unlock()
+	return c
+}
+
+// received returns the types of the messages queued for a client
+func received(t *testing.T, c *WSClient) []string {
+	t.Helper()
+	var types []string
+	for {
+		select {
+		case data := <-c.Send:
+			var msg WSMessage
+			if err := json.Unmarshal(data, &msg); err != nil {
+				t.Fatal(err)
+			}
+			types = append(types, msg.Type)
+		default:
+			return types
+		}
+	}
+}
+
+func TestChatsOfOtherUsersAreNotFound(t *testing.T) {
+	_, h, alice, bob := newIsolationServer(t)
+	chatID := createChat(t, h, alice, "alice")
+
+	for _, tc := range []struct {
+		method, path string
+		body         any
+	}{
+		{http.MethodGet, "/api/chats/" + chatID, nil},
+		{http.MethodGet, "/api/chats/" + chatID + "/messages", nil},
+		{http.MethodGet, "/api/chats/" + chatID + "/export?format=md", nil},
+		{http.MethodPut, "/api/chats/" + chatID, UpdateChatRequest{Name: "mine"}},
+		{http.MethodPost, "/api/chats/" + chatID + "/branch", SwitchBranchRequest{MessageID: "alice-msg"}},
+		{http.MethodPost, "/api/chats/" + chatID + "/messages/alice-msg/edit", EditMessageRequest{Content: "changed"}},
+		{http.MethodDelete, "/api/chats/" + chatID, nil},
+	} {
+		if rec := do(t, h, bob, tc.method, tc.path, tc.body); rec.Code != http.StatusNotFound {
+			t.Errorf("%s %s as bob: got %d, want 404", tc.method, tc.path, rec.Code)
+		}
+	}
+
+	// The chat is untouched and still readable by its owner
+	rec := do(t, h, alice, http.MethodGet, "/api/chats/"+chatID+"/messages", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("get messages as alice: %d %s", rec.Code, rec.Body)
+	}
+	var page MessagePageResponse
+	if err := json.NewDecoder(rec.Body).Decode(&page); err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Messages) != 1 || page.Messages[0].Content != "secret of alice" {
+		t.Errorf("alice's messages = %+v", page.Messages)
+	}
+}
+
+func TestChatListOnlyContainsOwnChats(t *testing.T) {
+	_, h, alice, bob := newIsolationServer(t)
+	aliceChat := createChat(t, h, alice, "alice")
+	bobChat := createChat(t, h, bob, "bob")
+
+	rec := do(t, h, bob, http.MethodGet, "/api/chats", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("list chats: %d %s", rec.Code, rec.Body)
+	}
+	var chats []storage.Chat
+	if err := json.NewDecoder(rec.Body).Decode(&chats); err != nil {
+		t.Fatal(err)
+	}
+	if len(chats) != 1 || chats[0].ID != bobChat {
+		t.Errorf("bob's chats = %+v, want only %s (not %s)", chats, bobChat, aliceChat)
+	}
+}
+
+func TestWebSocketRejectsOtherUsersChat(t *testing.T) {
+	s, h, alice, bob := newIsolationServer(t)
+	chatID := createChat(t, h, alice, "alice")
+	client := fakeClient(s, "bob-client", bob.id)
+
+	client.handleLoadMessages(LoadMessagesRequest{ChatID: chatID})
+	client.handleChatMessage(IncomingChatMessage{ChatID: chatID, Content: "hi"})
+	client.handleSwitchBranch(SwitchBranchRequest{ChatID: chatID, MessageID: "alice-msg"})
+
+	got := received(t, client)
+	if len(got) != 3 || got[0] != "chat.error" || got[1] != "chat.error" || got[2] != "chat.error" {
+		t.Errorf("bob received %v, want three chat.error", got)
+	}
+	messages, err := storage.GetChatMessages(chatID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 1 {
+		t.Errorf("alice's chat has %d messages, want 1", len(messages))
+	}
+}
+
+func TestBroadcastsOnlyReachTheChatOwner(t *testing.T) {
+	s, h, alice, bob := newIsolationServer(t)
+	chatID := createChat(t, h, alice, "alice")
+	aliceClient := fakeClient(s, "alice-client", alice.id)
+	bobClient := fakeClient(s, "bob-client", bob.id)
+
+	s.BroadcastMessage(chatID, &storage.Message{ID: "reply", ChatID: chatID, Role: "assistant", Content: "hello alice"})
+	s.SendToChat(chatID, "chat.tool_call", map[string]string{"chat_id": chatID})
+	s.SendToChat("unknown-chat", "chat.tool_call", map[string]string{"chat_id": "unknown-chat"})
+
+	if got := received(t, aliceClient); len(got) != 2 {
+		t.Errorf("alice received %v, want chat.message and chat.tool_call", got)
+	}
+	if got := received(t, bobClient); len(got) != 0 {
+		t.Errorf("bob received %v, want nothing", got)
+	}
+}
+
+func TestBlobsOfOtherUsersAreNotFound(t *testing.T) {
+	s, h, alice, bob := newIsolationServer(t)
+	blobs, err := blob.New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetBlobStore(blobs)
+
+	chatID := createChat(t, h, alice, "alice")
+	b, err := blobs.Put([]byte("alice's photo"), "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	refs, err := json.Marshal([]storage.AttachmentRef{{Type: "image", MimeType: b.MimeType, Hash: b.Hash, Size: b.Size}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := &storage.Message{ID: "photo-msg", ChatID: chatID, Role: "user", Attachments: string(refs), CreatedAt: time.Now()}
+	if err := storage.AddMessage(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := do(t, h, alice, http.MethodGet, "/api/blobs/"+b.Hash, nil)
+	if rec.Code != http.StatusOK || rec.Body.String() != "alice's photo" {
+		t.Fatalf("get blob as alice: %d %s", rec.Code, rec.Body)
+	}
+	if got := rec.Header().Get("Cache-Control"); !strings.HasPrefix(got, "private,") {
+		t.Errorf("Cache-Control = %q, want private", got)
+	}
+	if rec := do(t, h, bob, http.MethodGet, "/api/blobs/"+b.Hash, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("get blob as bob: got %d, want 404", rec.Code)
+	}
+}
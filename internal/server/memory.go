@@ -0,0 +1,108 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fipso/chadbot/internal/storage"
+)
+
+// MemoryRequest for creating or editing a memory
+type MemoryRequest struct {
+	Content string `json:"content"`
+}
+
+// handleMemories handles GET /api/memories and POST /api/memories
+func (s *WebSocketServer) handleMemories(w http.ResponseWriter, r *http.Request) {
+	userID := requestUserID(r)
+
+	switch r.Method {
+	case http.MethodGet:
+		memories, err := storage.GetUserMemories(userID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(memories)
+
+	case http.MethodPost:
+		var req MemoryRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if strings.TrimSpace(req.Content) == "" {
+			http.Error(w, "Content is required", http.StatusBadRequest)
+			return
+		}
+
+		memory, err := s.llmRouter.SaveMemory(userID, req.Content, "api", "")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(memory)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleMemoryByID handles GET/PUT/DELETE /api/memories/{id}
+func (s *WebSocketServer) handleMemoryByID(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/memories/"), "/")
+	if id == "" {
+		http.Error(w, "Memory ID required", http.StatusBadRequest)
+		return
+	}
+
+	memory, err := storage.GetMemory(id)
+	if err != nil || memory.UserID != requestUserID(r) {
+		http.Error(w, "Memory not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(memory)
+
+	case http.MethodPut:
+		var req MemoryRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if strings.TrimSpace(req.Content) == "" {
+			http.Error(w, "Content is required", http.StatusBadRequest)
+			return
+		}
+
+		// Clear the vector so it is re-embedded with the new content
+		memory.Content = strings.TrimSpace(req.Content)
+		memory.Model = ""
+		memory.Vector = nil
+		memory.UpdatedAt = time.Now()
+		if err := storage.UpdateMemory(memory); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(memory)
+
+	case http.MethodDelete:
+		if err := storage.DeleteMemory(id); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
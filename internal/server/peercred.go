@@ -0,0 +1,49 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+
+	"google.golang.org/grpc/credentials"
+
+	"github.com/fipso/chadbot/internal/plugin"
+)
+
+// peerCredentials attaches the Unix peer credentials of each connection to its streams,
+// so the plugin handler can check which user runs a plugin. It adds no encryption.
+type peerCredentials struct{}
+
+// ServerHandshake reads the credentials of the process on the other end of the socket
+func (peerCredentials) ServerHandshake(conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return conn, nil, nil
+	}
+	cred, err := readPeerCred(unixConn)
+	if err != nil {
+		// The connection may still authenticate with a token
+		log.Printf("[GRPC] Failed to read peer credentials: %v", err)
+		return conn, nil, nil
+	}
+	cred.SecurityLevel = credentials.NoSecurity
+	return conn, cred, nil
+}
+
+// ClientHandshake is not supported: the backend only accepts connections
+func (peerCredentials) ClientHandshake(context.Context, string, net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return nil, nil, errors.New("peer credentials are server-side only")
+}
+
+func (peerCredentials) Info() credentials.ProtocolInfo {
+	return credentials.ProtocolInfo{SecurityProtocol: plugin.AuthPeerCred}
+}
+
+func (c peerCredentials) Clone() credentials.TransportCredentials {
+	return c
+}
+
+func (peerCredentials) OverrideServerName(string) error {
+	return nil
+}
@@ -0,0 +1,27 @@
+package server
+
+import (
+	"net"
+	"syscall"
+
+	"github.com/fipso/chadbot/internal/plugin"
+)
+
+// readPeerCred reads SO_PEERCRED from a Unix socket connection
+func readPeerCred(conn *net.UnixConn) (plugin.PeerCred, error) {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return plugin.PeerCred{}, err
+	}
+	var ucred *syscall.Ucred
+	var credErr error
+	if err := raw.Control(func(fd uintptr) {
+		ucred, credErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	}); err != nil {
+		return plugin.PeerCred{}, err
+	}
+	if credErr != nil {
+		return plugin.PeerCred{}, credErr
+	}
+	return plugin.PeerCred{PID: ucred.Pid, UID: ucred.Uid, GID: ucred.Gid}, nil
+}
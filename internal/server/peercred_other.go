@@ -0,0 +1,15 @@
+//go:build !linux
+
+package server
+
+import (
+	"errors"
+	"net"
+
+	"github.com/fipso/chadbot/internal/plugin"
+)
+
+// readPeerCred is only implemented on Linux; elsewhere plugins authenticate with tokens
+func readPeerCred(*net.UnixConn) (plugin.PeerCred, error) {
+	return plugin.PeerCred{}, errors.New("peer credentials are not supported on this platform")
+}
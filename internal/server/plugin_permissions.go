@@ -0,0 +1,42 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ApprovePermissionsRequest is the request body for approving plugin permissions
+type ApprovePermissionsRequest struct {
+	Approved []string `json:"approved"`
+}
+
+// handlePluginPermissions handles GET/PUT /api/plugins/{name}/permissions. PUT replaces
+// the approved permissions (admin only) and applies them to the connected plugin.
+func (s *WebSocketServer) handlePluginPermissions(w http.ResponseWriter, r *http.Request, pluginName string) {
+	switch r.Method {
+	case http.MethodGet:
+
+	case http.MethodPut:
+		var req ApprovePermissionsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := s.pluginHandler.Approve(pluginName, req.Approved, requestUserID(r)); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	perms, err := s.pluginHandler.Permissions(pluginName)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(perms)
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/fipso/chadbot/internal/retention"
+)
+
+// RetentionResponse shows the retention policies and the last janitor run
+type RetentionResponse struct {
+	Config     *retention.Config `json:"config"`
+	LastReport *retention.Report `json:"last_report"`
+}
+
+// handleRetention handles GET /api/retention
+func (s *WebSocketServer) handleRetention(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.janitor == nil {
+		http.Error(w, "Retention is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	cfg, err := s.janitor.Config()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RetentionResponse{Config: cfg, LastReport: s.janitor.LastReport()})
+}
+
+// handleRetentionRun handles POST /api/retention/run - enforces the policies now
+func (s *WebSocketServer) handleRetentionRun(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.janitor == nil {
+		http.Error(w, "Retention is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	report, err := s.janitor.RunOnce()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// handleForgetChat handles POST /api/chats/{id}/forget - removes a chat and everything derived from it
+func (s *WebSocketServer) handleForgetChat(w http.ResponseWriter, r *http.Request, chatID string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.janitor == nil {
+		http.Error(w, "Retention is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	report, err := s.janitor.ForgetChat(chatID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,43 @@
+package server
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/fipso/chadbot/internal/retention"
+	"github.com/fipso/chadbot/internal/storage"
+)
+
+func TestForgetDeletedChat(t *testing.T) {
+	s, h, alice, bob := newIsolationServer(t)
+	janitor, err := retention.NewJanitor(filepath.Join(t.TempDir(), "retention.toml"), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetJanitor(janitor)
+
+	chatID := createChat(t, h, alice, "alice")
+	if rec := do(t, h, alice, http.MethodDelete, "/api/chats/"+chatID, nil); rec.Code >= 300 {
+		t.Fatalf("delete chat: %d %s", rec.Code, rec.Body)
+	}
+
+	// The deleted chat is still hidden from everyone but its owner's forget request
+	if rec := do(t, h, alice, http.MethodGet, "/api/chats/"+chatID, nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("get deleted chat: %d, want 404", rec.Code)
+	}
+	if rec := do(t, h, bob, http.MethodPost, "/api/chats/"+chatID+"/forget", nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("bob forgetting alice's deleted chat: %d, want 404", rec.Code)
+	}
+	if rec := do(t, h, alice, http.MethodPost, "/api/chats/"+chatID+"/forget", nil); rec.Code != http.StatusOK {
+		t.Fatalf("forget deleted chat: %d %s", rec.Code, rec.Body)
+	}
+
+	exists, err := storage.ChatExists(chatID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exists {
+		t.Fatal("forgotten chat still stored")
+	}
+}
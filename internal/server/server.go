@@ -6,12 +6,19 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	"github.com/fipso/chadbot/internal/access"
+	"github.com/fipso/chadbot/internal/auth"
+	"github.com/fipso/chadbot/internal/backup"
+	"github.com/fipso/chadbot/internal/blob"
 	"github.com/fipso/chadbot/internal/chat"
 	appconfig "github.com/fipso/chadbot/internal/config"
 	"github.com/fipso/chadbot/internal/event"
+	"github.com/fipso/chadbot/internal/knowledge"
 	"github.com/fipso/chadbot/internal/llm"
 	"github.com/fipso/chadbot/internal/plugin"
+	"github.com/fipso/chadbot/internal/retention"
 	"github.com/fipso/chadbot/internal/souls"
 	"github.com/fipso/chadbot/internal/storage"
 )
@@ -31,10 +38,16 @@ func (a *llmAdapter) Chat(ctx context.Context, messages []chat.Message, provider
 		}
 	}
 
-	// Build chat context
+	// Build chat context, answering with the chat owner's soul and default provider
 	var chatCtx *llm.ChatContext
 	if chatID != "" {
-		chatCtx = &llm.ChatContext{ChatID: chatID}
+		userID, _ := storage.GetChatUserID(chatID)
+		chatCtx = &llm.ChatContext{ChatID: chatID, UserID: userID}
+		if provider == "" {
+			if user, err := storage.GetUser(userID); err == nil {
+				provider = a.router.DefaultProviderFor(user.DefaultProvider)
+			}
+		}
 	}
 
 	resp, err := a.router.Chat(ctx, llmMsgs, provider, chatCtx)
@@ -56,8 +69,32 @@ type Config struct {
 	ZAIKey       string
 	DefaultLLM   string
 	DBPath       string
+	ToolSearch   bool     // Lazy tool discovery via search_tools
+	CoreTools    []string // Tool name globs always sent in tool search mode
+
+	Embedder       string // Embedder for semantic recall: "openai", "hash" or "" (disabled)
+	EmbeddingURL   string // Base URL of an OpenAI-compatible embeddings API
+	EmbeddingModel string // Embedding model name
+
+	MemoryExtract bool // Automatically extract long-term memories after each turn
+
+	KnowledgeDir string // Knowledge base directory (default ~/.config/chadbot/knowledge)
+	BlobDir      string // Attachment blob store directory (default ~/.config/chadbot/blobs)
+
+	RetentionConfig string // Retention policy file (default ~/.config/chadbot/retention.toml)
+	BackupConfig    string // Backup settings file (default ~/.config/chadbot/backup.toml)
+	AccessConfig    string // Skill access policy file (default ~/.config/chadbot/access.toml)
+
+	CORSOrigins []string // Origins allowed to call the API from a browser (default same host)
+
+	SocketMode  os.FileMode       // Plugin socket file mode (default 0660)
+	SocketGroup string            // Group of the plugin socket (default the backend's group)
+	PluginAuth  plugin.AuthConfig // How plugins prove their identity when registering
 }
 
+// blobGCInterval is how often unreferenced attachment blobs are removed
+const blobGCInterval = 6 * time.Hour
+
 // Server is the main chadbot server
 type Server struct {
 	config       *Config
@@ -70,6 +107,12 @@ type Server struct {
 	handler      *plugin.Handler
 	souls        *souls.Manager
 	pluginConfig *appconfig.PluginConfigManager
+	recall       *llm.Recall
+	knowledge    *knowledge.Manager
+	blobs        *blob.Store
+	janitor      *retention.Janitor
+	backups      *backup.Manager
+	access       *access.Manager
 }
 
 // New creates a new server instance
@@ -77,6 +120,9 @@ func New(config *Config) *Server {
 	if config.Socket == "" {
 		config.Socket = DefaultSocket
 	}
+	if config.SocketMode == 0 {
+		config.SocketMode = DefaultSocketMode
+	}
 	if config.HTTPAddr == "" {
 		config.HTTPAddr = ":8080"
 	}
@@ -89,6 +135,20 @@ func New(config *Config) *Server {
 		log.Fatalf("[Server] Failed to initialize database: %v", err)
 	}
 
+	// Create the first admin account on first run
+	if err := auth.Bootstrap(); err != nil {
+		log.Fatalf("[Server] Failed to create admin user: %v", err)
+	}
+
+	// Initialize blob store for attachment data and move legacy inline attachments into it
+	blobs, err := blob.New(config.BlobDir)
+	if err != nil {
+		log.Fatalf("[Server] Failed to initialize blob store: %v", err)
+	}
+	if _, err := blobs.MigrateInlineAttachments(); err != nil {
+		log.Printf("[Server] Warning: Failed to migrate inline attachments: %v", err)
+	}
+
 	// Initialize core components
 	registry := plugin.NewRegistry()
 	eventBus := event.NewBus()
@@ -111,9 +171,20 @@ func New(config *Config) *Server {
 
 	// Create chat service (reuses same logic as web UI)
 	chatService := chat.NewService(&llmAdapter{router: llmRouter})
+	chatService.SetBlobStore(blobs)
 
 	// Create handler with chat service and plugin config
 	handler := plugin.NewHandler(manager, chatService, pluginConfigManager)
+	handler.SetAuth(config.PluginAuth)
+
+	// API keys may be encrypted values or env:/file: references
+	for _, key := range []*string{&config.OpenAIKey, &config.AnthropicKey, &config.ZAIKey} {
+		resolved, err := appconfig.ResolveSecret(*key)
+		if err != nil {
+			log.Fatalf("[Server] Failed to resolve API key: %v", err)
+		}
+		*key = resolved
+	}
 
 	// Register LLM providers
 	if config.OpenAIKey != "" || os.Getenv("OPENAI_API_KEY") != "" {
@@ -128,13 +199,68 @@ func New(config *Config) *Server {
 	if config.DefaultLLM != "" {
 		llmRouter.SetDefaultProvider(config.DefaultLLM)
 	}
+	llmRouter.SetToolSearch(llm.ToolSearchConfig{
+		Enabled:   config.ToolSearch,
+		CoreTools: config.CoreTools,
+	})
+
+	llmRouter.SetMemoryConfig(llm.MemoryConfig{
+		AutoExtract: config.MemoryExtract,
+	})
+
+	// Decide which skills users may use; an invalid policy file must not silently allow everything
+	accessManager, err := access.NewManager(config.AccessConfig)
+	if err != nil {
+		log.Fatalf("[Server] Failed to load access policies: %v", err)
+	}
+	llmRouter.SetAccess(accessManager)
+
+	// Set up embeddings for semantic recall (also ranks tool search results and memories)
+	var recall *llm.Recall
+	embedder := newEmbedder(config)
+	if embedder != nil {
+		llmRouter.SetEmbedder(embedder)
+		recall = llm.NewRecall(embedder)
+		llmRouter.SetRecall(recall)
+	}
+
+	// Initialize knowledge base (ingests documents from the knowledge directory)
+	kb, err := knowledge.NewManager(config.KnowledgeDir, embedder)
+	if err != nil {
+		log.Printf("[Server] Warning: Failed to initialize knowledge base: %v", err)
+	} else {
+		llmRouter.RegisterBuiltin(kb.Skill())
+	}
 
 	// Create servers
 	grpc := NewGRPCServer(handler, config.Socket)
+	grpc.SetSocketPermissions(config.SocketMode, config.SocketGroup)
 	ws := NewWebSocketServer(config.HTTPAddr, eventBus, llmRouter, manager, handler, soulsManager, pluginConfigManager)
 
 	// Wire up WebSocket as message broadcaster for real-time plugin message updates
 	chatService.SetBroadcaster(ws)
+	ws.SetBlobStore(blobs)
+	ws.SetCORSOrigins(config.CORSOrigins)
+	ws.SetAccess(accessManager)
+
+	// Enforce retention policies and purge deleted chats
+	janitor, err := retention.NewJanitor(config.RetentionConfig, blobs, eventBus)
+	if err != nil {
+		log.Printf("[Server] Warning: Retention disabled: %v", err)
+	} else {
+		ws.SetJanitor(janitor)
+	}
+	if kb != nil {
+		ws.SetKnowledge(kb)
+	}
+
+	// Back up the database, config and souls on demand and on schedule
+	backups, err := backup.NewManager(config.BackupConfig, config.DBPath)
+	if err != nil {
+		log.Printf("[Server] Warning: Backups disabled: %v", err)
+	} else {
+		ws.SetBackups(backups)
+	}
 
 	return &Server{
 		config:       config,
@@ -147,6 +273,27 @@ func New(config *Config) *Server {
 		handler:      handler,
 		souls:        soulsManager,
 		pluginConfig: pluginConfigManager,
+		recall:       recall,
+		knowledge:    kb,
+		blobs:        blobs,
+		janitor:      janitor,
+		backups:      backups,
+		access:       accessManager,
+	}
+}
+
+// newEmbedder creates the configured embedder (nil if disabled)
+func newEmbedder(config *Config) llm.Embedder {
+	switch config.Embedder {
+	case "":
+		return nil
+	case "openai":
+		return llm.NewOpenAIEmbedder(config.OpenAIKey, config.EmbeddingURL, config.EmbeddingModel)
+	case "hash":
+		return llm.NewHashEmbedder(0)
+	default:
+		log.Printf("[Server] Warning: Unknown embedder %q, semantic recall disabled", config.Embedder)
+		return nil
 	}
 }
 
@@ -167,6 +314,29 @@ func (s *Server) Start(ctx context.Context) error {
 		}
 	}()
 
+	// Embed chat messages in the background for semantic recall
+	if s.recall != nil {
+		go s.recall.Run(ctx)
+	}
+
+	// Remove attachment blobs no message references anymore
+	go s.blobs.Run(ctx, blobGCInterval)
+
+	// Purge deleted chats and expired messages and plugin rows
+	if s.janitor != nil {
+		go s.janitor.Run(ctx)
+	}
+
+	// Take scheduled backups
+	if s.backups != nil {
+		go s.backups.Run(ctx)
+	}
+
+	// Ingest the knowledge base in the background
+	if s.knowledge != nil {
+		s.knowledge.Start()
+	}
+
 	// Start WebSocket server
 	go func() {
 		if err := s.ws.Start(); err != nil {
@@ -194,6 +364,10 @@ func (s *Server) Stop() {
 	log.Println("[Server] Stopping...")
 	s.grpc.Stop()
 	s.ws.Stop()
+	s.access.Stop()
+	if s.knowledge != nil {
+		s.knowledge.Stop()
+	}
 	log.Println("[Server] Stopped")
 }
 
@@ -0,0 +1,138 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/fipso/chadbot/internal/chat"
+	"github.com/fipso/chadbot/internal/storage"
+)
+
+const maxImportSize = 512 << 20
+
+// exportOptions reads export options from ?format=&attachments=inline&metadata=false
+func exportOptions(r *http.Request) (chat.ExportOptions, error) {
+	q := r.URL.Query()
+	opts := chat.ExportOptions{
+		Format:            q.Get("format"),
+		InlineAttachments: q.Get("attachments") == "inline",
+		NoMetadata:        q.Get("metadata") == "false",
+	}
+	switch opts.Format {
+	case "":
+		opts.Format = chat.FormatJSON
+	case chat.FormatJSON, chat.FormatMarkdown, chat.FormatJSONL:
+	default:
+		return opts, fmt.Errorf("unknown format %q (use json, md or jsonl)", opts.Format)
+	}
+	return opts, nil
+}
+
+// writeExport renders chats as a file download
+func (s *WebSocketServer) writeExport(w http.ResponseWriter, r *http.Request, chatIDs []string, filename string) {
+	opts, err := exportOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Render into a buffer so errors can still be reported with a status code
+	var buf bytes.Buffer
+	if err := chat.ExportChats(&buf, chatIDs, opts, s.blobs); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	switch opts.Format {
+	case chat.FormatMarkdown:
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	case chat.FormatJSONL:
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	default:
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s.%s", filename, opts.Format))
+	w.Write(buf.Bytes())
+}
+
+// handleChatExport handles GET /api/chats/{id}/export?format=md|json|jsonl
+func (s *WebSocketServer) handleChatExport(w http.ResponseWriter, r *http.Request, chatID string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if _, err := storage.GetChat(chatID); err != nil {
+		http.Error(w, "Chat not found", http.StatusNotFound)
+		return
+	}
+	s.writeExport(w, r, []string{chatID}, "chat-"+chatID)
+}
+
+// handleChatsExport handles GET /api/chats/export?format= - exports all chats of a user
+func (s *WebSocketServer) handleChatsExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID := requestUserID(r)
+
+	chats, err := storage.GetUserChats(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	ids := make([]string, len(chats))
+	for i, c := range chats {
+		ids[i] = c.ID
+	}
+	s.writeExport(w, r, ids, "chadbot-chats")
+}
+
+// handleChatsImport handles POST /api/chats/import?format= - imports an export
+// sent as request body or as multipart "file" field
+func (s *WebSocketServer) handleChatsImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID := requestUserID(r)
+	format := r.URL.Query().Get("format")
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	var body io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, "Failed to get file: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		body = file
+		if format == "" {
+			switch {
+			case strings.HasSuffix(header.Filename, ".md"):
+				format = chat.FormatMarkdown
+			case strings.HasSuffix(header.Filename, ".jsonl"):
+				format = chat.FormatJSONL
+			}
+		}
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		http.Error(w, "Failed to read import: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := chat.ImportChats(data, format, userID, s.blobs)
+	if err != nil {
+		http.Error(w, "Failed to import chats: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
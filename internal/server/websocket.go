@@ -2,11 +2,14 @@ package server
 
 import (
 	"context"
-	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -16,24 +19,24 @@ import (
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	pb "github.com/fipso/chadbot/gen/chadbot"
+	"github.com/fipso/chadbot/internal/access"
+	"github.com/fipso/chadbot/internal/auth"
+	"github.com/fipso/chadbot/internal/backup"
+	"github.com/fipso/chadbot/internal/blob"
 	"github.com/fipso/chadbot/internal/config"
 	"github.com/fipso/chadbot/internal/event"
+	"github.com/fipso/chadbot/internal/knowledge"
 	"github.com/fipso/chadbot/internal/llm"
 	"github.com/fipso/chadbot/internal/plugin"
+	"github.com/fipso/chadbot/internal/retention"
 	"github.com/fipso/chadbot/internal/souls"
 	"github.com/fipso/chadbot/internal/storage"
 )
 
-var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool {
-		return true // Allow all origins for development
-	},
-}
-
 // WSClient represents a connected WebSocket client
 type WSClient struct {
 	ID     string
-	UserID string // For now, same as client ID
+	UserID string // Authenticated user
 	Conn   *websocket.Conn
 	Send   chan []byte
 	Server *WebSocketServer
@@ -53,6 +56,14 @@ type IncomingChatMessage struct {
 	Soul     string `json:"soul,omitempty"`
 }
 
+// LoadMessagesRequest from PWA client to page through chat history
+type LoadMessagesRequest struct {
+	ChatID string `json:"chat_id"`
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+	Limit  int    `json:"limit,omitempty"`
+}
+
 // CreateChatRequest for creating a new chat
 type CreateChatRequest struct {
 	Name string `json:"name"`
@@ -68,6 +79,12 @@ type WebSocketServer struct {
 	pluginHandler *plugin.Handler
 	soulsManager  *souls.Manager
 	pluginConfig  *config.PluginConfigManager
+	knowledge     *knowledge.Manager
+	blobs         *blob.Store
+	janitor       *retention.Janitor
+	backups       *backup.Manager
+	access        *access.Manager
+	corsOrigins   []string
 	addr          string
 	server        *http.Server
 }
@@ -90,18 +107,60 @@ func NewWebSocketServer(addr string, eventBus *event.Bus, llmRouter *llm.Router,
 		ws.broadcastEvent(evt)
 	})
 
-	// Set up tool call callback to broadcast tool call events
+	// Set up tool call callback to send tool call events to the chat's owner
 	if llmRouter != nil {
 		llmRouter.SetToolCallCallback(func(evt llm.ToolCallEvent) {
-			ws.Broadcast("chat.tool_call", evt)
+			ws.SendToChat(evt.ChatID, "chat.tool_call", evt)
 		})
 	}
 
 	return ws
 }
 
+// SetKnowledge sets the knowledge base whose ingestion status is reported in /api/status
+func (s *WebSocketServer) SetKnowledge(kb *knowledge.Manager) {
+	s.knowledge = kb
+}
+
+// SetBlobStore sets the store that holds attachment data
+func (s *WebSocketServer) SetBlobStore(blobs *blob.Store) {
+	s.blobs = blobs
+}
+
+// SetJanitor sets the janitor that enforces retention policies and forgets chats
+func (s *WebSocketServer) SetJanitor(janitor *retention.Janitor) {
+	s.janitor = janitor
+}
+
+// SetBackups sets the manager that creates backup archives
+func (s *WebSocketServer) SetBackups(backups *backup.Manager) {
+	s.backups = backups
+}
+
+// SetAccess sets the skill access policies served by /api/access
+func (s *WebSocketServer) SetAccess(m *access.Manager) {
+	s.access = m
+}
+
+// SetCORSOrigins sets the origins allowed to call the API from a browser ("*" = any).
+// Without origins, pages from the same host on any port are allowed.
+func (s *WebSocketServer) SetCORSOrigins(origins []string) {
+	s.corsOrigins = origins
+}
+
 // Start starts the WebSocket server
 func (s *WebSocketServer) Start() error {
+	s.server = &http.Server{
+		Addr:    s.addr,
+		Handler: s.Handler(),
+	}
+
+	log.Printf("[WebSocket] Server listening on %s", s.addr)
+	return s.server.ListenAndServe()
+}
+
+// Handler returns the HTTP handler serving the WebSocket and REST API
+func (s *WebSocketServer) Handler() http.Handler {
 	mux := http.NewServeMux()
 
 	// WebSocket endpoint
@@ -110,6 +169,8 @@ func (s *WebSocketServer) Start() error {
 	// REST API endpoints
 	mux.HandleFunc("/api/chats", s.handleChats)
 	mux.HandleFunc("/api/chats/", s.handleChatByID)
+	mux.HandleFunc("/api/chats/export", s.handleChatsExport)
+	mux.HandleFunc("/api/chats/import", s.handleChatsImport)
 	mux.HandleFunc("/api/status", s.handleStatus)
 	mux.HandleFunc("/api/providers", s.handleProviders)
 	mux.HandleFunc("/api/plugins/", s.handlePluginConfig)
@@ -117,35 +178,30 @@ func (s *WebSocketServer) Start() error {
 	mux.HandleFunc("/api/config/import", s.handleConfigImport)
 	mux.HandleFunc("/api/souls", s.handleSouls)
 	mux.HandleFunc("/api/souls/", s.handleSoulByName)
+	mux.HandleFunc("/api/recall", s.handleRecall)
+	mux.HandleFunc("/api/search", s.handleSearch)
+	mux.HandleFunc("/api/memories", s.handleMemories)
+	mux.HandleFunc("/api/memories/", s.handleMemoryByID)
+	mux.HandleFunc("/api/blobs/", s.handleBlob)
+	mux.HandleFunc("/api/retention", s.handleRetention)
+	mux.HandleFunc("/api/retention/run", s.handleRetentionRun)
+	mux.HandleFunc("/api/admin/backup", s.handleBackup)
+	mux.HandleFunc("/api/admin/backup/", s.handleBackupDownload)
+	mux.HandleFunc("/api/access", s.handleAccess)
+	mux.HandleFunc("/api/auth/login", s.handleLogin)
+	mux.HandleFunc("/api/auth/logout", s.handleLogout)
+	mux.HandleFunc("/api/auth/me", s.handleMe)
+	mux.HandleFunc("/api/auth/password", s.handleChangePassword)
+	mux.HandleFunc("/api/auth/tokens", s.handleTokens)
+	mux.HandleFunc("/api/auth/tokens/", s.handleTokenByID)
+	mux.HandleFunc("/api/users", s.handleUsers)
+	mux.HandleFunc("/api/users/", s.handleUserByID)
 
 	// Health check
 	mux.HandleFunc("/health", s.handleHealth)
 
-	// Wrap with CORS middleware
-	handler := corsMiddleware(mux)
-
-	s.server = &http.Server{
-		Addr:    s.addr,
-		Handler: handler,
-	}
-
-	log.Printf("[WebSocket] Server listening on %s", s.addr)
-	return s.server.ListenAndServe()
-}
-
-func corsMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-
-		next.ServeHTTP(w, r)
-	})
+	// Wrap with auth and CORS middleware
+	return s.corsMiddleware(s.authMiddleware(mux))
 }
 
 // Stop stops the WebSocket server
@@ -162,21 +218,50 @@ func (s *WebSocketServer) handleHealth(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("ok"))
 }
 
+// SetDefaultProviderRequest sets the default provider of a user ("" = instance default)
+type SetDefaultProviderRequest struct {
+	Default string `json:"default"`
+}
+
+// handleProviders handles GET /api/providers and PUT /api/providers (sets the user's default)
 func (s *WebSocketServer) handleProviders(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
+	user := requestUser(r)
+
+	switch r.Method {
+	case http.MethodGet:
+
+	case http.MethodPut:
+		var req SetDefaultProviderRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Default != "" && !s.llmRouter.HasProvider(req.Default) {
+			http.Error(w, "Unknown provider "+req.Default, http.StatusBadRequest)
+			return
+		}
+		user.DefaultProvider = req.Default
+		if err := storage.UpdateUser(user); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+	default:
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	providers := s.llmRouter.ListProviders()
+	providers := s.llmRouter.ListProvidersFor(user.DefaultProvider)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(providers)
 }
 
 // StatusResponse is the response for /api/status
 type StatusResponse struct {
-	Plugins []PluginInfo `json:"plugins"`
-	Skills  []SkillInfo  `json:"skills"`
+	Plugins       []PluginInfo      `json:"plugins"`
+	Skills        []SkillInfo       `json:"skills"`
+	Knowledge     *knowledge.Status `json:"knowledge,omitempty"`
+	DatabaseBytes int64             `json:"database_bytes,omitempty"`
 }
 
 // PluginInfo represents a connected plugin
@@ -187,6 +272,8 @@ type PluginInfo struct {
 	Description string              `json:"description"`
 	Subscribed  []string            `json:"subscribed"`
 	Config      *PluginConfigInfo   `json:"config,omitempty"`
+	Storage     *storage.Usage      `json:"storage,omitempty"`
+	Permissions *plugin.Permissions `json:"permissions,omitempty"`
 }
 
 // PluginConfigInfo represents a plugin's configuration
@@ -227,6 +314,9 @@ func (s *WebSocketServer) handleStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Plugin config is only shown to admins, who are the only ones who can change it
+	isAdmin := requestUser(r).Role == auth.RoleAdmin
+
 	// Get plugins
 	plugins := s.pluginManager.List()
 	pluginInfos := make([]PluginInfo, len(plugins))
@@ -242,8 +332,23 @@ func (s *WebSocketServer) handleStatus(w http.ResponseWriter, r *http.Request) {
 		}
 		pluginNames[p.ID] = p.Name
 
+		if perms, err := s.pluginHandler.Permissions(p.Name); err != nil {
+			log.Printf("[WebSocket] Failed to get permissions of plugin %s: %v", p.Name, err)
+		} else {
+			pluginInfos[i].Permissions = perms
+		}
+
+		if usage, err := storage.PluginUsage(p.Name); err != nil {
+			log.Printf("[WebSocket] Failed to get storage usage for plugin %s: %v", p.Name, err)
+		} else {
+			if quota := storage.QuotaFromConfig(s.pluginConfig.GetPluginConfigs(p.Name)); quota != (storage.Quota{}) {
+				usage.Quota = &quota
+			}
+			pluginInfos[i].Storage = usage
+		}
+
 		// Add config info if schema is available
-		if p.ConfigSchema != nil && len(p.ConfigSchema.Fields) > 0 {
+		if isAdmin && p.ConfigSchema != nil && len(p.ConfigSchema.Fields) > 0 {
 			schemaFields := make([]ConfigFieldInfo, len(p.ConfigSchema.Fields))
 			for j, f := range p.ConfigSchema.Fields {
 				fieldType := "string"
@@ -256,6 +361,8 @@ func (s *WebSocketServer) handleStatus(w http.ResponseWriter, r *http.Request) {
 					fieldType = "string"
 				case pb.ConfigFieldType_CONFIG_FIELD_TYPE_STRING_ARRAY:
 					fieldType = "string_array"
+				case pb.ConfigFieldType_CONFIG_FIELD_TYPE_SECRET:
+					fieldType = "secret"
 				}
 				schemaFields[j] = ConfigFieldInfo{
 					Key:          f.Key,
@@ -301,26 +408,66 @@ func (s *WebSocketServer) handleStatus(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Add built-in skills (implemented by the backend itself)
+	for _, b := range s.llmRouter.ListBuiltins() {
+		skillInfos = append(skillInfos, SkillInfo{
+			Name:        b.Tool.Name,
+			Description: b.Tool.Description,
+			PluginName:  llm.BuiltinPluginName,
+			Parameters:  builtinParamInfos(b.Tool),
+		})
+	}
+
 	resp := StatusResponse{
 		Plugins: pluginInfos,
 		Skills:  skillInfos,
 	}
+	if s.knowledge != nil {
+		status := s.knowledge.Status()
+		resp.Knowledge = &status
+	}
+	if size, err := storage.DatabaseSize(); err == nil {
+		resp.DatabaseBytes = size
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
 }
 
+// builtinParamInfos converts a built-in tool's JSON schema to parameter infos
+func builtinParamInfos(tool llm.Tool) []SkillParamInfo {
+	props, _ := tool.Parameters["properties"].(map[string]interface{})
+	required, _ := tool.Parameters["required"].([]string)
+	isRequired := make(map[string]bool, len(required))
+	for _, name := range required {
+		isRequired[name] = true
+	}
+
+	params := make([]SkillParamInfo, 0, len(props))
+	for name, raw := range props {
+		prop, _ := raw.(map[string]interface{})
+		paramType, _ := prop["type"].(string)
+		desc, _ := prop["description"].(string)
+		params = append(params, SkillParamInfo{
+			Name:        name,
+			Type:        paramType,
+			Description: desc,
+			Required:    isRequired[name],
+		})
+	}
+	sort.Slice(params, func(i, j int) bool {
+		return params[i].Name < params[j].Name
+	})
+	return params
+}
+
 // handleChats handles GET /api/chats and POST /api/chats
 func (s *WebSocketServer) handleChats(w http.ResponseWriter, r *http.Request) {
-	// For now, use a default user ID (in production, extract from auth)
-	userID := r.URL.Query().Get("user_id")
-	if userID == "" {
-		userID = "default"
-	}
+	userID := requestUserID(r)
 
 	switch r.Method {
 	case http.MethodGet:
-		chats, err := storage.GetUserChatsWithMessages(userID)
+		chats, err := storage.GetUserChatsWithPreview(userID)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -366,24 +513,56 @@ type UpdateChatRequest struct {
 	Name string `json:"name"`
 }
 
-// handleChatByID handles GET/PUT/DELETE /api/chats/{id}
+// handleChatByID handles GET/PUT/DELETE /api/chats/{id}, GET /api/chats/{id}/messages,
+// POST /api/chats/{id}/messages/{messageId}/{edit,regenerate}, POST /api/chats/{id}/branch
+// GET /api/chats/{id}/export and POST /api/chats/{id}/forget
 func (s *WebSocketServer) handleChatByID(w http.ResponseWriter, r *http.Request) {
-	// Extract chat ID from URL
-	path := strings.TrimPrefix(r.URL.Path, "/api/chats/")
-	chatID := strings.TrimSuffix(path, "/")
+	// Extract chat ID and optional sub-resource from URL
+	path := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/chats/"), "/")
+	parts := strings.Split(path, "/")
+	chatID := parts[0]
 
 	if chatID == "" {
 		http.Error(w, "Chat ID required", http.StatusBadRequest)
 		return
 	}
 
+	// Other users' chats are reported as missing, so their IDs can't be probed.
+	// Deleted chats can still be forgotten until the janitor purges them.
+	lookup := storage.GetUserChat
+	if len(parts) == 2 && parts[1] == "forget" {
+		lookup = storage.GetUserChatUnscoped
+	}
+	chat, err := lookup(requestUserID(r), chatID)
+	if err != nil {
+		http.Error(w, "Chat not found", http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case len(parts) == 1:
+	case len(parts) == 2 && parts[1] == "messages":
+		s.handleChatMessages(w, r, chatID)
+		return
+	case len(parts) == 4 && parts[1] == "messages":
+		s.handleMessageAction(w, r, chatID, parts[2], parts[3])
+		return
+	case len(parts) == 2 && parts[1] == "branch":
+		s.handleSwitchBranchHTTP(w, r, chatID)
+		return
+	case len(parts) == 2 && parts[1] == "export":
+		s.handleChatExport(w, r, chatID)
+		return
+	case len(parts) == 2 && parts[1] == "forget":
+		s.handleForgetChat(w, r, chatID)
+		return
+	default:
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
 	switch r.Method {
 	case http.MethodGet:
-		chat, err := storage.GetChat(chatID)
-		if err != nil {
-			http.Error(w, "Chat not found", http.StatusNotFound)
-			return
-		}
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(chat)
 
@@ -394,12 +573,6 @@ func (s *WebSocketServer) handleChatByID(w http.ResponseWriter, r *http.Request)
 			return
 		}
 
-		chat, err := storage.GetChat(chatID)
-		if err != nil {
-			http.Error(w, "Chat not found", http.StatusNotFound)
-			return
-		}
-
 		chat.Name = req.Name
 		if err := storage.UpdateChat(chat); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -421,7 +594,205 @@ func (s *WebSocketServer) handleChatByID(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+// MessagePageResponse is a page of chat messages
+type MessagePageResponse struct {
+	ChatID   string            `json:"chat_id"`
+	Messages []storage.Message `json:"messages"` // Chronological order
+	HasMore  bool              `json:"has_more"` // More messages exist in the paging direction
+}
+
+const (
+	defaultMessagePageSize = 50
+	maxMessagePageSize     = 200
+)
+
+// handleChatMessages handles GET /api/chats/{id}/messages?before=&after=&limit=
+func (s *WebSocketServer) handleChatMessages(w http.ResponseWriter, r *http.Request, chatID string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	page := storage.MessagePage{
+		BeforeID: query.Get("before"),
+		AfterID:  query.Get("after"),
+		Limit:    defaultMessagePageSize,
+	}
+	if page.BeforeID != "" && page.AfterID != "" {
+		http.Error(w, "Use either before or after, not both", http.StatusBadRequest)
+		return
+	}
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		page.Limit = min(n, maxMessagePageSize)
+	}
+
+	resp, err := loadMessagePage(chatID, page)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// loadMessagePage loads a page of messages of an existing chat
+func loadMessagePage(chatID string, page storage.MessagePage) (*MessagePageResponse, error) {
+	if _, err := storage.GetChat(chatID); err != nil {
+		return nil, fmt.Errorf("chat not found")
+	}
+	messages, hasMore, err := storage.GetChatMessagesPage(chatID, page)
+	if err != nil {
+		return nil, err
+	}
+	if messages == nil {
+		messages = []storage.Message{}
+	}
+	return &MessagePageResponse{ChatID: chatID, Messages: messages, HasMore: hasMore}, nil
+}
+
+// handleRecall handles GET /api/recall?q=...&limit=&chat_id=&keyword_weight=
+func (s *WebSocketServer) handleRecall(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	recall := s.llmRouter.Recall()
+	if recall == nil {
+		http.Error(w, "Semantic recall not enabled (start with -embedder)", http.StatusServiceUnavailable)
+		return
+	}
+
+	query := r.URL.Query()
+	userID := requestUserID(r)
+
+	q := llm.RecallQuery{
+		Query:  query.Get("q"),
+		UserID: userID,
+		ChatID: query.Get("chat_id"),
+	}
+	if q.Query == "" {
+		http.Error(w, "Query parameter q is required", http.StatusBadRequest)
+		return
+	}
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		q.Limit = n
+	}
+	if v := query.Get("keyword_weight"); v != "" {
+		kw, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			http.Error(w, "Invalid keyword_weight", http.StatusBadRequest)
+			return
+		}
+		q.KeywordWeight = kw
+	}
+
+	results, err := recall.Search(r.Context(), q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if results == nil {
+		results = []llm.RecallResult{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
+
+// handleSearch handles GET /api/search?q=...&chat_id=&platform=&role=&from=&to=&limit=&offset=
+func (s *WebSocketServer) handleSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	userID := requestUserID(r)
+
+	q := storage.MessageSearchQuery{
+		Query:    query.Get("q"),
+		UserID:   userID,
+		ChatID:   query.Get("chat_id"),
+		Platform: query.Get("platform"),
+		Role:     query.Get("role"),
+	}
+	if strings.TrimSpace(q.Query) == "" {
+		http.Error(w, "Query parameter q is required", http.StatusBadRequest)
+		return
+	}
+
+	var err error
+	if q.Since, err = parseSearchDate(query.Get("from"), false); err != nil {
+		http.Error(w, "Invalid from date: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if q.Until, err = parseSearchDate(query.Get("to"), true); err != nil {
+		http.Error(w, "Invalid to date: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	for param, dst := range map[string]*int{"limit": &q.Limit, "offset": &q.Offset} {
+		if v := query.Get(param); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				http.Error(w, "Invalid "+param, http.StatusBadRequest)
+				return
+			}
+			*dst = n
+		}
+	}
+	q.Limit = min(q.Limit, 100)
+
+	results, err := storage.SearchMessages(q)
+	if errors.Is(err, storage.ErrSearchUnavailable) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if results == nil {
+		results = []storage.MessageSearchResult{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
+
+// parseSearchDate parses an RFC 3339 timestamp or a YYYY-MM-DD date (end of day if endOfDay is set)
+func parseSearchDate(v string, endOfDay bool) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", v)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
 func (s *WebSocketServer) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	// Browsers send the session cookie with cross-site WebSocket requests, so the origin is checked
+	upgrader := websocket.Upgrader{CheckOrigin: s.allowedOrigin}
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("[WebSocket] Upgrade error: %v", err)
@@ -429,11 +800,7 @@ func (s *WebSocketServer) handleWebSocket(w http.ResponseWriter, r *http.Request
 	}
 
 	clientID := uuid.New().String()
-	// For now, use query param or default user ID
-	userID := r.URL.Query().Get("user_id")
-	if userID == "" {
-		userID = "default"
-	}
+	userID := requestUserID(r)
 
 	client := &WSClient{
 		ID:     clientID,
@@ -527,6 +894,38 @@ func (c *WSClient) handleMessage(data []byte) {
 		}
 		c.handleChatMessage(chatMsg)
 
+	case "chat.load_messages":
+		var req LoadMessagesRequest
+		if err := json.Unmarshal(msg.Payload, &req); err != nil {
+			log.Printf("[WebSocket] Invalid load messages request: %v", err)
+			return
+		}
+		c.handleLoadMessages(req)
+
+	case "chat.edit":
+		var req EditMessageRequest
+		if err := json.Unmarshal(msg.Payload, &req); err != nil {
+			log.Printf("[WebSocket] Invalid edit request: %v", err)
+			return
+		}
+		c.handleEditMessage(req)
+
+	case "chat.regenerate":
+		var req RegenerateRequest
+		if err := json.Unmarshal(msg.Payload, &req); err != nil {
+			log.Printf("[WebSocket] Invalid regenerate request: %v", err)
+			return
+		}
+		c.handleRegenerate(req)
+
+	case "chat.switch_branch":
+		var req SwitchBranchRequest
+		if err := json.Unmarshal(msg.Payload, &req); err != nil {
+			log.Printf("[WebSocket] Invalid switch branch request: %v", err)
+			return
+		}
+		c.handleSwitchBranch(req)
+
 	case "ping":
 		c.send("pong", nil)
 
@@ -535,8 +934,42 @@ func (c *WSClient) handleMessage(data []byte) {
 	}
 }
 
+// ownsChat reports whether a chat belongs to the client's user and sends chat.error if not
+func (c *WSClient) ownsChat(chatID string) bool {
+	if _, err := storage.GetUserChat(c.UserID, chatID); err != nil {
+		c.send("chat.error", map[string]string{"chat_id": chatID, "error": "chat not found"})
+		return false
+	}
+	return true
+}
+
+// handleLoadMessages sends a page of chat messages to the client
+func (c *WSClient) handleLoadMessages(req LoadMessagesRequest) {
+	if !c.ownsChat(req.ChatID) {
+		return
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultMessagePageSize
+	}
+	resp, err := loadMessagePage(req.ChatID, storage.MessagePage{
+		BeforeID: req.Before,
+		AfterID:  req.After,
+		Limit:    min(limit, maxMessagePageSize),
+	})
+	if err != nil {
+		c.send("chat.error", map[string]string{"chat_id": req.ChatID, "error": err.Error()})
+		return
+	}
+	c.send("chat.messages", resp)
+}
+
 func (c *WSClient) handleChatMessage(msg IncomingChatMessage) {
-	// Save user message to database
+	if !c.ownsChat(msg.ChatID) {
+		return
+	}
+
+	// Save user message to database (appended to the active branch)
 	userMsg := &storage.Message{
 		ID:        uuid.New().String(),
 		ChatID:    msg.ChatID,
@@ -546,42 +979,81 @@ func (c *WSClient) handleChatMessage(msg IncomingChatMessage) {
 	}
 	if err := storage.AddMessage(userMsg); err != nil {
 		log.Printf("[WebSocket] Failed to save user message: %v", err)
+		c.send("chat.error", map[string]string{"chat_id": msg.ChatID, "error": "Failed to save message"})
+		return
 	}
 
-	// Emit event
-	event := &pb.Event{
+	// Confirm the stored message so the client knows its ID
+	c.send("chat.message", messagePayload(userMsg))
+	c.Server.emitReceived(userMsg, c.UserID)
+
+	// Process with LLM if available
+	if c.Server.llmRouter != nil {
+		go c.processWithLLM(replyRequest{
+			ChatID:   msg.ChatID,
+			ParentID: userMsg.ID,
+			UserID:   c.UserID,
+			Provider: msg.Provider,
+			Soul:     msg.Soul,
+		})
+	}
+}
+
+// emitReceived publishes chat.message.received for a user message
+func (s *WebSocketServer) emitReceived(msg *storage.Message, senderID string) {
+	s.eventBus.Publish(&pb.Event{
 		EventType: "chat.message.received",
 		Timestamp: timestamppb.Now(),
 		Data: &pb.Event_ChatMessage{
 			ChatMessage: &pb.ChatMessageEvent{
 				Platform:    "pwa",
 				ChatId:      msg.ChatID,
-				MessageId:   userMsg.ID,
-				SenderId:    c.UserID,
+				MessageId:   msg.ID,
+				SenderId:    senderID,
 				Content:     msg.Content,
 				ContentType: "text",
 			},
 		},
-	}
-	c.Server.eventBus.Publish(event)
+	})
+}
 
-	// Process with LLM if available
-	if c.Server.llmRouter != nil {
-		go c.processWithLLM(msg)
-	}
+// replyRequest asks for an assistant answer to the branch ending at ParentID
+type replyRequest struct {
+	ChatID   string
+	ParentID string
+	UserID   string
+	Provider string // Default provider if empty
+	Soul     string // "default" if empty
 }
 
-func (c *WSClient) processWithLLM(msg IncomingChatMessage) {
-	log.Printf("[WebSocket] processWithLLM called with soul=%q provider=%q", msg.Soul, msg.Provider)
+func (c *WSClient) processWithLLM(req replyRequest) {
+	log.Printf("[WebSocket] processWithLLM called with soul=%q provider=%q", req.Soul, req.Provider)
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Minute)
 	defer cancel()
 
+	assistantMsg, resp, err := c.Server.generateReply(ctx, req)
+	if err != nil {
+		c.send("chat.error", map[string]string{"chat_id": req.ChatID, "error": err.Error()})
+		return
+	}
+
+	// Send response back with soul, provider, and tool calls info
+	responsePayload := messagePayload(assistantMsg)
+	if len(resp.ToolCallRecords) > 0 {
+		responsePayload["tool_calls"] = resp.ToolCallRecords
+	}
+	c.send("chat.message", responsePayload)
+
+	c.Server.finishReply(assistantMsg, resp)
+}
+
+// generateReply runs the LLM on the branch ending at req.ParentID and stores the answer below it
+func (s *WebSocketServer) generateReply(ctx context.Context, req replyRequest) (*storage.Message, *llm.Response, error) {
 	// Load chat history from database
-	dbMessages, err := storage.GetChatMessages(msg.ChatID)
+	dbMessages, err := storage.GetBranch(req.ChatID, req.ParentID)
 	if err != nil {
 		log.Printf("[WebSocket] Failed to load chat history: %v", err)
-		c.send("chat.error", map[string]string{"error": "Failed to load chat history"})
-		return
+		return nil, nil, fmt.Errorf("failed to load chat history")
 	}
 
 	// Convert to LLM messages, filtering out display-only and plugin messages
@@ -596,44 +1068,38 @@ func (c *WSClient) processWithLLM(msg IncomingChatMessage) {
 			Content: m.Content,
 		})
 	}
-
-	// If no messages, something went wrong
 	if len(messages) == 0 {
-		messages = []llm.Message{
-			{Role: "user", Content: msg.Content},
-		}
+		return nil, nil, fmt.Errorf("no messages to answer")
 	}
 
-	// Determine provider (use default if not specified)
-	provider := msg.Provider
+	// Determine provider (use the user's default if not specified)
+	provider := req.Provider
 	if provider == "" {
-		providers := c.Server.llmRouter.ListProviders()
-		for _, p := range providers {
-			if p.IsDefault {
-				provider = p.Name
-				break
-			}
+		preferred := ""
+		if user, err := storage.GetUser(req.UserID); err == nil {
+			preferred = user.DefaultProvider
 		}
+		provider = s.llmRouter.DefaultProviderFor(preferred)
 	}
 
 	// Determine soul (use default if not specified)
-	soul := msg.Soul
+	soul := req.Soul
 	if soul == "" {
 		soul = "default"
 	}
 
-	chatCtx := &llm.ChatContext{ChatID: msg.ChatID, UserID: c.UserID, Soul: soul}
-	resp, err := c.Server.llmRouter.Chat(ctx, messages, provider, chatCtx)
+	chatCtx := &llm.ChatContext{ChatID: req.ChatID, UserID: req.UserID, Soul: soul}
+	resp, err := s.llmRouter.Chat(ctx, messages, provider, chatCtx)
 	if err != nil {
 		log.Printf("[WebSocket] LLM error: %v", err)
-		c.send("chat.error", map[string]string{"error": err.Error()})
-		return
+		return nil, nil, err
 	}
 
-	// Save assistant message to database with soul, provider, and tool calls
+	// Save assistant message below the answered message with soul, provider, and tool calls
 	assistantMsg := &storage.Message{
 		ID:        uuid.New().String(),
-		ChatID:    msg.ChatID,
+		ChatID:    req.ChatID,
+		ParentID:  req.ParentID,
 		Role:      "assistant",
 		Content:   resp.Content,
 		Soul:      soul,
@@ -649,29 +1115,27 @@ func (c *WSClient) processWithLLM(msg IncomingChatMessage) {
 		}
 	}
 
-	if err := storage.AddMessage(assistantMsg); err != nil {
+	if err := storage.AddBranchMessage(assistantMsg); err != nil {
 		log.Printf("[WebSocket] Failed to save assistant message: %v", err)
 	}
+	return assistantMsg, resp, nil
+}
 
-	// Send response back with soul, provider, and tool calls info
-	responsePayload := map[string]interface{}{
-		"id":         assistantMsg.ID,
-		"chat_id":    msg.ChatID,
-		"content":    resp.Content,
-		"role":       "assistant",
-		"soul":       soul,
-		"provider":   provider,
-		"created_at": assistantMsg.CreatedAt,
-	}
-	if len(resp.ToolCallRecords) > 0 {
-		responsePayload["tool_calls"] = resp.ToolCallRecords
-	}
-	c.send("chat.message", responsePayload)
-
+// finishReply stores deferred attachments of an answer and emits chat.message.sent
+func (s *WebSocketServer) finishReply(assistantMsg *storage.Message, resp *llm.Response) {
 	// Process deferred attachments (images, etc.) after assistant response
+	parentID := assistantMsg.ID
+	ownerID, _ := storage.GetChatUserID(assistantMsg.ChatID)
 	for _, da := range resp.DeferredAttachments {
 		if da.ChatID == "" {
-			da.ChatID = msg.ChatID
+			da.ChatID = assistantMsg.ChatID
+		}
+		// Tools may only post into other chats of the same user
+		if da.ChatID != assistantMsg.ChatID {
+			if _, err := storage.GetUserChat(ownerID, da.ChatID); err != nil {
+				log.Printf("[WebSocket] Dropped deferred attachment for chat %s of another user", da.ChatID)
+				continue
+			}
 		}
 
 		// Save to database
@@ -684,21 +1148,31 @@ func (c *WSClient) processWithLLM(msg IncomingChatMessage) {
 			CreatedAt:   time.Now(),
 		}
 
-		// Serialize attachments to JSON
-		if len(da.Attachments) > 0 {
-			attachmentsJSON, err := json.Marshal(da.Attachments)
-			if err == nil {
-				deferredMsg.Attachments = string(attachmentsJSON)
-			}
+		// Move attachment data into the blob store
+		attachmentsJSON, err := s.blobs.SaveAttachments(da.Attachments)
+		if err != nil {
+			log.Printf("[WebSocket] Failed to store deferred attachments: %v", err)
+			continue
 		}
-
-		if err := storage.AddMessage(deferredMsg); err != nil {
+		deferredMsg.Attachments = attachmentsJSON
+
+		// Attachments for this chat continue the answer's branch
+		if da.ChatID == assistantMsg.ChatID {
+			deferredMsg.ParentID = parentID
+			err = storage.AddBranchMessage(deferredMsg)
+		} else {
+			err = storage.AddMessage(deferredMsg)
+		}
+		if err != nil {
 			log.Printf("[WebSocket] Failed to save deferred message: %v", err)
 			continue
 		}
+		if da.ChatID == assistantMsg.ChatID {
+			parentID = deferredMsg.ID
+		}
 
 		// Broadcast to connected clients
-		c.Server.BroadcastMessage(da.ChatID, deferredMsg, da.Attachments)
+		s.BroadcastMessage(da.ChatID, deferredMsg)
 		log.Printf("[WebSocket] Added deferred attachment message: %s", deferredMsg.ID)
 	}
 
@@ -709,15 +1183,15 @@ func (c *WSClient) processWithLLM(msg IncomingChatMessage) {
 		Data: &pb.Event_ChatMessage{
 			ChatMessage: &pb.ChatMessageEvent{
 				Platform:    "pwa",
-				ChatId:      msg.ChatID,
+				ChatId:      assistantMsg.ChatID,
 				MessageId:   assistantMsg.ID,
 				SenderId:    "assistant",
-				Content:     resp.Content,
+				Content:     assistantMsg.Content,
 				ContentType: "text",
 			},
 		},
 	}
-	c.Server.eventBus.Publish(event)
+	s.eventBus.Publish(event)
 }
 
 func (c *WSClient) send(msgType string, payload any) {
@@ -749,11 +1223,15 @@ type SetConfigRequest struct {
 	Value string `json:"value"`
 }
 
-// handlePluginConfig handles GET/PUT /api/plugins/{name}/config
+// handlePluginConfig handles GET/PUT /api/plugins/{name}/config and /api/plugins/{name}/permissions
 func (s *WebSocketServer) handlePluginConfig(w http.ResponseWriter, r *http.Request) {
 	// Extract plugin name from URL: /api/plugins/{name}/config
 	path := strings.TrimPrefix(r.URL.Path, "/api/plugins/")
 	parts := strings.Split(path, "/")
+	if len(parts) == 2 && parts[1] == "permissions" {
+		s.handlePluginPermissions(w, r, parts[0])
+		return
+	}
 	if len(parts) < 2 || parts[1] != "config" {
 		http.Error(w, "Invalid URL format. Use /api/plugins/{name}/config", http.StatusBadRequest)
 		return
@@ -781,6 +1259,8 @@ func (s *WebSocketServer) handlePluginConfig(w http.ResponseWriter, r *http.Requ
 					fieldType = "string"
 				case pb.ConfigFieldType_CONFIG_FIELD_TYPE_STRING_ARRAY:
 					fieldType = "string_array"
+				case pb.ConfigFieldType_CONFIG_FIELD_TYPE_SECRET:
+					fieldType = "secret"
 				}
 				schema[i] = ConfigFieldInfo{
 					Key:          f.Key,
@@ -820,7 +1300,7 @@ func (s *WebSocketServer) handlePluginConfig(w http.ResponseWriter, r *http.Requ
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"success": true,
 			"key":     req.Key,
-			"value":   req.Value,
+			"value":   s.pluginConfig.GetPluginConfig(pluginName, req.Key),
 		})
 
 	default:
@@ -893,8 +1373,8 @@ func (s *WebSocketServer) notifyPluginsConfigChanged() {
 	for _, p := range plugins {
 		values := s.pluginConfig.GetPluginConfigs(p.Name)
 		// Notify each plugin through the handler
-		for key, value := range values {
-			s.pluginHandler.SetPluginConfig(p.Name, key, value)
+		for key := range values {
+			s.pluginHandler.NotifyConfigChanged(p.Name, key)
 		}
 	}
 }
@@ -903,6 +1383,17 @@ func (s *WebSocketServer) notifyPluginsConfigChanged() {
 type SoulInfo struct {
 	Name    string `json:"name"`
 	Content string `json:"content"`
+	Shared  bool   `json:"shared"` // Visible to all users, only admins can change it
+}
+
+// canWriteSoul reports whether a request may change a shared or personal soul.
+// Personal souls are written by default; shared souls only by admins.
+func canWriteSoul(w http.ResponseWriter, r *http.Request, shared bool) bool {
+	if shared && requestUser(r).Role != auth.RoleAdmin {
+		http.Error(w, "Only admins can change shared souls", http.StatusForbidden)
+		return false
+	}
+	return true
 }
 
 // handleSouls handles GET /api/souls (list) and POST /api/souls (create)
@@ -911,10 +1402,11 @@ func (s *WebSocketServer) handleSouls(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Souls not available", http.StatusServiceUnavailable)
 		return
 	}
+	userID := requestUserID(r)
 
 	switch r.Method {
 	case http.MethodGet:
-		soulsList, err := s.soulsManager.List()
+		soulsList, err := s.soulsManager.List(userID)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -925,6 +1417,7 @@ func (s *WebSocketServer) handleSouls(w http.ResponseWriter, r *http.Request) {
 			result[i] = SoulInfo{
 				Name:    soul.Name,
 				Content: soul.Content,
+				Shared:  soul.Shared,
 			}
 		}
 
@@ -942,8 +1435,11 @@ func (s *WebSocketServer) handleSouls(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Name is required", http.StatusBadRequest)
 			return
 		}
+		if !canWriteSoul(w, r, soul.Shared) {
+			return
+		}
 
-		if err := s.soulsManager.Save(&soul); err != nil {
+		if err := s.soulsManager.Save(userID, &soul); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -960,12 +1456,14 @@ func (s *WebSocketServer) handleSouls(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// handleSoulByName handles GET/PUT/DELETE /api/souls/{name}
+// handleSoulByName handles GET/PUT/DELETE /api/souls/{name}. DELETE removes the
+// personal soul unless ?shared=true is given.
 func (s *WebSocketServer) handleSoulByName(w http.ResponseWriter, r *http.Request) {
 	if s.soulsManager == nil {
 		http.Error(w, "Souls not available", http.StatusServiceUnavailable)
 		return
 	}
+	userID := requestUserID(r)
 
 	// Extract soul name from URL: /api/souls/{name}
 	path := strings.TrimPrefix(r.URL.Path, "/api/souls/")
@@ -978,7 +1476,7 @@ func (s *WebSocketServer) handleSoulByName(w http.ResponseWriter, r *http.Reques
 
 	switch r.Method {
 	case http.MethodGet:
-		soul, err := s.soulsManager.Get(name)
+		soul, err := s.soulsManager.Get(userID, name)
 		if err != nil {
 			http.Error(w, "Soul not found", http.StatusNotFound)
 			return
@@ -988,6 +1486,7 @@ func (s *WebSocketServer) handleSoulByName(w http.ResponseWriter, r *http.Reques
 		json.NewEncoder(w).Encode(SoulInfo{
 			Name:    soul.Name,
 			Content: soul.Content,
+			Shared:  soul.Shared,
 		})
 
 	case http.MethodPut:
@@ -996,9 +1495,12 @@ func (s *WebSocketServer) handleSoulByName(w http.ResponseWriter, r *http.Reques
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		if !canWriteSoul(w, r, soul.Shared) {
+			return
+		}
 
 		soul.Name = name // Use URL name
-		if err := s.soulsManager.Save(&soul); err != nil {
+		if err := s.soulsManager.Save(userID, &soul); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -1010,7 +1512,11 @@ func (s *WebSocketServer) handleSoulByName(w http.ResponseWriter, r *http.Reques
 		})
 
 	case http.MethodDelete:
-		if err := s.soulsManager.Delete(name); err != nil {
+		shared := r.URL.Query().Get("shared") == "true"
+		if !canWriteSoul(w, r, shared) {
+			return
+		}
+		if err := s.soulsManager.Delete(userID, name, shared); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -1030,32 +1536,21 @@ func (s *WebSocketServer) handleActiveSoul(w http.ResponseWriter, r *http.Reques
 	http.Error(w, "Active soul endpoint deprecated - souls are now selected per-message", http.StatusGone)
 }
 
+// broadcastEvent forwards a chat event to the clients of the chat's owner
 func (s *WebSocketServer) broadcastEvent(event *pb.Event) {
-	data, err := json.Marshal(map[string]any{
-		"type": "event",
-		"payload": map[string]any{
-			"event_type": event.EventType,
-			"source":     event.SourcePlugin,
-			"data":       event.GetChatMessage(),
-		},
-	})
-	if err != nil {
+	chatMsg := event.GetChatMessage()
+	if chatMsg == nil {
 		return
 	}
-
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	for _, client := range s.clients {
-		select {
-		case client.Send <- data:
-		default:
-		}
-	}
+	s.SendToChat(chatMsg.ChatId, "event", map[string]any{
+		"event_type": event.EventType,
+		"source":     event.SourcePlugin,
+		"data":       chatMsg,
+	})
 }
 
-// Broadcast sends a message to all connected clients
-func (s *WebSocketServer) Broadcast(msgType string, payload any) {
+// SendToUser sends a message to the connected clients of a user
+func (s *WebSocketServer) SendToUser(userID, msgType string, payload any) {
 	var payloadBytes json.RawMessage
 	if payload != nil {
 		payloadBytes, _ = json.Marshal(payload)
@@ -1075,6 +1570,9 @@ func (s *WebSocketServer) Broadcast(msgType string, payload any) {
 	defer s.mu.RUnlock()
 
 	for _, client := range s.clients {
+		if client.UserID != userID {
+			continue
+		}
 		select {
 		case client.Send <- data:
 		default:
@@ -1082,39 +1580,40 @@ func (s *WebSocketServer) Broadcast(msgType string, payload any) {
 	}
 }
 
-// BroadcastMessage broadcasts a chat message to connected WebSocket clients
-// This implements chat.MessageBroadcaster interface
-func (s *WebSocketServer) BroadcastMessage(chatID string, msg *storage.Message, attachments []*pb.Attachment) {
-	// Convert attachments to JSON-friendly format
-	var jsonAttachments []map[string]interface{}
-	for _, a := range attachments {
-		att := map[string]interface{}{
-			"type":      a.Type,
-			"mime_type": a.MimeType,
-		}
-		if len(a.Data) > 0 {
-			// Encode binary data as base64
-			att["data"] = base64.StdEncoding.EncodeToString(a.Data)
-		}
-		if a.Url != "" {
-			att["url"] = a.Url
-		}
-		if a.Filename != "" {
-			att["name"] = a.Filename
-		}
-		jsonAttachments = append(jsonAttachments, att)
+// SendToChat sends a message to the connected clients of the user owning a chat.
+// Messages for unknown chats are dropped.
+func (s *WebSocketServer) SendToChat(chatID, msgType string, payload any) {
+	userID, err := storage.GetChatUserID(chatID)
+	if err != nil {
+		return
 	}
+	s.SendToUser(userID, msgType, payload)
+}
 
+// BroadcastMessage sends a chat message to the clients of the chat's owner
+// This implements chat.MessageBroadcaster interface
+func (s *WebSocketServer) BroadcastMessage(chatID string, msg *storage.Message) {
+	payload := messagePayload(msg)
+	payload["chat_id"] = chatID
+	s.SendToChat(chatID, "chat.message", payload)
+}
+
+// messagePayload converts a stored message to a chat.message payload
+func messagePayload(msg *storage.Message) map[string]interface{} {
 	payload := map[string]interface{}{
-		"id":          msg.ID,
-		"chat_id":     chatID,
-		"content":     msg.Content,
-		"role":        msg.Role,
-		"created_at":  msg.CreatedAt,
+		"id":           msg.ID,
+		"chat_id":      msg.ChatID,
+		"content":      msg.Content,
+		"role":         msg.Role,
+		"created_at":   msg.CreatedAt,
 		"display_only": msg.DisplayOnly,
 	}
-	if len(jsonAttachments) > 0 {
-		payload["attachments"] = jsonAttachments
+	if msg.ParentID != "" {
+		payload["parent_id"] = msg.ParentID
+	}
+	// Attachments reference blobs, clients fetch the bytes from /api/blobs/{hash}
+	if attachments := blob.ParseAttachments(msg.Attachments); len(attachments) > 0 {
+		payload["attachments"] = attachments
 	}
 	if msg.Soul != "" {
 		payload["soul"] = msg.Soul
@@ -1122,7 +1621,5 @@ func (s *WebSocketServer) BroadcastMessage(chatID string, msg *storage.Message,
 	if msg.Provider != "" {
 		payload["provider"] = msg.Provider
 	}
-
-	s.Broadcast("chat.message", payload)
+	return payload
 }
-
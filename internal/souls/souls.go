@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 
@@ -15,12 +16,14 @@ import (
 type Soul struct {
 	Name    string `json:"name"`
 	Content string `json:"content"`
+	Shared  bool   `json:"shared"` // Visible to all users (souls/*.md), else personal (souls/users/{id}/*.md)
 }
 
 // ChangeHandler is called when souls change
 type ChangeHandler func()
 
-// Manager handles soul files in the souls/ directory
+// Manager handles soul files in the souls/ directory. Shared souls live in the
+// directory itself, personal souls in users/{userID}/ and override shared ones by name.
 type Manager struct {
 	dir           string
 	mu            sync.RWMutex
@@ -38,10 +41,11 @@ func NewManager() (*Manager, error) {
 	m := &Manager{dir: dir}
 
 	// Create default soul if none exist
-	souls, _ := m.List()
+	souls, _ := m.List("")
 	if len(souls) == 0 {
 		defaultSoul := &Soul{
-			Name: "default",
+			Name:   "default",
+			Shared: true,
 			Content: `You are a helpful AI assistant. You have access to tools/skills provided by plugins.
 IMPORTANT: Only use the tools that are explicitly provided to you. Do not make up or hallucinate tools that don't exist.
 If asked what tools you have, list ONLY the ones provided in the current conversation - nothing else.
@@ -52,7 +56,7 @@ When using tools to extract or gather data, be efficient with token usage:
 - Avoid redundant tool calls - plan your approach before executing
 - If a tool returns a large response, focus on the relevant parts in your answer`,
 		}
-		m.Save(defaultSoul)
+		m.Save("", defaultSoul)
 	}
 
 	// Create file watcher
@@ -98,9 +102,38 @@ func (m *Manager) Stop() {
 	}
 }
 
-// List returns all available souls
-func (m *Manager) List() ([]*Soul, error) {
-	entries, err := os.ReadDir(m.dir)
+// List returns the shared souls and the personal souls of a user
+func (m *Manager) List(userID string) ([]*Soul, error) {
+	shared, err := readSouls(m.dir, true)
+	if err != nil {
+		return nil, err
+	}
+	var personal []*Soul
+	if sanitizeName(userID) != "" {
+		personal, err = readSouls(m.userDir(userID), false)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	// Personal souls replace shared souls of the same name
+	names := make(map[string]bool, len(personal))
+	for _, soul := range personal {
+		names[soul.Name] = true
+	}
+	souls := personal
+	for _, soul := range shared {
+		if !names[soul.Name] {
+			souls = append(souls, soul)
+		}
+	}
+	sort.Slice(souls, func(i, j int) bool { return souls[i].Name < souls[j].Name })
+	return souls, nil
+}
+
+// readSouls reads the soul files of a directory
+func readSouls(dir string, shared bool) ([]*Soul, error) {
+	entries, err := os.ReadDir(dir)
 	if err != nil {
 		return nil, err
 	}
@@ -115,7 +148,7 @@ func (m *Manager) List() ([]*Soul, error) {
 		}
 
 		name := strings.TrimSuffix(entry.Name(), ".md")
-		content, err := os.ReadFile(filepath.Join(m.dir, entry.Name()))
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
 		if err != nil {
 			continue
 		}
@@ -123,16 +156,26 @@ func (m *Manager) List() ([]*Soul, error) {
 		souls = append(souls, &Soul{
 			Name:    name,
 			Content: string(content),
+			Shared:  shared,
 		})
 	}
 
 	return souls, nil
 }
 
-// Get returns a soul by name
-func (m *Manager) Get(name string) (*Soul, error) {
-	path := filepath.Join(m.dir, name+".md")
-	content, err := os.ReadFile(path)
+// Get returns a soul by name, preferring the user's personal soul
+func (m *Manager) Get(userID, name string) (*Soul, error) {
+	name = sanitizeName(name)
+	if name == "" {
+		return nil, fmt.Errorf("invalid soul name")
+	}
+
+	if sanitizeName(userID) != "" {
+		if content, err := os.ReadFile(filepath.Join(m.userDir(userID), name+".md")); err == nil {
+			return &Soul{Name: name, Content: string(content)}, nil
+		}
+	}
+	content, err := os.ReadFile(filepath.Join(m.dir, name+".md"))
 	if err != nil {
 		return nil, fmt.Errorf("soul not found: %s", name)
 	}
@@ -140,16 +183,17 @@ func (m *Manager) Get(name string) (*Soul, error) {
 	return &Soul{
 		Name:    name,
 		Content: string(content),
+		Shared:  true,
 	}, nil
 }
 
-// GetSystemPrompt returns the system prompt for a soul by name
+// GetSystemPrompt returns the system prompt for a soul of a user by name
 // Falls back to default prompt if soul not found
-func (m *Manager) GetSystemPrompt(name string) string {
+func (m *Manager) GetSystemPrompt(userID, name string) string {
 	if name == "" {
 		name = "default"
 	}
-	soul, err := m.Get(name)
+	soul, err := m.Get(userID, name)
 	if err != nil {
 		// Fallback to default prompt
 		return `You are a helpful AI assistant.`
@@ -157,37 +201,63 @@ func (m *Manager) GetSystemPrompt(name string) string {
 	return soul.Content
 }
 
-// Save creates or updates a soul
-func (m *Manager) Save(soul *Soul) error {
+// Save creates or updates a shared soul or a personal soul of a user
+func (m *Manager) Save(userID string, soul *Soul) error {
 	// Sanitize name - only allow alphanumeric, dash, underscore
 	name := sanitizeName(soul.Name)
 	if name == "" {
 		return fmt.Errorf("invalid soul name")
 	}
 
-	path := filepath.Join(m.dir, name+".md")
-	return os.WriteFile(path, []byte(soul.Content), 0644)
+	dir, err := m.soulDir(userID, soul.Shared)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, name+".md"), []byte(soul.Content), 0644)
 }
 
-// Delete removes a soul
-func (m *Manager) Delete(name string) error {
+// Delete removes a shared soul or a personal soul of a user
+func (m *Manager) Delete(userID, name string, shared bool) error {
+	name = sanitizeName(name)
 	if name == "" {
 		return fmt.Errorf("invalid soul name")
 	}
 
-	// Don't allow deleting the default soul
-	if name == "default" {
+	// Don't allow deleting the shared default soul
+	if name == "default" && shared {
 		return fmt.Errorf("cannot delete the default soul")
 	}
 
-	path := filepath.Join(m.dir, name+".md")
-	if err := os.Remove(path); err != nil {
+	dir, err := m.soulDir(userID, shared)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(dir, name+".md")); err != nil {
 		return fmt.Errorf("failed to delete soul: %w", err)
 	}
 
 	return nil
 }
 
+// soulDir returns the directory of shared souls or of a user's personal souls
+func (m *Manager) soulDir(userID string, shared bool) (string, error) {
+	if shared {
+		return m.dir, nil
+	}
+	if sanitizeName(userID) == "" {
+		return "", fmt.Errorf("personal souls need a user")
+	}
+	return m.userDir(userID), nil
+}
+
+// userDir returns the directory of a user's personal souls
+func (m *Manager) userDir(userID string) string {
+	return filepath.Join(m.dir, "users", sanitizeName(userID))
+}
+
 func sanitizeName(name string) string {
 	var result strings.Builder
 	for _, r := range name {
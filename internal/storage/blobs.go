@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"encoding/json"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CreateBlob records a blob (existing records are kept)
+func CreateBlob(blob *Blob) error {
+	return DB.Clauses(clause.OnConflict{DoNothing: true}).Create(blob).Error
+}
+
+// GetBlob returns the record of a blob
+func GetBlob(hash string) (*Blob, error) {
+	var blob Blob
+	if err := DB.First(&blob, "hash = ?", hash).Error; err != nil {
+		return nil, err
+	}
+	return &blob, nil
+}
+
+// DeleteBlob deletes the record of a blob
+func DeleteBlob(hash string) error {
+	return DB.Delete(&Blob{}, "hash = ?", hash).Error
+}
+
+// GetMessagesWithInlineAttachments returns messages whose attachments still embed their data,
+// ordered by ID starting after afterID
+func GetMessagesWithInlineAttachments(afterID string, limit int) ([]Message, error) {
+	var messages []Message
+	err := DB.Where("id > ? AND attachments LIKE ?", afterID, `%"data":%`).
+		Order("id").
+		Limit(limit).
+		Find(&messages).Error
+	return messages, err
+}
+
+// SetMessageAttachments replaces the attachments JSON of a message and updates its search index entry
+func SetMessageAttachments(messageID, attachments string) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&Message{}).Where("id = ?", messageID).Update("attachments", attachments).Error; err != nil {
+			return err
+		}
+		return reindexMessage(tx, messageID)
+	})
+}
+
+// UserReferencesBlob reports whether a message in one of the user's chats references a blob.
+// The hash must be hex, as it is matched inside the attachments JSON.
+func UserReferencesBlob(userID, hash string) (bool, error) {
+	var count int64
+	err := DB.Model(&Message{}).
+		Joins("JOIN chats ON chats.id = messages.chat_id AND chats.deleted_at IS NULL").
+		Where("chats.user_id = ? AND messages.attachments LIKE ?", userID, `%"hash":"`+hash+`"%`).
+		Limit(1).Count(&count).Error
+	return count > 0, err
+}
+
+// ReferencedBlobHashes returns the hashes of all blobs referenced by message attachments
+func ReferencedBlobHashes() (map[string]bool, error) {
+	var rows []string
+	if err := DB.Model(&Message{}).Where("attachments != ''").Pluck("attachments", &rows).Error; err != nil {
+		return nil, err
+	}
+
+	hashes := make(map[string]bool)
+	for _, row := range rows {
+		var refs []AttachmentRef
+		if err := json.Unmarshal([]byte(row), &refs); err != nil {
+			continue
+		}
+		for _, ref := range refs {
+			if ref.Hash != "" {
+				hashes[ref.Hash] = true
+			}
+		}
+	}
+	return hashes, nil
+}
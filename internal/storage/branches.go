@@ -0,0 +1,182 @@
+package storage
+
+import (
+	"fmt"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// branchSQL selects the IDs of the messages from a leaf up to the first message
+const branchSQL = `WITH RECURSIVE branch(id, parent_id) AS (
+		SELECT id, parent_id FROM messages WHERE id = ?
+		UNION
+		SELECT m.id, m.parent_id FROM messages m JOIN branch b ON m.id = b.parent_id
+	) SELECT id FROM branch`
+
+// maxBranchDepth bounds walks down the message tree
+const maxBranchDepth = 100000
+
+// backfillMessageTree links the messages of chats stored before branching into a single branch
+func backfillMessageTree(tx *gorm.DB) error {
+	var chatIDs []string
+	err := tx.Model(&Chat{}).Unscoped().
+		Where("(active_leaf = '' OR active_leaf IS NULL) AND id IN (SELECT DISTINCT chat_id FROM messages)").
+		Pluck("id", &chatIDs).Error
+	if err != nil {
+		return err
+	}
+
+	for _, chatID := range chatIDs {
+		var ids []string
+		if err := tx.Model(&Message{}).Where("chat_id = ?", chatID).
+			Order("created_at ASC, id ASC").Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		for i := 1; i < len(ids); i++ {
+			if err := tx.Model(&Message{}).Where("id = ?", ids[i]).
+				UpdateColumn("parent_id", ids[i-1]).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&Chat{}).Unscoped().Where("id = ?", chatID).
+			UpdateColumn("active_leaf", ids[len(ids)-1]).Error; err != nil {
+			return err
+		}
+	}
+	if len(chatIDs) > 0 {
+		log.Printf("[Storage] Linked messages of %d chats into branches", len(chatIDs))
+	}
+	return nil
+}
+
+// addMessage stores a message and makes it the active leaf of its chat.
+// If appendToBranch is set the message is attached to the current active leaf.
+func addMessage(msg *Message, appendToBranch bool) error {
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if appendToBranch {
+			var chat Chat
+			if err := tx.Unscoped().Select("active_leaf").Limit(1).Find(&chat, "id = ?", msg.ChatID).Error; err != nil {
+				return err
+			}
+			msg.ParentID = chat.ActiveLeaf
+		}
+		if err := tx.Create(msg).Error; err != nil {
+			return err
+		}
+		return tx.Model(&Chat{}).Where("id = ?", msg.ChatID).
+			UpdateColumns(map[string]any{"updated_at": msg.CreatedAt, "active_leaf": msg.ID}).Error
+	})
+	if err != nil {
+		return err
+	}
+	if err := indexMessage(DB, msg); err != nil {
+		log.Printf("[Storage] Failed to index message %s: %v", msg.ID, err)
+	}
+	return nil
+}
+
+// AddBranchMessage adds a message below msg.ParentID ("" = new first message), starting a
+// new branch if the parent already has replies. The new message becomes the active leaf.
+func AddBranchMessage(msg *Message) error {
+	if msg.ParentID != "" {
+		if _, err := GetChatMessage(msg.ChatID, msg.ParentID); err != nil {
+			return err
+		}
+	}
+	return addMessage(msg, false)
+}
+
+// GetChatMessage returns a message of a chat (an error wrapping gorm.ErrRecordNotFound if it has none)
+func GetChatMessage(chatID, messageID string) (*Message, error) {
+	var messages []Message
+	if err := DB.Where("id = ? AND chat_id = ?", messageID, chatID).Limit(1).Find(&messages).Error; err != nil {
+		return nil, err
+	}
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("message %s not found: %w", messageID, gorm.ErrRecordNotFound)
+	}
+	return &messages[0], nil
+}
+
+// GetBranch returns the messages from the first message down to leafID in chronological order
+func GetBranch(chatID, leafID string) ([]Message, error) {
+	var messages []Message
+	if leafID == "" {
+		return messages, nil
+	}
+	err := DB.Where("chat_id = ? AND id IN ("+branchSQL+")", chatID, leafID).
+		Order("created_at ASC, id ASC").
+		Find(&messages).Error
+	return messages, err
+}
+
+// LatestLeaf follows the most recent replies from a message down to the end of its branch
+func LatestLeaf(chatID, messageID string) (string, error) {
+	leaf := messageID
+	for range maxBranchDepth {
+		var children []string
+		err := DB.Model(&Message{}).
+			Where("chat_id = ? AND parent_id = ?", chatID, leaf).
+			Order("created_at DESC, id DESC").
+			Limit(1).
+			Pluck("id", &children).Error
+		if err != nil {
+			return "", err
+		}
+		if len(children) == 0 {
+			return leaf, nil
+		}
+		leaf = children[0]
+	}
+	return leaf, nil
+}
+
+// SwitchBranch activates the branch through a message (continuing with its latest replies)
+// and returns the new active leaf
+func SwitchBranch(chatID, messageID string) (string, error) {
+	if _, err := GetChatMessage(chatID, messageID); err != nil {
+		return "", err
+	}
+	leaf, err := LatestLeaf(chatID, messageID)
+	if err != nil {
+		return "", err
+	}
+	return leaf, SetActiveLeaf(chatID, leaf)
+}
+
+// SetActiveLeaf sets the last message of the active branch without touching updated_at
+func SetActiveLeaf(chatID, messageID string) error {
+	return DB.Model(&Chat{}).Where("id = ?", chatID).UpdateColumn("active_leaf", messageID).Error
+}
+
+// fillSiblings sets Siblings on messages that have alternative versions
+func fillSiblings(chatID string, messages []Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	parents := make([]string, 0, len(messages))
+	for _, m := range messages {
+		parents = append(parents, m.ParentID)
+	}
+
+	var rows []Message
+	err := DB.Select("id", "parent_id").
+		Where("chat_id = ? AND parent_id IN ?", chatID, parents).
+		Order("created_at ASC, id ASC").
+		Find(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	byParent := make(map[string][]string)
+	for _, r := range rows {
+		byParent[r.ParentID] = append(byParent[r.ParentID], r.ID)
+	}
+	for i := range messages {
+		if siblings := byParent[messages[i].ParentID]; len(siblings) > 1 {
+			messages[i].Siblings = siblings
+		}
+	}
+	return nil
+}
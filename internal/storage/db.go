@@ -1,8 +1,11 @@
 package storage
 
 import (
+	"fmt"
 	"log"
+	"slices"
 
+	"github.com/google/uuid"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -21,32 +24,56 @@ func Init(dbPath string) error {
 		return err
 	}
 
-	// Auto-migrate schemas
-	if err := DB.AutoMigrate(&Chat{}, &Message{}, &PluginConfig{}); err != nil {
+	// Apply versioned schema migrations
+	if err := migrate(); err != nil {
 		return err
 	}
 
+	initFTS()
+
 	log.Printf("[Storage] Database initialized: %s", dbPath)
 	return nil
 }
 
+// DatabaseSize returns the size of the database in bytes, including free pages
+func DatabaseSize() (int64, error) {
+	var pageCount, pageSize int64
+	if err := DB.Raw("PRAGMA page_count").Row().Scan(&pageCount); err != nil {
+		return 0, err
+	}
+	if err := DB.Raw("PRAGMA page_size").Row().Scan(&pageSize); err != nil {
+		return 0, err
+	}
+	return pageCount * pageSize, nil
+}
+
 // CreateChat creates a new chat
 func CreateChat(chat *Chat) error {
 	return DB.Create(chat).Error
 }
 
-// GetChat retrieves a chat by ID with messages
+// GetChat retrieves chat metadata by ID (use GetChatMessagesPage for its messages)
 func GetChat(chatID string) (*Chat, error) {
 	var chat Chat
-	err := DB.Preload("Messages", func(db *gorm.DB) *gorm.DB {
-		return db.Order("created_at ASC")
-	}).First(&chat, "id = ?", chatID).Error
-	if err != nil {
+	if err := DB.First(&chat, "id = ?", chatID).Error; err != nil {
 		return nil, err
 	}
 	return &chat, nil
 }
 
+// GetUserChat retrieves chat metadata by ID if the chat belongs to a user
+func GetUserChat(userID, chatID string) (*Chat, error) {
+	// Found with Find, as lookups of other users' chats are expected and not worth logging
+	var chats []Chat
+	if err := DB.Where("id = ? AND user_id = ?", chatID, userID).Limit(1).Find(&chats).Error; err != nil {
+		return nil, err
+	}
+	if len(chats) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &chats[0], nil
+}
+
 // GetUserChats retrieves all chats for a user
 func GetUserChats(userID string) ([]Chat, error) {
 	var chats []Chat
@@ -56,15 +83,36 @@ func GetUserChats(userID string) ([]Chat, error) {
 	return chats, err
 }
 
-// GetUserChatsWithMessages retrieves all chats for a user with their messages
-func GetUserChatsWithMessages(userID string) ([]Chat, error) {
-	var chats []Chat
-	err := DB.Preload("Messages", func(db *gorm.DB) *gorm.DB {
-		return db.Order("created_at ASC")
-	}).Where("user_id = ?", userID).
-		Order("updated_at DESC").
-		Find(&chats).Error
-	return chats, err
+// GetUserChatsWithPreview retrieves all chats for a user with a preview of their last message
+func GetUserChatsWithPreview(userID string) ([]Chat, error) {
+	chats, err := GetUserChats(userID)
+	if err != nil || len(chats) == 0 {
+		return chats, err
+	}
+
+	ids := make([]string, len(chats))
+	for i, c := range chats {
+		ids[i] = c.ID
+	}
+
+	var previews []MessagePreview
+	err = DB.Raw(`SELECT m.id, m.chat_id, m.role, substr(m.content, 1, ?) AS content,
+			m.attachments != '' AS has_attachments, m.created_at
+		FROM messages m
+		JOIN chats c ON c.active_leaf = m.id
+		WHERE c.id IN ?`, previewLength, ids).Scan(&previews).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byChat := make(map[string]*MessagePreview, len(previews))
+	for i := range previews {
+		byChat[previews[i].ChatID] = &previews[i]
+	}
+	for i := range chats {
+		chats[i].LastMessage = byChat[chats[i].ID]
+	}
+	return chats, nil
 }
 
 // UpdateChat updates a chat
@@ -77,37 +125,113 @@ func DeleteChat(chatID string) error {
 	return DB.Delete(&Chat{}, "id = ?", chatID).Error
 }
 
-// AddMessage adds a message to a chat
+// AddMessage appends a message to the active branch of a chat
 func AddMessage(msg *Message) error {
-	err := DB.Create(msg).Error
+	return addMessage(msg, true)
+}
+
+// GetChatMessages retrieves the messages on the active branch of a chat
+func GetChatMessages(chatID string) ([]Message, error) {
+	chat, err := GetChat(chatID)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	// Update chat's updated_at
-	return DB.Model(&Chat{}).Where("id = ?", msg.ChatID).Update("updated_at", msg.CreatedAt).Error
+	return GetBranch(chatID, chat.ActiveLeaf)
 }
 
-// GetChatMessages retrieves all messages for a chat
-func GetChatMessages(chatID string) ([]Message, error) {
-	var messages []Message
-	err := DB.Where("chat_id = ?", chatID).
-		Order("created_at ASC").
-		Find(&messages).Error
-	return messages, err
+// GetChatMessagesPage retrieves a page of the active branch of a chat in chronological order.
+// Without a cursor the latest messages are returned. hasMore reports whether further
+// messages exist in the paging direction (older for before/no cursor, newer for after).
+func GetChatMessagesPage(chatID string, page MessagePage) (messages []Message, hasMore bool, err error) {
+	chat, err := GetChat(chatID)
+	if err != nil {
+		return nil, false, err
+	}
+	q := DB.Where("chat_id = ? AND id IN ("+branchSQL+")", chatID, chat.ActiveLeaf)
+
+	cursorID, older := page.BeforeID, true
+	if page.AfterID != "" {
+		cursorID, older = page.AfterID, false
+	}
+	if cursorID != "" {
+		var cursor Message
+		if err := DB.Select("id", "created_at").First(&cursor, "id = ? AND chat_id = ?", cursorID, chatID).Error; err != nil {
+			return nil, false, fmt.Errorf("cursor message %s not found", cursorID)
+		}
+		if older {
+			q = q.Where("created_at < ? OR (created_at = ? AND id < ?)", cursor.CreatedAt, cursor.CreatedAt, cursor.ID)
+		} else {
+			q = q.Where("created_at > ? OR (created_at = ? AND id > ?)", cursor.CreatedAt, cursor.CreatedAt, cursor.ID)
+		}
+	}
+
+	if older {
+		q = q.Order("created_at DESC, id DESC")
+	} else {
+		q = q.Order("created_at ASC, id ASC")
+	}
+	if page.Limit > 0 {
+		q = q.Limit(page.Limit + 1)
+	}
+	if err := q.Find(&messages).Error; err != nil {
+		return nil, false, err
+	}
+
+	if page.Limit > 0 && len(messages) > page.Limit {
+		messages = messages[:page.Limit]
+		hasMore = true
+	}
+	if older {
+		slices.Reverse(messages)
+	}
+	return messages, hasMore, fillSiblings(chatID, messages)
 }
 
-// GetOrCreateLinkedChat finds or creates a chat linked to a messenger
+// GetChatActiveTools returns the JSON array of tools activated for a chat in tool search mode
+func GetChatActiveTools(chatID string) (string, error) {
+	var chat Chat
+	err := DB.Select("active_tools").First(&chat, "id = ?", chatID).Error
+	return chat.ActiveTools, err
+}
+
+// SetChatActiveTools stores the activated tools for a chat without touching updated_at
+func SetChatActiveTools(chatID, activeTools string) error {
+	return DB.Model(&Chat{}).Where("id = ?", chatID).UpdateColumn("active_tools", activeTools).Error
+}
+
+// GetLinkedChat retrieves a chat by ID if it is linked to a platform
+func GetLinkedChat(platform, chatID string) (*Chat, error) {
+	var chats []Chat
+	if err := DB.Where("id = ? AND platform = ?", chatID, platform).Limit(1).Find(&chats).Error; err != nil {
+		return nil, err
+	}
+	if len(chats) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &chats[0], nil
+}
+
+// GetOrCreateLinkedChat finds or creates a user's chat linked to a messenger
 // Returns the chat and a boolean indicating if it was newly created
 func GetOrCreateLinkedChat(platform, linkedID, name, userID string) (*Chat, bool, error) {
-	var chat Chat
-	err := DB.Where("platform = ? AND linked_id = ?", platform, linkedID).First(&chat).Error
-	if err == nil {
-		return &chat, false, nil
+	var chats []Chat
+	err := DB.Where("platform = ? AND linked_id = ? AND user_id = ?", platform, linkedID, userID).Limit(1).Find(&chats).Error
+	if err != nil {
+		return nil, false, err
+	}
+	if len(chats) > 0 {
+		return &chats[0], false, nil
 	}
 
-	// Create new linked chat
-	chat = Chat{
-		ID:       linkedID, // Use linked ID as primary key for simplicity
+	// Create new linked chat, keyed by its linked ID unless another chat has it
+	id := linkedID
+	if exists, err := ChatExists(id); err != nil {
+		return nil, false, err
+	} else if exists {
+		id = uuid.New().String()
+	}
+	chat := Chat{
+		ID:       id,
 		UserID:   userID,
 		Name:     name,
 		Platform: platform,
@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"encoding/binary"
+	"math"
+	"strings"
+)
+
+// EncodeVector serializes an embedding vector for storage
+func EncodeVector(vec []float32) []byte {
+	buf := make([]byte, 4*len(vec))
+	for i, v := range vec {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(v))
+	}
+	return buf
+}
+
+// DecodeVector deserializes an embedding vector
+func DecodeVector(buf []byte) []float32 {
+	vec := make([]float32, len(buf)/4)
+	for i := range vec {
+		vec[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	return vec
+}
+
+// GetMessagesWithoutEmbedding returns the oldest user/assistant messages not yet embedded with a model
+func GetMessagesWithoutEmbedding(model string, limit int) ([]Message, error) {
+	var messages []Message
+	err := DB.Model(&Message{}).
+		Joins("LEFT JOIN message_embeddings e ON e.message_id = messages.id AND e.model = ?", model).
+		Where("e.message_id IS NULL AND messages.role IN ? AND messages.content != ''", []string{"user", "assistant"}).
+		Order("messages.created_at ASC").
+		Limit(limit).
+		Find(&messages).Error
+	return messages, err
+}
+
+// SaveMessageEmbeddings stores embedding vectors
+func SaveMessageEmbeddings(embeddings []MessageEmbedding) error {
+	if len(embeddings) == 0 {
+		return nil
+	}
+	return DB.Save(&embeddings).Error
+}
+
+// GetMessageEmbeddings returns all embeddings of a model for a user's chats (optionally a single chat)
+func GetMessageEmbeddings(model, userID, chatID string) ([]MessageEmbedding, error) {
+	var embeddings []MessageEmbedding
+	q := DB.Model(&MessageEmbedding{}).
+		Joins("JOIN chats ON chats.id = message_embeddings.chat_id AND chats.deleted_at IS NULL").
+		Where("message_embeddings.model = ? AND length(message_embeddings.vector) > 0", model)
+	if userID != "" {
+		q = q.Where("chats.user_id = ?", userID)
+	}
+	if chatID != "" {
+		q = q.Where("message_embeddings.chat_id = ?", chatID)
+	}
+	err := q.Find(&embeddings).Error
+	return embeddings, err
+}
+
+// SearchMessagesByKeywords returns user/assistant messages containing any of the terms
+func SearchMessagesByKeywords(terms []string, userID, chatID string, limit int) ([]Message, error) {
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	var conds []string
+	var args []interface{}
+	for _, t := range terms {
+		conds = append(conds, "messages.content LIKE ?")
+		args = append(args, "%"+t+"%")
+	}
+
+	q := DB.Model(&Message{}).
+		Joins("JOIN chats ON chats.id = messages.chat_id AND chats.deleted_at IS NULL").
+		Where("messages.role IN ?", []string{"user", "assistant"}).
+		Where(strings.Join(conds, " OR "), args...)
+	if userID != "" {
+		q = q.Where("chats.user_id = ?", userID)
+	}
+	if chatID != "" {
+		q = q.Where("messages.chat_id = ?", chatID)
+	}
+
+	var messages []Message
+	err := q.Order("messages.created_at DESC").Limit(limit).Find(&messages).Error
+	return messages, err
+}
+
+// GetMessagesByIDs returns messages by their IDs
+func GetMessagesByIDs(ids []string) ([]Message, error) {
+	var messages []Message
+	if len(ids) == 0 {
+		return messages, nil
+	}
+	err := DB.Where("id IN ?", ids).Find(&messages).Error
+	return messages, err
+}
+
+// GetChatNames returns the names of chats by their IDs
+func GetChatNames(ids []string) (map[string]string, error) {
+	var chats []Chat
+	names := make(map[string]string)
+	if len(ids) == 0 {
+		return names, nil
+	}
+	if err := DB.Select("id", "name").Where("id IN ?", ids).Find(&chats).Error; err != nil {
+		return nil, err
+	}
+	for _, c := range chats {
+		names[c.ID] = c.Name
+	}
+	return names, nil
+}
+
+// GetChatUserID returns the owner of a chat
+func GetChatUserID(chatID string) (string, error) {
+	var chat Chat
+	err := DB.Select("user_id").First(&chat, "id = ?", chatID).Error
+	return chat.UserID, err
+}
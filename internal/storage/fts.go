@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"log"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// ftsEnabled is true when SQLite was built with FTS5 (go build -tags sqlite_fts5)
+var ftsEnabled bool
+
+// FTSEnabled reports whether full-text search tables are available
+func FTSEnabled() bool {
+	return ftsEnabled
+}
+
+// initFTS creates the full-text search tables if FTS5 is available
+func initFTS() {
+	// Silent session: the failing CREATE is expected without FTS5 and logged below
+	quiet := DB.Session(&gorm.Session{Logger: logger.Default.LogMode(logger.Silent)})
+	if err := quiet.Exec("CREATE VIRTUAL TABLE IF NOT EXISTS kb_chunks_fts USING fts5(location, content, tokenize='porter unicode61')").Error; err != nil {
+		log.Printf("[Storage] FTS5 not available, message search disabled and knowledge base falls back to LIKE search (build with -tags sqlite_fts5): %v", err)
+		return
+	}
+	if err := DB.Exec("CREATE VIRTUAL TABLE IF NOT EXISTS messages_fts USING fts5(content, tool_results, filenames, tokenize='porter unicode61')").Error; err != nil {
+		log.Printf("[Storage] Failed to create message search index: %v", err)
+		return
+	}
+	ftsEnabled = true
+
+	backfillMessageIndex()
+}
+
+// FTSQuery turns free text into an FTS5 query matching any of its words (prefix match)
+func FTSQuery(text string) string {
+	return strings.Join(ftsTerms(text), " OR ")
+}
+
+// FTSQueryAll turns free text into an FTS5 query matching all of its words (prefix match)
+func FTSQueryAll(text string) string {
+	return strings.Join(ftsTerms(text), " ")
+}
+
+// ftsTerms splits text into quoted FTS5 prefix terms (quoting disables FTS5 query syntax in user input)
+func ftsTerms(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r > 127)
+	})
+	terms := make([]string, len(words))
+	for i, w := range words {
+		terms[i] = `"` + w + `"*`
+	}
+	return terms
+}
@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// KBChunkMatch is a knowledge base chunk found by text search
+type KBChunkMatch struct {
+	KBChunk
+	Rank float64 // Lower is better (FTS5 bm25)
+}
+
+// ListKBDocuments returns all ingested knowledge base documents
+func ListKBDocuments() ([]KBDocument, error) {
+	var docs []KBDocument
+	err := DB.Order("path ASC").Find(&docs).Error
+	return docs, err
+}
+
+// ReplaceKBDocument stores a document and replaces its chunks
+func ReplaceKBDocument(doc *KBDocument, chunks []KBChunk) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := deleteKBChunks(tx, doc.Path); err != nil {
+			return err
+		}
+		if err := tx.Save(doc).Error; err != nil {
+			return err
+		}
+		if len(chunks) == 0 {
+			return nil
+		}
+		if err := tx.Create(&chunks).Error; err != nil {
+			return err
+		}
+		if ftsEnabled {
+			for _, c := range chunks {
+				if err := tx.Exec("INSERT INTO kb_chunks_fts(rowid, location, content) VALUES (?, ?, ?)", c.ID, c.Location, c.Content).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteKBDocument removes a document and its chunks
+func DeleteKBDocument(path string) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := deleteKBChunks(tx, path); err != nil {
+			return err
+		}
+		return tx.Delete(&KBDocument{}, "path = ?", path).Error
+	})
+}
+
+// deleteKBChunks removes the chunks of a document (and their full-text index rows)
+func deleteKBChunks(tx *gorm.DB, path string) error {
+	if ftsEnabled {
+		if err := tx.Exec("DELETE FROM kb_chunks_fts WHERE rowid IN (SELECT id FROM kb_chunks WHERE document_path = ?)", path).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Delete(&KBChunk{}, "document_path = ?", path).Error
+}
+
+// SearchKBChunks finds chunks of the given souls matching the query (FTS5 if available, otherwise LIKE)
+func SearchKBChunks(query string, souls []string, limit int) ([]KBChunkMatch, error) {
+	var matches []KBChunkMatch
+	if ftsEnabled {
+		ftsQuery := FTSQuery(query)
+		if ftsQuery == "" {
+			return nil, nil
+		}
+		err := DB.Raw(`SELECT kb_chunks.*, bm25(kb_chunks_fts) AS rank
+			FROM kb_chunks_fts JOIN kb_chunks ON kb_chunks.id = kb_chunks_fts.rowid
+			WHERE kb_chunks_fts MATCH ? AND kb_chunks.soul IN ?
+			ORDER BY rank LIMIT ?`, ftsQuery, souls, limit).Scan(&matches).Error
+		return matches, err
+	}
+
+	words := strings.Fields(query)
+	if len(words) == 0 {
+		return nil, nil
+	}
+	var conds []string
+	var args []interface{}
+	for _, w := range words {
+		conds = append(conds, "content LIKE ?")
+		args = append(args, "%"+w+"%")
+	}
+	var chunks []KBChunk
+	err := DB.Where("soul IN ?", souls).
+		Where(strings.Join(conds, " OR "), args...).
+		Limit(limit).
+		Find(&chunks).Error
+	for _, c := range chunks {
+		matches = append(matches, KBChunkMatch{KBChunk: c})
+	}
+	return matches, err
+}
+
+// GetKBChunks returns all chunks of the given souls (including vectors)
+func GetKBChunks(souls []string) ([]KBChunk, error) {
+	var chunks []KBChunk
+	err := DB.Where("soul IN ?", souls).Find(&chunks).Error
+	return chunks, err
+}
+
+// GetKBChunksWithoutVector returns chunks not yet embedded with a model
+func GetKBChunksWithoutVector(model string, limit int) ([]KBChunk, error) {
+	var chunks []KBChunk
+	err := DB.Where("model != ? OR vector IS NULL", model).
+		Order("id ASC").
+		Limit(limit).
+		Find(&chunks).Error
+	return chunks, err
+}
+
+// SetKBChunkVector stores the embedding of a chunk
+func SetKBChunkVector(id uint, model string, vector []byte) error {
+	return DB.Model(&KBChunk{}).Where("id = ?", id).UpdateColumns(map[string]interface{}{
+		"model":  model,
+		"vector": vector,
+	}).Error
+}
+
+// CountKBChunksWithoutVector counts chunks not yet embedded with a model
+func CountKBChunksWithoutVector(model string) (int64, error) {
+	var count int64
+	err := DB.Model(&KBChunk{}).Where("model != ? OR vector IS NULL", model).Count(&count).Error
+	return count, err
+}
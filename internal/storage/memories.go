@@ -0,0 +1,42 @@
+package storage
+
+// CreateMemory stores a new memory
+func CreateMemory(m *Memory) error {
+	return DB.Create(m).Error
+}
+
+// GetMemory retrieves a memory by ID
+func GetMemory(id string) (*Memory, error) {
+	var m Memory
+	if err := DB.First(&m, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// GetUserMemories retrieves all memories of a user, newest first
+func GetUserMemories(userID string) ([]Memory, error) {
+	var memories []Memory
+	err := DB.Where("user_id = ?", userID).
+		Order("updated_at DESC").
+		Find(&memories).Error
+	return memories, err
+}
+
+// UpdateMemory saves changes to a memory
+func UpdateMemory(m *Memory) error {
+	return DB.Save(m).Error
+}
+
+// SetMemoryVector stores the embedding of a memory without touching updated_at
+func SetMemoryVector(id, model string, vector []byte) error {
+	return DB.Model(&Memory{}).Where("id = ?", id).UpdateColumns(map[string]interface{}{
+		"model":  model,
+		"vector": vector,
+	}).Error
+}
+
+// DeleteMemory deletes a memory
+func DeleteMemory(id string) error {
+	return DB.Delete(&Memory{}, "id = ?", id).Error
+}
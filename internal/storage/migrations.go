@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CoreNamespace is the migration namespace of the core models
+const CoreNamespace = "core"
+
+// SchemaMigration records a migration applied to a namespace ("core" or a plugin table prefix)
+type SchemaMigration struct {
+	Namespace string    `gorm:"primaryKey" json:"namespace"`
+	Version   int       `gorm:"primaryKey" json:"version"`
+	Name      string    `json:"name"`
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+// Migration is a versioned schema change
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+}
+
+// coreMigrations evolve the core schema. Append new migrations, never change applied ones:
+// model changes need a migration (e.g. tx.Migrator().AddColumn) to reach existing databases.
+var coreMigrations = []Migration{
+	{1, "baseline schema", func(tx *gorm.DB) error {
+		// Creates the schema of new databases and adopts databases created by AutoMigrate
+		return tx.AutoMigrate(&Chat{}, &Message{}, &PluginConfig{}, &MessageEmbedding{}, &Memory{}, &KBDocument{}, &KBChunk{}, &Blob{})
+	}},
+	{2, "link messages into branches", backfillMessageTree},
+	{3, "add plugin key-value and document stores", func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&KVEntry{}, &Document{}, &DocumentIndex{}, &DocumentField{})
+	}},
+	{4, "add users, sessions and API tokens", func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&User{}, &Session{}, &APIToken{})
+	}},
+	{5, "add default provider of users", func(tx *gorm.DB) error {
+		// New databases got the column from migration 4, which migrates the current model
+		if tx.Migrator().HasColumn(&User{}, "DefaultProvider") {
+			return nil
+		}
+		return tx.Migrator().AddColumn(&User{}, "DefaultProvider")
+	}},
+	{6, "add plugin permission grants", func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&PluginGrant{})
+	}},
+	// Migration 1 added active_leaf as NULL to existing chats, which migration 2 skipped
+	{7, "link messages of chats without active leaf", backfillMessageTree},
+}
+
+// migrate applies the core migrations that have not been applied yet
+func migrate() error {
+	if err := DB.AutoMigrate(&SchemaMigration{}); err != nil {
+		return err
+	}
+	_, err := applyMigrations(CoreNamespace, coreMigrations)
+	return err
+}
+
+// LatestCoreVersion returns the core schema version this build migrates databases to
+func LatestCoreVersion() int {
+	return coreMigrations[len(coreMigrations)-1].Version
+}
+
+// SchemaVersion returns the highest migration version applied to a namespace (0 = none)
+func SchemaVersion(namespace string) (int, error) {
+	var version int
+	err := DB.Model(&SchemaMigration{}).Where("namespace = ?", namespace).
+		Select("COALESCE(MAX(version), 0)").Scan(&version).Error
+	return version, err
+}
+
+// applyMigrations runs the migrations of a namespace that have not been applied, each in its own
+// transaction, and returns the resulting schema version
+func applyMigrations(namespace string, migrations []Migration) (int, error) {
+	last := 0
+	for _, m := range migrations {
+		if m.Version <= last {
+			return 0, fmt.Errorf("migration versions must be positive and ascending (%d after %d)", m.Version, last)
+		}
+		last = m.Version
+	}
+
+	var applied []int
+	if err := DB.Model(&SchemaMigration{}).Where("namespace = ?", namespace).Pluck("version", &applied).Error; err != nil {
+		return 0, err
+	}
+	done := make(map[int]bool, len(applied))
+	version := 0
+	for _, v := range applied {
+		done[v] = true
+		version = max(version, v)
+	}
+
+	for _, m := range migrations {
+		if done[m.Version] {
+			continue
+		}
+
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{
+				Namespace: namespace,
+				Version:   m.Version,
+				Name:      m.Name,
+				AppliedAt: time.Now(),
+			}).Error
+		})
+		if err != nil {
+			return version, fmt.Errorf("migration %d (%s) failed: %w", m.Version, m.Name, err)
+		}
+		version = max(version, m.Version)
+		log.Printf("[Storage] Applied migration %s/%d: %s", namespace, m.Version, m.Name)
+	}
+
+	if version > last {
+		log.Printf("[Storage] Schema of %s is at version %d, newer than its latest migration %d", namespace, version, last)
+	}
+	return version, nil
+}
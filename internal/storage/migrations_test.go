@@ -0,0 +1,239 @@
+package storage
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	pb "github.com/fipso/chadbot/gen/chadbot"
+)
+
+// legacyChat and legacyMessage are the models of databases created by AutoMigrate before
+// versioned migrations and branches
+type legacyChat struct {
+	ID        string `gorm:"primaryKey"`
+	UserID    string
+	Name      string
+	Platform  string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt
+}
+
+func (legacyChat) TableName() string { return "chats" }
+
+type legacyMessage struct {
+	ID        string `gorm:"primaryKey"`
+	ChatID    string
+	Role      string
+	Content   string
+	CreatedAt time.Time
+}
+
+func (legacyMessage) TableName() string { return "messages" }
+
+func TestMigrateLegacyDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chadbot.db")
+	legacy, err := gorm.Open(sqlite.Open(path), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := legacy.AutoMigrate(&legacyChat{}, &legacyMessage{}); err != nil {
+		t.Fatal(err)
+	}
+	at := time.Now()
+	legacy.Create(&legacyChat{ID: "c1", UserID: "u1", Name: "Old", Platform: "web", CreatedAt: at, UpdatedAt: at})
+	for i, id := range []string{"m1", "m2", "m3"} {
+		legacy.Create(&legacyMessage{ID: id, ChatID: "c1", Role: "user", Content: id, CreatedAt: at.Add(time.Duration(i) * time.Second)})
+	}
+	if db, err := legacy.DB(); err == nil {
+		db.Close()
+	}
+
+	if err := Init(path); err != nil {
+		t.Fatalf("init legacy database: %v", err)
+	}
+	version, err := SchemaVersion(CoreNamespace)
+	if err != nil || version != LatestCoreVersion() {
+		t.Fatalf("schema version %d (%v), want %d", version, err, LatestCoreVersion())
+	}
+
+	// Migration 2 linked the existing messages into one branch
+	chat, err := GetChat("c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chat.ActiveLeaf != "m3" {
+		t.Fatalf("active leaf %q, want m3", chat.ActiveLeaf)
+	}
+	messages, err := GetChatMessages("c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 3 || messages[1].ParentID != "m1" || messages[2].ParentID != "m2" {
+		t.Fatalf("active branch %+v, want m1 <- m2 <- m3", messages)
+	}
+
+	// Opening the migrated database again applies nothing
+	if err := Init(path); err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	var count int64
+	DB.Model(&SchemaMigration{}).Where("namespace = ?", CoreNamespace).Count(&count)
+	if int(count) != len(coreMigrations) {
+		t.Fatalf("%d core migrations recorded, want %d", count, len(coreMigrations))
+	}
+}
+
+func TestApplyMigrations(t *testing.T) {
+	newTestDB(t)
+	var ran []int
+	// step creates table test_<letter of v>, then fails with fail if set
+	step := func(v int, fail error) Migration {
+		return Migration{Version: v, Name: "test", Up: func(tx *gorm.DB) error {
+			ran = append(ran, v)
+			if err := tx.Exec("CREATE TABLE test_" + string(rune('a'+v)) + " (id INTEGER)").Error; err != nil {
+				return err
+			}
+			return fail
+		}}
+	}
+
+	if _, err := applyMigrations("test", []Migration{step(2, nil), step(1, nil)}); err == nil {
+		t.Fatal("descending versions accepted")
+	}
+	if _, err := applyMigrations("test", []Migration{step(0, nil)}); err == nil {
+		t.Fatal("version 0 accepted")
+	}
+
+	failed := errors.New("boom")
+	version, err := applyMigrations("test", []Migration{step(1, nil), step(2, failed), step(3, nil)})
+	if !errors.Is(err, failed) || version != 1 {
+		t.Fatalf("applyMigrations = %d, %v, want version 1 and the failure", version, err)
+	}
+	if DB.Migrator().HasTable("test_c") {
+		t.Fatal("failed migration was not rolled back")
+	}
+	if DB.Migrator().HasTable("test_d") {
+		t.Fatal("migration after a failed one was applied")
+	}
+
+	// Applied migrations are skipped, the rest run in order
+	ran = nil
+	version, err = applyMigrations("test", []Migration{step(1, nil), step(2, nil), step(3, nil)})
+	if err != nil || version != 3 {
+		t.Fatalf("applyMigrations = %d, %v, want 3", version, err)
+	}
+	if len(ran) != 2 || ran[0] != 2 || ran[1] != 3 {
+		t.Fatalf("ran migrations %v, want [2 3]", ran)
+	}
+}
+
+func TestPluginMigrate(t *testing.T) {
+	newTestDB(t)
+	ps := NewPluginStorage("migtest")
+	createNotes := &pb.Migration{Version: 1, Name: "create notes", Steps: []*pb.MigrationStep{
+		{Step: &pb.MigrationStep_CreateTable{CreateTable: &pb.CreateTableRequest{
+			TableName: "notes",
+			Columns:   []*pb.ColumnDef{{Name: "id", Type: "INTEGER", PrimaryKey: true}},
+		}}},
+	}}
+	addBody := &pb.Migration{Version: 2, Name: "add body", Steps: []*pb.MigrationStep{
+		{Step: &pb.MigrationStep_AddColumn{AddColumn: &pb.AddColumnStep{
+			TableName: "notes", Column: &pb.ColumnDef{Name: "body", Type: "TEXT"},
+		}}},
+		{Step: &pb.MigrationStep_CreateIndex{CreateIndex: &pb.CreateIndexStep{
+			TableName: "notes", IndexName: "notes_body", Columns: []string{"body"},
+		}}},
+	}}
+	migrate := func(migrations ...*pb.Migration) *pb.StorageResponse {
+		return ps.HandleRequest(&pb.StorageRequest{Operation: &pb.StorageRequest_Migrate{Migrate: &pb.MigrateRequest{Migrations: migrations}}})
+	}
+
+	if resp := migrate(createNotes, addBody); !resp.Success || resp.SchemaVersion != 2 {
+		t.Fatalf("migrate: success=%v version=%d error=%q", resp.Success, resp.SchemaVersion, resp.Error)
+	}
+	if !DB.Migrator().HasColumn("plugin_migtest_notes", "body") || !DB.Migrator().HasIndex("plugin_migtest_notes", "plugin_migtest_notes_body") {
+		t.Fatal("migrations did not create the namespaced column and index")
+	}
+	if resp := migrate(createNotes, addBody); !resp.Success || resp.SchemaVersion != 2 {
+		t.Fatalf("repeated migrate: success=%v version=%d error=%q", resp.Success, resp.SchemaVersion, resp.Error)
+	}
+
+	// A failing step rolls back its whole migration
+	broken := &pb.Migration{Version: 3, Name: "broken", Steps: []*pb.MigrationStep{
+		{Step: &pb.MigrationStep_AddColumn{AddColumn: &pb.AddColumnStep{
+			TableName: "notes", Column: &pb.ColumnDef{Name: "title", Type: "TEXT"},
+		}}},
+		{Step: &pb.MigrationStep_DropColumn{DropColumn: &pb.DropColumnStep{TableName: "notes", Column: "missing"}}},
+	}}
+	resp := migrate(createNotes, addBody, broken)
+	if resp.Success || resp.SchemaVersion != 2 {
+		t.Fatalf("broken migration: success=%v version=%d, want failure at version 2", resp.Success, resp.SchemaVersion)
+	}
+	if DB.Migrator().HasColumn("plugin_migtest_notes", "title") {
+		t.Fatal("step of a failed migration was kept")
+	}
+
+	// Raw SQL steps need the raw SQL permission
+	rawSQL := &pb.Migration{Version: 3, Name: "raw", Steps: []*pb.MigrationStep{
+		{Step: &pb.MigrationStep_Sql{Sql: &pb.SQLStep{Statement: "DROP TABLE {notes}"}}},
+	}}
+	if resp := migrate(createNotes, addBody, rawSQL); resp.Success {
+		t.Fatal("raw SQL migration applied without permission")
+	}
+	if !DB.Migrator().HasTable("plugin_migtest_notes") {
+		t.Fatal("raw SQL step ran without permission")
+	}
+}
+
+func TestMigrateLegacyPluginPrefix(t *testing.T) {
+	newTestDB(t)
+	for _, stmt := range []string{
+		"CREATE TABLE plugin_mcp_bridge_notes (id INTEGER PRIMARY KEY, body TEXT)",
+		"CREATE INDEX plugin_mcp_bridge_notes_body ON plugin_mcp_bridge_notes (body)",
+		"INSERT INTO plugin_mcp_bridge_notes (body) VALUES ('kept')",
+	} {
+		if err := DB.Exec(stmt).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := DB.Create(&SchemaMigration{Namespace: "plugin_mcp_bridge", Version: 1, Name: "create notes", AppliedAt: time.Now()}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := MigrateLegacyPluginPrefix("mcp-bridge"); err != nil {
+		t.Fatal(err)
+	}
+	ps := NewPluginStorage("mcp-bridge")
+	table, _ := ps.NamespacedTable("notes")
+	index, _ := ps.NamespacedTable("notes_body")
+	var body string
+	if err := DB.Raw("SELECT body FROM " + table).Scan(&body).Error; err != nil || body != "kept" {
+		t.Fatalf("moved table: %q, %v", body, err)
+	}
+	if !DB.Migrator().HasIndex(table, index) {
+		t.Fatal("index not moved")
+	}
+	if DB.Migrator().HasTable("plugin_mcp_bridge_notes") {
+		t.Fatal("legacy table kept")
+	}
+	if version, err := SchemaVersion(ps.prefix); err != nil || version != 1 {
+		t.Fatalf("schema version under the new prefix %d (%v), want 1", version, err)
+	}
+
+	// Later tables under the legacy prefix belong to plugin "mcp"
+	if err := DB.Exec("CREATE TABLE plugin_mcp_bridge_log (id INTEGER)").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := MigrateLegacyPluginPrefix("mcp-bridge"); err != nil {
+		t.Fatal(err)
+	}
+	if !DB.Migrator().HasTable("plugin_mcp_bridge_log") {
+		t.Fatal("table of another plugin moved on a second run")
+	}
+}
@@ -8,29 +8,66 @@ import (
 
 // Chat represents a conversation
 type Chat struct {
-	ID        string         `gorm:"primaryKey" json:"id"`
-	UserID    string         `gorm:"index" json:"user_id"`
-	Name      string         `json:"name"`
-	Platform  string         `gorm:"index" json:"platform"`  // "web", "whatsapp", "telegram", etc.
-	LinkedID  string         `gorm:"index" json:"linked_id"` // External chat ID (e.g., WhatsApp JID)
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
-	Messages  []Message      `gorm:"foreignKey:ChatID" json:"messages"`
+	ID          string         `gorm:"primaryKey" json:"id"`
+	UserID      string         `gorm:"index" json:"user_id"`
+	Name        string         `json:"name"`
+	Platform    string         `gorm:"index" json:"platform"`  // "web", "whatsapp", "telegram", etc.
+	LinkedID    string         `gorm:"index" json:"linked_id"` // External chat ID (e.g., WhatsApp JID)
+	ActiveTools string         `json:"-"`                      // JSON array of tools activated via search_tools
+	ActiveLeaf  string         `json:"active_leaf,omitempty"`  // Last message of the active branch
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+	Messages    []Message      `gorm:"foreignKey:ChatID" json:"messages,omitempty"`
+
+	LastMessage *MessagePreview `gorm:"-" json:"last_message,omitempty"` // Set by GetUserChatsWithPreview
 }
 
 // Message represents a single message in a chat
 type Message struct {
 	ID          string    `gorm:"primaryKey" json:"id"`
 	ChatID      string    `gorm:"index" json:"chat_id"`
-	Role        string    `json:"role"` // "user", "assistant", or "plugin"
+	ParentID    string    `gorm:"index" json:"parent_id,omitempty"` // Previous message on its branch ("" = first message)
+	Role        string    `json:"role"`                             // "user", "assistant", or "plugin"
 	Content     string    `json:"content"`
 	DisplayOnly bool      `json:"display_only"`       // If true, not sent to LLM
-	Attachments string    `json:"attachments"`        // JSON array of attachments
+	Attachments string    `json:"attachments"`        // JSON array of AttachmentRef
 	ToolCalls   string    `json:"tool_calls"`         // JSON array of tool calls made during this response
 	Soul        string    `json:"soul,omitempty"`     // Soul used for this response
 	Provider    string    `json:"provider,omitempty"` // LLM provider used for this response
 	CreatedAt   time.Time `json:"created_at"`
+
+	Siblings []string `gorm:"-" json:"siblings,omitempty"` // Alternative versions incl. this one (set when branched)
+}
+
+// AttachmentRef is a message attachment whose bytes live in the blob store
+type AttachmentRef struct {
+	Type     string `json:"type"`                // "image", "file", etc.
+	MimeType string `json:"mime_type,omitempty"` // e.g. "image/png"
+	Hash     string `json:"hash,omitempty"`      // SHA-256 of the blob (empty for URL-only attachments)
+	Size     int64  `json:"size,omitempty"`
+	URL      string `json:"url,omitempty"`
+	Filename string `json:"filename,omitempty"`
+}
+
+// MessagePreview is a shortened message for chat lists
+type MessagePreview struct {
+	ID             string    `json:"id"`
+	ChatID         string    `json:"chat_id"`
+	Role           string    `json:"role"`
+	Content        string    `json:"content"` // Truncated to previewLength characters
+	HasAttachments bool      `json:"has_attachments"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// previewLength is the max content length of a MessagePreview
+const previewLength = 200
+
+// MessagePage selects a page of messages by cursor (at most one of BeforeID/AfterID)
+type MessagePage struct {
+	BeforeID string // Messages older than this message
+	AfterID  string // Messages newer than this message
+	Limit    int    // Max messages (0 = no limit)
 }
 
 // ToolCallRecord represents a single tool call for storage/display
@@ -41,6 +78,7 @@ type ToolCallRecord struct {
 	Result    string            `json:"result"`
 	Error     string            `json:"error,omitempty"`
 	Duration  int64             `json:"duration_ms"`
+	Children  []ToolCallRecord  `json:"children,omitempty"` // Tool calls made by a delegated sub-agent
 }
 
 // PluginConfig stores plugin configuration values
@@ -51,3 +89,132 @@ type PluginConfig struct {
 	Value     string    `json:"value"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
+
+// PluginGrant is a permission a user approved for a plugin
+type PluginGrant struct {
+	ID         uint      `gorm:"primaryKey" json:"-"`
+	PluginName string    `gorm:"index:idx_plugin_grant,unique" json:"plugin_name"`
+	Permission string    `gorm:"index:idx_plugin_grant,unique" json:"permission"`
+	GrantedBy  string    `json:"granted_by"` // ID of the approving user
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// MessageEmbedding stores the embedding vector of a message for semantic recall
+type MessageEmbedding struct {
+	MessageID string    `gorm:"primaryKey" json:"message_id"`
+	Model     string    `gorm:"primaryKey" json:"model"` // Embedder name (vectors of different models are not comparable)
+	ChatID    string    `gorm:"index" json:"chat_id"`
+	Vector    []byte    `json:"-"` // Little-endian float32 values (empty if the embedder rejected the message)
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Memory is a long-term fact about a user, shared across all of their chats
+type Memory struct {
+	ID        string    `gorm:"primaryKey" json:"id"`
+	UserID    string    `gorm:"index" json:"user_id"`
+	Content   string    `json:"content"`
+	Source    string    `json:"source"`            // "skill", "auto" or "api"
+	ChatID    string    `json:"chat_id,omitempty"` // Chat the memory was learned in
+	Model     string    `json:"-"`                 // Embedder name of Vector
+	Vector    []byte    `json:"-"`                 // Embedding of Content (little-endian float32)
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Blob is a content-addressed file in the blob store
+type Blob struct {
+	Hash      string    `gorm:"primaryKey" json:"hash"` // Hex SHA-256 of the content
+	Size      int64     `json:"size"`
+	MimeType  string    `json:"mime_type"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// KBDocument is a file ingested into the knowledge base
+type KBDocument struct {
+	Path      string    `gorm:"primaryKey" json:"path"` // Relative to the knowledge directory
+	Soul      string    `gorm:"index" json:"soul"`      // Soul the document belongs to ("" = all souls)
+	Size      int64     `json:"size"`
+	ModTime   time.Time `json:"mod_time"`
+	Chunks    int       `json:"chunks"`
+	Error     string    `json:"error,omitempty"`
+	IndexedAt time.Time `json:"indexed_at"`
+}
+
+// KBChunk is a retrievable passage of a knowledge base document
+type KBChunk struct {
+	ID           uint   `gorm:"primaryKey" json:"id"`
+	DocumentPath string `gorm:"index" json:"document_path"`
+	Soul         string `gorm:"index" json:"soul"`
+	Ordinal      int    `json:"ordinal"`  // Position within the document
+	Location     string `json:"location"` // Heading path or page number for citations
+	Content      string `json:"content"`
+	Model        string `json:"-"` // Embedder name of Vector
+	Vector       []byte `json:"-"` // Embedding of Content (little-endian float32)
+}
+
+// KVEntry is a key-value entry of a plugin
+type KVEntry struct {
+	Plugin    string `gorm:"primaryKey" json:"plugin"`
+	Key       string `gorm:"primaryKey" json:"key"`
+	Value     []byte `json:"value"`
+	Version   int64  `json:"version"`                 // Incremented on every write, for compare-and-swap
+	ExpiresAt int64  `gorm:"index" json:"expires_at"` // Unix milliseconds, 0 = never
+	UpdatedAt int64  `json:"updated_at"`              // Unix milliseconds
+}
+
+// Document is a JSON document in a plugin's collection
+type Document struct {
+	Plugin     string `gorm:"primaryKey" json:"plugin"`
+	Collection string `gorm:"primaryKey" json:"collection"`
+	ID         string `gorm:"primaryKey" json:"id"`
+	Data       string `json:"data"` // JSON object
+	Version    int64  `json:"version"`
+	CreatedAt  int64  `json:"created_at"` // Unix milliseconds
+	UpdatedAt  int64  `json:"updated_at"`
+}
+
+// DocumentIndex declares an indexed field of a collection
+type DocumentIndex struct {
+	Plugin     string `gorm:"primaryKey" json:"plugin"`
+	Collection string `gorm:"primaryKey" json:"collection"`
+	Field      string `gorm:"primaryKey" json:"field"`
+}
+
+// DocumentField is the value of an indexed field of a document
+type DocumentField struct {
+	Plugin     string `gorm:"primaryKey;index:idx_document_field_value,priority:1" json:"plugin"`
+	Collection string `gorm:"primaryKey;index:idx_document_field_value,priority:2" json:"collection"`
+	DocID      string `gorm:"primaryKey" json:"doc_id"`
+	Field      string `gorm:"primaryKey;index:idx_document_field_value,priority:3" json:"field"`
+	Value      string `gorm:"index:idx_document_field_value,priority:4" json:"value"` // Canonical JSON
+}
+
+// User is an account that can sign in to the web UI and API
+type User struct {
+	ID              string    `gorm:"primaryKey" json:"id"`
+	Username        string    `gorm:"uniqueIndex" json:"username"`
+	PasswordHash    string    `json:"-"`                          // bcrypt
+	Role            string    `json:"role"`                       // "admin" or "user"
+	DefaultProvider string    `json:"default_provider,omitempty"` // LLM provider for the user's chats ("" = instance default)
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// Session is a signed-in browser session, identified by a cookie
+type Session struct {
+	TokenHash string    `gorm:"primaryKey" json:"-"` // Hex SHA-256 of the cookie value
+	UserID    string    `gorm:"index" json:"user_id"`
+	ExpiresAt time.Time `gorm:"index" json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// APIToken is a bearer token for scripts and other API clients
+type APIToken struct {
+	ID         string     `gorm:"primaryKey" json:"id"`
+	UserID     string     `gorm:"index" json:"user_id"`
+	Name       string     `json:"name"`
+	TokenHash  string     `gorm:"uniqueIndex" json:"-"` // Hex SHA-256 of the token
+	Prefix     string     `json:"prefix"`               // Start of the token, to recognize it
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
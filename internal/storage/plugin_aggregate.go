@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+
+	pb "github.com/fipso/chadbot/gen/chadbot"
+)
+
+// aggregateFunctions are the aggregate functions plugins may use
+var aggregateFunctions = map[string]bool{"count": true, "sum": true, "avg": true, "min": true, "max": true}
+
+func (ps *PluginStorage) count(reqID string, req *pb.CountRequest) *pb.StorageResponse {
+	resp := &pb.StorageResponse{RequestId: reqID}
+
+	tableName, err := ps.NamespacedTable(req.TableName)
+	if err != nil {
+		resp.Error = err.Error()
+		return resp
+	}
+
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s", tableName)
+	where, values, err := ps.whereSQL(DB, tableName, req.Filter, req.WhereClause, req.WhereArgs)
+	if err != nil {
+		resp.Error = err.Error()
+		return resp
+	}
+	if where != "" {
+		query += " WHERE " + where
+	}
+
+	if err := DB.Raw(query, values...).Scan(&resp.Count).Error; err != nil {
+		resp.Error = err.Error()
+		return resp
+	}
+
+	resp.Success = true
+	return resp
+}
+
+func (ps *PluginStorage) aggregate(reqID string, req *pb.AggregateRequest) *pb.StorageResponse {
+	resp := &pb.StorageResponse{RequestId: reqID}
+
+	query, values, err := ps.aggregateSQL(req)
+	if err != nil {
+		resp.Error = err.Error()
+		return resp
+	}
+
+	sqlDB, err := DB.DB()
+	if err != nil {
+		resp.Error = err.Error()
+		return resp
+	}
+	rows, err := sqlDB.Query(query, values...)
+	if err != nil {
+		resp.Error = err.Error()
+		return resp
+	}
+	defer rows.Close()
+
+	if resp.Rows, err = scanTypedRows(rows); err != nil {
+		resp.Error = err.Error()
+		return resp
+	}
+
+	resp.Success = true
+	return resp
+}
+
+// aggregateSQL builds a SELECT of aggregate functions, grouped by the requested columns
+func (ps *PluginStorage) aggregateSQL(req *pb.AggregateRequest) (string, []any, error) {
+	tableName, err := ps.NamespacedTable(req.TableName)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(req.Aggregates) == 0 {
+		return "", nil, fmt.Errorf("aggregate needs at least one function")
+	}
+
+	var selects []string
+	for _, col := range req.GroupBy {
+		if !ValidTableNameRegex.MatchString(col) {
+			return "", nil, fmt.Errorf("invalid column name: %s", col)
+		}
+		selects = append(selects, col)
+	}
+
+	for _, agg := range req.Aggregates {
+		fn := strings.ToLower(agg.Function)
+		if !aggregateFunctions[fn] {
+			return "", nil, fmt.Errorf("unknown aggregate function: %s", agg.Function)
+		}
+
+		arg, alias := "*", fn
+		if agg.Column != "" {
+			if !ValidTableNameRegex.MatchString(agg.Column) {
+				return "", nil, fmt.Errorf("invalid column name: %s", agg.Column)
+			}
+			arg, alias = agg.Column, fn+"_"+agg.Column
+		} else if fn != "count" {
+			return "", nil, fmt.Errorf("%s needs a column", fn)
+		}
+
+		if agg.Alias != "" {
+			if !ValidTableNameRegex.MatchString(agg.Alias) {
+				return "", nil, fmt.Errorf("invalid alias: %s", agg.Alias)
+			}
+			alias = agg.Alias
+		}
+		selects = append(selects, fmt.Sprintf("%s(%s) AS %s", strings.ToUpper(fn), arg, alias))
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(selects, ", "), tableName)
+	where, values, err := ps.whereSQL(DB, tableName, req.Filter, req.WhereClause, req.WhereArgs)
+	if err != nil {
+		return "", nil, err
+	}
+	if where != "" {
+		query += " WHERE " + where
+	}
+	if len(req.GroupBy) > 0 {
+		query += " GROUP BY " + strings.Join(req.GroupBy, ", ")
+	}
+	return query, values, nil
+}
@@ -0,0 +1,314 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	pb "github.com/fipso/chadbot/gen/chadbot"
+)
+
+// fieldPathRegex matches document field paths like "name" or "owner.id"
+var fieldPathRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*(\.[a-zA-Z_][a-zA-Z0-9_]*)*$`)
+
+// docs handles a request on one of the plugin's document collections
+func (ps *PluginStorage) docs(reqID string, req *pb.DocRequest) *pb.StorageResponse {
+	resp := &pb.StorageResponse{RequestId: reqID}
+
+	if !ValidTableNameRegex.MatchString(req.Collection) {
+		resp.Error = fmt.Sprintf("invalid collection name: %s", req.Collection)
+		return resp
+	}
+
+	var err error
+	switch op := req.Op.(type) {
+	case *pb.DocRequest_Put:
+		err = ps.docPut(resp, req.Collection, op.Put)
+	case *pb.DocRequest_Get:
+		err = ps.docGet(resp, req.Collection, op.Get)
+	case *pb.DocRequest_Delete:
+		err = ps.docDelete(resp, req.Collection, op.Delete)
+	case *pb.DocRequest_Find:
+		err = ps.docFind(resp, req.Collection, op.Find)
+	case *pb.DocRequest_EnsureIndex:
+		err = ps.docEnsureIndex(req.Collection, op.EnsureIndex)
+	default:
+		err = fmt.Errorf("unknown docs operation")
+	}
+	if err == errConflict {
+		resp.Conflict = true
+	}
+	if err != nil {
+		resp.Error = err.Error()
+		return resp
+	}
+
+	resp.Success = true
+	return resp
+}
+
+func (ps *PluginStorage) docPut(resp *pb.StorageResponse, collection string, req *pb.DocPut) error {
+	var data map[string]any
+	if err := json.Unmarshal([]byte(req.Json), &data); err != nil || data == nil {
+		return fmt.Errorf("documents must be JSON objects")
+	}
+	id := req.Id
+	if id == "" {
+		id = uuid.New().String()
+	} else if err := validKey(id); err != nil {
+		return err
+	}
+
+	doc := Document{Plugin: ps.pluginName, Collection: collection, ID: id, Data: req.Json}
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var existing []Document
+		if err := tx.Where("plugin = ? AND collection = ? AND id = ?", ps.pluginName, collection, id).
+			Limit(1).Find(&existing).Error; err != nil {
+			return err
+		}
+
+		now := time.Now().UnixMilli()
+		doc.CreatedAt, doc.UpdatedAt, doc.Version = now, now, 1
+		if len(existing) > 0 {
+			doc.CreatedAt = existing[0].CreatedAt
+			doc.Version = existing[0].Version + 1
+		}
+
+		if req.Cas {
+			current := int64(0)
+			if len(existing) > 0 {
+				current = existing[0].Version
+			}
+			if current != req.ExpectedVersion {
+				return errConflict
+			}
+		}
+
+		if len(existing) == 0 {
+			if err := tx.Create(&doc).Error; err != nil {
+				return err
+			}
+		} else {
+			// Conditional on the version read above, so a concurrent write is never overwritten
+			res := tx.Model(&Document{}).
+				Where("plugin = ? AND collection = ? AND id = ? AND version = ?", ps.pluginName, collection, id, existing[0].Version).
+				Updates(map[string]any{"data": doc.Data, "version": doc.Version, "updated_at": doc.UpdatedAt})
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				return errConflict
+			}
+		}
+		return indexDocument(tx, &doc)
+	})
+	if err != nil {
+		return err
+	}
+
+	resp.AffectedRows = 1
+	resp.Documents = []*pb.Document{doc.proto()}
+	return nil
+}
+
+func (ps *PluginStorage) docGet(resp *pb.StorageResponse, collection string, req *pb.DocGet) error {
+	var docs []Document
+	err := DB.Where("plugin = ? AND collection = ? AND id = ?", ps.pluginName, collection, req.Id).
+		Limit(1).Find(&docs).Error
+	if err != nil {
+		return err
+	}
+	for _, d := range docs {
+		resp.Documents = append(resp.Documents, d.proto())
+	}
+	return nil
+}
+
+func (ps *PluginStorage) docDelete(resp *pb.StorageResponse, collection string, req *pb.DocDelete) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("plugin = ? AND collection = ? AND id = ?", ps.pluginName, collection, req.Id).Delete(&Document{})
+		if res.Error != nil {
+			return res.Error
+		}
+		resp.AffectedRows = res.RowsAffected
+		return tx.Where("plugin = ? AND collection = ? AND doc_id = ?", ps.pluginName, collection, req.Id).
+			Delete(&DocumentField{}).Error
+	})
+}
+
+func (ps *PluginStorage) docFind(resp *pb.StorageResponse, collection string, req *pb.DocFind) error {
+	indexed, err := ps.indexedFields(DB, collection)
+	if err != nil {
+		return err
+	}
+
+	query := DB.Where("plugin = ? AND collection = ?", ps.pluginName, collection)
+	for _, m := range req.Matches {
+		if !indexed[m.Field] {
+			return fmt.Errorf("field %s of collection %s is not indexed", m.Field, collection)
+		}
+		value, err := canonicalJSON([]byte(m.JsonValue))
+		if err != nil {
+			return fmt.Errorf("invalid value for %s: %w", m.Field, err)
+		}
+		query = query.Where(`EXISTS (SELECT 1 FROM document_fields f WHERE f.plugin = documents.plugin
+			AND f.collection = documents.collection AND f.doc_id = documents.id AND f.field = ? AND f.value = ?)`,
+			m.Field, value)
+	}
+
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+	var docs []Document
+	if err := query.Order("created_at, id").Limit(limit).Offset(int(req.Offset)).Find(&docs).Error; err != nil {
+		return err
+	}
+	for _, d := range docs {
+		resp.Documents = append(resp.Documents, d.proto())
+	}
+	return nil
+}
+
+func (ps *PluginStorage) docEnsureIndex(collection string, req *pb.DocEnsureIndex) error {
+	for _, field := range req.Fields {
+		if !fieldPathRegex.MatchString(field) {
+			return fmt.Errorf("invalid field path: %s", field)
+		}
+	}
+
+	return DB.Transaction(func(tx *gorm.DB) error {
+		indexed, err := ps.indexedFields(tx, collection)
+		if err != nil {
+			return err
+		}
+		var added []string
+		for _, field := range req.Fields {
+			if indexed[field] {
+				continue
+			}
+			if err := tx.Create(&DocumentIndex{Plugin: ps.pluginName, Collection: collection, Field: field}).Error; err != nil {
+				return err
+			}
+			added = append(added, field)
+		}
+		if len(added) == 0 {
+			return nil
+		}
+
+		// Index the existing documents
+		var docs []Document
+		err = tx.Where("plugin = ? AND collection = ?", ps.pluginName, collection).
+			FindInBatches(&docs, 500, func(batch *gorm.DB, _ int) error {
+				for i := range docs {
+					if err := insertFields(tx, &docs[i], added); err != nil {
+						return err
+					}
+				}
+				return nil
+			}).Error
+		return err
+	})
+}
+
+// indexedFields returns the indexed fields of a collection
+func (ps *PluginStorage) indexedFields(tx *gorm.DB, collection string) (map[string]bool, error) {
+	var fields []string
+	err := tx.Model(&DocumentIndex{}).Where("plugin = ? AND collection = ?", ps.pluginName, collection).
+		Pluck("field", &fields).Error
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		result[f] = true
+	}
+	return result, nil
+}
+
+// indexDocument replaces the indexed field values of a document
+func indexDocument(tx *gorm.DB, doc *Document) error {
+	err := tx.Where("plugin = ? AND collection = ? AND doc_id = ?", doc.Plugin, doc.Collection, doc.ID).
+		Delete(&DocumentField{}).Error
+	if err != nil {
+		return err
+	}
+	var fields []string
+	err = tx.Model(&DocumentIndex{}).Where("plugin = ? AND collection = ?", doc.Plugin, doc.Collection).
+		Pluck("field", &fields).Error
+	if err != nil || len(fields) == 0 {
+		return err
+	}
+	return insertFields(tx, doc, fields)
+}
+
+// insertFields stores the values of the given fields of a document (missing fields are skipped)
+func insertFields(tx *gorm.DB, doc *Document, fields []string) error {
+	var data any
+	if err := json.Unmarshal([]byte(doc.Data), &data); err != nil {
+		return err
+	}
+
+	var rows []DocumentField
+	for _, field := range fields {
+		value, ok := fieldValue(data, field)
+		if !ok {
+			continue
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		rows = append(rows, DocumentField{
+			Plugin:     doc.Plugin,
+			Collection: doc.Collection,
+			DocID:      doc.ID,
+			Field:      field,
+			Value:      string(encoded),
+		})
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	return tx.Create(&rows).Error
+}
+
+// fieldValue returns the value at a dotted path of a decoded JSON document
+func fieldValue(data any, path string) (any, bool) {
+	for _, part := range strings.Split(path, ".") {
+		obj, ok := data.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if data, ok = obj[part]; !ok {
+			return nil, false
+		}
+	}
+	return data, true
+}
+
+// canonicalJSON re-encodes a JSON value the way indexed field values are stored
+func canonicalJSON(value []byte) (string, error) {
+	var v any
+	if err := json.Unmarshal(value, &v); err != nil {
+		return "", err
+	}
+	encoded, err := json.Marshal(v)
+	return string(encoded), err
+}
+
+// proto converts a document for the plugin protocol
+func (d Document) proto() *pb.Document {
+	return &pb.Document{
+		Id:        d.ID,
+		Json:      d.Data,
+		Version:   d.Version,
+		CreatedAt: d.CreatedAt,
+		UpdatedAt: d.UpdatedAt,
+	}
+}
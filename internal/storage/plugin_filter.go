@@ -0,0 +1,224 @@
+package storage
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+
+	pb "github.com/fipso/chadbot/gen/chadbot"
+)
+
+// RawSQLConfigKey is the plugin config key (config.toml) that allows a plugin to send raw SQL:
+// where clauses, order by expressions, column expressions, SQL migration steps and non-literal defaults.
+// Raw SQL is not confined to the plugin's namespace, so it is off by default.
+const RawSQLConfigKey = "storage_raw_sql"
+
+var (
+	// columnTypeRegex matches SQLite type names like TEXT or VARCHAR(255), nothing that
+	// could carry a constraint such as REFERENCES
+	columnTypeRegex = regexp.MustCompile(`(?i)^(TEXT|CLOB|CHARACTER|CHAR|VARCHAR|NVARCHAR|INTEGER|INT|TINYINT|SMALLINT|BIGINT|` +
+		`REAL|FLOAT|DOUBLE|DOUBLE PRECISION|NUMERIC|DECIMAL|BOOLEAN|BLOB|DATE|DATETIME|TIMESTAMP|JSON)` +
+		`(\(\s*\d+\s*(,\s*\d+\s*)?\))?$`)
+	// defaultLiteralRegex matches literal default values
+	defaultLiteralRegex = regexp.MustCompile(`(?i)^(-?\d+(\.\d+)?|'([^']|'')*'|NULL|TRUE|FALSE|CURRENT_TIME|CURRENT_DATE|CURRENT_TIMESTAMP)$`)
+)
+
+// filterOps maps filter operators to SQL comparison operators
+var filterOps = map[pb.FilterOp]string{
+	pb.FilterOp_FILTER_OP_EQ:   "=",
+	pb.FilterOp_FILTER_OP_NE:   "!=",
+	pb.FilterOp_FILTER_OP_LT:   "<",
+	pb.FilterOp_FILTER_OP_LE:   "<=",
+	pb.FilterOp_FILTER_OP_GT:   ">",
+	pb.FilterOp_FILTER_OP_GE:   ">=",
+	pb.FilterOp_FILTER_OP_LIKE: "LIKE",
+}
+
+// SetRawSQL allows or forbids raw SQL for the plugin
+func (ps *PluginStorage) SetRawSQL(allowed bool) {
+	ps.rawSQL.Store(allowed)
+}
+
+// requireRawSQL fails unless raw SQL is allowed for the plugin
+func (ps *PluginStorage) requireRawSQL(what string) error {
+	if ps.rawSQL.Load() {
+		return nil
+	}
+	return fmt.Errorf("%s needs raw SQL, which is disabled for plugin %s (set %s = true in its config)",
+		what, ps.pluginName, RawSQLConfigKey)
+}
+
+// tableColumns returns the column names of a table
+func tableColumns(tx *gorm.DB, tableName string) (map[string]bool, error) {
+	var cols []struct{ Name string }
+	if err := tx.Raw(fmt.Sprintf("PRAGMA table_info(%s)", tableName)).Scan(&cols).Error; err != nil {
+		return nil, err
+	}
+	if len(cols) == 0 {
+		return nil, fmt.Errorf("no such table: %s", tableName)
+	}
+	result := make(map[string]bool, len(cols))
+	for _, c := range cols {
+		result[c.Name] = true
+	}
+	return result, nil
+}
+
+// whereSQL returns the condition of a request from its filter or, if allowed, its raw where clause
+func (ps *PluginStorage) whereSQL(tx *gorm.DB, tableName string, filter *pb.Filter, clause string, clauseArgs []string) (string, []any, error) {
+	if clause != "" {
+		if filter != nil {
+			return "", nil, fmt.Errorf("use either a filter or a where clause")
+		}
+		if err := ps.requireRawSQL("where clause"); err != nil {
+			return "", nil, err
+		}
+		args := make([]any, len(clauseArgs))
+		for i, a := range clauseArgs {
+			args[i] = a
+		}
+		return clause, args, nil
+	}
+	if filter == nil {
+		return "", nil, nil
+	}
+
+	cols, err := tableColumns(tx, tableName)
+	if err != nil {
+		return "", nil, err
+	}
+	return filterSQL(filter, cols)
+}
+
+// filterSQL compiles a filter to a SQL condition on known columns
+func filterSQL(f *pb.Filter, cols map[string]bool) (string, []any, error) {
+	switch e := f.GetExpr().(type) {
+	case *pb.Filter_Condition:
+		return conditionSQL(e.Condition, cols)
+	case *pb.Filter_And:
+		return joinFilters(e.And.GetFilters(), "AND", "1 = 1", cols)
+	case *pb.Filter_Or:
+		return joinFilters(e.Or.GetFilters(), "OR", "1 = 0", cols)
+	case *pb.Filter_Not:
+		cond, args, err := filterSQL(e.Not, cols)
+		if err != nil {
+			return "", nil, err
+		}
+		return "NOT (" + cond + ")", args, nil
+	default:
+		return "", nil, fmt.Errorf("empty filter")
+	}
+}
+
+// joinFilters combines filters with AND or OR (empty = the given identity condition)
+func joinFilters(filters []*pb.Filter, op, empty string, cols map[string]bool) (string, []any, error) {
+	if len(filters) == 0 {
+		return empty, nil, nil
+	}
+	conds := make([]string, len(filters))
+	var args []any
+	for i, f := range filters {
+		cond, condArgs, err := filterSQL(f, cols)
+		if err != nil {
+			return "", nil, err
+		}
+		conds[i] = "(" + cond + ")"
+		args = append(args, condArgs...)
+	}
+	return strings.Join(conds, " "+op+" "), args, nil
+}
+
+// conditionSQL compiles a single column condition
+func conditionSQL(c *pb.Condition, cols map[string]bool) (string, []any, error) {
+	if !cols[c.Column] {
+		return "", nil, fmt.Errorf("unknown column: %s", c.Column)
+	}
+
+	switch c.Op {
+	case pb.FilterOp_FILTER_OP_IS_NULL:
+		return c.Column + " IS NULL", nil, nil
+	case pb.FilterOp_FILTER_OP_IS_NOT_NULL:
+		return c.Column + " IS NOT NULL", nil, nil
+
+	case pb.FilterOp_FILTER_OP_IN, pb.FilterOp_FILTER_OP_NOT_IN:
+		not := c.Op == pb.FilterOp_FILTER_OP_NOT_IN
+		if len(c.Values) == 0 {
+			if not {
+				return "1 = 1", nil, nil
+			}
+			return "1 = 0", nil, nil
+		}
+		placeholders := make([]string, len(c.Values))
+		args := make([]any, len(c.Values))
+		for i, v := range c.Values {
+			placeholders[i] = "?"
+			args[i] = valueArg(v)
+		}
+		op := "IN"
+		if not {
+			op = "NOT IN"
+		}
+		return fmt.Sprintf("%s %s (%s)", c.Column, op, strings.Join(placeholders, ", ")), args, nil
+	}
+
+	op, ok := filterOps[c.Op]
+	if !ok {
+		return "", nil, fmt.Errorf("unknown filter operator: %v", c.Op)
+	}
+	if valueArg(c.Value) == nil {
+		return "", nil, fmt.Errorf("condition on %s needs a value (use is_null to match NULL)", c.Column)
+	}
+	return fmt.Sprintf("%s %s ?", c.Column, op), []any{valueArg(c.Value)}, nil
+}
+
+// orderSQL returns the ORDER BY expression of a query. Sort columns must exist; legacy order_by
+// strings other than "column [ASC|DESC], ..." need raw SQL.
+func (ps *PluginStorage) orderSQL(order []*pb.OrderBy, orderBy string, cols map[string]bool) (string, error) {
+	if len(order) == 0 && orderBy != "" {
+		parsed, ok := parseOrderBy(orderBy, cols)
+		if !ok {
+			if err := ps.requireRawSQL("order by " + orderBy); err != nil {
+				return "", err
+			}
+			return orderBy, nil
+		}
+		order = parsed
+	}
+
+	terms := make([]string, len(order))
+	for i, o := range order {
+		if !cols[o.Column] {
+			return "", fmt.Errorf("unknown column: %s", o.Column)
+		}
+		terms[i] = o.Column + " ASC"
+		if o.Desc {
+			terms[i] = o.Column + " DESC"
+		}
+	}
+	return strings.Join(terms, ", "), nil
+}
+
+// parseOrderBy parses a "column [ASC|DESC], ..." string on known columns
+func parseOrderBy(orderBy string, cols map[string]bool) ([]*pb.OrderBy, bool) {
+	var order []*pb.OrderBy
+	for _, term := range strings.Split(orderBy, ",") {
+		fields := strings.Fields(term)
+		if len(fields) == 0 || len(fields) > 2 || !cols[fields[0]] {
+			return nil, false
+		}
+		o := &pb.OrderBy{Column: fields[0]}
+		if len(fields) == 2 {
+			switch strings.ToUpper(fields[1]) {
+			case "ASC":
+			case "DESC":
+				o.Desc = true
+			default:
+				return nil, false
+			}
+		}
+		order = append(order, o)
+	}
+	return order, true
+}
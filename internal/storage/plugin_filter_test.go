@@ -0,0 +1,193 @@
+package storage
+
+import (
+	"testing"
+
+	pb "github.com/fipso/chadbot/gen/chadbot"
+)
+
+// testColumns are the columns of the table filters and orders are checked against
+var testColumns = map[string]bool{"id": true, "name": true, "score": true}
+
+func text(s string) *pb.Value {
+	return &pb.Value{Kind: &pb.Value_TextValue{TextValue: s}}
+}
+
+func cond(column string, op pb.FilterOp, value *pb.Value) *pb.Filter {
+	return &pb.Filter{Expr: &pb.Filter_Condition{Condition: &pb.Condition{Column: column, Op: op, Value: value}}}
+}
+
+func TestFilterSQL(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter *pb.Filter
+		want   string // Empty if the filter must be rejected
+	}{
+		{"equals", cond("name", pb.FilterOp_FILTER_OP_EQ, text("x")), "name = ?"},
+		{"is null", cond("score", pb.FilterOp_FILTER_OP_IS_NULL, nil), "score IS NULL"},
+		{"and", &pb.Filter{Expr: &pb.Filter_And{And: &pb.FilterList{Filters: []*pb.Filter{
+			cond("id", pb.FilterOp_FILTER_OP_GT, text("1")),
+			cond("name", pb.FilterOp_FILTER_OP_LIKE, text("a%")),
+		}}}}, "(id > ?) AND (name LIKE ?)"},
+		{"empty or", &pb.Filter{Expr: &pb.Filter_Or{Or: &pb.FilterList{}}}, "1 = 0"},
+		{"not", &pb.Filter{Expr: &pb.Filter_Not{Not: cond("id", pb.FilterOp_FILTER_OP_EQ, text("1"))}}, "NOT (id = ?)"},
+		{"in", &pb.Filter{Expr: &pb.Filter_Condition{Condition: &pb.Condition{
+			Column: "id", Op: pb.FilterOp_FILTER_OP_IN, Values: []*pb.Value{text("1"), text("2")},
+		}}}, "id IN (?, ?)"},
+
+		{"empty filter", &pb.Filter{}, ""},
+		{"unknown column", cond("password", pb.FilterOp_FILTER_OP_EQ, text("x")), ""},
+		{"injected column", cond("id = 1 OR 1", pb.FilterOp_FILTER_OP_EQ, text("x")), ""},
+		{"subquery column", cond("(SELECT secret FROM users)", pb.FilterOp_FILTER_OP_EQ, text("x")), ""},
+		{"unknown column in and", &pb.Filter{Expr: &pb.Filter_And{And: &pb.FilterList{Filters: []*pb.Filter{
+			cond("id", pb.FilterOp_FILTER_OP_EQ, text("1")),
+			cond("id; DROP TABLE users", pb.FilterOp_FILTER_OP_EQ, text("1")),
+		}}}}, ""},
+		{"unknown column in not", &pb.Filter{Expr: &pb.Filter_Not{Not: cond("x", pb.FilterOp_FILTER_OP_IS_NULL, nil)}}, ""},
+		{"missing value", cond("name", pb.FilterOp_FILTER_OP_EQ, nil), ""},
+		{"unknown operator", cond("name", pb.FilterOp(99), text("x")), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := filterSQL(tt.filter, testColumns)
+			if tt.want == "" {
+				if err == nil {
+					t.Fatalf("accepted as %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrderSQL(t *testing.T) {
+	tests := []struct {
+		orderBy string
+		want    string // Empty if the order must be rejected without raw SQL
+	}{
+		{"id", "id ASC"},
+		{"score DESC, name", "score DESC, name ASC"},
+		{"name desc", "name DESC"},
+		{"", ""},
+		{"password", ""},
+		{"id; DROP TABLE users", ""},
+		{"(SELECT secret FROM users)", ""},
+		{"id DESC LIMIT 1", ""},
+		{"id, ", ""},
+		{"random()", ""},
+	}
+	ps := NewPluginStorage("test")
+	for _, tt := range tests {
+		t.Run(tt.orderBy, func(t *testing.T) {
+			got, err := ps.orderSQL(nil, tt.orderBy, testColumns)
+			if tt.want == "" && tt.orderBy != "" {
+				if err == nil {
+					t.Fatalf("accepted as %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := ps.orderSQL([]*pb.OrderBy{{Column: "id DESC; --"}}, "", testColumns); err == nil {
+		t.Fatal("structured order on an unknown column accepted")
+	}
+	ps.SetRawSQL(true)
+	if got, err := ps.orderSQL(nil, "random()", testColumns); err != nil || got != "random()" {
+		t.Fatalf("raw order by with raw SQL allowed: %q, %v", got, err)
+	}
+}
+
+func TestNamespacedTable(t *testing.T) {
+	tests := []struct {
+		plugin, table string
+		want          string // Empty if the name must be rejected
+	}{
+		{"notes", "items", "plugin_notes_items"},
+		{"notes", "seen_posts", "plugin_notes_seen_posts"},
+		{"x2", "t", "plugin_x2_t"},
+
+		{"notes", "", ""},
+		{"notes", "_items", ""},
+		{"notes", "items; DROP TABLE users", ""},
+		{"notes", "../users", ""},
+		{"notes", "items\"", ""},
+		// Other names are encoded: plugin "a" with table "b_x" and plugin "a_b" with table "x"
+		// must not share plugin_a_b_x
+		{"a", "b_x", "plugin_a_b_x"},
+		{"a_b", "x", "plugin_0615f62_x"},
+		{"a-b", "x", "plugin_0612d62_x"},
+		{"mcp-bridge", "notes", "plugin_06d63702d627269646765_notes"},
+		{"Notes", "items", "plugin_04e6f746573_items"},
+		{"2notes", "items", "plugin_0326e6f746573_items"},
+		{"", "items", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.plugin+"/"+tt.table, func(t *testing.T) {
+			got, err := NewPluginStorage(tt.plugin).NamespacedTable(tt.table)
+			if tt.want == "" {
+				if err == nil {
+					t.Fatalf("accepted as %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestColumnSQL(t *testing.T) {
+	tests := []struct {
+		col  *pb.ColumnDef
+		want string // Empty if the column must be rejected without raw SQL
+	}{
+		{&pb.ColumnDef{Name: "id", Type: "INTEGER", PrimaryKey: true}, "id INTEGER PRIMARY KEY"},
+		{&pb.ColumnDef{Name: "name", Type: "VARCHAR(255)", NotNull: true}, "name VARCHAR(255) NOT NULL"},
+		{&pb.ColumnDef{Name: "price", Type: "decimal(10, 2)"}, "price decimal(10, 2)"},
+		{&pb.ColumnDef{Name: "done", Type: "BOOLEAN", DefaultValue: "FALSE"}, "done BOOLEAN DEFAULT FALSE"},
+		{&pb.ColumnDef{Name: "note", Type: "TEXT", DefaultValue: "'it''s'"}, "note TEXT DEFAULT 'it''s'"},
+
+		{&pb.ColumnDef{Name: "chat", Type: "TEXT REFERENCES messages"}, ""},
+		{&pb.ColumnDef{Name: "id", Type: "INTEGER PRIMARY KEY"}, ""},
+		{&pb.ColumnDef{Name: "v", Type: "TEXT CHECK (v IN (SELECT secret FROM users))"}, ""},
+		{&pb.ColumnDef{Name: "v", Type: "TEXT GENERATED ALWAYS AS (1)"}, ""},
+		{&pb.ColumnDef{Name: "v", Type: "BLOBBY"}, ""},
+		{&pb.ColumnDef{Name: "v", Type: "TEXT", DefaultValue: "(SELECT secret FROM users)"}, ""},
+		{&pb.ColumnDef{Name: "v w", Type: "TEXT"}, ""},
+	}
+	ps := NewPluginStorage("test")
+	for _, tt := range tests {
+		t.Run(tt.col.Name+" "+tt.col.Type, func(t *testing.T) {
+			got, err := ps.columnSQL(tt.col)
+			if tt.want == "" {
+				if err == nil {
+					t.Fatalf("accepted as %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package storage
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GetPluginGrants returns the permissions approved for a plugin
+func GetPluginGrants(pluginName string) ([]string, error) {
+	var permissions []string
+	err := DB.Model(&PluginGrant{}).Where("plugin_name = ?", pluginName).
+		Order("permission ASC").Pluck("permission", &permissions).Error
+	return permissions, err
+}
+
+// SetPluginGrants replaces the permissions approved for a plugin
+func SetPluginGrants(pluginName string, permissions []string, grantedBy string) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("plugin_name = ?", pluginName).Delete(&PluginGrant{}).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		for _, p := range permissions {
+			grant := &PluginGrant{PluginName: pluginName, Permission: p, GrantedBy: grantedBy, CreatedAt: now}
+			if err := tx.Create(grant).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
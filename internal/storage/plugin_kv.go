@@ -0,0 +1,180 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	pb "github.com/fipso/chadbot/gen/chadbot"
+)
+
+const (
+	maxKeyLength     = 1024
+	defaultListLimit = 1000
+)
+
+// errConflict reports a failed compare-and-swap
+var errConflict = errors.New("version conflict")
+
+// liveKV is the condition on kv_entries that excludes expired entries (arg: now in Unix milliseconds)
+const liveKV = "(expires_at = 0 OR expires_at > ?)"
+
+// kv handles a key-value request in the plugin's key space
+func (ps *PluginStorage) kv(reqID string, req *pb.KVRequest) *pb.StorageResponse {
+	resp := &pb.StorageResponse{RequestId: reqID}
+
+	var err error
+	switch op := req.Op.(type) {
+	case *pb.KVRequest_Get:
+		err = ps.kvGet(resp, op.Get)
+	case *pb.KVRequest_Set:
+		err = ps.kvSet(resp, op.Set)
+	case *pb.KVRequest_Delete:
+		err = ps.kvDelete(resp, op.Delete)
+	case *pb.KVRequest_List:
+		err = ps.kvList(resp, op.List)
+	default:
+		err = fmt.Errorf("unknown kv operation")
+	}
+	if err != nil {
+		resp.Error = err.Error()
+		return resp
+	}
+
+	resp.Success = !resp.Conflict
+	if resp.Conflict {
+		resp.Error = errConflict.Error()
+	}
+	return resp
+}
+
+// validKey checks a key's length
+func validKey(key string) error {
+	if key == "" || len(key) > maxKeyLength {
+		return fmt.Errorf("keys must have 1 to %d bytes", maxKeyLength)
+	}
+	return nil
+}
+
+func (ps *PluginStorage) kvGet(resp *pb.StorageResponse, req *pb.KVGet) error {
+	var entries []KVEntry
+	err := DB.Where("plugin = ? AND key = ? AND "+liveKV, ps.pluginName, req.Key, time.Now().UnixMilli()).
+		Limit(1).Find(&entries).Error
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		resp.Entries = append(resp.Entries, e.proto(false))
+	}
+	return nil
+}
+
+func (ps *PluginStorage) kvSet(resp *pb.StorageResponse, req *pb.KVSet) error {
+	if err := validKey(req.Key); err != nil {
+		return err
+	}
+	if req.TtlSeconds < 0 {
+		return fmt.Errorf("ttl must not be negative")
+	}
+
+	now := time.Now().UnixMilli()
+	var expiresAt int64
+	if req.TtlSeconds > 0 {
+		expiresAt = now + req.TtlSeconds*1000
+	}
+	value := req.Value
+	if value == nil {
+		value = []byte{}
+	}
+
+	// Single statements, so concurrent compare-and-swaps can't both succeed
+	var versions []int64
+	var err error
+	switch {
+	case req.Cas && req.ExpectedVersion > 0:
+		err = DB.Raw(`UPDATE kv_entries SET value = ?, version = version + 1, expires_at = ?, updated_at = ?
+			WHERE plugin = ? AND key = ? AND version = ? AND `+liveKV+` RETURNING version`,
+			value, expiresAt, now, ps.pluginName, req.Key, req.ExpectedVersion, now).Scan(&versions).Error
+	case req.Cas:
+		// Create: only replaces an expired entry
+		err = DB.Raw(`INSERT INTO kv_entries (plugin, key, value, version, expires_at, updated_at) VALUES (?, ?, ?, 1, ?, ?)
+			ON CONFLICT (plugin, key) DO UPDATE SET value = excluded.value, version = kv_entries.version + 1,
+				expires_at = excluded.expires_at, updated_at = excluded.updated_at
+			WHERE kv_entries.expires_at != 0 AND kv_entries.expires_at <= ? RETURNING version`,
+			ps.pluginName, req.Key, value, expiresAt, now, now).Scan(&versions).Error
+	default:
+		err = DB.Raw(`INSERT INTO kv_entries (plugin, key, value, version, expires_at, updated_at) VALUES (?, ?, ?, 1, ?, ?)
+			ON CONFLICT (plugin, key) DO UPDATE SET value = excluded.value, version = kv_entries.version + 1,
+				expires_at = excluded.expires_at, updated_at = excluded.updated_at
+			RETURNING version`,
+			ps.pluginName, req.Key, value, expiresAt, now).Scan(&versions).Error
+	}
+	if err != nil {
+		return err
+	}
+	if len(versions) == 0 {
+		resp.Conflict = true
+		return nil
+	}
+
+	resp.AffectedRows = 1
+	resp.Entries = []*pb.KVEntry{{Key: req.Key, Version: versions[0], ExpiresAt: expiresAt}}
+	return nil
+}
+
+func (ps *PluginStorage) kvDelete(resp *pb.StorageResponse, req *pb.KVDelete) error {
+	query := DB.Where("plugin = ? AND key = ?", ps.pluginName, req.Key)
+	if req.Cas {
+		query = query.Where("version = ? AND "+liveKV, req.ExpectedVersion, time.Now().UnixMilli())
+	}
+	res := query.Delete(&KVEntry{})
+	if res.Error != nil {
+		return res.Error
+	}
+	resp.AffectedRows = res.RowsAffected
+	resp.Conflict = req.Cas && res.RowsAffected == 0
+	return nil
+}
+
+func (ps *PluginStorage) kvList(resp *pb.StorageResponse, req *pb.KVList) error {
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+
+	query := DB.Where("plugin = ? AND "+liveKV, ps.pluginName, time.Now().UnixMilli())
+	if req.Prefix != "" {
+		// substr instead of LIKE, which is case-insensitive and treats % and _ as wildcards
+		query = query.Where("substr(key, 1, length(?)) = ?", req.Prefix, req.Prefix)
+	}
+	if req.After != "" {
+		query = query.Where("key > ?", req.After)
+	}
+	if req.KeysOnly {
+		query = query.Omit("value")
+	}
+
+	var entries []KVEntry
+	if err := query.Order("key").Limit(limit).Find(&entries).Error; err != nil {
+		return err
+	}
+	for _, e := range entries {
+		resp.Entries = append(resp.Entries, e.proto(req.KeysOnly))
+	}
+	return nil
+}
+
+// proto converts an entry for the plugin protocol
+func (e KVEntry) proto(keyOnly bool) *pb.KVEntry {
+	entry := &pb.KVEntry{Key: e.Key, Version: e.Version, ExpiresAt: e.ExpiresAt}
+	if !keyOnly {
+		entry.Value = e.Value
+	}
+	return entry
+}
+
+// DeleteExpiredKV deletes key-value entries whose TTL has passed
+func DeleteExpiredKV() (int64, error) {
+	res := DB.Where("expires_at != 0 AND expires_at <= ?", time.Now().UnixMilli()).Delete(&KVEntry{})
+	return res.RowsAffected, res.Error
+}
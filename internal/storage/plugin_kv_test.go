@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	pb "github.com/fipso/chadbot/gen/chadbot"
+)
+
+func kvRequest(req *pb.KVRequest) *pb.StorageRequest {
+	return &pb.StorageRequest{Operation: &pb.StorageRequest_Kv{Kv: req}}
+}
+
+func kvSet(key, value string, cas bool, expected int64) *pb.StorageRequest {
+	return kvRequest(&pb.KVRequest{Op: &pb.KVRequest_Set{Set: &pb.KVSet{
+		Key: key, Value: []byte(value), Cas: cas, ExpectedVersion: expected,
+	}}})
+}
+
+func kvGet(key string) *pb.StorageRequest {
+	return kvRequest(&pb.KVRequest{Op: &pb.KVRequest_Get{Get: &pb.KVGet{Key: key}}})
+}
+
+// expireKV moves the expiry of a key into the past
+func expireKV(t *testing.T, plugin, key string) {
+	t.Helper()
+	err := DB.Model(&KVEntry{}).Where("plugin = ? AND key = ?", plugin, key).
+		Update("expires_at", time.Now().Add(-time.Second).UnixMilli()).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestKVCompareAndSwap(t *testing.T) {
+	newTestDB(t)
+	ps := NewPluginStorage("kvtest")
+
+	steps := []struct {
+		name     string
+		req      *pb.StorageRequest
+		conflict bool
+		version  int64 // Version after a successful write
+	}{
+		{"create", kvSet("k", "a", true, 0), false, 1},
+		{"create existing", kvSet("k", "b", true, 0), true, 0},
+		{"swap current version", kvSet("k", "c", true, 1), false, 2},
+		{"swap stale version", kvSet("k", "d", true, 1), true, 0},
+		{"plain set", kvSet("k", "e", false, 0), false, 3},
+		{"delete stale version", kvRequest(&pb.KVRequest{Op: &pb.KVRequest_Delete{Delete: &pb.KVDelete{Key: "k", Cas: true, ExpectedVersion: 2}}}), true, 0},
+		{"delete current version", kvRequest(&pb.KVRequest{Op: &pb.KVRequest_Delete{Delete: &pb.KVDelete{Key: "k", Cas: true, ExpectedVersion: 3}}}), false, 0},
+		{"swap deleted key", kvSet("k", "f", true, 3), true, 0},
+	}
+	for _, step := range steps {
+		resp := ps.HandleRequest(step.req)
+		if resp.Conflict != step.conflict || resp.Success == step.conflict {
+			t.Fatalf("%s: success=%v conflict=%v error=%q, want conflict=%v",
+				step.name, resp.Success, resp.Conflict, resp.Error, step.conflict)
+		}
+		if step.version > 0 && (len(resp.Entries) != 1 || resp.Entries[0].Version != step.version) {
+			t.Fatalf("%s: entries %v, want version %d", step.name, resp.Entries, step.version)
+		}
+	}
+
+	if resp := ps.HandleRequest(kvGet("k")); len(resp.Entries) != 0 {
+		t.Fatalf("deleted key still found: %v", resp.Entries)
+	}
+
+	// Keys are per plugin
+	ps.HandleRequest(kvSet("shared", "mine", false, 0))
+	if resp := NewPluginStorage("other").HandleRequest(kvGet("shared")); len(resp.Entries) != 0 {
+		t.Fatalf("other plugin read the key: %v", resp.Entries)
+	}
+}
+
+func TestKVTTL(t *testing.T) {
+	newTestDB(t)
+	ps := NewPluginStorage("kvtest")
+
+	resp := ps.HandleRequest(kvRequest(&pb.KVRequest{Op: &pb.KVRequest_Set{Set: &pb.KVSet{
+		Key: "session", Value: []byte("v"), TtlSeconds: 60,
+	}}}))
+	if !resp.Success || resp.Entries[0].ExpiresAt <= time.Now().UnixMilli() {
+		t.Fatalf("set with ttl: %v %q", resp.Entries, resp.Error)
+	}
+	if resp := ps.HandleRequest(kvGet("session")); len(resp.Entries) != 1 || string(resp.Entries[0].Value) != "v" {
+		t.Fatalf("live key not found: %v", resp.Entries)
+	}
+	if resp := ps.HandleRequest(kvRequest(&pb.KVRequest{Op: &pb.KVRequest_Set{Set: &pb.KVSet{Key: "bad", TtlSeconds: -1}}})); resp.Success {
+		t.Fatal("negative ttl accepted")
+	}
+
+	expireKV(t, "kvtest", "session")
+
+	// Expired entries are invisible before the janitor removes them
+	if resp := ps.HandleRequest(kvGet("session")); len(resp.Entries) != 0 {
+		t.Fatalf("expired key found: %v", resp.Entries)
+	}
+	list := ps.HandleRequest(kvRequest(&pb.KVRequest{Op: &pb.KVRequest_List{List: &pb.KVList{}}}))
+	if len(list.Entries) != 0 {
+		t.Fatalf("expired key listed: %v", list.Entries)
+	}
+	if resp := ps.HandleRequest(kvSet("session", "old", true, 1)); !resp.Conflict {
+		t.Fatal("swap of an expired entry succeeded")
+	}
+
+	// Creating with cas replaces the expired entry
+	resp = ps.HandleRequest(kvSet("session", "new", true, 0))
+	if !resp.Success || resp.Entries[0].ExpiresAt != 0 {
+		t.Fatalf("create over expired entry: %v %q", resp.Entries, resp.Error)
+	}
+
+	ps.HandleRequest(kvRequest(&pb.KVRequest{Op: &pb.KVRequest_Set{Set: &pb.KVSet{Key: "gone", TtlSeconds: 60}}}))
+	expireKV(t, "kvtest", "gone")
+	n, err := DeleteExpiredKV()
+	if err != nil || n != 1 {
+		t.Fatalf("DeleteExpiredKV = %d, %v, want 1", n, err)
+	}
+	if resp := ps.HandleRequest(kvGet("session")); len(resp.Entries) != 1 {
+		t.Fatal("janitor removed a live key")
+	}
+}
+
+func TestDocCompareAndSwap(t *testing.T) {
+	newTestDB(t)
+	ps := NewPluginStorage("doctest")
+	put := func(id, json string, cas bool, expected int64) *pb.StorageResponse {
+		return ps.HandleRequest(&pb.StorageRequest{Operation: &pb.StorageRequest_Docs{Docs: &pb.DocRequest{
+			Collection: "notes",
+			Op:         &pb.DocRequest_Put{Put: &pb.DocPut{Id: id, Json: json, Cas: cas, ExpectedVersion: expected}},
+		}}})
+	}
+
+	steps := []struct {
+		name     string
+		resp     func() *pb.StorageResponse
+		conflict bool
+		version  int64
+	}{
+		{"create", func() *pb.StorageResponse { return put("n1", `{"a":1}`, true, 0) }, false, 1},
+		{"create existing", func() *pb.StorageResponse { return put("n1", `{"a":2}`, true, 0) }, true, 0},
+		{"swap current version", func() *pb.StorageResponse { return put("n1", `{"a":3}`, true, 1) }, false, 2},
+		{"swap stale version", func() *pb.StorageResponse { return put("n1", `{"a":4}`, true, 1) }, true, 0},
+		{"plain put", func() *pb.StorageResponse { return put("n1", `{"a":5}`, false, 0) }, false, 3},
+	}
+	for _, step := range steps {
+		resp := step.resp()
+		if resp.Conflict != step.conflict || resp.Success == step.conflict {
+			t.Fatalf("%s: success=%v conflict=%v error=%q, want conflict=%v",
+				step.name, resp.Success, resp.Conflict, resp.Error, step.conflict)
+		}
+		if step.version > 0 && (len(resp.Documents) != 1 || resp.Documents[0].Version != step.version) {
+			t.Fatalf("%s: documents %v, want version %d", step.name, resp.Documents, step.version)
+		}
+	}
+}
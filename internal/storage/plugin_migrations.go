@@ -0,0 +1,176 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+
+	pb "github.com/fipso/chadbot/gen/chadbot"
+)
+
+// sqlTableRef matches {table} references in raw SQL migration steps
+var sqlTableRef = regexp.MustCompile(`\{([a-zA-Z][a-zA-Z0-9_]*)\}`)
+
+// migrate applies the plugin's registered migrations and reports its schema version
+func (ps *PluginStorage) migrate(reqID string, req *pb.MigrateRequest) *pb.StorageResponse {
+	resp := &pb.StorageResponse{RequestId: reqID}
+
+	migrations := make([]Migration, len(req.Migrations))
+	for i, m := range req.Migrations {
+		steps := m.Steps
+		migrations[i] = Migration{
+			Version: int(m.Version),
+			Name:    m.Name,
+			Up: func(tx *gorm.DB) error {
+				updates := false
+				for j, step := range steps {
+					if err := ps.applyStep(tx, step); err != nil {
+						return fmt.Errorf("step %d: %w", j+1, err)
+					}
+					if _, ok := step.Step.(*pb.MigrationStep_Update); ok {
+						updates = true
+					}
+				}
+				if updates {
+					return ps.checkUpdatedBytes(tx)
+				}
+				return nil
+			},
+		}
+	}
+
+	version, err := applyMigrations(ps.prefix, migrations)
+	resp.SchemaVersion = int32(version)
+	if err != nil {
+		resp.Error = err.Error()
+		resp.QuotaExceeded = errors.Is(err, ErrQuotaExceeded)
+		return resp
+	}
+
+	resp.Success = true
+	return resp
+}
+
+// applyStep executes a single migration step in the plugin namespace
+func (ps *PluginStorage) applyStep(tx *gorm.DB, step *pb.MigrationStep) error {
+	switch s := step.Step.(type) {
+	case *pb.MigrationStep_CreateTable:
+		query, err := ps.createTableSQL(s.CreateTable)
+		if err != nil {
+			return err
+		}
+		return tx.Exec(query).Error
+
+	case *pb.MigrationStep_DropTable:
+		table, err := ps.NamespacedTable(s.DropTable.TableName)
+		if err != nil {
+			return err
+		}
+		ifExists := ""
+		if s.DropTable.IfExists {
+			ifExists = "IF EXISTS "
+		}
+		return tx.Exec(fmt.Sprintf("DROP TABLE %s%s", ifExists, table)).Error
+
+	case *pb.MigrationStep_RenameTable:
+		table, err := ps.NamespacedTable(s.RenameTable.TableName)
+		if err != nil {
+			return err
+		}
+		newTable, err := ps.NamespacedTable(s.RenameTable.NewName)
+		if err != nil {
+			return err
+		}
+		return tx.Exec(fmt.Sprintf("ALTER TABLE %s RENAME TO %s", table, newTable)).Error
+
+	case *pb.MigrationStep_AddColumn:
+		table, err := ps.NamespacedTable(s.AddColumn.TableName)
+		if err != nil {
+			return err
+		}
+		if s.AddColumn.Column == nil {
+			return fmt.Errorf("add_column needs a column")
+		}
+		col, err := ps.columnSQL(s.AddColumn.Column)
+		if err != nil {
+			return err
+		}
+		return tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", table, col)).Error
+
+	case *pb.MigrationStep_RenameColumn:
+		table, err := pluginColumn(ps.pluginName, s.RenameColumn.TableName, s.RenameColumn.Column)
+		if err != nil {
+			return err
+		}
+		if !ValidTableNameRegex.MatchString(s.RenameColumn.NewName) {
+			return fmt.Errorf("invalid column name: %s", s.RenameColumn.NewName)
+		}
+		return tx.Exec(fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s",
+			table, s.RenameColumn.Column, s.RenameColumn.NewName)).Error
+
+	case *pb.MigrationStep_DropColumn:
+		table, err := pluginColumn(ps.pluginName, s.DropColumn.TableName, s.DropColumn.Column)
+		if err != nil {
+			return err
+		}
+		return tx.Exec(fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", table, s.DropColumn.Column)).Error
+
+	case *pb.MigrationStep_CreateIndex:
+		idx := s.CreateIndex
+		table, err := ps.NamespacedTable(idx.TableName)
+		if err != nil {
+			return err
+		}
+		name, err := ps.NamespacedTable(idx.IndexName)
+		if err != nil {
+			return fmt.Errorf("invalid index name: %s", idx.IndexName)
+		}
+		if len(idx.Columns) == 0 {
+			return fmt.Errorf("index %s needs columns", idx.IndexName)
+		}
+		for _, c := range idx.Columns {
+			if !ValidTableNameRegex.MatchString(c) {
+				return fmt.Errorf("invalid column name: %s", c)
+			}
+		}
+		unique := ""
+		if idx.Unique {
+			unique = "UNIQUE "
+		}
+		return tx.Exec(fmt.Sprintf("CREATE %sINDEX IF NOT EXISTS %s ON %s (%s)",
+			unique, name, table, strings.Join(idx.Columns, ", "))).Error
+
+	case *pb.MigrationStep_DropIndex:
+		name, err := ps.NamespacedTable(s.DropIndex.IndexName)
+		if err != nil {
+			return fmt.Errorf("invalid index name: %s", s.DropIndex.IndexName)
+		}
+		return tx.Exec(fmt.Sprintf("DROP INDEX IF EXISTS %s", name)).Error
+
+	case *pb.MigrationStep_Update:
+		query, args, err := ps.updateSQL(tx, s.Update)
+		if err != nil {
+			return err
+		}
+		return tx.Exec(query, args...).Error
+
+	case *pb.MigrationStep_Sql:
+		if err := ps.requireRawSQL("SQL migration step"); err != nil {
+			return err
+		}
+		query := sqlTableRef.ReplaceAllStringFunc(s.Sql.Statement, func(ref string) string {
+			return ps.prefix + "_" + ref[1:len(ref)-1]
+		})
+		args := make([]any, len(s.Sql.Args))
+		for i, a := range s.Sql.Args {
+			args[i] = a
+		}
+		return tx.Exec(query, args...).Error
+
+	default:
+		return fmt.Errorf("unknown migration step")
+	}
+}
@@ -0,0 +1,341 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	pb "github.com/fipso/chadbot/gen/chadbot"
+)
+
+// Plugin config keys (config.toml) that limit a plugin's storage. Values are
+// positive integers; empty or 0 means unlimited:
+//
+//	[plugins.xscroll]
+//	storage_max_rows = 100000
+//	storage_max_bytes = 268435456
+const (
+	MaxRowsConfigKey  = "storage_max_rows"
+	MaxBytesConfigKey = "storage_max_bytes"
+)
+
+// ReservedConfigKeys are plugin config keys only the user may set
+var ReservedConfigKeys = []string{RawSQLConfigKey, MaxRowsConfigKey, MaxBytesConfigKey}
+
+// ErrQuotaExceeded is returned (wrapped) when a write would exceed a plugin's quota
+var ErrQuotaExceeded = errors.New("storage quota exceeded")
+
+// usageMaxAge is how long a computed usage is trusted before it is recomputed
+const usageMaxAge = time.Minute
+
+// Quota limits the rows and approximate bytes a plugin may store (0 = unlimited)
+type Quota struct {
+	MaxRows  int64 `json:"max_rows,omitempty"`
+	MaxBytes int64 `json:"max_bytes,omitempty"`
+}
+
+// QuotaFromConfig reads a plugin's quota from its config values
+func QuotaFromConfig(values map[string]string) Quota {
+	parse := func(key string) int64 {
+		n, err := strconv.ParseInt(strings.TrimSpace(values[key]), 10, 64)
+		if err != nil || n < 0 {
+			return 0
+		}
+		return n
+	}
+	return Quota{MaxRows: parse(MaxRowsConfigKey), MaxBytes: parse(MaxBytesConfigKey)}
+}
+
+// TableUsage is the storage used by one table, the key-value store or a document collection
+type TableUsage struct {
+	Name  string `json:"name"`
+	Kind  string `json:"kind"` // "table", "kv" or "documents"
+	Rows  int64  `json:"rows"`
+	Bytes int64  `json:"bytes"`
+}
+
+// Usage is the storage used by a plugin. Bytes count the stored values, not
+// SQLite's page and index overhead.
+type Usage struct {
+	Rows       int64        `json:"rows"`
+	Bytes      int64        `json:"bytes"`
+	Tables     []TableUsage `json:"tables"`
+	Quota      *Quota       `json:"quota,omitempty"`
+	ComputedAt time.Time    `json:"computed_at"`
+}
+
+// usageCache holds the last computed usage per plugin. Totals are adjusted
+// after writes that add rows; other writes invalidate the entry.
+var usageCache = struct {
+	sync.Mutex
+	m map[string]*Usage
+}{m: make(map[string]*Usage)}
+
+// PluginUsage returns the storage used by a plugin, computed at most usageMaxAge ago
+func PluginUsage(pluginName string) (*Usage, error) {
+	usageCache.Lock()
+	defer usageCache.Unlock()
+	return cachedUsage(pluginName)
+}
+
+// cachedUsage returns the cached usage or recomputes it. Caller holds usageCache.
+func cachedUsage(pluginName string) (*Usage, error) {
+	u, ok := usageCache.m[pluginName]
+	if !ok || time.Since(u.ComputedAt) >= usageMaxAge {
+		var err error
+		if u, err = NewPluginStorage(pluginName).computeUsage(DB); err != nil {
+			return nil, err
+		}
+		usageCache.m[pluginName] = u
+	}
+	copied := *u
+	copied.Tables = slices.Clone(u.Tables)
+	return &copied, nil
+}
+
+// add records rows and bytes added to a table, key-value store or collection
+func (u *Usage) add(delta TableUsage) {
+	u.Rows += delta.Rows
+	u.Bytes += delta.Bytes
+	for i, t := range u.Tables {
+		if t.Kind == delta.Kind && t.Name == delta.Name {
+			u.Tables[i].Rows += delta.Rows
+			u.Tables[i].Bytes += delta.Bytes
+			return
+		}
+	}
+	u.Tables = append(u.Tables, delta)
+}
+
+// computeUsage measures the plugin's tables, key-value entries and documents
+func (ps *PluginStorage) computeUsage(db *gorm.DB) (*Usage, error) {
+	u := &Usage{Tables: []TableUsage{}, ComputedAt: time.Now()}
+
+	// Prefixes never start another plugin's (see pluginPrefix), so this matches only the plugin's tables
+	var tables []string
+	if err := db.Raw("SELECT name FROM sqlite_master WHERE type = 'table' AND name LIKE ? ESCAPE '\\' ORDER BY name",
+		strings.ReplaceAll(ps.prefix, "_", "\\_")+"\\_%").Scan(&tables).Error; err != nil {
+		return nil, err
+	}
+	for _, table := range tables {
+		columns, err := tableColumns(db, table)
+		if err != nil {
+			return nil, err
+		}
+		sizes := make([]string, 0, len(columns))
+		for col := range columns {
+			sizes = append(sizes, fmt.Sprintf(`COALESCE(length(CAST("%s" AS BLOB)), 0)`, col))
+		}
+		if len(sizes) == 0 {
+			sizes = append(sizes, "0")
+		}
+		t := TableUsage{Name: strings.TrimPrefix(table, ps.prefix+"_"), Kind: "table"}
+		row := db.Raw(fmt.Sprintf(`SELECT COUNT(*), COALESCE(SUM(%s), 0) FROM "%s"`, strings.Join(sizes, " + "), table)).Row()
+		if err := row.Scan(&t.Rows, &t.Bytes); err != nil {
+			return nil, err
+		}
+		u.Tables = append(u.Tables, t)
+	}
+
+	kv := TableUsage{Name: "kv", Kind: "kv"}
+	if err := db.Raw("SELECT COUNT(*), COALESCE(SUM(length(key) + length(value)), 0) FROM kv_entries WHERE plugin = ?",
+		ps.pluginName).Row().Scan(&kv.Rows, &kv.Bytes); err != nil {
+		return nil, err
+	}
+	if kv.Rows > 0 {
+		u.Tables = append(u.Tables, kv)
+	}
+
+	var collections []TableUsage
+	if err := db.Raw(`SELECT collection AS "name", 'documents' AS "kind", COUNT(*) AS "rows",
+			COALESCE(SUM(length(id) + length(data)), 0) AS "bytes"
+		FROM documents WHERE plugin = ? GROUP BY collection ORDER BY collection`,
+		ps.pluginName).Scan(&collections).Error; err != nil {
+		return nil, err
+	}
+	u.Tables = append(u.Tables, collections...)
+
+	for _, t := range u.Tables {
+		u.Rows += t.Rows
+		u.Bytes += t.Bytes
+	}
+	return u, nil
+}
+
+// SetQuota sets the plugin's storage quota
+func (ps *PluginStorage) SetQuota(quota Quota) {
+	ps.quota.Store(&quota)
+}
+
+// checkQuota fails if adding rows and bytes would exceed the plugin's quota
+func (ps *PluginStorage) checkQuota(added []TableUsage) error {
+	quota := ps.quota.Load()
+	if quota == nil || (quota.MaxRows == 0 && quota.MaxBytes == 0) {
+		return nil
+	}
+
+	usageCache.Lock()
+	defer usageCache.Unlock()
+	u, err := cachedUsage(ps.pluginName)
+	if err != nil {
+		return fmt.Errorf("failed to check storage quota: %w", err)
+	}
+	var rows, bytes int64
+	for _, t := range added {
+		rows += t.Rows
+		bytes += t.Bytes
+	}
+	if quota.MaxRows > 0 && u.Rows+rows > quota.MaxRows {
+		return fmt.Errorf("%w for plugin %s: %d of %d rows used", ErrQuotaExceeded, ps.pluginName, u.Rows, quota.MaxRows)
+	}
+	if quota.MaxBytes > 0 && u.Bytes+bytes > quota.MaxBytes {
+		return fmt.Errorf("%w for plugin %s: %d of %d bytes used", ErrQuotaExceeded, ps.pluginName, u.Bytes, quota.MaxBytes)
+	}
+	return nil
+}
+
+// checkUpdatedBytes fails if the plugin's stored bytes, measured inside the transaction
+// after its updates ran, exceed the byte quota. Updates can grow rows by any amount,
+// so they are checked against the result instead of an estimate.
+func (ps *PluginStorage) checkUpdatedBytes(tx *gorm.DB) error {
+	quota := ps.quota.Load()
+	if quota == nil || quota.MaxBytes == 0 {
+		return nil
+	}
+	u, err := ps.computeUsage(tx)
+	if err != nil {
+		return fmt.Errorf("failed to check storage quota: %w", err)
+	}
+	if u.Bytes > quota.MaxBytes {
+		return fmt.Errorf("%w for plugin %s: update would use %d of %d bytes", ErrQuotaExceeded, ps.pluginName, u.Bytes, quota.MaxBytes)
+	}
+	return nil
+}
+
+// trackWrite updates the cached usage after a successful request
+func (ps *PluginStorage) trackWrite(req *pb.StorageRequest, added []TableUsage) {
+	if readOnly(req) {
+		return
+	}
+	usageCache.Lock()
+	defer usageCache.Unlock()
+	u, ok := usageCache.m[ps.pluginName]
+	if !ok {
+		return
+	}
+	if !addsRows(req) {
+		delete(usageCache.m, ps.pluginName)
+		return
+	}
+	// Upserts and overwrites may replace rows, so this can overcount until the next recompute
+	for _, t := range added {
+		u.add(t)
+	}
+}
+
+// readOnly reports whether a request can't change stored data
+func readOnly(req *pb.StorageRequest) bool {
+	switch op := req.Operation.(type) {
+	case *pb.StorageRequest_Query, *pb.StorageRequest_Count, *pb.StorageRequest_Aggregate:
+		return true
+	case *pb.StorageRequest_Kv:
+		switch op.Kv.GetOp().(type) {
+		case *pb.KVRequest_Get, *pb.KVRequest_List:
+			return true
+		}
+	case *pb.StorageRequest_Docs:
+		switch op.Docs.GetOp().(type) {
+		case *pb.DocRequest_Get, *pb.DocRequest_Find:
+			return true
+		}
+	}
+	return false
+}
+
+// addsRows reports whether a request only adds or replaces rows, so the
+// cached usage can be adjusted instead of recomputed
+func addsRows(req *pb.StorageRequest) bool {
+	switch op := req.Operation.(type) {
+	case *pb.StorageRequest_Insert, *pb.StorageRequest_Upsert:
+		return true
+	case *pb.StorageRequest_Kv:
+		_, ok := op.Kv.GetOp().(*pb.KVRequest_Set)
+		return ok
+	case *pb.StorageRequest_Docs:
+		_, ok := op.Docs.GetOp().(*pb.DocRequest_Put)
+		return ok
+	case *pb.StorageRequest_Batch:
+		for _, o := range op.Batch.Operations {
+			switch o.Operation.(type) {
+			case *pb.BatchOperation_Insert, *pb.BatchOperation_Upsert:
+			default:
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// writeUsage estimates the rows and bytes a request adds per table. Deletes and
+// schema changes add no rows; updates are checked by checkUpdatedBytes.
+func writeUsage(req *pb.StorageRequest) []TableUsage {
+	var added []TableUsage
+	insert := func(table string, values map[string]string, typed map[string]*pb.Value) {
+		added = append(added, TableUsage{Name: table, Kind: "table", Rows: 1, Bytes: valuesSize(values, typed)})
+	}
+	switch op := req.Operation.(type) {
+	case *pb.StorageRequest_Insert:
+		insert(op.Insert.TableName, op.Insert.Values, op.Insert.TypedValues)
+	case *pb.StorageRequest_Upsert:
+		insert(op.Upsert.TableName, op.Upsert.Values, op.Upsert.TypedValues)
+	case *pb.StorageRequest_Batch:
+		for _, o := range op.Batch.Operations {
+			switch b := o.Operation.(type) {
+			case *pb.BatchOperation_Insert:
+				insert(b.Insert.TableName, b.Insert.Values, b.Insert.TypedValues)
+			case *pb.BatchOperation_Upsert:
+				insert(b.Upsert.TableName, b.Upsert.Values, b.Upsert.TypedValues)
+			}
+		}
+	case *pb.StorageRequest_Kv:
+		if set, ok := op.Kv.GetOp().(*pb.KVRequest_Set); ok {
+			added = append(added, TableUsage{Name: "kv", Kind: "kv", Rows: 1, Bytes: int64(len(set.Set.Key) + len(set.Set.Value))})
+		}
+	case *pb.StorageRequest_Docs:
+		if put, ok := op.Docs.GetOp().(*pb.DocRequest_Put); ok {
+			added = append(added, TableUsage{Name: op.Docs.Collection, Kind: "documents", Rows: 1, Bytes: int64(len(put.Put.Id) + len(put.Put.Json))})
+		}
+	}
+	return added
+}
+
+// valuesSize approximates the stored size of a row's values the way
+// computeUsage measures them (numbers by their text length)
+func valuesSize(values map[string]string, typed map[string]*pb.Value) int64 {
+	var n int64
+	for _, v := range values {
+		n += int64(len(v))
+	}
+	for _, v := range typed {
+		switch k := v.GetKind().(type) {
+		case *pb.Value_IntValue:
+			n += int64(len(strconv.FormatInt(k.IntValue, 10)))
+		case *pb.Value_FloatValue:
+			n += int64(len(strconv.FormatFloat(k.FloatValue, 'g', -1, 64)))
+		case *pb.Value_TextValue:
+			n += int64(len(k.TextValue))
+		case *pb.Value_BytesValue:
+			n += int64(len(k.BytesValue))
+		case *pb.Value_BoolValue:
+			n++
+		}
+	}
+	return n
+}
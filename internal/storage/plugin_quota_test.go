@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	pb "github.com/fipso/chadbot/gen/chadbot"
+)
+
+// newTestDB opens a fresh database for a test
+func newTestDB(t *testing.T) {
+	t.Helper()
+	if err := Init(filepath.Join(t.TempDir(), "chadbot.db")); err != nil {
+		t.Fatalf("init storage: %v", err)
+	}
+}
+
+// newNotesStorage returns storage for a test plugin with a notes table holding one row
+func newNotesStorage(t *testing.T) *PluginStorage {
+	t.Helper()
+	ps := NewPluginStorage("quotatest")
+	resp := ps.HandleRequest(&pb.StorageRequest{Operation: &pb.StorageRequest_CreateTable{CreateTable: &pb.CreateTableRequest{
+		TableName: "notes",
+		Columns: []*pb.ColumnDef{
+			{Name: "id", Type: "INTEGER", PrimaryKey: true},
+			{Name: "body", Type: "TEXT"},
+		},
+	}}})
+	if !resp.Success {
+		t.Fatalf("create table: %s", resp.Error)
+	}
+	resp = ps.HandleRequest(&pb.StorageRequest{Operation: &pb.StorageRequest_Insert{Insert: &pb.InsertRequest{
+		TableName: "notes",
+		Values:    map[string]string{"id": "1", "body": "short"},
+	}}})
+	if !resp.Success {
+		t.Fatalf("insert: %s", resp.Error)
+	}
+	return ps
+}
+
+func TestUpdateQuota(t *testing.T) {
+	newTestDB(t)
+	ps := newNotesStorage(t)
+	ps.SetQuota(Quota{MaxBytes: 100})
+
+	update := func(body string) *pb.UpdateRequest {
+		return &pb.UpdateRequest{TableName: "notes", Values: map[string]string{"body": body}}
+	}
+	large := strings.Repeat("x", 200)
+
+	tests := []struct {
+		name     string
+		req      *pb.StorageRequest
+		exceeded bool
+	}{
+		{"small update", &pb.StorageRequest{Operation: &pb.StorageRequest_Update{Update: update("still short")}}, false},
+		{"update past limit", &pb.StorageRequest{Operation: &pb.StorageRequest_Update{Update: update(large)}}, true},
+		{"batch update past limit", &pb.StorageRequest{Operation: &pb.StorageRequest_Batch{Batch: &pb.BatchRequest{
+			Operations: []*pb.BatchOperation{{Operation: &pb.BatchOperation_Update{Update: update(large)}}},
+		}}}, true},
+		{"migration update past limit", &pb.StorageRequest{Operation: &pb.StorageRequest_Migrate{Migrate: &pb.MigrateRequest{
+			Migrations: []*pb.Migration{{Version: 1, Name: "grow", Steps: []*pb.MigrationStep{
+				{Step: &pb.MigrationStep_Update{Update: update(large)}},
+			}}},
+		}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := ps.HandleRequest(tt.req)
+			if resp.Success == tt.exceeded || resp.QuotaExceeded != tt.exceeded {
+				t.Fatalf("success=%v quota_exceeded=%v error=%q, want exceeded=%v",
+					resp.Success, resp.QuotaExceeded, resp.Error, tt.exceeded)
+			}
+		})
+	}
+
+	// Refused updates are rolled back
+	usage, err := ps.computeUsage(DB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.Bytes > 100 {
+		t.Fatalf("usage %d bytes after refused updates, want at most 100", usage.Bytes)
+	}
+}
@@ -2,9 +2,16 @@ package storage
 
 import (
 	"database/sql"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"log"
 	"regexp"
+	"slices"
 	"strings"
+	"sync/atomic"
+
+	"gorm.io/gorm"
 
 	pb "github.com/fipso/chadbot/gen/chadbot"
 )
@@ -12,27 +19,94 @@ import (
 // ValidTableNameRegex ensures table names are safe
 var ValidTableNameRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]*$`)
 
+// plainPluginNameRegex matches plugin names used as they are in table prefixes
+var plainPluginNameRegex = regexp.MustCompile(`^[a-z][a-z0-9]*$`)
+
+// pluginPrefix returns the table prefix of a plugin. Names of lowercase letters and digits
+// starting with a letter are used as they are (plugin_notes), other names are hex encoded
+// behind a 0 (plugin "mcp-bridge" is plugin_06d63702d627269646765). Neither contains an
+// underscore and they differ in their first character, so a prefix followed by "_" never
+// starts another plugin's and no table name can reach into another plugin's namespace.
+func pluginPrefix(pluginName string) string {
+	if plainPluginNameRegex.MatchString(pluginName) {
+		return "plugin_" + pluginName
+	}
+	return "plugin_0" + hex.EncodeToString([]byte(pluginName))
+}
+
 // PluginStorage handles namespaced storage for plugins
 type PluginStorage struct {
 	pluginName string
 	prefix     string
+	rawSQL     atomic.Bool           // See RawSQLConfigKey
+	quota      atomic.Pointer[Quota] // See MaxRowsConfigKey and MaxBytesConfigKey
 }
 
 // NewPluginStorage creates a new plugin storage handler
 // Uses plugin name for persistence across restarts
 func NewPluginStorage(pluginName string) *PluginStorage {
-	// Create a safe prefix from plugin name
-	safeName := strings.ReplaceAll(pluginName, "-", "_")
-	safeName = strings.ReplaceAll(safeName, " ", "_")
-	prefix := "plugin_" + safeName
 	return &PluginStorage{
 		pluginName: pluginName,
-		prefix:     prefix,
+		prefix:     pluginPrefix(pluginName),
 	}
 }
 
+// legacyPluginPrefix is the table prefix plugins had before names were encoded: hyphens and
+// spaces became underscores, so "mcp-bridge" shared plugin_mcp_bridge_ with tables of "mcp"
+func legacyPluginPrefix(pluginName string) string {
+	return "plugin_" + strings.NewReplacer("-", "_", " ", "_").Replace(pluginName)
+}
+
+// MigrateLegacyPluginPrefix moves the tables, indexes and migration records of a plugin whose
+// prefix changed when names were encoded to its current prefix. It runs once per plugin, as
+// later tables under the legacy prefix may belong to another plugin.
+func MigrateLegacyPluginPrefix(pluginName string) error {
+	legacy, current := legacyPluginPrefix(pluginName), pluginPrefix(pluginName)
+	if legacy == current {
+		return nil
+	}
+	_, err := applyMigrations("legacy:"+pluginName, []Migration{{Version: 1, Name: "move tables to " + current, Up: func(tx *gorm.DB) error {
+		pattern := strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(legacy) + "\\_%"
+		var tables []string
+		if err := tx.Raw("SELECT name FROM sqlite_master WHERE type = 'table' AND name LIKE ? ESCAPE '\\'", pattern).
+			Scan(&tables).Error; err != nil {
+			return err
+		}
+		for _, table := range tables {
+			renamed := current + table[len(legacy):]
+			if err := tx.Exec(fmt.Sprintf(`ALTER TABLE "%s" RENAME TO "%s"`, table, renamed)).Error; err != nil {
+				return err
+			}
+		}
+
+		// SQLite can't rename indexes, they are created again under their new name
+		var indexes []struct{ Name, SQL string }
+		if err := tx.Raw("SELECT name, sql FROM sqlite_master WHERE type = 'index' AND sql IS NOT NULL AND name LIKE ? ESCAPE '\\'", pattern).
+			Scan(&indexes).Error; err != nil {
+			return err
+		}
+		for _, idx := range indexes {
+			if err := tx.Exec(fmt.Sprintf(`DROP INDEX "%s"`, idx.Name)).Error; err != nil {
+				return err
+			}
+			if err := tx.Exec(strings.Replace(idx.SQL, idx.Name, current+idx.Name[len(legacy):], 1)).Error; err != nil {
+				return err
+			}
+		}
+
+		if len(tables) > 0 {
+			log.Printf("[Storage] Moved %d tables of plugin %s from %s to %s", len(tables), pluginName, legacy, current)
+		}
+		return tx.Model(&SchemaMigration{}).Where("namespace = ?", legacy).Update("namespace", current).Error
+	}}})
+	return err
+}
+
 // NamespacedTable returns the full table name with plugin prefix
 func (ps *PluginStorage) NamespacedTable(tableName string) (string, error) {
+	if ps.pluginName == "" {
+		return "", fmt.Errorf("plugin name is required")
+	}
 	if !ValidTableNameRegex.MatchString(tableName) {
 		return "", fmt.Errorf("invalid table name: %s", tableName)
 	}
@@ -45,6 +119,15 @@ func (ps *PluginStorage) HandleRequest(req *pb.StorageRequest) *pb.StorageRespon
 		RequestId: req.RequestId,
 	}
 
+	added := writeUsage(req)
+	if len(added) > 0 {
+		if err := ps.checkQuota(added); err != nil {
+			resp.Error = err.Error()
+			resp.QuotaExceeded = true
+			return resp
+		}
+	}
+
 	switch op := req.Operation.(type) {
 	case *pb.StorageRequest_CreateTable:
 		resp = ps.createTable(req.RequestId, op.CreateTable)
@@ -58,38 +141,61 @@ func (ps *PluginStorage) HandleRequest(req *pb.StorageRequest) *pb.StorageRespon
 		resp = ps.delete(req.RequestId, op.Delete)
 	case *pb.StorageRequest_Query:
 		resp = ps.query(req.RequestId, op.Query)
+	case *pb.StorageRequest_Migrate:
+		resp = ps.migrate(req.RequestId, op.Migrate)
+	case *pb.StorageRequest_Batch:
+		resp = ps.batch(req.RequestId, op.Batch)
+	case *pb.StorageRequest_Upsert:
+		resp = ps.upsert(req.RequestId, op.Upsert)
+	case *pb.StorageRequest_Count:
+		resp = ps.count(req.RequestId, op.Count)
+	case *pb.StorageRequest_Aggregate:
+		resp = ps.aggregate(req.RequestId, op.Aggregate)
+	case *pb.StorageRequest_Kv:
+		resp = ps.kv(req.RequestId, op.Kv)
+	case *pb.StorageRequest_Docs:
+		resp = ps.docs(req.RequestId, op.Docs)
 	default:
 		resp.Success = false
 		resp.Error = "unknown operation"
 	}
 
+	if resp.Success {
+		ps.trackWrite(req, added)
+	}
 	return resp
 }
 
 func (ps *PluginStorage) createTable(reqID string, req *pb.CreateTableRequest) *pb.StorageResponse {
 	resp := &pb.StorageResponse{RequestId: reqID}
 
-	tableName, err := ps.NamespacedTable(req.TableName)
+	query, err := ps.createTableSQL(req)
 	if err != nil {
 		resp.Error = err.Error()
 		return resp
 	}
 
-	// Build CREATE TABLE statement
+	if err := DB.Exec(query).Error; err != nil {
+		resp.Error = err.Error()
+		return resp
+	}
+
+	resp.Success = true
+	return resp
+}
+
+// createTableSQL builds the CREATE TABLE statement for a namespaced table
+func (ps *PluginStorage) createTableSQL(req *pb.CreateTableRequest) (string, error) {
+	tableName, err := ps.NamespacedTable(req.TableName)
+	if err != nil {
+		return "", err
+	}
+
 	var cols []string
 	for _, col := range req.Columns {
-		colDef := fmt.Sprintf("%s %s", col.Name, col.Type)
-		if col.PrimaryKey {
-			colDef += " PRIMARY KEY"
-		}
-		if col.NotNull {
-			colDef += " NOT NULL"
-		}
-		if col.Unique {
-			colDef += " UNIQUE"
-		}
-		if col.DefaultValue != "" {
-			colDef += fmt.Sprintf(" DEFAULT %s", col.DefaultValue)
+		colDef, err := ps.columnSQL(col)
+		if err != nil {
+			return "", err
 		}
 		cols = append(cols, colDef)
 	}
@@ -99,15 +205,39 @@ func (ps *PluginStorage) createTable(reqID string, req *pb.CreateTableRequest) *
 		ifNotExists = "IF NOT EXISTS "
 	}
 
-	query := fmt.Sprintf("CREATE TABLE %s%s (%s)", ifNotExists, tableName, strings.Join(cols, ", "))
+	return fmt.Sprintf("CREATE TABLE %s%s (%s)", ifNotExists, tableName, strings.Join(cols, ", ")), nil
+}
 
-	if err := DB.Exec(query).Error; err != nil {
-		resp.Error = err.Error()
-		return resp
+// columnSQL builds a column definition. Types other than SQLite type names like
+// VARCHAR(255) and non-literal defaults need raw SQL.
+func (ps *PluginStorage) columnSQL(col *pb.ColumnDef) (string, error) {
+	if !ValidTableNameRegex.MatchString(col.Name) {
+		return "", fmt.Errorf("invalid column name: %s", col.Name)
 	}
-
-	resp.Success = true
-	return resp
+	if !columnTypeRegex.MatchString(col.Type) {
+		if err := ps.requireRawSQL("column type " + col.Type); err != nil {
+			return "", err
+		}
+	}
+	if col.DefaultValue != "" && !defaultLiteralRegex.MatchString(col.DefaultValue) {
+		if err := ps.requireRawSQL("default value " + col.DefaultValue); err != nil {
+			return "", err
+		}
+	}
+	colDef := fmt.Sprintf("%s %s", col.Name, col.Type)
+	if col.PrimaryKey {
+		colDef += " PRIMARY KEY"
+	}
+	if col.NotNull {
+		colDef += " NOT NULL"
+	}
+	if col.Unique {
+		colDef += " UNIQUE"
+	}
+	if col.DefaultValue != "" {
+		colDef += fmt.Sprintf(" DEFAULT %s", col.DefaultValue)
+	}
+	return colDef, nil
 }
 
 func (ps *PluginStorage) dropTable(reqID string, req *pb.DropTableRequest) *pb.StorageResponse {
@@ -136,111 +266,224 @@ func (ps *PluginStorage) dropTable(reqID string, req *pb.DropTableRequest) *pb.S
 }
 
 func (ps *PluginStorage) insert(reqID string, req *pb.InsertRequest) *pb.StorageResponse {
+	return ps.write(reqID, &pb.BatchOperation{Operation: &pb.BatchOperation_Insert{Insert: req}})
+}
+
+func (ps *PluginStorage) update(reqID string, req *pb.UpdateRequest) *pb.StorageResponse {
+	return ps.write(reqID, &pb.BatchOperation{Operation: &pb.BatchOperation_Update{Update: req}})
+}
+
+func (ps *PluginStorage) delete(reqID string, req *pb.DeleteRequest) *pb.StorageResponse {
+	return ps.write(reqID, &pb.BatchOperation{Operation: &pb.BatchOperation_Delete{Delete: req}})
+}
+
+func (ps *PluginStorage) upsert(reqID string, req *pb.UpsertRequest) *pb.StorageResponse {
+	return ps.write(reqID, &pb.BatchOperation{Operation: &pb.BatchOperation_Upsert{Upsert: req}})
+}
+
+// write runs a single write operation
+func (ps *PluginStorage) write(reqID string, op *pb.BatchOperation) *pb.StorageResponse {
+	resp := ps.batch(reqID, &pb.BatchRequest{Operations: []*pb.BatchOperation{op}})
+	if resp.Success {
+		resp.AffectedRows = resp.Results[0].AffectedRows
+		resp.LastInsertId = resp.Results[0].LastInsertId
+		resp.Results = nil
+	}
+	return resp
+}
+
+// batch runs write operations in one transaction; any failure rolls back all of them
+func (ps *PluginStorage) batch(reqID string, req *pb.BatchRequest) *pb.StorageResponse {
 	resp := &pb.StorageResponse{RequestId: reqID}
 
-	tableName, err := ps.NamespacedTable(req.TableName)
+	results := make([]*pb.OperationResult, 0, len(req.Operations))
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		updates := false
+		for i, op := range req.Operations {
+			result, err := ps.execOperation(tx, op)
+			if err != nil {
+				if len(req.Operations) > 1 {
+					return fmt.Errorf("operation %d: %w", i+1, err)
+				}
+				return err
+			}
+			results = append(results, result)
+			if _, ok := op.Operation.(*pb.BatchOperation_Update); ok {
+				updates = true
+			}
+		}
+		if updates {
+			return ps.checkUpdatedBytes(tx)
+		}
+		return nil
+	})
 	if err != nil {
 		resp.Error = err.Error()
+		resp.QuotaExceeded = errors.Is(err, ErrQuotaExceeded)
 		return resp
 	}
 
-	var columns []string
-	var placeholders []string
-	var values []interface{}
+	resp.Success = true
+	resp.Results = results
+	for _, r := range results {
+		resp.AffectedRows += r.AffectedRows
+	}
+	return resp
+}
 
-	for col, val := range req.Values {
-		columns = append(columns, col)
-		placeholders = append(placeholders, "?")
-		values = append(values, val)
+// execOperation runs a write operation in a transaction
+func (ps *PluginStorage) execOperation(tx *gorm.DB, op *pb.BatchOperation) (*pb.OperationResult, error) {
+	var query string
+	var args []any
+	var err error
+	inserts := false
+
+	switch o := op.Operation.(type) {
+	case *pb.BatchOperation_Insert:
+		query, args, err = ps.insertSQL(o.Insert)
+		inserts = true
+	case *pb.BatchOperation_Update:
+		query, args, err = ps.updateSQL(tx, o.Update)
+	case *pb.BatchOperation_Delete:
+		query, args, err = ps.deleteSQL(tx, o.Delete)
+	case *pb.BatchOperation_Upsert:
+		query, args, err = ps.upsertSQL(o.Upsert)
+		inserts = true
+	default:
+		err = fmt.Errorf("unknown operation")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res := tx.Exec(query, args...)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	result := &pb.OperationResult{AffectedRows: res.RowsAffected}
+	if inserts {
+		// Same connection as the insert inside the transaction
+		if err := tx.Raw("SELECT last_insert_rowid()").Scan(&result.LastInsertId).Error; err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// insertSQL builds an INSERT statement
+func (ps *PluginStorage) insertSQL(req *pb.InsertRequest) (string, []any, error) {
+	tableName, err := ps.NamespacedTable(req.TableName)
+	if err != nil {
+		return "", nil, err
+	}
+	columns, args, err := rowValues(req.Values, req.TypedValues)
+	if err != nil {
+		return "", nil, err
+	}
+
+	placeholders := make([]string, len(columns))
+	values := make([]any, len(columns))
+	for i, col := range columns {
+		placeholders[i] = "?"
+		values[i] = args[col]
 	}
 
 	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
 		tableName,
 		strings.Join(columns, ", "),
 		strings.Join(placeholders, ", "))
+	return query, values, nil
+}
 
-	result := DB.Exec(query, values...)
-	if result.Error != nil {
-		resp.Error = result.Error.Error()
-		return resp
+// upsertSQL builds an INSERT ... ON CONFLICT statement
+func (ps *PluginStorage) upsertSQL(req *pb.UpsertRequest) (string, []any, error) {
+	if len(req.ConflictColumns) == 0 {
+		return "", nil, fmt.Errorf("upsert needs conflict columns")
+	}
+	for _, col := range req.ConflictColumns {
+		if !ValidTableNameRegex.MatchString(col) {
+			return "", nil, fmt.Errorf("invalid column name: %s", col)
+		}
+	}
+	query, values, err := ps.insertSQL(&pb.InsertRequest{TableName: req.TableName, Values: req.Values, TypedValues: req.TypedValues})
+	if err != nil {
+		return "", nil, err
 	}
 
-	resp.Success = true
-	resp.AffectedRows = result.RowsAffected
-
-	// Get last insert ID using raw SQL
-	var lastID int64
-	DB.Raw("SELECT last_insert_rowid()").Scan(&lastID)
-	resp.LastInsertId = lastID
+	updates := req.UpdateColumns
+	if len(updates) == 0 {
+		columns, _, _ := rowValues(req.Values, req.TypedValues)
+		for _, col := range columns {
+			if !slices.Contains(req.ConflictColumns, col) {
+				updates = append(updates, col)
+			}
+		}
+	}
 
-	return resp
+	query += fmt.Sprintf(" ON CONFLICT (%s) DO ", strings.Join(req.ConflictColumns, ", "))
+	if len(updates) == 0 {
+		return query + "NOTHING", values, nil
+	}
+	setClauses := make([]string, len(updates))
+	for i, col := range updates {
+		if !ValidTableNameRegex.MatchString(col) {
+			return "", nil, fmt.Errorf("invalid column name: %s", col)
+		}
+		setClauses[i] = fmt.Sprintf("%s = excluded.%s", col, col)
+	}
+	return query + "UPDATE SET " + strings.Join(setClauses, ", "), values, nil
 }
 
-func (ps *PluginStorage) update(reqID string, req *pb.UpdateRequest) *pb.StorageResponse {
-	resp := &pb.StorageResponse{RequestId: reqID}
-
+// updateSQL builds an UPDATE statement
+func (ps *PluginStorage) updateSQL(tx *gorm.DB, req *pb.UpdateRequest) (string, []any, error) {
 	tableName, err := ps.NamespacedTable(req.TableName)
 	if err != nil {
-		resp.Error = err.Error()
-		return resp
+		return "", nil, err
+	}
+	columns, args, err := rowValues(req.Values, req.TypedValues)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(columns) == 0 {
+		return "", nil, fmt.Errorf("update needs values")
 	}
 
-	var setClauses []string
-	var values []interface{}
-
-	for col, val := range req.Values {
-		setClauses = append(setClauses, fmt.Sprintf("%s = ?", col))
-		values = append(values, val)
+	setClauses := make([]string, len(columns))
+	values := make([]any, 0, len(columns)+len(req.WhereArgs))
+	for i, col := range columns {
+		setClauses[i] = fmt.Sprintf("%s = ?", col)
+		values = append(values, args[col])
 	}
 
 	query := fmt.Sprintf("UPDATE %s SET %s", tableName, strings.Join(setClauses, ", "))
 
-	if req.WhereClause != "" {
-		query += " WHERE " + req.WhereClause
-		for _, arg := range req.WhereArgs {
-			values = append(values, arg)
-		}
+	where, whereArgs, err := ps.whereSQL(tx, tableName, req.Filter, req.WhereClause, req.WhereArgs)
+	if err != nil {
+		return "", nil, err
 	}
-
-	result := DB.Exec(query, values...)
-	if result.Error != nil {
-		resp.Error = result.Error.Error()
-		return resp
+	if where != "" {
+		query += " WHERE " + where
+		values = append(values, whereArgs...)
 	}
-
-	resp.Success = true
-	resp.AffectedRows = result.RowsAffected
-	return resp
+	return query, values, nil
 }
 
-func (ps *PluginStorage) delete(reqID string, req *pb.DeleteRequest) *pb.StorageResponse {
-	resp := &pb.StorageResponse{RequestId: reqID}
-
+// deleteSQL builds a DELETE statement
+func (ps *PluginStorage) deleteSQL(tx *gorm.DB, req *pb.DeleteRequest) (string, []any, error) {
 	tableName, err := ps.NamespacedTable(req.TableName)
 	if err != nil {
-		resp.Error = err.Error()
-		return resp
+		return "", nil, err
 	}
 
 	query := fmt.Sprintf("DELETE FROM %s", tableName)
-	var values []interface{}
-
-	if req.WhereClause != "" {
-		query += " WHERE " + req.WhereClause
-		for _, arg := range req.WhereArgs {
-			values = append(values, arg)
-		}
+	where, values, err := ps.whereSQL(tx, tableName, req.Filter, req.WhereClause, req.WhereArgs)
+	if err != nil {
+		return "", nil, err
 	}
-
-	result := DB.Exec(query, values...)
-	if result.Error != nil {
-		resp.Error = result.Error.Error()
-		return resp
+	if where != "" {
+		query += " WHERE " + where
 	}
-
-	resp.Success = true
-	resp.AffectedRows = result.RowsAffected
-	return resp
+	return query, values, nil
 }
 
 func (ps *PluginStorage) query(reqID string, req *pb.QueryRequest) *pb.StorageResponse {
@@ -252,23 +495,44 @@ func (ps *PluginStorage) query(reqID string, req *pb.QueryRequest) *pb.StorageRe
 		return resp
 	}
 
+	tableCols, err := tableColumns(DB, tableName)
+	if err != nil {
+		resp.Error = err.Error()
+		return resp
+	}
+
 	columns := "*"
 	if len(req.Columns) > 0 {
+		for _, col := range req.Columns {
+			if tableCols[col] {
+				continue
+			}
+			if err := ps.requireRawSQL("column " + col); err != nil {
+				resp.Error = err.Error()
+				return resp
+			}
+		}
 		columns = strings.Join(req.Columns, ", ")
 	}
 
 	query := fmt.Sprintf("SELECT %s FROM %s", columns, tableName)
-	var values []interface{}
 
-	if req.WhereClause != "" {
-		query += " WHERE " + req.WhereClause
-		for _, arg := range req.WhereArgs {
-			values = append(values, arg)
-		}
+	where, values, err := ps.whereSQL(DB, tableName, req.Filter, req.WhereClause, req.WhereArgs)
+	if err != nil {
+		resp.Error = err.Error()
+		return resp
+	}
+	if where != "" {
+		query += " WHERE " + where
 	}
 
-	if req.OrderBy != "" {
-		query += " ORDER BY " + req.OrderBy
+	orderBy, err := ps.orderSQL(req.Order, req.OrderBy, tableCols)
+	if err != nil {
+		resp.Error = err.Error()
+		return resp
+	}
+	if orderBy != "" {
+		query += " ORDER BY " + orderBy
 	}
 
 	if req.Limit > 0 {
@@ -298,6 +562,15 @@ func (ps *PluginStorage) query(reqID string, req *pb.QueryRequest) *pb.StorageRe
 		return resp
 	}
 
+	if req.Typed {
+		if resp.Rows, err = scanTypedRows(rows); err != nil {
+			resp.Error = err.Error()
+			return resp
+		}
+		resp.Success = true
+		return resp
+	}
+
 	for rows.Next() {
 		// Create slice to hold column values
 		columnPtrs := make([]interface{}, len(colNames))
@@ -323,6 +596,10 @@ func (ps *PluginStorage) query(reqID string, req *pb.QueryRequest) *pb.StorageRe
 		}
 		resp.Rows = append(resp.Rows, row)
 	}
+	if err := rows.Err(); err != nil {
+		resp.Error = err.Error()
+		return resp
+	}
 
 	resp.Success = true
 	return resp
@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+	"time"
+
+	pb "github.com/fipso/chadbot/gen/chadbot"
+)
+
+// rowValues merges the string and typed values of a write and returns the validated
+// column names in a stable order with their SQL arguments
+func rowValues(values map[string]string, typed map[string]*pb.Value) ([]string, map[string]any, error) {
+	args := make(map[string]any, len(values)+len(typed))
+	for col, v := range values {
+		args[col] = v
+	}
+	for col, v := range typed {
+		if _, ok := values[col]; ok {
+			return nil, nil, fmt.Errorf("column %s has a string and a typed value", col)
+		}
+		args[col] = valueArg(v)
+	}
+
+	columns := make([]string, 0, len(args))
+	for col := range args {
+		if !ValidTableNameRegex.MatchString(col) {
+			return nil, nil, fmt.Errorf("invalid column name: %s", col)
+		}
+		columns = append(columns, col)
+	}
+	slices.Sort(columns)
+	return columns, args, nil
+}
+
+// valueArg converts a typed value to a SQL argument (bools become 0/1, no kind becomes NULL)
+func valueArg(v *pb.Value) any {
+	switch k := v.GetKind().(type) {
+	case *pb.Value_IntValue:
+		return k.IntValue
+	case *pb.Value_FloatValue:
+		return k.FloatValue
+	case *pb.Value_TextValue:
+		return k.TextValue
+	case *pb.Value_BytesValue:
+		return k.BytesValue
+	case *pb.Value_BoolValue:
+		if k.BoolValue {
+			return int64(1)
+		}
+		return int64(0)
+	default:
+		return nil
+	}
+}
+
+// toValue converts a scanned SQL value to a typed value
+func toValue(v any) *pb.Value {
+	switch x := v.(type) {
+	case nil:
+		return &pb.Value{Kind: &pb.Value_NullValue{NullValue: true}}
+	case int64:
+		return &pb.Value{Kind: &pb.Value_IntValue{IntValue: x}}
+	case float64:
+		return &pb.Value{Kind: &pb.Value_FloatValue{FloatValue: x}}
+	case string:
+		return &pb.Value{Kind: &pb.Value_TextValue{TextValue: x}}
+	case []byte:
+		return &pb.Value{Kind: &pb.Value_BytesValue{BytesValue: x}}
+	case bool:
+		return &pb.Value{Kind: &pb.Value_BoolValue{BoolValue: x}}
+	case time.Time:
+		// The driver parses columns declared as DATETIME/TIMESTAMP
+		return &pb.Value{Kind: &pb.Value_TextValue{TextValue: x.Format(time.RFC3339Nano)}}
+	default:
+		return &pb.Value{Kind: &pb.Value_TextValue{TextValue: fmt.Sprint(x)}}
+	}
+}
+
+// scanTypedRows reads all rows with their SQLite storage classes preserved
+func scanTypedRows(rows *sql.Rows) ([]*pb.Row, error) {
+	colNames, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*pb.Row
+	for rows.Next() {
+		vals := make([]any, len(colNames))
+		ptrs := make([]any, len(colNames))
+		for i := range vals {
+			ptrs[i] = &vals[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+
+		row := &pb.Row{TypedValues: make(map[string]*pb.Value, len(colNames))}
+		for i, colName := range colNames {
+			row.TypedValues[colName] = toValue(vals[i])
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
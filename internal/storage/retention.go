@@ -0,0 +1,230 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Plugin table timestamp formats
+const (
+	TimestampUnix      = "unix"     // Seconds since epoch (default)
+	TimestampUnixMilli = "unix_ms"  // Milliseconds since epoch
+	TimestampDatetime  = "datetime" // Text understood by SQLite datetime() (RFC 3339, "2006-01-02 15:04:05")
+)
+
+// PurgeResult counts the rows a purge removed
+type PurgeResult struct {
+	Chats      int64 `json:"chats"`
+	Messages   int64 `json:"messages"`
+	Embeddings int64 `json:"embeddings"`
+	Memories   int64 `json:"memories"`
+}
+
+// deleteMessages hard-deletes the messages matching a condition with their search index entries and embeddings
+func deleteMessages(tx *gorm.DB, result *PurgeResult, where string, args ...any) error {
+	if ftsEnabled {
+		if err := tx.Exec("DELETE FROM messages_fts WHERE rowid IN (SELECT rowid FROM messages WHERE "+where+")", args...).Error; err != nil {
+			return err
+		}
+	}
+	res := tx.Exec("DELETE FROM message_embeddings WHERE message_id IN (SELECT id FROM messages WHERE "+where+")", args...)
+	if res.Error != nil {
+		return res.Error
+	}
+	result.Embeddings += res.RowsAffected
+
+	res = tx.Exec("DELETE FROM messages WHERE "+where, args...)
+	if res.Error != nil {
+		return res.Error
+	}
+	result.Messages += res.RowsAffected
+	return nil
+}
+
+// repairTrees detaches messages whose parent was deleted and moves active leaves
+// of chats whose leaf was deleted to their latest remaining message
+func repairTrees(tx *gorm.DB) error {
+	err := tx.Exec(`UPDATE messages SET parent_id = ''
+		WHERE parent_id != '' AND parent_id NOT IN (SELECT id FROM messages)`).Error
+	if err != nil {
+		return err
+	}
+	return tx.Exec(`UPDATE chats SET active_leaf = COALESCE(
+			(SELECT id FROM messages WHERE chat_id = chats.id ORDER BY created_at DESC, id DESC LIMIT 1), '')
+		WHERE active_leaf != '' AND active_leaf NOT IN (SELECT id FROM messages)`).Error
+}
+
+// PurgeChat hard-deletes a chat (also if soft-deleted) with its messages, search index entries
+// and embeddings. withMemories also deletes the memories learned in the chat.
+func PurgeChat(chatID string, withMemories bool) (PurgeResult, error) {
+	var result PurgeResult
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := deleteMessages(tx, &result, "chat_id = ?", chatID); err != nil {
+			return err
+		}
+		// Embeddings of messages that were already removed
+		res := tx.Where("chat_id = ?", chatID).Delete(&MessageEmbedding{})
+		if res.Error != nil {
+			return res.Error
+		}
+		result.Embeddings += res.RowsAffected
+
+		if withMemories {
+			res = tx.Where("chat_id = ?", chatID).Delete(&Memory{})
+			if res.Error != nil {
+				return res.Error
+			}
+			result.Memories = res.RowsAffected
+		}
+
+		res = tx.Unscoped().Delete(&Chat{}, "id = ?", chatID)
+		if res.Error != nil {
+			return res.Error
+		}
+		result.Chats = res.RowsAffected
+		return nil
+	})
+	return result, err
+}
+
+// GetChatUnscoped retrieves a chat including soft-deleted ones
+func GetChatUnscoped(chatID string) (*Chat, error) {
+	var chat Chat
+	if err := DB.Unscoped().First(&chat, "id = ?", chatID).Error; err != nil {
+		return nil, err
+	}
+	return &chat, nil
+}
+
+// GetUserChatUnscoped retrieves a chat of a user, including a soft-deleted one
+func GetUserChatUnscoped(userID, chatID string) (*Chat, error) {
+	var chats []Chat
+	if err := DB.Unscoped().Where("id = ? AND user_id = ?", chatID, userID).Limit(1).Find(&chats).Error; err != nil {
+		return nil, err
+	}
+	if len(chats) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &chats[0], nil
+}
+
+// DeletedChatsBefore returns the IDs of chats soft-deleted before a time
+func DeletedChatsBefore(before time.Time) ([]string, error) {
+	var ids []string
+	err := DB.Unscoped().Model(&Chat{}).
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", before).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// ChatScope selects the chats a retention rule applies to (empty = all chats)
+type ChatScope struct {
+	Platform string
+	ChatID   string
+}
+
+// where returns the SQL condition on messages.chat_id for a scope
+func (s ChatScope) where() (string, []any) {
+	switch {
+	case s.ChatID != "":
+		return "chat_id = ?", []any{s.ChatID}
+	case s.Platform != "":
+		return "chat_id IN (SELECT id FROM chats WHERE platform = ?)", []any{s.Platform}
+	default:
+		return "1 = 1", nil
+	}
+}
+
+// DeleteMessagesBefore hard-deletes messages of the scoped chats created before a time
+func DeleteMessagesBefore(scope ChatScope, before time.Time) (PurgeResult, error) {
+	var result PurgeResult
+	where, args := scope.where()
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := deleteMessages(tx, &result, where+" AND created_at < ?", append(args, before)...); err != nil {
+			return err
+		}
+		if result.Messages == 0 {
+			return nil
+		}
+		return repairTrees(tx)
+	})
+	return result, err
+}
+
+// TrimMessages hard-deletes all but the newest keep messages of each scoped chat
+func TrimMessages(scope ChatScope, keep int) (PurgeResult, error) {
+	var result PurgeResult
+	where, args := scope.where()
+
+	var chatIDs []string
+	err := DB.Model(&Message{}).Where(where, args...).
+		Group("chat_id").Having("COUNT(*) > ?", keep).
+		Pluck("chat_id", &chatIDs).Error
+	if err != nil || len(chatIDs) == 0 {
+		return result, err
+	}
+
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		for _, chatID := range chatIDs {
+			err := deleteMessages(tx, &result, `chat_id = ? AND id NOT IN
+				(SELECT id FROM messages WHERE chat_id = ? ORDER BY created_at DESC, id DESC LIMIT ?)`,
+				chatID, chatID, keep)
+			if err != nil {
+				return err
+			}
+		}
+		return repairTrees(tx)
+	})
+	return result, err
+}
+
+// pluginColumn returns the namespaced table of a plugin and validates a column name
+func pluginColumn(pluginName, table, column string) (string, error) {
+	tableName, err := NewPluginStorage(pluginName).NamespacedTable(table)
+	if err != nil {
+		return "", err
+	}
+	if !ValidTableNameRegex.MatchString(column) {
+		return "", fmt.Errorf("invalid column name: %s", column)
+	}
+	return tableName, nil
+}
+
+// DeleteExpiredPluginRows deletes rows of a plugin table whose timestamp column is before a time
+func DeleteExpiredPluginRows(pluginName, table, column, format string, before time.Time) (int64, error) {
+	tableName, err := pluginColumn(pluginName, table, column)
+	if err != nil {
+		return 0, err
+	}
+
+	var cond string
+	var arg any
+	switch format {
+	case TimestampUnix, "":
+		cond, arg = column+" < ?", before.Unix()
+	case TimestampUnixMilli:
+		cond, arg = column+" < ?", before.UnixMilli()
+	case TimestampDatetime:
+		cond, arg = "datetime("+column+") < datetime(?)", before.UTC().Format(time.RFC3339)
+	default:
+		return 0, fmt.Errorf("unknown timestamp format %q", format)
+	}
+
+	res := DB.Exec(fmt.Sprintf("DELETE FROM %s WHERE %s", tableName, cond), arg)
+	return res.RowsAffected, res.Error
+}
+
+// DeletePluginChatRows deletes rows of a plugin table whose chat column matches one of the keys
+func DeletePluginChatRows(pluginName, table, column string, keys []string) (int64, error) {
+	tableName, err := pluginColumn(pluginName, table, column)
+	if err != nil {
+		return 0, err
+	}
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	res := DB.Exec(fmt.Sprintf("DELETE FROM %s WHERE %s IN ?", tableName, column), keys)
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,198 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const backfillBatchSize = 500
+
+// ErrSearchUnavailable is returned by SearchMessages when SQLite was built without FTS5
+var ErrSearchUnavailable = errors.New("message search requires FTS5 (build with -tags sqlite_fts5)")
+
+// MessageSearchQuery is a full-text search over messages
+type MessageSearchQuery struct {
+	Query    string
+	UserID   string    // Only search this user's chats (empty = all)
+	ChatID   string    // Only search this chat
+	Platform string    // Only search chats of this platform ("web", "whatsapp", ...)
+	Role     string    // Only search messages with this role
+	Since    time.Time // Only messages created at or after (zero = no limit)
+	Until    time.Time // Only messages created before (zero = no limit)
+	Limit    int       // Max results (default 20)
+	Offset   int
+
+	HighlightStart string // Marker inserted before matches in snippets (default "<mark>")
+	HighlightEnd   string // Marker inserted after matches in snippets (default "</mark>")
+}
+
+// MessageSearchResult is a message found by full-text search
+type MessageSearchResult struct {
+	MessageID string    `json:"message_id"`
+	ChatID    string    `json:"chat_id"`
+	ChatName  string    `json:"chat_name"`
+	Platform  string    `json:"platform"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+	Snippet   string    `json:"snippet"`
+}
+
+// indexMessage adds a message to the full-text index
+func indexMessage(db *gorm.DB, msg *Message) error {
+	if !ftsEnabled {
+		return nil
+	}
+	return db.Exec("INSERT INTO messages_fts(rowid, content, tool_results, filenames) SELECT rowid, ?, ?, ? FROM messages WHERE id = ?",
+		msg.Content, toolCallText(msg.ToolCalls), attachmentFilenames(msg.Attachments), msg.ID).Error
+}
+
+// reindexMessage replaces the full-text index entry of a message after it was changed
+func reindexMessage(db *gorm.DB, messageID string) error {
+	if !ftsEnabled {
+		return nil
+	}
+	var msg Message
+	if err := db.Where("id = ?", messageID).First(&msg).Error; err != nil {
+		return err
+	}
+	if err := db.Exec("DELETE FROM messages_fts WHERE rowid = (SELECT rowid FROM messages WHERE id = ?)", messageID).Error; err != nil {
+		return err
+	}
+	return indexMessage(db, &msg)
+}
+
+// backfillMessageIndex indexes messages stored before the full-text index existed
+func backfillMessageIndex() {
+	total := 0
+	for {
+		var messages []Message
+		err := DB.Where("rowid NOT IN (SELECT rowid FROM messages_fts)").
+			Limit(backfillBatchSize).
+			Find(&messages).Error
+		if err != nil {
+			log.Printf("[Storage] Message index backfill failed: %v", err)
+			return
+		}
+		if len(messages) == 0 {
+			break
+		}
+
+		err = DB.Transaction(func(tx *gorm.DB) error {
+			for i := range messages {
+				if err := indexMessage(tx, &messages[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			log.Printf("[Storage] Message index backfill failed: %v", err)
+			return
+		}
+		total += len(messages)
+	}
+	if total > 0 {
+		log.Printf("[Storage] Indexed %d existing messages for full-text search", total)
+	}
+}
+
+// toolCallText flattens tool call names and results (including sub-agent calls) for indexing
+func toolCallText(toolCallsJSON string) string {
+	if toolCallsJSON == "" {
+		return ""
+	}
+	var records []ToolCallRecord
+	if err := json.Unmarshal([]byte(toolCallsJSON), &records); err != nil {
+		return ""
+	}
+
+	var sb strings.Builder
+	var walk func([]ToolCallRecord)
+	walk = func(records []ToolCallRecord) {
+		for _, r := range records {
+			sb.WriteString(r.Name)
+			sb.WriteString(": ")
+			sb.WriteString(r.Result)
+			sb.WriteString("\n")
+			walk(r.Children)
+		}
+	}
+	walk(records)
+	return sb.String()
+}
+
+// attachmentFilenames returns the filenames of a message's attachments
+func attachmentFilenames(attachmentsJSON string) string {
+	if attachmentsJSON == "" {
+		return ""
+	}
+	var attachments []struct {
+		Filename string `json:"filename"`
+	}
+	if err := json.Unmarshal([]byte(attachmentsJSON), &attachments); err != nil {
+		return ""
+	}
+	var names []string
+	for _, a := range attachments {
+		if a.Filename != "" {
+			names = append(names, a.Filename)
+		}
+	}
+	return strings.Join(names, " ")
+}
+
+// SearchMessages runs a full-text search over messages (ErrSearchUnavailable without FTS5)
+func SearchMessages(q MessageSearchQuery) ([]MessageSearchResult, error) {
+	if q.Limit <= 0 {
+		q.Limit = 20
+	}
+	if q.HighlightStart == "" && q.HighlightEnd == "" {
+		q.HighlightStart, q.HighlightEnd = "<mark>", "</mark>"
+	}
+
+	if !ftsEnabled {
+		return nil, ErrSearchUnavailable
+	}
+	var results []MessageSearchResult
+	match := FTSQueryAll(q.Query)
+	if match == "" {
+		return results, nil
+	}
+	tx := DB.Table("messages_fts").
+		Select(`messages.id AS message_id, messages.chat_id, chats.name AS chat_name, chats.platform,
+			messages.role, messages.created_at,
+			snippet(messages_fts, -1, ?, ?, '…', 16) AS snippet`, q.HighlightStart, q.HighlightEnd).
+		Joins("JOIN messages ON messages.rowid = messages_fts.rowid").
+		Where("messages_fts MATCH ?", match).
+		Order("bm25(messages_fts)")
+
+	tx = tx.Joins("JOIN chats ON chats.id = messages.chat_id AND chats.deleted_at IS NULL")
+	if q.UserID != "" {
+		tx = tx.Where("chats.user_id = ?", q.UserID)
+	}
+	if q.ChatID != "" {
+		tx = tx.Where("messages.chat_id = ?", q.ChatID)
+	}
+	if q.Platform != "" {
+		tx = tx.Where("chats.platform = ?", q.Platform)
+	}
+	if q.Role != "" {
+		tx = tx.Where("messages.role = ?", q.Role)
+	}
+	if !q.Since.IsZero() {
+		tx = tx.Where("messages.created_at >= ?", q.Since)
+	}
+	if !q.Until.IsZero() {
+		tx = tx.Where("messages.created_at < ?", q.Until)
+	}
+
+	if err := tx.Limit(q.Limit).Offset(q.Offset).Scan(&results).Error; err != nil {
+		return nil, err
+	}
+	return results, nil
+}
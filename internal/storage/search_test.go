@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSearchFindsChangedAttachments(t *testing.T) {
+	newTestDB(t)
+	if err := CreateChat(&Chat{ID: "c1", UserID: "u1", Name: "Search", Platform: "web"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddMessage(&Message{ID: "m1", ChatID: "c1", Role: "user", Content: "see attached", CreatedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+
+	search := func(query string) ([]MessageSearchResult, error) {
+		return SearchMessages(MessageSearchQuery{Query: query, UserID: "u1"})
+	}
+	if !FTSEnabled() {
+		if _, err := search("attached"); !errors.Is(err, ErrSearchUnavailable) {
+			t.Fatalf("search without FTS5: %v, want ErrSearchUnavailable", err)
+		}
+		t.Skip("message index needs FTS5 (go test -tags sqlite_fts5)")
+	}
+
+	if err := SetMessageAttachments("m1", `[{"type":"file","filename":"invoice.pdf"}]`); err != nil {
+		t.Fatal(err)
+	}
+	results, err := search("invoice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].MessageID != "m1" {
+		t.Fatalf("search for the new filename found %v, want m1", results)
+	}
+
+	// The old index entry is replaced, not duplicated
+	if results, err = search("attached"); err != nil || len(results) != 1 {
+		t.Fatalf("search for the content found %d results (%v), want 1", len(results), err)
+	}
+	if err := SetMessageAttachments("m1", ""); err != nil {
+		t.Fatal(err)
+	}
+	if results, err = search("invoice"); err != nil || len(results) != 0 {
+		t.Fatalf("removed filename still found: %v, %v", results, err)
+	}
+}
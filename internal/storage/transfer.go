@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"gorm.io/gorm"
+)
+
+// GetAllChatMessages retrieves the messages of all branches of a chat in chronological order
+func GetAllChatMessages(chatID string) ([]Message, error) {
+	var messages []Message
+	err := DB.Where("chat_id = ?", chatID).
+		Order("created_at ASC, id ASC").
+		Find(&messages).Error
+	return messages, err
+}
+
+// ChatExists reports whether a chat ID is taken (including soft-deleted chats)
+func ChatExists(chatID string) (bool, error) {
+	var count int64
+	err := DB.Model(&Chat{}).Unscoped().Where("id = ?", chatID).Count(&count).Error
+	return count > 0, err
+}
+
+// TakenMessageIDs returns which of the given message IDs already exist
+func TakenMessageIDs(ids []string) (map[string]bool, error) {
+	taken := make(map[string]bool)
+	for start := 0; start < len(ids); start += 500 {
+		end := min(start+500, len(ids))
+		var found []string
+		if err := DB.Model(&Message{}).Where("id IN ?", ids[start:end]).Pluck("id", &found).Error; err != nil {
+			return nil, err
+		}
+		for _, id := range found {
+			taken[id] = true
+		}
+	}
+	return taken, nil
+}
+
+// ImportChats stores chats with their messages in one transaction, keeping their IDs and
+// timestamps. Nothing is stored if any chat fails.
+func ImportChats(chats []Chat) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		for i := range chats {
+			chat := &chats[i]
+			if err := tx.Omit("Messages").Create(chat).Error; err != nil {
+				return err
+			}
+			if len(chat.Messages) == 0 {
+				continue
+			}
+			if err := tx.CreateInBatches(chat.Messages, 100).Error; err != nil {
+				return err
+			}
+			for j := range chat.Messages {
+				if err := indexMessage(tx, &chat.Messages[j]); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
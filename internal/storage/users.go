@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CountUsers returns the number of user accounts
+func CountUsers() (int64, error) {
+	var n int64
+	err := DB.Model(&User{}).Count(&n).Error
+	return n, err
+}
+
+// CreateUser stores a new user
+func CreateUser(u *User) error {
+	return DB.Create(u).Error
+}
+
+// GetUser retrieves a user by ID
+func GetUser(id string) (*User, error) {
+	var u User
+	if err := DB.First(&u, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// GetUserByUsername retrieves a user by username
+func GetUserByUsername(username string) (*User, error) {
+	// Found with Find, as unknown usernames are expected and not worth logging
+	var users []User
+	if err := DB.Where("username = ?", username).Limit(1).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &users[0], nil
+}
+
+// ListUsers returns all users ordered by username
+func ListUsers() ([]User, error) {
+	var users []User
+	err := DB.Order("username").Find(&users).Error
+	return users, err
+}
+
+// UpdateUser saves changes to a user
+func UpdateUser(u *User) error {
+	return DB.Save(u).Error
+}
+
+// DeleteUser deletes a user with their sessions and API tokens
+func DeleteUser(id string) error {
+	if err := DB.Delete(&Session{}, "user_id = ?", id).Error; err != nil {
+		return err
+	}
+	if err := DB.Delete(&APIToken{}, "user_id = ?", id).Error; err != nil {
+		return err
+	}
+	return DB.Delete(&User{}, "id = ?", id).Error
+}
+
+// CreateSession stores a new session
+func CreateSession(s *Session) error {
+	return DB.Create(s).Error
+}
+
+// GetSession retrieves an unexpired session by token hash
+func GetSession(tokenHash string) (*Session, error) {
+	var sessions []Session
+	if err := DB.Where("token_hash = ? AND expires_at > ?", tokenHash, time.Now()).Limit(1).Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	if len(sessions) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &sessions[0], nil
+}
+
+// DeleteSession deletes a session
+func DeleteSession(tokenHash string) error {
+	return DB.Delete(&Session{}, "token_hash = ?", tokenHash).Error
+}
+
+// DeleteUserSessions deletes all sessions of a user except the one given (may be empty)
+func DeleteUserSessions(userID, keepTokenHash string) error {
+	return DB.Delete(&Session{}, "user_id = ? AND token_hash != ?", userID, keepTokenHash).Error
+}
+
+// DeleteExpiredSessions deletes sessions past their expiry
+func DeleteExpiredSessions() error {
+	return DB.Delete(&Session{}, "expires_at <= ?", time.Now()).Error
+}
+
+// CreateAPIToken stores a new API token
+func CreateAPIToken(t *APIToken) error {
+	return DB.Create(t).Error
+}
+
+// GetAPITokenByHash retrieves an API token by token hash
+func GetAPITokenByHash(tokenHash string) (*APIToken, error) {
+	var tokens []APIToken
+	if err := DB.Where("token_hash = ?", tokenHash).Limit(1).Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &tokens[0], nil
+}
+
+// GetUserAPITokens retrieves the API tokens of a user, newest first
+func GetUserAPITokens(userID string) ([]APIToken, error) {
+	var tokens []APIToken
+	err := DB.Where("user_id = ?", userID).Order("created_at DESC").Find(&tokens).Error
+	return tokens, err
+}
+
+// TouchAPIToken records that a token was used
+func TouchAPIToken(id string, at time.Time) error {
+	return DB.Model(&APIToken{}).Where("id = ?", id).UpdateColumn("last_used_at", at).Error
+}
+
+// DeleteAPIToken deletes an API token of a user
+func DeleteAPIToken(userID, id string) (bool, error) {
+	result := DB.Delete(&APIToken{}, "id = ? AND user_id = ?", id, userID)
+	return result.RowsAffected > 0, result.Error
+}
@@ -3,9 +3,12 @@ package sdk
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"google.golang.org/grpc"
@@ -40,7 +43,10 @@ type Client struct {
 	version       string
 	description   string
 	documentation string // PLUGIN.md content
+	docsRequired  bool   // LLM must read the docs before using skills
 	socket        string
+	token         string   // Proves the plugin's identity to the backend
+	permissions   []string // Capabilities the plugin needs, approved by the user
 	pluginID      string
 
 	conn   *grpc.ClientConn
@@ -57,10 +63,12 @@ type Client struct {
 	chatLLMHandler    ChatLLMResponseHandler
 	pendingChatReqs   map[string]chan *pb.ChatGetOrCreateResponse
 	pendingAddMsgReqs map[string]chan *pb.ChatAddMessageResponse
+	pendingGetMsgReqs map[string]chan *pb.ChatGetMessagesResponse
 	pendingLLMReqs    map[string]chan *pb.ChatLLMResponse
 
 	// Storage handlers
 	pendingStorageReqs map[string]chan *pb.StorageResponse
+	storageSeq         atomic.Int64 // Shared by all storage clients so request IDs stay unique
 
 	// Config
 	configSchema         *pb.ConfigSchema
@@ -87,11 +95,13 @@ func NewClient(name, version, description string) *Client {
 		version:                  version,
 		description:              description,
 		socket:                   "/tmp/chadbot.sock",
+		token:                    os.Getenv(TokenEnv),
 		skills:                   make(map[string]*pb.Skill),
 		skillHandlers:            make(map[string]SkillHandler),
 		skillHandlersWithContext: make(map[string]SkillHandlerWithContext),
 		pendingChatReqs:          make(map[string]chan *pb.ChatGetOrCreateResponse),
 		pendingAddMsgReqs:        make(map[string]chan *pb.ChatAddMessageResponse),
+		pendingGetMsgReqs:        make(map[string]chan *pb.ChatGetMessagesResponse),
 		pendingLLMReqs:           make(map[string]chan *pb.ChatLLMResponse),
 		pendingStorageReqs:       make(map[string]chan *pb.StorageResponse),
 		configValues:             make(map[string]string),
@@ -100,6 +110,23 @@ func NewClient(name, version, description string) *Client {
 	}
 }
 
+// TokenEnv holds the plugin token, set by chadpm when it starts a plugin
+const TokenEnv = "CHADBOT_PLUGIN_TOKEN"
+
+// WithToken sets the plugin token (default from CHADBOT_PLUGIN_TOKEN)
+func (c *Client) WithToken(token string) *Client {
+	c.token = token
+	return c
+}
+
+// WithPermissions declares the capabilities the plugin needs: "chat.read", "chat.write",
+// "llm.request", "storage", "events.subscribe:<pattern>", "events.emit:<pattern>" and
+// "config.read:<plugin>". The backend refuses them until a user approves them.
+func (c *Client) WithPermissions(permissions ...string) *Client {
+	c.permissions = append(c.permissions, permissions...)
+	return c
+}
+
 // WithSocket sets a custom socket path
 func (c *Client) WithSocket(socket string) *Client {
 	c.socket = socket
@@ -113,6 +140,14 @@ func (c *Client) SetDocumentation(markdown string) *Client {
 	return c
 }
 
+// SetDocumentationRequired marks the documentation as mandatory: the LLM must call
+// read_plugin_docs before it can use this plugin's skills
+// This should be called before Connect()
+func (c *Client) SetDocumentationRequired(required bool) *Client {
+	c.docsRequired = required
+	return c
+}
+
 // RegisterSkill registers a skill with the backend
 func (c *Client) RegisterSkill(skill *pb.Skill, handler SkillHandler) {
 	c.mu.Lock()
@@ -190,7 +225,8 @@ func (c *Client) sendDocumentation() error {
 	if err := c.stream.Send(&pb.PluginMessage{
 		Payload: &pb.PluginMessage_Documentation{
 			Documentation: &pb.PluginDocumentation{
-				Content: c.documentation,
+				Content:  c.documentation,
+				Required: c.docsRequired,
 			},
 		},
 	}); err != nil {
@@ -208,6 +244,8 @@ func (c *Client) register() error {
 				Name:        c.name,
 				Version:     c.version,
 				Description: c.description,
+				Token:       c.token,
+				Permissions: c.permissions,
 			},
 		},
 	}); err != nil {
@@ -220,12 +258,18 @@ func (c *Client) register() error {
 	}
 
 	resp := msg.GetRegisterResponse()
-	if resp == nil || !resp.Success {
+	if resp == nil {
 		return fmt.Errorf("registration failed")
 	}
+	if !resp.Success {
+		return fmt.Errorf("registration failed: %s", resp.Message)
+	}
 
 	c.pluginID = resp.PluginId
 	log.Printf("[SDK] Registered as %s (id: %s)", c.name, c.pluginID)
+	if len(resp.PendingPermissions) > 0 {
+		log.Printf("[SDK] Waiting for the user to approve permissions: %v", resp.PendingPermissions)
+	}
 	return nil
 }
 
@@ -355,6 +399,14 @@ func (c *Client) handleMessage(msg *pb.BackendMessage) {
 		}
 		c.mu.Unlock()
 
+	case *pb.BackendMessage_ChatGetMessagesResponse:
+		c.mu.Lock()
+		if ch, ok := c.pendingGetMsgReqs[payload.ChatGetMessagesResponse.RequestId]; ok {
+			ch <- payload.ChatGetMessagesResponse
+			delete(c.pendingGetMsgReqs, payload.ChatGetMessagesResponse.RequestId)
+		}
+		c.mu.Unlock()
+
 	case *pb.BackendMessage_ChatLlmResponse:
 		resp := payload.ChatLlmResponse
 		// Check for pending sync requests first
@@ -498,6 +550,7 @@ func (c *Client) OnChatLLMResponse(handler ChatLLMResponseHandler) {
 }
 
 // ChatGetOrCreate gets or creates a chat linked to a messenger
+// The backend uses the plugin's name as platform and its configured chat owner
 func (c *Client) ChatGetOrCreate(platform, linkedID, name string) (*pb.ChatGetOrCreateResponse, error) {
 	reqID := fmt.Sprintf("chat_goc_%d", time.Now().UnixNano())
 	if err := c.stream.Send(&pb.PluginMessage{
@@ -569,6 +622,53 @@ func (c *Client) ChatAddMessage(chatID, role, content string, opts ...ChatAddMes
 	}
 }
 
+// ChatGetMessagesOptions contains optional paging parameters for ChatGetMessages
+type ChatGetMessagesOptions struct {
+	Limit    int32  // Max messages (0 = 50, at most 200)
+	BeforeID string // Only messages older than this message (newest page first)
+	AfterID  string // Only messages newer than this message
+}
+
+// ChatGetMessages returns messages of a chat in chronological order.
+// Without a cursor the latest Limit messages are returned; HasMore reports whether more exist.
+func (c *Client) ChatGetMessages(chatID string, opts ...ChatGetMessagesOptions) (*pb.ChatGetMessagesResponse, error) {
+	req := &pb.ChatGetMessagesRequest{
+		RequestId: fmt.Sprintf("chat_get_%d", time.Now().UnixNano()),
+		ChatId:    chatID,
+	}
+	if len(opts) > 0 {
+		req.Limit = opts[0].Limit
+		req.BeforeId = opts[0].BeforeID
+		req.AfterId = opts[0].AfterID
+	}
+
+	c.mu.Lock()
+	ch := make(chan *pb.ChatGetMessagesResponse, 1)
+	c.pendingGetMsgReqs[req.RequestId] = ch
+	c.mu.Unlock()
+
+	if err := c.stream.Send(&pb.PluginMessage{
+		Payload: &pb.PluginMessage_ChatGetMessages{
+			ChatGetMessages: req,
+		},
+	}); err != nil {
+		c.mu.Lock()
+		delete(c.pendingGetMsgReqs, req.RequestId)
+		c.mu.Unlock()
+		return nil, err
+	}
+
+	select {
+	case resp := <-ch:
+		return resp, nil
+	case <-time.After(10 * time.Second):
+		c.mu.Lock()
+		delete(c.pendingGetMsgReqs, req.RequestId)
+		c.mu.Unlock()
+		return nil, fmt.Errorf("timeout waiting for get messages response")
+	}
+}
+
 // ChatLLMRequest requests an LLM response for a chat (async, use OnChatLLMResponse to handle)
 func (c *Client) ChatLLMRequest(chatID, provider string) error {
 	reqID := fmt.Sprintf("chat_llm_%d", time.Now().UnixNano())
@@ -628,16 +728,15 @@ func (c *Client) Storage() *StorageClient {
 
 // StorageClient provides storage operations for plugins
 type StorageClient struct {
-	client     *Client
-	requestSeq int64
-	mu         sync.Mutex
+	client *Client
 }
 
+// ErrQuotaExceeded is returned (wrapped) when a write would exceed the storage
+// quota the user configured for the plugin
+var ErrQuotaExceeded = errors.New("storage quota exceeded")
+
 func (s *StorageClient) nextRequestID() string {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	s.requestSeq++
-	return fmt.Sprintf("storage_%d", s.requestSeq)
+	return fmt.Sprintf("storage_%d", s.client.storageSeq.Add(1))
 }
 
 func (s *StorageClient) waitForResponse(reqID string) (*pb.StorageResponse, error) {
@@ -735,6 +834,7 @@ func (s *StorageClient) Insert(table string, values map[string]string) error {
 }
 
 // Update updates rows in a table
+// A non-empty where clause is raw SQL, which the user must allow for the plugin; prefer UpdateWhere.
 func (s *StorageClient) Update(table string, values map[string]string, where string, whereArgs ...string) error {
 	reqID := s.nextRequestID()
 
@@ -766,6 +866,7 @@ func (s *StorageClient) Update(table string, values map[string]string, where str
 }
 
 // Delete deletes rows from a table
+// A non-empty where clause is raw SQL, which the user must allow for the plugin; prefer DeleteWhere.
 func (s *StorageClient) Delete(table string, where string, whereArgs ...string) error {
 	reqID := s.nextRequestID()
 
@@ -796,6 +897,7 @@ func (s *StorageClient) Delete(table string, where string, whereArgs ...string)
 }
 
 // Query queries a table and returns the rows
+// A non-empty where clause is raw SQL, which the user must allow for the plugin; prefer Find.
 func (s *StorageClient) Query(table string, columns []string, where string, whereArgs []string, orderBy string, limit, offset int32) ([]*pb.Row, error) {
 	reqID := s.nextRequestID()
 
@@ -832,6 +934,397 @@ func (s *StorageClient) Query(table string, columns []string, where string, wher
 	return resp.Rows, nil
 }
 
+// Upsert inserts a row or updates the row it conflicts with on the given columns
+func (s *StorageClient) Upsert(table string, values map[string]string, conflictColumns ...string) error {
+	_, err := s.send(&pb.StorageRequest{
+		Operation: &pb.StorageRequest_Upsert{
+			Upsert: &pb.UpsertRequest{
+				TableName:       table,
+				Values:          values,
+				ConflictColumns: conflictColumns,
+			},
+		},
+	}, 10*time.Second)
+	return err
+}
+
+// InsertTyped inserts a row with typed values
+func (s *StorageClient) InsertTyped(table string, values map[string]*pb.Value) error {
+	_, err := s.send(&pb.StorageRequest{
+		Operation: &pb.StorageRequest_Insert{
+			Insert: &pb.InsertRequest{TableName: table, TypedValues: values},
+		},
+	}, 10*time.Second)
+	return err
+}
+
+// UpdateTyped updates rows with typed values
+// A non-empty where clause is raw SQL, which the user must allow for the plugin; prefer UpdateTypedWhere.
+func (s *StorageClient) UpdateTyped(table string, values map[string]*pb.Value, where string, whereArgs ...string) error {
+	_, err := s.send(&pb.StorageRequest{
+		Operation: &pb.StorageRequest_Update{
+			Update: &pb.UpdateRequest{
+				TableName:   table,
+				TypedValues: values,
+				WhereClause: where,
+				WhereArgs:   whereArgs,
+			},
+		},
+	}, 10*time.Second)
+	return err
+}
+
+// UpsertTyped inserts a row with typed values or updates the row it conflicts with
+func (s *StorageClient) UpsertTyped(table string, values map[string]*pb.Value, conflictColumns ...string) error {
+	_, err := s.send(&pb.StorageRequest{
+		Operation: &pb.StorageRequest_Upsert{
+			Upsert: &pb.UpsertRequest{
+				TableName:       table,
+				TypedValues:     values,
+				ConflictColumns: conflictColumns,
+			},
+		},
+	}, 10*time.Second)
+	return err
+}
+
+// QueryTyped queries a table and returns rows with typed values
+// A non-empty where clause is raw SQL, which the user must allow for the plugin; prefer FindTyped.
+func (s *StorageClient) QueryTyped(table string, columns []string, where string, whereArgs []string, orderBy string, limit, offset int32) ([]TypedRow, error) {
+	resp, err := s.send(&pb.StorageRequest{
+		Operation: &pb.StorageRequest_Query{
+			Query: &pb.QueryRequest{
+				TableName:   table,
+				Columns:     columns,
+				WhereClause: where,
+				WhereArgs:   whereArgs,
+				OrderBy:     orderBy,
+				Limit:       limit,
+				Offset:      offset,
+				Typed:       true,
+			},
+		},
+	}, 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	return typedRows(resp.Rows), nil
+}
+
+// Count returns the number of rows matching a condition (empty = all rows)
+// A non-empty where clause is raw SQL, which the user must allow for the plugin; prefer CountWhere.
+func (s *StorageClient) Count(table string, where string, whereArgs ...string) (int64, error) {
+	resp, err := s.send(&pb.StorageRequest{
+		Operation: &pb.StorageRequest_Count{
+			Count: &pb.CountRequest{TableName: table, WhereClause: where, WhereArgs: whereArgs},
+		},
+	}, 10*time.Second)
+	if err != nil {
+		return 0, err
+	}
+	return resp.Count, nil
+}
+
+// Aggregate runs aggregate functions over the matching rows, one result row per group.
+// Rows hold the group by columns and one column per aggregate alias (default function_column).
+// A non-empty where clause is raw SQL, which the user must allow for the plugin; prefer AggregateWhere.
+func (s *StorageClient) Aggregate(table string, aggregates []*pb.Aggregate, groupBy []string, where string, whereArgs ...string) ([]TypedRow, error) {
+	resp, err := s.send(&pb.StorageRequest{
+		Operation: &pb.StorageRequest_Aggregate{
+			Aggregate: &pb.AggregateRequest{
+				TableName:   table,
+				Aggregates:  aggregates,
+				WhereClause: where,
+				WhereArgs:   whereArgs,
+				GroupBy:     groupBy,
+			},
+		},
+	}, 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	return typedRows(resp.Rows), nil
+}
+
+// Find queries a table with a structured filter
+func (s *StorageClient) Find(table string, q Select) ([]*pb.Row, error) {
+	resp, err := s.send(&pb.StorageRequest{
+		Operation: &pb.StorageRequest_Query{Query: q.request(table, false)},
+	}, 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Rows, nil
+}
+
+// FindTyped queries a table with a structured filter and returns rows with typed values
+func (s *StorageClient) FindTyped(table string, q Select) ([]TypedRow, error) {
+	resp, err := s.send(&pb.StorageRequest{
+		Operation: &pb.StorageRequest_Query{Query: q.request(table, true)},
+	}, 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	return typedRows(resp.Rows), nil
+}
+
+// UpdateWhere updates the rows matching a filter (nil = all rows)
+func (s *StorageClient) UpdateWhere(table string, values map[string]string, filter *pb.Filter) error {
+	_, err := s.send(&pb.StorageRequest{
+		Operation: &pb.StorageRequest_Update{
+			Update: &pb.UpdateRequest{TableName: table, Values: values, Filter: filter},
+		},
+	}, 10*time.Second)
+	return err
+}
+
+// UpdateTypedWhere updates the rows matching a filter with typed values
+func (s *StorageClient) UpdateTypedWhere(table string, values map[string]*pb.Value, filter *pb.Filter) error {
+	_, err := s.send(&pb.StorageRequest{
+		Operation: &pb.StorageRequest_Update{
+			Update: &pb.UpdateRequest{TableName: table, TypedValues: values, Filter: filter},
+		},
+	}, 10*time.Second)
+	return err
+}
+
+// DeleteWhere deletes the rows matching a filter (nil = all rows)
+func (s *StorageClient) DeleteWhere(table string, filter *pb.Filter) error {
+	_, err := s.send(&pb.StorageRequest{
+		Operation: &pb.StorageRequest_Delete{
+			Delete: &pb.DeleteRequest{TableName: table, Filter: filter},
+		},
+	}, 10*time.Second)
+	return err
+}
+
+// CountWhere returns the number of rows matching a filter (nil = all rows)
+func (s *StorageClient) CountWhere(table string, filter *pb.Filter) (int64, error) {
+	resp, err := s.send(&pb.StorageRequest{
+		Operation: &pb.StorageRequest_Count{
+			Count: &pb.CountRequest{TableName: table, Filter: filter},
+		},
+	}, 10*time.Second)
+	if err != nil {
+		return 0, err
+	}
+	return resp.Count, nil
+}
+
+// AggregateWhere runs aggregate functions over the rows matching a filter, one result row per group
+func (s *StorageClient) AggregateWhere(table string, aggregates []*pb.Aggregate, groupBy []string, filter *pb.Filter) ([]TypedRow, error) {
+	resp, err := s.send(&pb.StorageRequest{
+		Operation: &pb.StorageRequest_Aggregate{
+			Aggregate: &pb.AggregateRequest{
+				TableName:  table,
+				Aggregates: aggregates,
+				GroupBy:    groupBy,
+				Filter:     filter,
+			},
+		},
+	}, 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	return typedRows(resp.Rows), nil
+}
+
+// send sends a storage request and waits for its response
+func (s *StorageClient) send(req *pb.StorageRequest, timeout time.Duration) (*pb.StorageResponse, error) {
+	req.RequestId = s.nextRequestID()
+
+	s.client.mu.Lock()
+	ch := make(chan *pb.StorageResponse, 1)
+	s.client.pendingStorageReqs[req.RequestId] = ch
+	s.client.mu.Unlock()
+
+	if err := s.client.stream.Send(&pb.PluginMessage{
+		Payload: &pb.PluginMessage_StorageRequest{StorageRequest: req},
+	}); err != nil {
+		s.client.mu.Lock()
+		delete(s.client.pendingStorageReqs, req.RequestId)
+		s.client.mu.Unlock()
+		return nil, err
+	}
+
+	select {
+	case resp := <-ch:
+		if resp.QuotaExceeded {
+			return resp, fmt.Errorf("%w: %s", ErrQuotaExceeded, resp.Error)
+		}
+		if !resp.Success {
+			return resp, fmt.Errorf("storage error: %s", resp.Error)
+		}
+		return resp, nil
+	case <-time.After(timeout):
+		s.client.mu.Lock()
+		delete(s.client.pendingStorageReqs, req.RequestId)
+		s.client.mu.Unlock()
+		return nil, fmt.Errorf("timeout waiting for storage response")
+	}
+}
+
+// Batch collects write operations that are committed in one transaction
+type Batch struct {
+	storage    *StorageClient
+	operations []*pb.BatchOperation
+}
+
+// Batch starts a batch of writes. Nothing is sent until Commit.
+func (s *StorageClient) Batch() *Batch {
+	return &Batch{storage: s}
+}
+
+// Insert adds an insert to the batch
+func (b *Batch) Insert(table string, values map[string]string) *Batch {
+	b.operations = append(b.operations, &pb.BatchOperation{
+		Operation: &pb.BatchOperation_Insert{Insert: &pb.InsertRequest{TableName: table, Values: values}},
+	})
+	return b
+}
+
+// Update adds an update to the batch
+// A non-empty where clause is raw SQL, which the user must allow for the plugin; prefer UpdateWhere.
+func (b *Batch) Update(table string, values map[string]string, where string, whereArgs ...string) *Batch {
+	b.operations = append(b.operations, &pb.BatchOperation{
+		Operation: &pb.BatchOperation_Update{Update: &pb.UpdateRequest{
+			TableName:   table,
+			Values:      values,
+			WhereClause: where,
+			WhereArgs:   whereArgs,
+		}},
+	})
+	return b
+}
+
+// Delete adds a delete to the batch
+// A non-empty where clause is raw SQL, which the user must allow for the plugin; prefer DeleteWhere.
+func (b *Batch) Delete(table string, where string, whereArgs ...string) *Batch {
+	b.operations = append(b.operations, &pb.BatchOperation{
+		Operation: &pb.BatchOperation_Delete{Delete: &pb.DeleteRequest{
+			TableName:   table,
+			WhereClause: where,
+			WhereArgs:   whereArgs,
+		}},
+	})
+	return b
+}
+
+// Upsert adds an upsert to the batch
+func (b *Batch) Upsert(table string, values map[string]string, conflictColumns ...string) *Batch {
+	b.operations = append(b.operations, &pb.BatchOperation{
+		Operation: &pb.BatchOperation_Upsert{Upsert: &pb.UpsertRequest{
+			TableName:       table,
+			Values:          values,
+			ConflictColumns: conflictColumns,
+		}},
+	})
+	return b
+}
+
+// InsertTyped adds an insert with typed values to the batch
+func (b *Batch) InsertTyped(table string, values map[string]*pb.Value) *Batch {
+	b.operations = append(b.operations, &pb.BatchOperation{
+		Operation: &pb.BatchOperation_Insert{Insert: &pb.InsertRequest{TableName: table, TypedValues: values}},
+	})
+	return b
+}
+
+// UpdateTyped adds an update with typed values to the batch
+// A non-empty where clause is raw SQL, which the user must allow for the plugin; prefer UpdateTypedWhere.
+func (b *Batch) UpdateTyped(table string, values map[string]*pb.Value, where string, whereArgs ...string) *Batch {
+	b.operations = append(b.operations, &pb.BatchOperation{
+		Operation: &pb.BatchOperation_Update{Update: &pb.UpdateRequest{
+			TableName:   table,
+			TypedValues: values,
+			WhereClause: where,
+			WhereArgs:   whereArgs,
+		}},
+	})
+	return b
+}
+
+// UpsertTyped adds an upsert with typed values to the batch
+func (b *Batch) UpsertTyped(table string, values map[string]*pb.Value, conflictColumns ...string) *Batch {
+	b.operations = append(b.operations, &pb.BatchOperation{
+		Operation: &pb.BatchOperation_Upsert{Upsert: &pb.UpsertRequest{
+			TableName:       table,
+			TypedValues:     values,
+			ConflictColumns: conflictColumns,
+		}},
+	})
+	return b
+}
+
+// UpdateWhere adds an update of the rows matching a filter to the batch
+func (b *Batch) UpdateWhere(table string, values map[string]string, filter *pb.Filter) *Batch {
+	b.operations = append(b.operations, &pb.BatchOperation{
+		Operation: &pb.BatchOperation_Update{Update: &pb.UpdateRequest{TableName: table, Values: values, Filter: filter}},
+	})
+	return b
+}
+
+// UpdateTypedWhere adds an update with typed values of the rows matching a filter to the batch
+func (b *Batch) UpdateTypedWhere(table string, values map[string]*pb.Value, filter *pb.Filter) *Batch {
+	b.operations = append(b.operations, &pb.BatchOperation{
+		Operation: &pb.BatchOperation_Update{Update: &pb.UpdateRequest{TableName: table, TypedValues: values, Filter: filter}},
+	})
+	return b
+}
+
+// DeleteWhere adds a delete of the rows matching a filter to the batch
+func (b *Batch) DeleteWhere(table string, filter *pb.Filter) *Batch {
+	b.operations = append(b.operations, &pb.BatchOperation{
+		Operation: &pb.BatchOperation_Delete{Delete: &pb.DeleteRequest{TableName: table, Filter: filter}},
+	})
+	return b
+}
+
+// Len returns the number of operations in the batch
+func (b *Batch) Len() int {
+	return len(b.operations)
+}
+
+// Commit runs all operations in one transaction. If one fails, none is applied.
+func (b *Batch) Commit() ([]*pb.OperationResult, error) {
+	if len(b.operations) == 0 {
+		return nil, nil
+	}
+	resp, err := b.storage.send(&pb.StorageRequest{
+		Operation: &pb.StorageRequest_Batch{
+			Batch: &pb.BatchRequest{Operations: b.operations},
+		},
+	}, 60*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Results, nil
+}
+
+// Tx runs fn to fill a batch and commits it, e.g. for multi-step updates that must be atomic
+func (s *StorageClient) Tx(fn func(b *Batch) error) error {
+	b := s.Batch()
+	if err := fn(b); err != nil {
+		return err
+	}
+	_, err := b.Commit()
+	return err
+}
+
+// Migrate registers the plugin's ordered schema migrations. Migrations that were not applied
+// yet run once each in their own transaction. Returns the schema version (also on failure).
+func (s *StorageClient) Migrate(migrations []*pb.Migration) (int32, error) {
+	resp, err := s.send(&pb.StorageRequest{
+		Operation: &pb.StorageRequest_Migrate{
+			Migrate: &pb.MigrateRequest{Migrations: migrations},
+		},
+	}, 60*time.Second) // Migrations may rewrite large tables
+	if resp == nil {
+		return 0, err
+	}
+	return resp.SchemaVersion, err
+}
+
 // ConfigField defines a config field for the plugin
 type ConfigField struct {
 	Key          string
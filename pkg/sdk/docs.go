@@ -0,0 +1,119 @@
+package sdk
+
+import (
+	"encoding/json"
+	"time"
+
+	pb "github.com/fipso/chadbot/gen/chadbot"
+)
+
+// DocsClient provides the plugin's JSON document collections
+type DocsClient struct {
+	storage *StorageClient
+}
+
+// Docs provides access to the plugin's document collections
+func (c *Client) Docs() *DocsClient {
+	return &DocsClient{storage: c.Storage()}
+}
+
+// Collection returns a document collection (created on first write)
+func (d *DocsClient) Collection(name string) *Collection {
+	return &Collection{storage: d.storage, name: name}
+}
+
+// Collection is a named set of JSON documents
+type Collection struct {
+	storage *StorageClient
+	name    string
+}
+
+// do sends a request on the collection
+func (c *Collection) do(req *pb.DocRequest) (*pb.StorageResponse, error) {
+	req.Collection = c.name
+	resp, err := c.storage.send(&pb.StorageRequest{
+		Operation: &pb.StorageRequest_Docs{Docs: req},
+	}, 30*time.Second)
+	if resp != nil && resp.Conflict {
+		return resp, ErrConflict
+	}
+	return resp, err
+}
+
+// Put stores v (encoded as a JSON object) under an ID (empty = generated) and returns the stored document
+func (c *Collection) Put(id string, v any) (*pb.Document, error) {
+	return c.put(&pb.DocPut{Id: id}, v)
+}
+
+// CompareAndPut stores v only if the document is at expectedVersion (0 = it must not exist).
+// Returns ErrConflict if the version differs.
+func (c *Collection) CompareAndPut(id string, expectedVersion int64, v any) (*pb.Document, error) {
+	return c.put(&pb.DocPut{Id: id, Cas: true, ExpectedVersion: expectedVersion}, v)
+}
+
+func (c *Collection) put(req *pb.DocPut, v any) (*pb.Document, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	req.Json = string(data)
+	resp, err := c.do(&pb.DocRequest{Op: &pb.DocRequest_Put{Put: req}})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Documents[0], nil
+}
+
+// Get decodes a document into v; ok is false if it doesn't exist
+func (c *Collection) Get(id string, v any) (ok bool, err error) {
+	doc, err := c.GetDocument(id)
+	if err != nil || doc == nil {
+		return false, err
+	}
+	return true, DecodeDocument(doc, v)
+}
+
+// GetDocument returns a document with its version (nil if it doesn't exist)
+func (c *Collection) GetDocument(id string) (*pb.Document, error) {
+	resp, err := c.do(&pb.DocRequest{Op: &pb.DocRequest_Get{Get: &pb.DocGet{Id: id}}})
+	if err != nil || len(resp.Documents) == 0 {
+		return nil, err
+	}
+	return resp.Documents[0], nil
+}
+
+// Delete deletes a document
+func (c *Collection) Delete(id string) error {
+	_, err := c.do(&pb.DocRequest{Op: &pb.DocRequest_Delete{Delete: &pb.DocDelete{Id: id}}})
+	return err
+}
+
+// Find returns the documents whose indexed fields equal all values in match (nil = all documents)
+// in creation order, at most limit (0 = 1000)
+func (c *Collection) Find(match map[string]any, limit int32) ([]*pb.Document, error) {
+	find := &pb.DocFind{Limit: limit}
+	for field, value := range match {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		find.Matches = append(find.Matches, &pb.DocFieldMatch{Field: field, JsonValue: string(data)})
+	}
+
+	resp, err := c.do(&pb.DocRequest{Op: &pb.DocRequest_Find{Find: find}})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Documents, nil
+}
+
+// EnsureIndex indexes fields (paths like "owner.id") so Find can match them
+func (c *Collection) EnsureIndex(fields ...string) error {
+	_, err := c.do(&pb.DocRequest{Op: &pb.DocRequest_EnsureIndex{EnsureIndex: &pb.DocEnsureIndex{Fields: fields}}})
+	return err
+}
+
+// DecodeDocument decodes a document's JSON into v
+func DecodeDocument(doc *pb.Document, v any) error {
+	return json.Unmarshal([]byte(doc.Json), v)
+}
@@ -0,0 +1,124 @@
+package sdk
+
+import (
+	pb "github.com/fipso/chadbot/gen/chadbot"
+)
+
+// cond builds a filter condition
+func cond(column string, op pb.FilterOp, value any) *pb.Filter {
+	c := &pb.Condition{Column: column, Op: op}
+	if value != nil {
+		c.Value = ValueOf(value)
+	}
+	return &pb.Filter{Expr: &pb.Filter_Condition{Condition: c}}
+}
+
+// Eq matches rows whose column equals value
+func Eq(column string, value any) *pb.Filter {
+	return cond(column, pb.FilterOp_FILTER_OP_EQ, value)
+}
+
+// Ne matches rows whose column differs from value
+func Ne(column string, value any) *pb.Filter {
+	return cond(column, pb.FilterOp_FILTER_OP_NE, value)
+}
+
+// Lt matches rows whose column is less than value
+func Lt(column string, value any) *pb.Filter {
+	return cond(column, pb.FilterOp_FILTER_OP_LT, value)
+}
+
+// Le matches rows whose column is less than or equal to value
+func Le(column string, value any) *pb.Filter {
+	return cond(column, pb.FilterOp_FILTER_OP_LE, value)
+}
+
+// Gt matches rows whose column is greater than value
+func Gt(column string, value any) *pb.Filter {
+	return cond(column, pb.FilterOp_FILTER_OP_GT, value)
+}
+
+// Ge matches rows whose column is greater than or equal to value
+func Ge(column string, value any) *pb.Filter {
+	return cond(column, pb.FilterOp_FILTER_OP_GE, value)
+}
+
+// Like matches rows whose column matches a SQL LIKE pattern
+func Like(column, pattern string) *pb.Filter {
+	return cond(column, pb.FilterOp_FILTER_OP_LIKE, pattern)
+}
+
+// In matches rows whose column equals one of the values
+func In(column string, values ...any) *pb.Filter {
+	return inFilter(column, pb.FilterOp_FILTER_OP_IN, values)
+}
+
+// NotIn matches rows whose column equals none of the values
+func NotIn(column string, values ...any) *pb.Filter {
+	return inFilter(column, pb.FilterOp_FILTER_OP_NOT_IN, values)
+}
+
+func inFilter(column string, op pb.FilterOp, values []any) *pb.Filter {
+	c := &pb.Condition{Column: column, Op: op, Values: make([]*pb.Value, len(values))}
+	for i, v := range values {
+		c.Values[i] = ValueOf(v)
+	}
+	return &pb.Filter{Expr: &pb.Filter_Condition{Condition: c}}
+}
+
+// IsNull matches rows whose column is NULL
+func IsNull(column string) *pb.Filter {
+	return cond(column, pb.FilterOp_FILTER_OP_IS_NULL, nil)
+}
+
+// IsNotNull matches rows whose column is not NULL
+func IsNotNull(column string) *pb.Filter {
+	return cond(column, pb.FilterOp_FILTER_OP_IS_NOT_NULL, nil)
+}
+
+// And matches rows matching all filters
+func And(filters ...*pb.Filter) *pb.Filter {
+	return &pb.Filter{Expr: &pb.Filter_And{And: &pb.FilterList{Filters: filters}}}
+}
+
+// Or matches rows matching any of the filters
+func Or(filters ...*pb.Filter) *pb.Filter {
+	return &pb.Filter{Expr: &pb.Filter_Or{Or: &pb.FilterList{Filters: filters}}}
+}
+
+// Not matches rows not matching the filter
+func Not(filter *pb.Filter) *pb.Filter {
+	return &pb.Filter{Expr: &pb.Filter_Not{Not: filter}}
+}
+
+// Asc sorts by a column in ascending order
+func Asc(column string) *pb.OrderBy {
+	return &pb.OrderBy{Column: column}
+}
+
+// Desc sorts by a column in descending order
+func Desc(column string) *pb.OrderBy {
+	return &pb.OrderBy{Column: column, Desc: true}
+}
+
+// Select describes a filtered query
+type Select struct {
+	Columns []string      // Empty = all columns
+	Filter  *pb.Filter    // Nil = all rows
+	OrderBy []*pb.OrderBy // e.g. sdk.Desc("created_at")
+	Limit   int32
+	Offset  int32
+}
+
+// request builds the query request of a select
+func (q Select) request(table string, typed bool) *pb.QueryRequest {
+	return &pb.QueryRequest{
+		TableName: table,
+		Columns:   q.Columns,
+		Filter:    q.Filter,
+		Order:     q.OrderBy,
+		Limit:     q.Limit,
+		Offset:    q.Offset,
+		Typed:     typed,
+	}
+}
@@ -0,0 +1,165 @@
+package sdk
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	pb "github.com/fipso/chadbot/gen/chadbot"
+)
+
+// ErrConflict is returned when a compare-and-swap finds a different version
+var ErrConflict = errors.New("version conflict")
+
+// KVClient provides the plugin's key-value store
+type KVClient struct {
+	storage *StorageClient
+}
+
+// KV provides access to the plugin's key-value store
+func (c *Client) KV() *KVClient {
+	return &KVClient{storage: c.Storage()}
+}
+
+// do sends a key-value request
+func (k *KVClient) do(req *pb.KVRequest) (*pb.StorageResponse, error) {
+	resp, err := k.storage.send(&pb.StorageRequest{
+		Operation: &pb.StorageRequest_Kv{Kv: req},
+	}, 10*time.Second)
+	if resp != nil && resp.Conflict {
+		return resp, ErrConflict
+	}
+	return resp, err
+}
+
+// ttlSeconds rounds a TTL up to whole seconds (0 = never expires)
+func ttlSeconds(ttl time.Duration) int64 {
+	if ttl <= 0 {
+		return 0
+	}
+	return int64((ttl + time.Second - 1) / time.Second)
+}
+
+// Get returns the value of a key; ok is false if it doesn't exist or expired
+func (k *KVClient) Get(key string) (value []byte, ok bool, err error) {
+	entry, err := k.GetEntry(key)
+	if err != nil || entry == nil {
+		return nil, false, err
+	}
+	return entry.Value, true, nil
+}
+
+// GetEntry returns a key's entry with its version, for compare-and-swap (nil if missing)
+func (k *KVClient) GetEntry(key string) (*pb.KVEntry, error) {
+	resp, err := k.do(&pb.KVRequest{Op: &pb.KVRequest_Get{Get: &pb.KVGet{Key: key}}})
+	if err != nil || len(resp.Entries) == 0 {
+		return nil, err
+	}
+	return resp.Entries[0], nil
+}
+
+// Set stores a value
+func (k *KVClient) Set(key string, value []byte) error {
+	return k.SetTTL(key, value, 0)
+}
+
+// SetTTL stores a value that expires after ttl (0 = never)
+func (k *KVClient) SetTTL(key string, value []byte, ttl time.Duration) error {
+	_, err := k.do(&pb.KVRequest{Op: &pb.KVRequest_Set{Set: &pb.KVSet{
+		Key:        key,
+		Value:      value,
+		TtlSeconds: ttlSeconds(ttl),
+	}}})
+	return err
+}
+
+// CompareAndSwap stores a value only if the key is at expectedVersion (0 = the key must not exist)
+// and returns the new version. Returns ErrConflict if the version differs.
+func (k *KVClient) CompareAndSwap(key string, expectedVersion int64, value []byte, ttl time.Duration) (int64, error) {
+	resp, err := k.do(&pb.KVRequest{Op: &pb.KVRequest_Set{Set: &pb.KVSet{
+		Key:             key,
+		Value:           value,
+		TtlSeconds:      ttlSeconds(ttl),
+		Cas:             true,
+		ExpectedVersion: expectedVersion,
+	}}})
+	if err != nil {
+		return 0, err
+	}
+	return resp.Entries[0].Version, nil
+}
+
+// Delete deletes a key
+func (k *KVClient) Delete(key string) error {
+	_, err := k.do(&pb.KVRequest{Op: &pb.KVRequest_Delete{Delete: &pb.KVDelete{Key: key}}})
+	return err
+}
+
+// CompareAndDelete deletes a key only if it is at expectedVersion. Returns ErrConflict otherwise.
+func (k *KVClient) CompareAndDelete(key string, expectedVersion int64) error {
+	_, err := k.do(&pb.KVRequest{Op: &pb.KVRequest_Delete{Delete: &pb.KVDelete{
+		Key:             key,
+		Cas:             true,
+		ExpectedVersion: expectedVersion,
+	}}})
+	return err
+}
+
+// List returns all entries whose keys start with prefix, in key order
+func (k *KVClient) List(prefix string) ([]*pb.KVEntry, error) {
+	return k.list(prefix, false)
+}
+
+// Keys returns all keys that start with prefix, in order
+func (k *KVClient) Keys(prefix string) ([]string, error) {
+	entries, err := k.list(prefix, true)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, len(entries))
+	for i, e := range entries {
+		keys[i] = e.Key
+	}
+	return keys, nil
+}
+
+// list pages through the entries of a prefix
+func (k *KVClient) list(prefix string, keysOnly bool) ([]*pb.KVEntry, error) {
+	const pageSize = 1000
+	var entries []*pb.KVEntry
+	after := ""
+	for {
+		resp, err := k.do(&pb.KVRequest{Op: &pb.KVRequest_List{List: &pb.KVList{
+			Prefix:   prefix,
+			After:    after,
+			Limit:    pageSize,
+			KeysOnly: keysOnly,
+		}}})
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, resp.Entries...)
+		if len(resp.Entries) < pageSize {
+			return entries, nil
+		}
+		after = resp.Entries[len(resp.Entries)-1].Key
+	}
+}
+
+// GetJSON decodes the JSON value of a key into v; ok is false if it doesn't exist
+func (k *KVClient) GetJSON(key string, v any) (ok bool, err error) {
+	value, ok, err := k.Get(key)
+	if err != nil || !ok {
+		return false, err
+	}
+	return true, json.Unmarshal(value, v)
+}
+
+// SetJSON stores v as JSON
+func (k *KVClient) SetJSON(key string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return k.Set(key, data)
+}
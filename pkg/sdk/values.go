@@ -0,0 +1,161 @@
+package sdk
+
+import (
+	"fmt"
+
+	pb "github.com/fipso/chadbot/gen/chadbot"
+)
+
+// Null returns a NULL storage value
+func Null() *pb.Value {
+	return &pb.Value{Kind: &pb.Value_NullValue{NullValue: true}}
+}
+
+// Int returns an INTEGER storage value
+func Int(v int64) *pb.Value {
+	return &pb.Value{Kind: &pb.Value_IntValue{IntValue: v}}
+}
+
+// Float returns a REAL storage value
+func Float(v float64) *pb.Value {
+	return &pb.Value{Kind: &pb.Value_FloatValue{FloatValue: v}}
+}
+
+// Text returns a TEXT storage value
+func Text(v string) *pb.Value {
+	return &pb.Value{Kind: &pb.Value_TextValue{TextValue: v}}
+}
+
+// Bytes returns a BLOB storage value
+func Bytes(v []byte) *pb.Value {
+	return &pb.Value{Kind: &pb.Value_BytesValue{BytesValue: v}}
+}
+
+// Bool returns a boolean storage value (stored as 0/1)
+func Bool(v bool) *pb.Value {
+	return &pb.Value{Kind: &pb.Value_BoolValue{BoolValue: v}}
+}
+
+// TypedRow is a query result row with typed column values
+type TypedRow map[string]*pb.Value
+
+// Has reports whether the row has a column
+func (r TypedRow) Has(col string) bool {
+	_, ok := r[col]
+	return ok
+}
+
+// IsNull reports whether a column is NULL or missing
+func (r TypedRow) IsNull(col string) bool {
+	switch r[col].GetKind().(type) {
+	case nil, *pb.Value_NullValue:
+		return true
+	default:
+		return false
+	}
+}
+
+// Int returns an integer column. Bools read as 0/1; ok is false for NULL and other types.
+func (r TypedRow) Int(col string) (int64, bool) {
+	switch k := r[col].GetKind().(type) {
+	case *pb.Value_IntValue:
+		return k.IntValue, true
+	case *pb.Value_BoolValue:
+		if k.BoolValue {
+			return 1, true
+		}
+		return 0, true
+	default:
+		return 0, false
+	}
+}
+
+// Float returns a numeric column as float64
+func (r TypedRow) Float(col string) (float64, bool) {
+	switch k := r[col].GetKind().(type) {
+	case *pb.Value_FloatValue:
+		return k.FloatValue, true
+	case *pb.Value_IntValue:
+		return float64(k.IntValue), true
+	default:
+		return 0, false
+	}
+}
+
+// Text returns a text column
+func (r TypedRow) Text(col string) (string, bool) {
+	if k, ok := r[col].GetKind().(*pb.Value_TextValue); ok {
+		return k.TextValue, true
+	}
+	return "", false
+}
+
+// Bytes returns a blob column (text columns are returned as their bytes)
+func (r TypedRow) Bytes(col string) ([]byte, bool) {
+	switch k := r[col].GetKind().(type) {
+	case *pb.Value_BytesValue:
+		return k.BytesValue, true
+	case *pb.Value_TextValue:
+		return []byte(k.TextValue), true
+	default:
+		return nil, false
+	}
+}
+
+// Bool returns a boolean column. Integers read as true unless 0.
+func (r TypedRow) Bool(col string) (bool, bool) {
+	switch k := r[col].GetKind().(type) {
+	case *pb.Value_BoolValue:
+		return k.BoolValue, true
+	case *pb.Value_IntValue:
+		return k.IntValue != 0, true
+	default:
+		return false, false
+	}
+}
+
+// String returns a text column or "" if it is missing, NULL or not text
+func (r TypedRow) String(col string) string {
+	s, _ := r.Text(col)
+	return s
+}
+
+// typedRows converts response rows to typed rows
+func typedRows(rows []*pb.Row) []TypedRow {
+	result := make([]TypedRow, len(rows))
+	for i, row := range rows {
+		result[i] = row.TypedValues
+	}
+	return result
+}
+
+// ValueOf converts a Go value to a storage value: nil, integers, floats, strings, []byte, bool
+// or *pb.Value. Other types are stored as their text form.
+func ValueOf(v any) *pb.Value {
+	switch x := v.(type) {
+	case nil:
+		return Null()
+	case *pb.Value:
+		return x
+	case int:
+		return Int(int64(x))
+	case int32:
+		return Int(int64(x))
+	case int64:
+		return Int(x)
+	case uint32:
+		return Int(int64(x))
+	case float32:
+		return Float(float64(x))
+	case float64:
+		return Float(x)
+	case string:
+		return Text(x)
+	case []byte:
+		return Bytes(x)
+	case bool:
+		return Bool(x)
+	default:
+		return Text(fmt.Sprint(x))
+	}
+}
@@ -14,6 +14,7 @@ import (
 
 func main() {
 	client := sdk.NewClient("example", "1.0.0", "Example plugin demonstrating skills and events")
+	client.WithPermissions("events.subscribe:chat.message.*")
 
 	// Register a weather skill
 	client.RegisterSkill(&pb.Skill{
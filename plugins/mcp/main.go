@@ -9,6 +9,8 @@ import (
 	"os"
 	"os/exec"
 	"os/signal"
+	"sort"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -24,11 +26,15 @@ import (
 //go:embed PLUGIN.md
 var pluginDocumentation string
 
-const serversTable = "servers"
+const (
+	serversCollection = "servers"
+	serversTable      = "servers" // Legacy table, migrated to the servers collection
+)
 
 var (
 	client  *sdk.Client
 	storage *sdk.StorageClient
+	servers *sdk.Collection
 	manager *ServerManager
 )
 
@@ -254,7 +260,8 @@ func main() {
 	}
 	client = sdk.NewClient("mcp", "1.0.0", "MCP (Model Context Protocol) server integration - spawn and invoke MCP servers")
 	client = client.WithSocket(socketPath)
-	client = client.SetDocumentation(pluginDocumentation)
+	client = client.SetDocumentation(pluginDocumentation).SetDocumentationRequired(true)
+	client = client.WithPermissions("storage", "events.emit:mcp.server.*")
 
 	// Initialize server manager
 	manager = NewServerManager()
@@ -275,6 +282,7 @@ func main() {
 
 	// Initialize storage
 	storage = client.Storage()
+	servers = client.Docs().Collection(serversCollection)
 	if err := initStorage(); err != nil {
 		log.Fatalf("[MCP] Failed to initialize storage: %v", err)
 	}
@@ -392,38 +400,53 @@ func registerSkills() {
 func initStorage() error {
 	log.Println("[MCP] Initializing storage...")
 
-	// Create servers table
-	if err := storage.CreateTable(serversTable, []*pb.ColumnDef{
-		{Name: "name", Type: "TEXT", PrimaryKey: true},
-		{Name: "command", Type: "TEXT", NotNull: true},
-		{Name: "args", Type: "TEXT", NotNull: true},
-		{Name: "working_dir", Type: "TEXT"},
-		{Name: "env", Type: "TEXT"},
-		{Name: "auto_start", Type: "INTEGER"},
-		{Name: "created_at", Type: "INTEGER", NotNull: true},
-	}, true); err != nil {
+	if err := servers.EnsureIndex("auto_start"); err != nil {
 		return err
 	}
+	return migrateLegacyServers()
+}
 
-	return nil
+// migrateLegacyServers moves server configs from the table used before document storage
+func migrateLegacyServers() error {
+	rows, err := storage.Query(serversTable, nil, "", nil, "", 0, 0)
+	if err != nil && !strings.Contains(err.Error(), "no such table") {
+		return err
+	}
+	for _, row := range rows {
+		config, err := rowToConfig(row)
+		if err != nil {
+			log.Printf("[MCP] Skipping legacy server config: %v", err)
+			continue
+		}
+		if _, err := servers.Put(config.Name, config); err != nil {
+			return err
+		}
+	}
+	if len(rows) > 0 {
+		log.Printf("[MCP] Migrated %d server configs to document storage", len(rows))
+	}
+
+	_, err = storage.Migrate([]*pb.Migration{{
+		Version: 1,
+		Name:    "drop servers table",
+		Steps: []*pb.MigrationStep{{Step: &pb.MigrationStep_DropTable{DropTable: &pb.DropTableRequest{
+			TableName: serversTable,
+			IfExists:  true,
+		}}}},
+	}})
+	return err
 }
 
 func autoStartServers(ctx context.Context) {
-	rows, err := storage.Query(serversTable, nil, "auto_start = ?", []string{"1"}, "", 100, 0)
+	configs, err := findServers(map[string]any{"auto_start": true})
 	if err != nil {
 		log.Printf("[MCP] Failed to query auto-start servers: %v", err)
 		return
 	}
 
-	log.Printf("[MCP] Found %d servers to auto-start", len(rows))
-
-	for _, row := range rows {
-		config, err := rowToConfig(row)
-		if err != nil {
-			log.Printf("[MCP] Failed to parse server config: %v", err)
-			continue
-		}
+	log.Printf("[MCP] Found %d servers to auto-start", len(configs))
 
+	for _, config := range configs {
 		log.Printf("[MCP] Auto-starting server: %s", config.Name)
 		if err := manager.Connect(ctx, config); err != nil {
 			log.Printf("[MCP] Failed to auto-start server %s: %v", config.Name, err)
@@ -431,6 +454,7 @@ func autoStartServers(ctx context.Context) {
 	}
 }
 
+// rowToConfig converts a row of the legacy servers table
 func rowToConfig(row *pb.Row) (*MCPServerConfig, error) {
 	config := &MCPServerConfig{
 		Name:       row.Values["name"],
@@ -459,15 +483,36 @@ func rowToConfig(row *pb.Row) (*MCPServerConfig, error) {
 	return config, nil
 }
 
+// findServers returns the server configs matching indexed fields (nil = all), sorted by name
+func findServers(match map[string]any) ([]*MCPServerConfig, error) {
+	docs, err := servers.Find(match, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	configs := make([]*MCPServerConfig, 0, len(docs))
+	for _, doc := range docs {
+		config := &MCPServerConfig{}
+		if err := sdk.DecodeDocument(doc, config); err != nil {
+			log.Printf("[MCP] Failed to parse server config %s: %v", doc.Id, err)
+			continue
+		}
+		configs = append(configs, config)
+	}
+	sort.Slice(configs, func(i, j int) bool { return configs[i].Name < configs[j].Name })
+	return configs, nil
+}
+
 func loadServerConfig(name string) (*MCPServerConfig, error) {
-	rows, err := storage.Query(serversTable, nil, "name = ?", []string{name}, "", 1, 0)
+	config := &MCPServerConfig{}
+	ok, err := servers.Get(name, config)
 	if err != nil {
 		return nil, err
 	}
-	if len(rows) == 0 {
+	if !ok {
 		return nil, fmt.Errorf("server %s not found", name)
 	}
-	return rowToConfig(rows[0])
+	return config, nil
 }
 
 // Skill handlers
@@ -486,7 +531,6 @@ func handleAddServer(ctx context.Context, args map[string]string) (string, error
 			return "", fmt.Errorf("invalid args JSON: %w", err)
 		}
 	}
-	argsJSON, _ := json.Marshal(argsSlice)
 
 	// Parse env
 	var envMap map[string]string
@@ -495,35 +539,24 @@ func handleAddServer(ctx context.Context, args map[string]string) (string, error
 			return "", fmt.Errorf("invalid env JSON: %w", err)
 		}
 	}
-	envJSON, _ := json.Marshal(envMap)
 
-	autoStart := "0"
-	if args["auto_start"] == "true" {
-		autoStart = "1"
+	config := &MCPServerConfig{
+		Name:       name,
+		Command:    command,
+		Args:       argsSlice,
+		WorkingDir: args["working_dir"],
+		Env:        envMap,
+		AutoStart:  args["auto_start"] == "true",
+		CreatedAt:  time.Now().Unix(),
 	}
 
-	// Insert or update server config
-	err := storage.Insert(serversTable, map[string]string{
-		"name":        name,
-		"command":     command,
-		"args":        string(argsJSON),
-		"working_dir": args["working_dir"],
-		"env":         string(envJSON),
-		"auto_start":  autoStart,
-		"created_at":  fmt.Sprintf("%d", time.Now().Unix()),
-	})
-	if err != nil {
-		// Try update if insert fails (already exists)
-		updateErr := storage.Update(serversTable, map[string]string{
-			"command":     command,
-			"args":        string(argsJSON),
-			"working_dir": args["working_dir"],
-			"env":         string(envJSON),
-			"auto_start":  autoStart,
-		}, "name = ?", name)
-		if updateErr != nil {
-			return "", fmt.Errorf("failed to save server: insert=%v, update=%v", err, updateErr)
-		}
+	// Insert or update server config, keeping its creation time
+	var existing MCPServerConfig
+	if ok, err := servers.Get(name, &existing); err == nil && ok {
+		config.CreatedAt = existing.CreatedAt
+	}
+	if _, err := servers.Put(name, config); err != nil {
+		return "", fmt.Errorf("failed to save server: %w", err)
 	}
 
 	return fmt.Sprintf("Server %s added successfully", name), nil
@@ -542,7 +575,7 @@ func handleRemoveServer(ctx context.Context, args map[string]string) (string, er
 	manager.RemoveServer(name)
 
 	// Delete from storage
-	if err := storage.Delete(serversTable, "name = ?", name); err != nil {
+	if err := servers.Delete(name); err != nil {
 		return "", fmt.Errorf("failed to delete server: %w", err)
 	}
 
@@ -584,7 +617,7 @@ func handleDisconnect(ctx context.Context, args map[string]string) (string, erro
 }
 
 func handleListServers(ctx context.Context, args map[string]string) (string, error) {
-	rows, err := storage.Query(serversTable, nil, "", nil, "name ASC", 100, 0)
+	configs, err := findServers(nil)
 	if err != nil {
 		return "", fmt.Errorf("failed to query servers: %w", err)
 	}
@@ -600,13 +633,8 @@ func handleListServers(ctx context.Context, args map[string]string) (string, err
 		ConnectedAt string `json:"connected_at,omitempty"`
 	}
 
-	servers := make([]serverInfo, 0, len(rows))
-	for _, row := range rows {
-		config, err := rowToConfig(row)
-		if err != nil {
-			continue
-		}
-
+	list := make([]serverInfo, 0, len(configs))
+	for _, config := range configs {
 		info := serverInfo{
 			Name:       config.Name,
 			Command:    config.Command,
@@ -622,10 +650,10 @@ func handleListServers(ctx context.Context, args map[string]string) (string, err
 			info.ConnectedAt = state.ConnectedAt.Format(time.RFC3339)
 		}
 
-		servers = append(servers, info)
+		list = append(list, info)
 	}
 
-	data, _ := json.Marshal(servers)
+	data, _ := json.Marshal(list)
 	return string(data), nil
 }
 
@@ -792,10 +820,10 @@ func handleStatus(ctx context.Context, args map[string]string) (string, error) {
 	}
 
 	// Count total configured servers
-	rows, _ := storage.Query(serversTable, nil, "", nil, "", 100, 0)
+	configs, _ := findServers(nil)
 
 	status := map[string]interface{}{
-		"configured_servers":  len(rows),
+		"configured_servers":  len(configs),
 		"connected_servers":   connectedCount,
 		"connected_names":     connectedServers,
 		"total_tools":         totalTools,
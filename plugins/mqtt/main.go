@@ -58,6 +58,7 @@ func main() {
 	client = sdk.NewClient("mqtt", "1.0.0", "MQTT broker integration - subscribe to topics and receive messages as events")
 	client = client.WithSocket(socketPath)
 	client = client.SetDocumentation(pluginDocumentation)
+	client = client.WithPermissions("storage", "events.emit:mqtt.message.*")
 
 	// Register skills
 	registerSkills()
@@ -118,7 +119,7 @@ func registerConfig() error {
 			Key:          "password",
 			Label:        "Password",
 			Description:  "MQTT broker password (optional)",
-			Type:         pb.ConfigFieldType_CONFIG_FIELD_TYPE_STRING,
+			Type:         pb.ConfigFieldType_CONFIG_FIELD_TYPE_SECRET,
 			DefaultValue: "",
 		},
 		{
@@ -478,9 +479,9 @@ func handleSubscribe(ctx context.Context, args map[string]string) (string, error
 	})
 	if err != nil {
 		// May already exist, try update
-		updateErr := storage.Update(subscriptionsTable, map[string]string{
+		updateErr := storage.UpdateWhere(subscriptionsTable, map[string]string{
 			"qos": fmt.Sprintf("%d", qos),
-		}, "topic = ?", topic)
+		}, sdk.Eq("topic", topic))
 		if updateErr != nil {
 			log.Printf("[MQTT] Failed to store subscription: insert=%v, update=%v", err, updateErr)
 		}
@@ -502,7 +503,7 @@ func handleUnsubscribe(ctx context.Context, args map[string]string) (string, err
 	}
 
 	// Remove from DB
-	if err := storage.Delete(subscriptionsTable, "topic = ?", topic); err != nil {
+	if err := storage.DeleteWhere(subscriptionsTable, sdk.Eq("topic", topic)); err != nil {
 		log.Printf("[MQTT] Failed to delete subscription: %v", err)
 	}
 
@@ -603,7 +604,11 @@ func handleGetMessages(ctx context.Context, args map[string]string) (string, err
 		}
 	}
 
-	rows, err := storage.Query(messagesTable, nil, "topic = ?", []string{topic}, "timestamp DESC", limit, 0)
+	rows, err := storage.Find(messagesTable, sdk.Select{
+		Filter:  sdk.Eq("topic", topic),
+		OrderBy: []*pb.OrderBy{sdk.Desc("timestamp")},
+		Limit:   limit,
+	})
 	if err != nil {
 		return "", fmt.Errorf("failed to query messages: %w", err)
 	}
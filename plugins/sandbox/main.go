@@ -97,7 +97,7 @@ func main() {
 	}
 	client = sdk.NewClient("sandbox", "1.0.0", "Isolated Docker container execution with gVisor security")
 	client = client.WithSocket(socketPath)
-	client = client.SetDocumentation(pluginDocumentation)
+	client = client.SetDocumentation(pluginDocumentation).SetDocumentationRequired(true)
 
 	// Register skills
 	registerSkills()
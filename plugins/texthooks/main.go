@@ -54,6 +54,7 @@ func main() {
 	// Create SDK client
 	client = sdk.NewClient("texthooks", "0.1.0", "User-defined automation hooks with natural language instructions")
 	client = client.WithSocket(socketPath)
+	client = client.WithPermissions("storage", "chat.write", "llm.request", "events.subscribe:*")
 
 	// Register skills before connecting
 	registerSkills()
@@ -106,22 +107,67 @@ func main() {
 }
 
 func initStorage() error {
-	// Create hooks table
-	columns := []*pb.ColumnDef{
-		{Name: "id", Type: "TEXT", PrimaryKey: true},
-		{Name: "name", Type: "TEXT", NotNull: true},
-		{Name: "events", Type: "TEXT", NotNull: true},    // JSON array
-		{Name: "body", Type: "TEXT", NotNull: true},
-		{Name: "enabled", Type: "TEXT", NotNull: true},   // "true" or "false"
-		{Name: "created_at", Type: "TEXT", NotNull: true},
-		{Name: "updated_at", Type: "TEXT", NotNull: true},
-	}
-
-	if err := storage.CreateTable(hooksTable, columns, true); err != nil {
-		return fmt.Errorf("failed to create hooks table: %w", err)
+	version, err := storage.Migrate([]*pb.Migration{
+		{
+			Version: 1,
+			Name:    "create hooks table",
+			Steps: []*pb.MigrationStep{{Step: &pb.MigrationStep_CreateTable{CreateTable: &pb.CreateTableRequest{
+				TableName: hooksTable,
+				Columns: []*pb.ColumnDef{
+					{Name: "id", Type: "TEXT", PrimaryKey: true},
+					{Name: "name", Type: "TEXT", NotNull: true},
+					{Name: "events", Type: "TEXT", NotNull: true}, // JSON array
+					{Name: "body", Type: "TEXT", NotNull: true},
+					{Name: "enabled", Type: "TEXT", NotNull: true}, // "true" or "false" until version 3
+					{Name: "created_at", Type: "TEXT", NotNull: true},
+					{Name: "updated_at", Type: "TEXT", NotNull: true},
+				},
+				IfNotExists: true, // Adopt tables created before migrations
+			}}}},
+		},
+		{
+			Version: 2,
+			Name:    "index enabled hooks",
+			Steps: []*pb.MigrationStep{{Step: &pb.MigrationStep_CreateIndex{CreateIndex: &pb.CreateIndexStep{
+				IndexName: "hooks_enabled",
+				TableName: hooksTable,
+				Columns:   []string{"enabled"},
+			}}}},
+		},
+		{
+			Version: 3,
+			Name:    "store enabled as boolean",
+			Steps: []*pb.MigrationStep{
+				// SQLite can't change a column's type, so the flag moves to a new column
+				{Step: &pb.MigrationStep_DropIndex{DropIndex: &pb.DropIndexStep{IndexName: "hooks_enabled"}}},
+				{Step: &pb.MigrationStep_AddColumn{AddColumn: &pb.AddColumnStep{
+					TableName: hooksTable,
+					Column:    &pb.ColumnDef{Name: "enabled_flag", Type: "BOOLEAN", NotNull: true, DefaultValue: "0"},
+				}}},
+				{Step: &pb.MigrationStep_Update{Update: &pb.UpdateRequest{
+					TableName:   hooksTable,
+					TypedValues: map[string]*pb.Value{"enabled_flag": sdk.Bool(true)},
+					Filter:      sdk.Eq("enabled", "true"),
+				}}},
+				{Step: &pb.MigrationStep_DropColumn{DropColumn: &pb.DropColumnStep{TableName: hooksTable, Column: "enabled"}}},
+				{Step: &pb.MigrationStep_RenameColumn{RenameColumn: &pb.RenameColumnStep{
+					TableName: hooksTable,
+					Column:    "enabled_flag",
+					NewName:   "enabled",
+				}}},
+				{Step: &pb.MigrationStep_CreateIndex{CreateIndex: &pb.CreateIndexStep{
+					IndexName: "hooks_enabled",
+					TableName: hooksTable,
+					Columns:   []string{"enabled"},
+				}}},
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to migrate hooks table: %w", err)
 	}
 
-	log.Println("Storage initialized")
+	log.Printf("Storage initialized (schema version %d)", version)
 	return nil
 }
 
@@ -243,14 +289,14 @@ func handleCreate(ctx context.Context, args map[string]string) (string, error) {
 	now := time.Now().Format(time.RFC3339)
 	id := uuid.New().String()
 
-	if err := storage.Insert(hooksTable, map[string]string{
-		"id":         id,
-		"name":       name,
-		"events":     string(eventsJSON),
-		"body":       body,
-		"enabled":    "true",
-		"created_at": now,
-		"updated_at": now,
+	if err := storage.InsertTyped(hooksTable, map[string]*pb.Value{
+		"id":         sdk.Text(id),
+		"name":       sdk.Text(name),
+		"events":     sdk.Text(string(eventsJSON)),
+		"body":       sdk.Text(body),
+		"enabled":    sdk.Bool(true),
+		"created_at": sdk.Text(now),
+		"updated_at": sdk.Text(now),
 	}); err != nil {
 		return "", fmt.Errorf("failed to create hook: %w", err)
 	}
@@ -352,7 +398,7 @@ func handleUpdate(ctx context.Context, args map[string]string) (string, error) {
 
 	updates["updated_at"] = time.Now().Format(time.RFC3339)
 
-	if err := storage.Update(hooksTable, updates, "id = ?", hook.ID); err != nil {
+	if err := storage.UpdateWhere(hooksTable, updates, sdk.Eq("id", hook.ID)); err != nil {
 		return "", fmt.Errorf("failed to update hook: %w", err)
 	}
 
@@ -376,7 +422,7 @@ func handleDelete(ctx context.Context, args map[string]string) (string, error) {
 	}
 
 	name := hook.Name
-	if err := storage.Delete(hooksTable, "id = ?", hook.ID); err != nil {
+	if err := storage.DeleteWhere(hooksTable, sdk.Eq("id", hook.ID)); err != nil {
 		return "", fmt.Errorf("failed to delete hook: %w", err)
 	}
 
@@ -403,10 +449,10 @@ func handleEnable(ctx context.Context, args map[string]string) (string, error) {
 		return fmt.Sprintf("Hook '%s' is already enabled", hook.Name), nil
 	}
 
-	if err := storage.Update(hooksTable, map[string]string{
-		"enabled":    "true",
-		"updated_at": time.Now().Format(time.RFC3339),
-	}, "id = ?", hook.ID); err != nil {
+	if err := storage.UpdateTypedWhere(hooksTable, map[string]*pb.Value{
+		"enabled":    sdk.Bool(true),
+		"updated_at": sdk.Text(time.Now().Format(time.RFC3339)),
+	}, sdk.Eq("id", hook.ID)); err != nil {
 		return "", fmt.Errorf("failed to enable hook: %w", err)
 	}
 
@@ -433,10 +479,10 @@ func handleDisable(ctx context.Context, args map[string]string) (string, error)
 		return fmt.Sprintf("Hook '%s' is already disabled", hook.Name), nil
 	}
 
-	if err := storage.Update(hooksTable, map[string]string{
-		"enabled":    "false",
-		"updated_at": time.Now().Format(time.RFC3339),
-	}, "id = ?", hook.ID); err != nil {
+	if err := storage.UpdateTypedWhere(hooksTable, map[string]*pb.Value{
+		"enabled":    sdk.Bool(false),
+		"updated_at": sdk.Text(time.Now().Format(time.RFC3339)),
+	}, sdk.Eq("id", hook.ID)); err != nil {
 		return "", fmt.Errorf("failed to disable hook: %w", err)
 	}
 
@@ -444,25 +490,26 @@ func handleDisable(ctx context.Context, args map[string]string) (string, error)
 }
 
 // rowToHook converts a storage row to a Hook struct
-func rowToHook(row *pb.Row) (*Hook, error) {
+func rowToHook(row sdk.TypedRow) (*Hook, error) {
 	var events []string
-	if err := json.Unmarshal([]byte(row.Values["events"]), &events); err != nil {
+	if err := json.Unmarshal([]byte(row.String("events")), &events); err != nil {
 		return nil, fmt.Errorf("failed to parse events: %w", err)
 	}
 
+	enabled, _ := row.Bool("enabled")
 	return &Hook{
-		ID:        row.Values["id"],
-		Name:      row.Values["name"],
+		ID:        row.String("id"),
+		Name:      row.String("name"),
 		Events:    events,
-		Body:      row.Values["body"],
-		Enabled:   row.Values["enabled"] == "true",
-		CreatedAt: row.Values["created_at"],
-		UpdatedAt: row.Values["updated_at"],
+		Body:      row.String("body"),
+		Enabled:   enabled,
+		CreatedAt: row.String("created_at"),
+		UpdatedAt: row.String("updated_at"),
 	}, nil
 }
 
 func getAllHooks() ([]Hook, error) {
-	rows, err := storage.Query(hooksTable, nil, "", nil, "created_at DESC", 0, 0)
+	rows, err := storage.QueryTyped(hooksTable, nil, "", nil, "created_at DESC", 0, 0)
 	if err != nil {
 		return nil, err
 	}
@@ -481,7 +528,7 @@ func getAllHooks() ([]Hook, error) {
 }
 
 func getEnabledHooks() ([]Hook, error) {
-	rows, err := storage.Query(hooksTable, nil, "enabled = ?", []string{"true"}, "", 0, 0)
+	rows, err := storage.FindTyped(hooksTable, sdk.Select{Filter: sdk.Eq("enabled", true)})
 	if err != nil {
 		return nil, err
 	}
@@ -501,7 +548,7 @@ func getEnabledHooks() ([]Hook, error) {
 
 func findHook(idOrPrefix string) (*Hook, error) {
 	// Try exact match first
-	rows, err := storage.Query(hooksTable, nil, "id = ?", []string{idOrPrefix}, "", 1, 0)
+	rows, err := storage.FindTyped(hooksTable, sdk.Select{Filter: sdk.Eq("id", idOrPrefix), Limit: 1})
 	if err != nil {
 		return nil, err
 	}
@@ -510,7 +557,7 @@ func findHook(idOrPrefix string) (*Hook, error) {
 	}
 
 	// Try prefix match
-	rows, err = storage.Query(hooksTable, nil, "id LIKE ?", []string{idOrPrefix + "%"}, "", 1, 0)
+	rows, err = storage.FindTyped(hooksTable, sdk.Select{Filter: sdk.Like("id", idOrPrefix+"%"), Limit: 1})
 	if err != nil {
 		return nil, err
 	}
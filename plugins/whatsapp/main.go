@@ -29,7 +29,10 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 )
 
-const messagesTable = "messages"
+const (
+	messagesTable      = "messages"
+	chatForgottenEvent = "chat.forgotten" // Published when a user forgets a chat
+)
 
 var (
 	client    *sdk.Client
@@ -63,10 +66,14 @@ func main() {
 	}
 	client = sdk.NewClient("whatsapp", "1.0.0", "WhatsApp messaging integration via whatsmeow")
 	client = client.WithSocket(socketPath)
+	client = client.WithPermissions("storage", "chat.write", "llm.request", "events.subscribe:chat.forgotten")
 
 	// Register skills
 	registerSkills()
 
+	// Delete stored messages of forgotten chats
+	client.OnEvent(handleChatForgotten)
+
 	// Connect to chadbot backend
 	if err := client.Connect(ctx); err != nil {
 		log.Fatalf("[WhatsApp] Failed to connect to backend: %v", err)
@@ -92,6 +99,10 @@ func main() {
 	// Handle chat LLM responses
 	client.OnChatLLMResponse(handleLLMResponse)
 
+	if err := client.Subscribe([]string{chatForgottenEvent}); err != nil {
+		log.Printf("[WhatsApp] Failed to subscribe to %s: %v", chatForgottenEvent, err)
+	}
+
 	// Initialize WhatsApp
 	if err := initWhatsApp(ctx); err != nil {
 		log.Fatalf("[WhatsApp] Failed to initialize WhatsApp: %v", err)
@@ -247,6 +258,8 @@ func handleWhatsAppEvent(evt interface{}) {
 func handleHistorySync(evt *events.HistorySync) {
 	log.Printf("[WhatsApp] History sync received: %d conversations", len(evt.Data.GetConversations()))
 
+	// Store the whole sync in one transaction (messages already stored are refreshed)
+	batch := storage.Batch()
 	for _, conv := range evt.Data.GetConversations() {
 		chatJID := conv.GetID()
 		for _, msg := range conv.GetMessages() {
@@ -287,25 +300,35 @@ func handleHistorySync(evt *events.HistorySync) {
 				}
 			}
 
-			storeMessage(msgInfo.GetID(), chatJID, senderJID, text, int64(timestamp), fromMe)
+			batch.Upsert(messagesTable, messageRow(msgInfo.GetID(), chatJID, senderJID, text, int64(timestamp), fromMe), "id")
 		}
 	}
+
+	if _, err := batch.Commit(); err != nil {
+		log.Printf("[WhatsApp] Failed to store %d synced messages: %v", batch.Len(), err)
+		return
+	}
+	log.Printf("[WhatsApp] Stored %d synced messages", batch.Len())
 }
 
-func storeMessage(msgID, chatJID, senderJID, content string, timestamp int64, fromMe bool) {
+// messageRow builds the storage row of a message
+func messageRow(msgID, chatJID, senderJID, content string, timestamp int64, fromMe bool) map[string]string {
 	fromMeInt := "0"
 	if fromMe {
 		fromMeInt = "1"
 	}
-
-	err := storage.Insert(messagesTable, map[string]string{
+	return map[string]string{
 		"id":         msgID,
 		"chat_jid":   chatJID,
 		"sender_jid": senderJID,
 		"content":    content,
 		"timestamp":  strconv.FormatInt(timestamp, 10),
 		"from_me":    fromMeInt,
-	})
+	}
+}
+
+func storeMessage(msgID, chatJID, senderJID, content string, timestamp int64, fromMe bool) {
+	err := storage.Insert(messagesTable, messageRow(msgID, chatJID, senderJID, content, timestamp, fromMe))
 	if err != nil {
 		// Silently ignore duplicate key errors (message already exists from history sync)
 		// Only log actual errors
@@ -403,7 +426,11 @@ func handleSelfMessage(content, chatJID string) {
 
 func syncHistoricalMessages(chatID, chatJID string) {
 	// Load recent messages from our storage (last 50)
-	rows, err := storage.Query(messagesTable, nil, "chat_jid = ?", []string{chatJID}, "timestamp ASC", 50, 0)
+	rows, err := storage.Find(messagesTable, sdk.Select{
+		Filter:  sdk.Eq("chat_jid", chatJID),
+		OrderBy: []*pb.OrderBy{sdk.Asc("timestamp")},
+		Limit:   50,
+	})
 	if err != nil {
 		log.Printf("[WhatsApp] Failed to load historical messages: %v", err)
 		return
@@ -455,6 +482,28 @@ func handleLLMResponse(resp *pb.ChatLLMResponse) {
 	}
 }
 
+// handleChatForgotten deletes the stored messages of a forgotten WhatsApp chat
+func handleChatForgotten(event *pb.Event) {
+	if event.EventType != chatForgottenEvent {
+		return
+	}
+	payload := event.GetGeneric().GetPayload().AsMap()
+	platform, _ := payload["platform"].(string)
+	chatJID, _ := payload["linked_id"].(string)
+	if platform != "whatsapp" || chatJID == "" {
+		return
+	}
+
+	if err := storage.DeleteWhere(messagesTable, sdk.Eq("chat_jid", chatJID)); err != nil {
+		log.Printf("[WhatsApp] Failed to delete messages of forgotten chat %s: %v", chatJID, err)
+		return
+	}
+	pendingLLMMu.Lock()
+	delete(pendingLLM, chatJID)
+	pendingLLMMu.Unlock()
+	log.Printf("[WhatsApp] Deleted stored messages of forgotten chat %s", chatJID)
+}
+
 func handleSendMessage(ctx context.Context, args map[string]string) (string, error) {
 	to := args["to"]
 	message := args["message"]
@@ -641,7 +690,12 @@ func handleGetChatHistory(ctx context.Context, args map[string]string) (string,
 	}
 
 	// Query messages from storage
-	rows, err := storage.Query(messagesTable, nil, "chat_jid = ?", []string{chatJID}, "timestamp DESC", limit, offset)
+	rows, err := storage.Find(messagesTable, sdk.Select{
+		Filter:  sdk.Eq("chat_jid", chatJID),
+		OrderBy: []*pb.OrderBy{sdk.Desc("timestamp")},
+		Limit:   limit,
+		Offset:  offset,
+	})
 	if err != nil {
 		return "", fmt.Errorf("failed to query messages: %w", err)
 	}
@@ -4,6 +4,7 @@ import (
 	"context"
 	_ "embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -11,6 +12,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -36,7 +38,10 @@ const (
 	pluginVersion = "1.0.0"
 	pluginDesc    = "Scroll through X.com (Twitter) with human-like behavior and extract tweets"
 
-	tweetsTable = "tweets"
+	// Tweets are stored in the key-value store under tweetKeyPrefix+id.
+	// tweetsTable is the pre-KV table, migrated and dropped on startup.
+	tweetKeyPrefix = "tweet/"
+	tweetsTable    = "tweets"
 
 	// Default values
 	defaultDebugPort        = 9222
@@ -50,6 +55,7 @@ const (
 var (
 	client  *sdk.Client
 	storage *sdk.StorageClient
+	kv      *sdk.KVClient
 
 	// Scroll session state
 	sessionMu      sync.Mutex
@@ -93,6 +99,7 @@ func main() {
 
 	client = sdk.NewClient(pluginName, pluginVersion, pluginDesc)
 	client = client.SetDocumentation(pluginDocumentation)
+	client = client.WithPermissions("storage", "events.emit:xscroll.tweet.*")
 
 	// Register skills
 	registerSkills()
@@ -104,13 +111,11 @@ func main() {
 
 	// Initialize storage
 	storage = client.Storage()
+	kv = client.KV()
 	if err := initStorage(); err != nil {
 		log.Fatalf("Failed to initialize storage: %v", err)
 	}
 
-	// Start cleanup goroutine
-	go cleanupRoutine(ctx)
-
 	// Handle shutdown
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
@@ -129,26 +134,79 @@ func main() {
 }
 
 func initStorage() error {
-	columns := []*pb.ColumnDef{
-		{Name: "id", Type: "TEXT", PrimaryKey: true},
-		{Name: "url", Type: "TEXT"},
-		{Name: "author_name", Type: "TEXT"},
-		{Name: "author_handle", Type: "TEXT"},
-		{Name: "content", Type: "TEXT"},
-		{Name: "timestamp", Type: "TEXT"},
-		{Name: "images", Type: "TEXT"},       // JSON array
-		{Name: "videos", Type: "TEXT"},       // JSON array
-		{Name: "extracted_at", Type: "INTEGER"},
-	}
-
-	if err := storage.CreateTable(tweetsTable, columns, true); err != nil {
-		return fmt.Errorf("failed to create tweets table: %w", err)
+	if err := migrateLegacyTweets(); err != nil {
+		return fmt.Errorf("failed to migrate tweets table: %w", err)
 	}
 
 	log.Println("Storage initialized")
 	return nil
 }
 
+// migrateLegacyTweets moves tweets from the old tweets table into the
+// key-value store, keeping their remaining retention, then drops the table.
+func migrateLegacyTweets() error {
+	rows, err := storage.Query(tweetsTable, nil, "", nil, "", 0, 0)
+	if err != nil && !strings.Contains(err.Error(), "no such table") {
+		return err
+	}
+	migrated := 0
+	for _, row := range rows {
+		extractedAt, _ := strconv.ParseInt(row.Values["extracted_at"], 10, 64)
+		tweet := Tweet{
+			ID:           row.Values["id"],
+			URL:          row.Values["url"],
+			AuthorName:   row.Values["author_name"],
+			AuthorHandle: row.Values["author_handle"],
+			Content:      row.Values["content"],
+			Timestamp:    row.Values["timestamp"],
+			ExtractedAt:  extractedAt,
+		}
+		json.Unmarshal([]byte(row.Values["images"]), &tweet.Images)
+		json.Unmarshal([]byte(row.Values["videos"]), &tweet.Videos)
+		if tweet.ID == "" || tweetTTL(tweet) <= 0 {
+			continue
+		}
+		if err := saveTweet(tweet); err != nil {
+			return err
+		}
+		migrated++
+	}
+	if migrated > 0 {
+		log.Printf("Migrated %d tweets to key-value storage", migrated)
+	}
+
+	_, err = storage.Migrate([]*pb.Migration{{
+		Version: 1,
+		Name:    "drop tweets table",
+		Steps: []*pb.MigrationStep{{Step: &pb.MigrationStep_DropTable{DropTable: &pb.DropTableRequest{
+			TableName: tweetsTable,
+			IfExists:  true,
+		}}}},
+	}})
+	return err
+}
+
+// loadTweets returns all stored tweets, newest first
+func loadTweets() ([]Tweet, error) {
+	entries, err := kv.List(tweetKeyPrefix)
+	if err != nil {
+		return nil, err
+	}
+	tweets := make([]Tweet, 0, len(entries))
+	for _, entry := range entries {
+		var tweet Tweet
+		if err := json.Unmarshal(entry.Value, &tweet); err != nil {
+			log.Printf("Skipping invalid tweet %s: %v", entry.Key, err)
+			continue
+		}
+		tweets = append(tweets, tweet)
+	}
+	sort.Slice(tweets, func(i, j int) bool {
+		return tweets[i].ExtractedAt > tweets[j].ExtractedAt
+	})
+	return tweets, nil
+}
+
 func registerSkills() {
 	// xscroll_start - Start scrolling and extracting
 	client.RegisterSkill(&pb.Skill{
@@ -301,11 +359,11 @@ func handleStatus(ctx context.Context, args map[string]string) (string, error) {
 
 	if !running {
 		// Get total tweets in database
-		rows, err := storage.Query(tweetsTable, nil, "", nil, "", 0, 0)
+		keys, err := kv.Keys(tweetKeyPrefix)
 		if err != nil {
 			return "No scroll session running. Failed to query database.", nil
 		}
-		return fmt.Sprintf("No scroll session running.\n\nDatabase contains %d tweets.", len(rows)), nil
+		return fmt.Sprintf("No scroll session running.\n\nDatabase contains %d tweets.", len(keys)), nil
 	}
 
 	duration := time.Since(stats.StartedAt).Round(time.Second)
@@ -323,55 +381,56 @@ func handleStatus(ctx context.Context, args map[string]string) (string, error) {
 }
 
 func handleGetTweets(ctx context.Context, args map[string]string) (string, error) {
-	limit := int32(50)
+	limit := 50
 	if v, ok := args["limit"]; ok && v != "" {
 		if l, err := strconv.Atoi(v); err == nil && l > 0 {
-			limit = int32(l)
+			limit = l
 		}
 	}
 
-	var whereClause string
-	var whereArgs []string
-
 	// Filter by time
+	var since int64
 	if v, ok := args["since_hours"]; ok && v != "" {
 		if h, err := strconv.ParseFloat(v, 64); err == nil && h > 0 {
-			sinceTime := time.Now().Add(-time.Duration(h * float64(time.Hour))).Unix()
-			whereClause = "extracted_at > ?"
-			whereArgs = append(whereArgs, strconv.FormatInt(sinceTime, 10))
+			since = time.Now().Add(-time.Duration(h * float64(time.Hour))).Unix()
 		}
 	}
 
 	// Filter by author
-	if v, ok := args["author"]; ok && v != "" {
-		author := strings.TrimPrefix(v, "@")
-		if whereClause != "" {
-			whereClause += " AND author_handle LIKE ?"
-		} else {
-			whereClause = "author_handle LIKE ?"
-		}
-		whereArgs = append(whereArgs, "%"+author+"%")
-	}
+	author := strings.ToLower(strings.TrimPrefix(args["author"], "@"))
 
-	rows, err := storage.Query(tweetsTable, nil, whereClause, whereArgs, "extracted_at DESC", limit, 0)
+	tweets, err := loadTweets()
 	if err != nil {
 		return "", fmt.Errorf("failed to query tweets: %w", err)
 	}
 
-	if len(rows) == 0 {
+	var matched []Tweet
+	for _, tweet := range tweets {
+		if tweet.ExtractedAt <= since {
+			continue
+		}
+		if author != "" && !strings.Contains(strings.ToLower(tweet.AuthorHandle), author) {
+			continue
+		}
+		matched = append(matched, tweet)
+		if len(matched) == limit {
+			break
+		}
+	}
+
+	if len(matched) == 0 {
 		return "No tweets found matching the criteria", nil
 	}
 
 	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf("Found %d tweets:\n\n", len(rows)))
+	sb.WriteString(fmt.Sprintf("Found %d tweets:\n\n", len(matched)))
 
-	for _, row := range rows {
-		extractedAt, _ := strconv.ParseInt(row.Values["extracted_at"], 10, 64)
-		t := time.Unix(extractedAt, 0)
+	for _, tweet := range matched {
+		t := time.Unix(tweet.ExtractedAt, 0)
 
-		sb.WriteString(fmt.Sprintf("**@%s** (%s)\n", row.Values["author_handle"], row.Values["author_name"]))
-		sb.WriteString(fmt.Sprintf("%s\n", truncate(row.Values["content"], 200)))
-		sb.WriteString(fmt.Sprintf("_%s_ | %s\n\n", row.Values["timestamp"], t.Format("2006-01-02 15:04")))
+		sb.WriteString(fmt.Sprintf("**@%s** (%s)\n", tweet.AuthorHandle, tweet.AuthorName))
+		sb.WriteString(fmt.Sprintf("%s\n", truncate(tweet.Content, 200)))
+		sb.WriteString(fmt.Sprintf("_%s_ | %s\n\n", tweet.Timestamp, t.Format("2006-01-02 15:04")))
 	}
 
 	return sb.String(), nil
@@ -680,31 +739,27 @@ func getStringSlice(m map[string]interface{}, key string) []string {
 	return result
 }
 
+// saveTweet stores a tweet unless it already exists. The entry expires once
+// the retention period has passed since extraction.
 func saveTweet(tweet Tweet) error {
-	// Check if tweet already exists in database
-	rows, err := storage.Query(tweetsTable, []string{"id"}, "id = ?", []string{tweet.ID}, "", 1, 0)
+	value, err := json.Marshal(tweet)
 	if err != nil {
 		return err
 	}
-	if len(rows) > 0 {
+
+	// Expected version 0 only succeeds if the key does not exist yet
+	_, err = kv.CompareAndSwap(tweetKeyPrefix+tweet.ID, 0, value, tweetTTL(tweet))
+	if errors.Is(err, sdk.ErrConflict) {
 		// Already exists, skip
 		return nil
 	}
+	return err
+}
 
-	imagesJSON, _ := json.Marshal(tweet.Images)
-	videosJSON, _ := json.Marshal(tweet.Videos)
-
-	return storage.Insert(tweetsTable, map[string]string{
-		"id":            tweet.ID,
-		"url":           tweet.URL,
-		"author_name":   tweet.AuthorName,
-		"author_handle": tweet.AuthorHandle,
-		"content":       tweet.Content,
-		"timestamp":     tweet.Timestamp,
-		"images":        string(imagesJSON),
-		"videos":        string(videosJSON),
-		"extracted_at":  strconv.FormatInt(tweet.ExtractedAt, 10),
-	})
+// tweetTTL returns how long a tweet is kept after it was extracted
+func tweetTTL(tweet Tweet) time.Duration {
+	expiresAt := time.Unix(tweet.ExtractedAt, 0).Add(time.Duration(retentionDays) * 24 * time.Hour)
+	return time.Until(expiresAt)
 }
 
 func emitTweetEvent(tweet Tweet) {
@@ -749,32 +804,6 @@ func toInterfaceSlice(s []string) []interface{} {
 	return result
 }
 
-func cleanupRoutine(ctx context.Context) {
-	ticker := time.NewTicker(1 * time.Hour)
-	defer ticker.Stop()
-
-	// Run immediately on startup
-	cleanupOldTweets()
-
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			cleanupOldTweets()
-		}
-	}
-}
-
-func cleanupOldTweets() {
-	cutoff := time.Now().Add(-time.Duration(retentionDays) * 24 * time.Hour).Unix()
-	if err := storage.Delete(tweetsTable, "extracted_at < ?", strconv.FormatInt(cutoff, 10)); err != nil {
-		log.Printf("Failed to cleanup old tweets: %v", err)
-	} else {
-		log.Printf("Cleaned up tweets older than %d days", retentionDays)
-	}
-}
-
 func truncate(s string, maxLen int) string {
 	if len(s) <= maxLen {
 		return s